@@ -94,7 +94,9 @@ func (b *BlockChain) CommitBlock(block *util.Block) (newTip, reorg bool, newHeig
 	newHeight = parentHeader.Height + 1
 	header.Height = newHeight
 	header.TotalWork = cumulativeWork
-	fps, err = b.db.CommitBlock(block, newTip)
+	// A new tip causing a reorganize is saved as a fork block, it becomes the
+	// chain tip in ProcessReorganize.
+	fps, err = b.db.CommitBlock(block, newTip && !reorg)
 	if err != nil {
 		return newTip, reorg, 0, 0, err
 	}
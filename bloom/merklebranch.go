@@ -1,19 +1,113 @@
 package bloom
 
 import (
+	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
+
 	"github.com/elastos/Elastos.ELA.SPV/util"
 
+	"github.com/elastos/Elastos.ELA/auxpow"
 	"github.com/elastos/Elastos.ELA/common"
 	"github.com/elastos/Elastos.ELA/p2p/msg"
 )
 
+// maxMerkleBranches is the maximum branches of a merkle branch, which is the
+// depth of the merkle tree of a block.
+const maxMerkleBranches = 32
+
 type MerkleBranch struct {
 	Branches []common.Uint256
 	Index    int
 }
 
+// MerkleRoot returns the merkle root calculated from the transaction hash
+// and the branches.
+func (b *MerkleBranch) MerkleRoot(txId common.Uint256) common.Uint256 {
+	return auxpow.GetMerkleRoot(txId, b.Branches, b.Index)
+}
+
+func (b *MerkleBranch) Serialize(w io.Writer) error {
+	if len(b.Branches) > maxMerkleBranches {
+		str := fmt.Sprintf("too many merkle branches [count %v, max %v]",
+			len(b.Branches), maxMerkleBranches)
+		return common.FuncError("MerkleBranch.Serialize", str)
+	}
+	if err := common.WriteUint32(w, uint32(b.Index)); err != nil {
+		return err
+	}
+	if err := common.WriteVarUint(w, uint64(len(b.Branches))); err != nil {
+		return err
+	}
+	for _, branch := range b.Branches {
+		if err := branch.Serialize(w); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (b *MerkleBranch) Deserialize(r io.Reader) error {
+	index, err := common.ReadUint32(r)
+	if err != nil {
+		return err
+	}
+	count, err := common.ReadVarUint(r, 0)
+	if err != nil {
+		return err
+	}
+	if count > maxMerkleBranches {
+		return fmt.Errorf("MerkleBranch.Deserialize too many merkle"+
+			" branches [count %v, max %v]", count, maxMerkleBranches)
+	}
+	b.Index = int(index)
+	b.Branches = make([]common.Uint256, count)
+	for i := range b.Branches {
+		if err := b.Branches[i].Deserialize(r); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// merkleBranchJSON is the JSON format of MerkleBranch, hashes are encoded as
+// reversed hex string the same as main chain RPC.
+type merkleBranchJSON struct {
+	Branches []string `json:"branches"`
+	Index    int      `json:"index"`
+}
+
+func (b MerkleBranch) MarshalJSON() ([]byte, error) {
+	branches := make([]string, 0, len(b.Branches))
+	for _, branch := range b.Branches {
+		branches = append(branches, branch.String())
+	}
+	return json.Marshal(merkleBranchJSON{Branches: branches, Index: b.Index})
+}
+
+func (b *MerkleBranch) UnmarshalJSON(data []byte) error {
+	var branch merkleBranchJSON
+	if err := json.Unmarshal(data, &branch); err != nil {
+		return err
+	}
+	if len(branch.Branches) > maxMerkleBranches {
+		return fmt.Errorf("MerkleBranch.UnmarshalJSON too many merkle"+
+			" branches [count %v, max %v]", len(branch.Branches), maxMerkleBranches)
+	}
+	branches := make([]common.Uint256, 0, len(branch.Branches))
+	for _, h := range branch.Branches {
+		hash, err := common.Uint256FromHexString(h)
+		if err != nil {
+			return err
+		}
+		branches = append(branches, *hash)
+	}
+	b.Branches = branches
+	b.Index = branch.Index
+	return nil
+}
+
 func GetTxMerkleBranch(msg msg.MerkleBlock, txId *common.Uint256) (*MerkleBranch, error) {
 	mNodes := &merkleNodes{
 		root:     msg.Header.(util.BlockHeader).MerkleRoot(),
@@ -43,7 +137,9 @@ func (m *merkleNodes) GetMerkleBranch(txId *common.Uint256) (mb *MerkleBranch, e
 		return nil, err
 	}
 
-	m.calcTxIndex(txId)
+	if err := m.calcTxIndex(txId); err != nil {
+		return nil, err
+	}
 	m.calcBranchRoute()
 
 	mb = new(MerkleBranch)
@@ -1,7 +1,9 @@
 package bloom
 
 import (
+	"bytes"
 	"crypto/rand"
+	"encoding/json"
 	"fmt"
 	"os"
 	"testing"
@@ -12,6 +14,8 @@ import (
 	"github.com/elastos/Elastos.ELA/p2p/msg"
 
 	"github.com/elastos/Elastos.ELA.SPV/util"
+
+	"github.com/stretchr/testify/assert"
 )
 
 // Ensure header implement BlockHeader interface.
@@ -43,6 +47,30 @@ func TestMerkleBlock_GetTxMerkleBranch(t *testing.T) {
 	}
 }
 
+func TestMerkleBranch_Serialize(t *testing.T) {
+	txId := *randHash()
+	branch := MerkleBranch{Index: 5}
+	for i := 0; i < 4; i++ {
+		branch.Branches = append(branch.Branches, *randHash())
+	}
+	merkleRoot := branch.MerkleRoot(txId)
+	assert.Equal(t, auxpow.GetMerkleRoot(txId, branch.Branches, branch.Index),
+		merkleRoot)
+
+	buf := new(bytes.Buffer)
+	assert.NoError(t, branch.Serialize(buf))
+	var decoded MerkleBranch
+	assert.NoError(t, decoded.Deserialize(buf))
+	assert.Equal(t, branch, decoded)
+
+	data, err := json.Marshal(branch)
+	assert.NoError(t, err)
+	decoded = MerkleBranch{}
+	assert.NoError(t, json.Unmarshal(data, &decoded))
+	assert.Equal(t, branch, decoded)
+	assert.Equal(t, merkleRoot, decoded.MerkleRoot(txId))
+}
+
 func run(txs uint32) {
 	mBlock := mBlock{
 		NumTx:       txs,
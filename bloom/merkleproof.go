@@ -1,6 +1,8 @@
 package bloom
 
 import (
+	"encoding/hex"
+	"encoding/json"
 	"fmt"
 	"io"
 
@@ -83,3 +85,66 @@ func (p *MerkleProof) Deserialize(r io.Reader) error {
 		"merkle proof flags size")
 	return err
 }
+
+// merkleProofJSON is the JSON format of MerkleProof, hashes are encoded as
+// reversed hex string the same as main chain RPC.
+type merkleProofJSON struct {
+	BlockHash    string   `json:"blockhash"`
+	Height       uint32   `json:"height"`
+	Transactions uint32   `json:"transactions"`
+	Hashes       []string `json:"hashes"`
+	Flags        string   `json:"flags"`
+}
+
+func (p MerkleProof) MarshalJSON() ([]byte, error) {
+	hashes := make([]string, 0, len(p.Hashes))
+	for _, hash := range p.Hashes {
+		hashes = append(hashes, hash.String())
+	}
+	return json.Marshal(merkleProofJSON{
+		BlockHash:    p.BlockHash.String(),
+		Height:       p.Height,
+		Transactions: p.Transactions,
+		Hashes:       hashes,
+		Flags:        hex.EncodeToString(p.Flags),
+	})
+}
+
+func (p *MerkleProof) UnmarshalJSON(data []byte) error {
+	var proof merkleProofJSON
+	if err := json.Unmarshal(data, &proof); err != nil {
+		return err
+	}
+
+	if len(proof.Hashes) > int(pact.MaxTxPerBlock) {
+		return fmt.Errorf("MerkleProof.UnmarshalJSON too many transaction"+
+			" hashes [count %v, max %v]", len(proof.Hashes), pact.MaxTxPerBlock)
+	}
+	blockHash, err := common.Uint256FromHexString(proof.BlockHash)
+	if err != nil {
+		return err
+	}
+	hashes := make([]*common.Uint256, 0, len(proof.Hashes))
+	for _, h := range proof.Hashes {
+		hash, err := common.Uint256FromHexString(h)
+		if err != nil {
+			return err
+		}
+		hashes = append(hashes, hash)
+	}
+	flags, err := hex.DecodeString(proof.Flags)
+	if err != nil {
+		return err
+	}
+	if len(flags) > int(maxFlagsPerMerkleProof) {
+		return fmt.Errorf("MerkleProof.UnmarshalJSON too many flag bytes"+
+			" [count %v, max %v]", len(flags), maxFlagsPerMerkleProof)
+	}
+
+	p.BlockHash = *blockHash
+	p.Height = proof.Height
+	p.Transactions = proof.Transactions
+	p.Hashes = hashes
+	p.Flags = flags
+	return nil
+}
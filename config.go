@@ -34,6 +34,10 @@ type configParams struct {
 	PermanentPeers []string
 	RPCPort        uint16
 	DebugLevel     string
+
+	// EnableProofRPC enables the proof provider RPC actions gettxproof,
+	// getheader and getheaders.
+	EnableProofRPC bool
 }
 
 func loadConfig() *configParams {
@@ -9,6 +9,7 @@ import (
 type chainDB struct {
 	h Headers
 	t TxsDB
+	j ReorgJournal
 }
 
 // Headers returns the headers database that stored
@@ -35,77 +36,140 @@ func (d *chainDB) CommitBlock(block *util.Block, newTip bool) (fps uint32, err e
 
 // ProcessReorganize switch chain data to the new best chain.
 func (d *chainDB) ProcessReorganize(commonAncestor, prevTip, newTip *util.Header) error {
-	// 1. Move previous main chain data to fork.
-	root := commonAncestor.Hash()
-	header := prevTip
-	hash := header.Hash()
-	for !hash.IsEqual(root) {
-		// Move transactions to fork.
-		txs, err := d.t.GetTxs(header.Height)
-		if err != nil {
+	record := &ReorgRecord{
+		CommonAncestor: commonAncestor.Hash(),
+		PrevTip:        prevTip.Hash(),
+		NewTip:         newTip.Hash(),
+		Stage:          ReorgDisconnect,
+		Height:         commonAncestor.Height + 1,
+	}
+	if err := d.j.Put(record); err != nil {
+		return err
+	}
+	return d.reorganize(record, false)
+}
+
+// recover resumes the reorganize interrupted by a crash if any.
+func (d *chainDB) recover() error {
+	record, err := d.j.Get()
+	if err != nil {
+		return err
+	}
+	if record == nil {
+		return nil
+	}
+	return d.reorganize(record, true)
+}
+
+// reorganize runs the reorganize from the stage in record and saves the
+// progress into journal, every step can be replayed so the reorganize can be
+// resumed after a crash.
+func (d *chainDB) reorganize(record *ReorgRecord, resume bool) error {
+	prevTip, err := d.h.Get(&record.PrevTip)
+	if err != nil {
+		return err
+	}
+	newTip, err := d.h.Get(&record.NewTip)
+	if err != nil {
+		return err
+	}
+	oldChain, err := d.branch(record.CommonAncestor, prevTip)
+	if err != nil {
+		return err
+	}
+	newChain, err := d.branch(record.CommonAncestor, newTip)
+	if err != nil {
+		return err
+	}
+
+	// 1. Copy previous main chain data to fork, main chain transactions are
+	// not changed in this stage, so it is safe to copy them again.
+	if record.Stage == ReorgDisconnect {
+		for _, header := range oldChain {
+			txs, err := d.t.GetTxs(header.Height)
+			if err != nil {
+				return err
+			}
+			hash := header.Hash()
+			if err := d.t.PutForkTxs(txs, &hash); err != nil {
+				return err
+			}
+		}
+		record.Stage = ReorgDelete
+		if err := d.j.Put(record); err != nil {
 			return err
 		}
-		err = d.t.PutForkTxs(txs, &hash)
-		if err != nil {
+	}
+
+	// 2. Delete previous main chain transactions from the tip.
+	if record.Stage == ReorgDelete {
+		for _, header := range oldChain {
+			if err := d.t.DelTxs(header.Height); err != nil {
+				return err
+			}
+		}
+		record.Stage = ReorgConnect
+		if err := d.j.Put(record); err != nil {
 			return err
 		}
+	}
+
+	// 3. Move new best chain data from fork to main chain.  It is important
+	// to put transactions by order, so we can process UTXOs STXOs correctly.
+	for i := len(newChain) - 1; i >= 0; i-- {
+		header := newChain[i]
+		if header.Height < record.Height {
+			continue
+		}
 
-		// Delete transactions from main chain.
-		err = d.t.DelTxs(header.Height)
+		// The transactions may be put before the crash, delete them to avoid
+		// putting transactions twice.
+		if resume {
+			if err := d.t.DelTxs(header.Height); err != nil {
+				return err
+			}
+			resume = false
+		}
+
+		hash := header.Hash()
+		txs, err := d.t.GetForkTxs(&hash)
 		if err != nil {
 			return err
 		}
+		if _, err := d.t.PutTxs(txs, header.Height); err != nil {
+			return err
+		}
 
-		// Move to previous header.
-		header, err = d.h.GetPrevious(header)
-		if err != nil {
+		record.Height = header.Height + 1
+		if err := d.j.Put(record); err != nil {
 			return err
 		}
-		hash = header.Hash()
 	}
 
-	// 2. Move new best chain data from fork to main chain.
-	// subChain stores all fork chain headers by their previous hash, so we can
-	// index next header by previous header hash.
-	subChain := make(map[common.Uint256]*util.Header)
-	header = newTip
-	hash = header.Hash()
+	// Set new chain tip.
+	if err := d.h.Put(newTip, true); err != nil {
+		return err
+	}
+	return d.j.Delete()
+}
+
+// branch returns the headers from tip to the header next to root.
+func (d *chainDB) branch(root common.Uint256, tip *util.Header) ([]*util.Header, error) {
+	var headers []*util.Header
+	header := tip
+	hash := header.Hash()
 	for !hash.IsEqual(root) {
-		// Index header by it's previous header hash.
-		subChain[header.Previous()] = header
+		headers = append(headers, header)
 
 		// Move to previous header.
 		var err error
 		header, err = d.h.GetPrevious(header)
 		if err != nil {
-			return err
+			return nil, err
 		}
 		hash = header.Hash()
 	}
-
-	// Connect sub chain to main chain from fork root.  It is important to put
-	// transactions by order, so we can process UTXOs STXOs correctly.
-	tip := newTip.Hash()
-	header = subChain[root]
-	newRoot := header.Hash()
-	for !newRoot.IsEqual(tip) {
-		// Put transactions to main chain.
-		txs, err := d.t.GetForkTxs(&newRoot)
-		if err != nil {
-			return err
-		}
-		_, err = d.t.PutTxs(txs, header.Height)
-		if err != nil {
-			return err
-		}
-
-		// Move to next header.
-		header = subChain[newRoot]
-		newRoot = header.Hash()
-	}
-
-	// Set new chain tip.
-	return d.h.Put(newTip, true)
+	return headers, nil
 }
 
 // Clear delete all data in database.
@@ -13,13 +13,31 @@ type ChainStore interface {
 	Headers() Headers
 
 	// CommitBlock save a block into database, returns how many
-	// false positive transactions are and error.
+	// false positive transactions are and error.  newTip must be set only
+	// if the block extends the current best header, a new tip causing a
+	// reorganize is saved as a fork block and becomes the chain tip in
+	// ProcessReorganize, so the main chain is not changed before the
+	// reorganize journal written.
 	CommitBlock(block *util.Block, newTip bool) (fps uint32, err error)
 
 	// ProcessReorganize switch chain data to the new best chain.
 	ProcessReorganize(commonAncestor, prevTip, newTip *util.Header) error
 }
 
+// NewChainDB creates a chain store on the headers and transactions database,
+// the reorganize journal is kept in memory so an interrupted reorganize can
+// not be resumed after restart, use NewChainDBWithJournal instead.
 func NewChainDB(h Headers, t TxsDB) ChainStore {
-	return &chainDB{h: h, t: t}
+	return &chainDB{h: h, t: t, j: NewMemoryJournal()}
+}
+
+// NewChainDBWithJournal creates a chain store on the headers and transactions
+// database, and writes the reorganize progress into the journal.  A reorganize
+// interrupted by a crash is resumed before returning the chain store.
+func NewChainDBWithJournal(h Headers, t TxsDB, j ReorgJournal) (ChainStore, error) {
+	db := &chainDB{h: h, t: t, j: j}
+	if err := db.recover(); err != nil {
+		return nil, err
+	}
+	return db, nil
 }
@@ -0,0 +1,354 @@
+// Package dbtest provides the conformance tests shared by all database
+// backends, so every implementation behaves the same to the blockchain.
+package dbtest
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"io"
+	"testing"
+
+	"github.com/elastos/Elastos.ELA.SPV/database"
+	"github.com/elastos/Elastos.ELA.SPV/util"
+
+	"github.com/elastos/Elastos.ELA/common"
+	"github.com/stretchr/testify/assert"
+)
+
+// NewHeader creates a header of the backend connected to the previous header,
+// previous is nil for the genesis header. Headers created with different nonce
+// must have different hashes.
+type NewHeader func(previous *util.Header, timestamp, nonce uint32) *util.Header
+
+// tx is a minimal util.Transaction used to check transactions are moved
+// between main chain and fork chains correctly.
+type tx struct {
+	id uint32
+}
+
+func (t *tx) Hash() common.Uint256 {
+	var buf [4]byte
+	binary.BigEndian.PutUint32(buf[:], t.id)
+	return sha256.Sum256(buf[:])
+}
+
+func (t *tx) Serialize(w io.Writer) error {
+	return binary.Write(w, binary.BigEndian, t.id)
+}
+
+func (t *tx) Deserialize(r io.Reader) error {
+	return binary.Read(r, binary.BigEndian, &t.id)
+}
+
+func (t *tx) MatchFilter(filter util.Filter) bool {
+	return true
+}
+
+func newBlock(header *util.Header, ids ...uint32) *util.Block {
+	block := &util.Block{Header: *header}
+	for _, id := range ids {
+		block.Transactions = append(block.Transactions, &tx{id: id})
+	}
+	return block
+}
+
+func txIds(txs []util.Transaction) []uint32 {
+	ids := make([]uint32, 0, len(txs))
+	for _, t := range txs {
+		ids = append(ids, t.(*tx).id)
+	}
+	return ids
+}
+
+// TestChainHeaders checks the headers database saves headers, indexes the
+// best chain and follows the best chain changes. The database must be empty.
+func TestChainHeaders(t *testing.T, headers database.ChainHeaders, newHeader NewHeader) {
+	_, err := headers.GetBest()
+	assert.Error(t, err)
+
+	// main chain from height 0 to 10, timestamps increase by 10 seconds.
+	var chain []*util.Header
+	var previous *util.Header
+	for i := uint32(0); i <= 10; i++ {
+		header := newHeader(previous, 1000+i*10, 0)
+		if !assert.NoError(t, headers.Put(header, true)) {
+			t.FailNow()
+		}
+		chain = append(chain, header)
+		previous = header
+	}
+
+	best, err := headers.GetBest()
+	assert.NoError(t, err)
+	assert.Equal(t, chain[10].Hash(), best.Hash())
+	hash := chain[5].Hash()
+	header, err := headers.Get(&hash)
+	assert.NoError(t, err)
+	assert.Equal(t, uint32(5), header.Height)
+	header, err = headers.GetPrevious(chain[5])
+	assert.NoError(t, err)
+	assert.Equal(t, chain[4].Hash(), header.Hash())
+
+	// fork header is saved but not indexed.
+	fork := newHeader(chain[7], 1080, 1)
+	assert.NoError(t, headers.Put(fork, false))
+	hash = fork.Hash()
+	_, err = headers.Get(&hash)
+	assert.NoError(t, err)
+	header, err = headers.GetByHeight(8)
+	assert.NoError(t, err)
+	assert.Equal(t, chain[8].Hash(), header.Hash())
+
+	result, err := headers.GetRange(2, 4)
+	assert.NoError(t, err)
+	if assert.Equal(t, 3, len(result)) {
+		for i, header := range result {
+			assert.Equal(t, chain[2+i].Hash(), header.Hash())
+		}
+	}
+	header, err = headers.GetByTime(1035)
+	assert.NoError(t, err)
+	assert.Equal(t, uint32(4), header.Height)
+	_, err = headers.GetByTime(1101)
+	assert.Error(t, err)
+	mtp, err := headers.MedianTimePast(10)
+	assert.NoError(t, err)
+	assert.Equal(t, uint32(1050), mtp)
+
+	// switch to the shorter fork chain.
+	fork9 := newHeader(fork, 1090, 1)
+	assert.NoError(t, headers.Put(fork9, true))
+	best, err = headers.GetBest()
+	assert.NoError(t, err)
+	assert.Equal(t, fork9.Hash(), best.Hash())
+	result, err = headers.GetRange(7, 9)
+	assert.NoError(t, err)
+	if assert.Equal(t, 3, len(result)) {
+		assert.Equal(t, chain[7].Hash(), result[0].Hash())
+		assert.Equal(t, fork.Hash(), result[1].Hash())
+		assert.Equal(t, fork9.Hash(), result[2].Hash())
+	}
+	_, err = headers.GetByHeight(10)
+	assert.Error(t, err)
+
+	assert.NoError(t, headers.Clear())
+	_, err = headers.GetBest()
+	assert.Error(t, err)
+	_, err = headers.GetByHeight(0)
+	assert.Error(t, err)
+}
+
+// newChains commits the main chain from height 0 to 3, each block has a
+// transaction with id equals to the height, and the fork blocks on height 2
+// and 3 with transaction ids 102 and 103.  Returns the main chain headers and
+// the fork header on height 4, which is not committed.
+func newChains(t *testing.T, store database.ChainStore,
+	newHeader NewHeader) ([]*util.Header, *util.Header) {
+	var chain []*util.Header
+	var previous *util.Header
+	for i := uint32(0); i <= 3; i++ {
+		header := newHeader(previous, 1000+i*10, 0)
+		_, err := store.CommitBlock(newBlock(header, i), true)
+		if !assert.NoError(t, err) {
+			t.FailNow()
+		}
+		chain = append(chain, header)
+		previous = header
+	}
+
+	fork2 := newHeader(chain[1], 1020, 1)
+	_, err := store.CommitBlock(newBlock(fork2, 102), false)
+	assert.NoError(t, err)
+	fork3 := newHeader(fork2, 1030, 1)
+	_, err = store.CommitBlock(newBlock(fork3, 103), false)
+	assert.NoError(t, err)
+	return chain, newHeader(fork3, 1040, 1)
+}
+
+// reorganize commits the fork header on height 4, which causes a reorganize,
+// as a fork block and switches to the fork chain.
+func reorganize(store database.ChainStore, chain []*util.Header,
+	fork4 *util.Header) error {
+	if _, err := store.CommitBlock(newBlock(fork4, 104), false); err != nil {
+		return err
+	}
+	return store.ProcessReorganize(chain[1], chain[3], fork4)
+}
+
+// checkReorganized checks the fork chain becomes the best chain, the old main
+// chain transactions are moved to fork, and the new best chain transactions
+// are on the main chain.
+func checkReorganized(t *testing.T, store database.ChainStore,
+	txs database.TxsDB, chain []*util.Header, fork4 *util.Header) {
+	best, err := store.Headers().GetBest()
+	assert.NoError(t, err)
+	assert.Equal(t, fork4.Hash(), best.Hash())
+
+	for i, ids := range [][]uint32{{0}, {1}, {102}, {103}, {104}} {
+		result, err := txs.GetTxs(uint32(i))
+		assert.NoError(t, err)
+		assert.Equal(t, ids, txIds(result))
+	}
+	for i, ids := range map[int][]uint32{2: {2}, 3: {3}} {
+		hash := chain[i].Hash()
+		result, err := txs.GetForkTxs(&hash)
+		assert.NoError(t, err)
+		assert.Equal(t, ids, txIds(result))
+	}
+}
+
+// TestChainStore checks the chain store created on the headers and
+// transactions database commits blocks and moves transactions to the new best
+// chain on reorganize. The databases must be empty.
+func TestChainStore(t *testing.T, headers database.Headers, txs database.TxsDB,
+	newHeader NewHeader) {
+	store := database.NewChainDB(headers, txs)
+
+	chain, fork4 := newChains(t, store, newHeader)
+	result, err := txs.GetTxs(2)
+	assert.NoError(t, err)
+	assert.Equal(t, []uint32{2}, txIds(result))
+
+	// fork chain from height 2 to 4 becomes the best chain.
+	if !assert.NoError(t, reorganize(store, chain, fork4)) {
+		t.FailNow()
+	}
+	checkReorganized(t, store, txs, chain, fork4)
+
+	assert.NoError(t, store.Clear())
+}
+
+// errCrash is returned by the writes after the process is killed.
+var errCrash = errors.New("process killed")
+
+// crasher kills the process before the write at the crash step, all writes
+// fail after the process killed.
+type crasher struct {
+	steps int
+	crash int
+}
+
+func (c *crasher) write() error {
+	if c.steps >= c.crash {
+		return errCrash
+	}
+	c.steps++
+	return nil
+}
+
+type crashHeaders struct {
+	database.Headers
+	c *crasher
+}
+
+func (h *crashHeaders) Put(header *util.Header, newTip bool) error {
+	if err := h.c.write(); err != nil {
+		return err
+	}
+	return h.Headers.Put(header, newTip)
+}
+
+type crashTxs struct {
+	database.TxsDB
+	c *crasher
+}
+
+func (t *crashTxs) PutTxs(txs []util.Transaction, height uint32) (uint32, error) {
+	if err := t.c.write(); err != nil {
+		return 0, err
+	}
+	return t.TxsDB.PutTxs(txs, height)
+}
+
+func (t *crashTxs) PutForkTxs(txs []util.Transaction, hash *common.Uint256) error {
+	if err := t.c.write(); err != nil {
+		return err
+	}
+	return t.TxsDB.PutForkTxs(txs, hash)
+}
+
+func (t *crashTxs) DelTxs(height uint32) error {
+	if err := t.c.write(); err != nil {
+		return err
+	}
+	return t.TxsDB.DelTxs(height)
+}
+
+type crashJournal struct {
+	database.ReorgJournal
+	c *crasher
+}
+
+func (j *crashJournal) Put(record *database.ReorgRecord) error {
+	if err := j.c.write(); err != nil {
+		return err
+	}
+	return j.ReorgJournal.Put(record)
+}
+
+func (j *crashJournal) Delete() error {
+	if err := j.c.write(); err != nil {
+		return err
+	}
+	return j.ReorgJournal.Delete()
+}
+
+// TestChainStoreRecovery kills the process at every write while reorganizing
+// the chain store, and checks the chain store created on the same databases
+// after restart ends with the same result as TestChainStore.  newStores must
+// return empty databases, they are closed after each crash.
+func TestChainStoreRecovery(t *testing.T,
+	newStores func() (database.Headers, database.TxsDB), newHeader NewHeader) {
+	for crash := 0; ; crash++ {
+		headers, txs := newStores()
+		journal := database.NewMemoryJournal()
+		store, err := database.NewChainDBWithJournal(headers, txs, journal)
+		if !assert.NoError(t, err) {
+			t.FailNow()
+		}
+		chain, fork4 := newChains(t, store, newHeader)
+
+		c := &crasher{crash: crash}
+		store, err = database.NewChainDBWithJournal(
+			&crashHeaders{Headers: headers, c: c},
+			&crashTxs{TxsDB: txs, c: c},
+			&crashJournal{ReorgJournal: journal, c: c})
+		if !assert.NoError(t, err) {
+			t.FailNow()
+		}
+		err = reorganize(store, chain, fork4)
+		finished := err == nil
+		if !finished && !assert.Equal(t, errCrash, err) {
+			t.FailNow()
+		}
+
+		// Restart and resume the interrupted reorganize.
+		store, err = database.NewChainDBWithJournal(headers, txs, journal)
+		if !assert.NoError(t, err, "crash at step %d", crash) {
+			t.FailNow()
+		}
+		record, err := journal.Get()
+		assert.NoError(t, err)
+		assert.Nil(t, record)
+
+		// The process is killed before the reorganize journal written, the
+		// main chain is not changed and the block will be received again.
+		best, err := headers.GetBest()
+		assert.NoError(t, err)
+		if best.Hash() != fork4.Hash() {
+			assert.Equal(t, chain[3].Hash(), best.Hash(),
+				"crash at step %d", crash)
+			result, err := txs.GetTxs(3)
+			assert.NoError(t, err)
+			assert.Equal(t, []uint32{3}, txIds(result))
+			assert.NoError(t, reorganize(store, chain, fork4))
+		}
+		checkReorganized(t, store, txs, chain, fork4)
+
+		assert.NoError(t, store.Close())
+		if finished {
+			break
+		}
+	}
+}
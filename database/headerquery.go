@@ -0,0 +1,154 @@
+package database
+
+import (
+	"errors"
+	"sort"
+
+	"github.com/elastos/Elastos.ELA.SPV/util"
+)
+
+// medianTimeBlocks is the number of previous blocks which should be used to
+// calculate the median time.
+const medianTimeBlocks = 11
+
+var (
+	// ErrChainChanged indicates the best chain has been reorganized while
+	// iterating headers.
+	ErrChainChanged = errors.New("best chain changed while iterating")
+
+	// ErrHeaderNotFound indicates no header on the best chain matches the
+	// query.
+	ErrHeaderNotFound = errors.New("header not found")
+)
+
+// headerIterator walks headers by height, and makes sure the headers are
+// connected so the result will not mix up headers from different chains.
+type headerIterator struct {
+	getByHeight func(height uint32) (*util.Header, error)
+	next        uint32
+	end         uint32
+	done        bool
+	header      *util.Header
+	err         error
+}
+
+// NewHeaderIterator returns a HeaderIterator walks from start to end height,
+// both inclusive, reading headers by the getByHeight function.
+func NewHeaderIterator(getByHeight func(height uint32) (*util.Header, error),
+	start, end uint32) HeaderIterator {
+	return &headerIterator{
+		getByHeight: getByHeight,
+		next:        start,
+		end:         end,
+		done:        start > end,
+	}
+}
+
+func (it *headerIterator) Next() bool {
+	if it.done {
+		return false
+	}
+
+	header, err := it.getByHeight(it.next)
+	if err != nil {
+		it.err = err
+		it.done = true
+		return false
+	}
+	if it.header != nil && header.Previous() != it.header.Hash() {
+		it.err = ErrChainChanged
+		it.done = true
+		return false
+	}
+	it.header = header
+
+	if it.next == it.end {
+		it.done = true
+	} else {
+		it.next++
+	}
+	return true
+}
+
+func (it *headerIterator) Header() *util.Header {
+	return it.header
+}
+
+func (it *headerIterator) Error() error {
+	return it.err
+}
+
+// GetHeaderRange returns headers from start to end height, both inclusive,
+// by walking the iterator.
+func GetHeaderRange(it HeaderIterator) ([]*util.Header, error) {
+	var headers []*util.Header
+	for it.Next() {
+		headers = append(headers, it.Header())
+	}
+	return headers, it.Error()
+}
+
+// SearchHeaderByTime returns the first header with timestamp at or after the
+// given timestamp by binary search over heights from 0 to bestHeight. Block
+// timestamps are increasing in general, the result is the first header after
+// which all timestamps are at or after the given timestamp in this case.
+func SearchHeaderByTime(getByHeight func(height uint32) (*util.Header, error),
+	headerTime func(header *util.Header) (uint32, error),
+	bestHeight uint32, timestamp uint32) (*util.Header, error) {
+	var searchErr error
+	height := sort.Search(int(bestHeight)+1, func(i int) bool {
+		if searchErr != nil {
+			return true
+		}
+		header, err := getByHeight(uint32(i))
+		if err != nil {
+			searchErr = err
+			return true
+		}
+		t, err := headerTime(header)
+		if err != nil {
+			searchErr = err
+			return true
+		}
+		return t >= timestamp
+	})
+	if searchErr != nil {
+		return nil, searchErr
+	}
+	if height > int(bestHeight) {
+		return nil, ErrHeaderNotFound
+	}
+	return getByHeight(uint32(height))
+}
+
+// CalcMedianTimePast returns the median timestamp of the last blocks ended
+// with the given height.
+func CalcMedianTimePast(getByHeight func(height uint32) (*util.Header, error),
+	headerTime func(header *util.Header) (uint32, error),
+	height uint32) (uint32, error) {
+	timestamps := make([]uint32, 0, medianTimeBlocks)
+	for i := 0; i < medianTimeBlocks; i++ {
+		header, err := getByHeight(height)
+		if err != nil {
+			if len(timestamps) > 0 {
+				// headers before the sync start point are not stored.
+				break
+			}
+			return 0, err
+		}
+		t, err := headerTime(header)
+		if err != nil {
+			return 0, err
+		}
+		timestamps = append(timestamps, t)
+		if height == 0 {
+			break
+		}
+		height--
+	}
+
+	sort.Slice(timestamps, func(i, j int) bool {
+		return timestamps[i] < timestamps[j]
+	})
+	return timestamps[len(timestamps)/2], nil
+}
@@ -22,3 +22,38 @@ type Headers interface {
 	// Get the header on chain tip
 	GetBest() (*util.Header, error)
 }
+
+// ChainHeaders is the headers database with the best chain indexed by height.
+type ChainHeaders interface {
+	Headers
+
+	// Get the header on the best chain by height
+	GetByHeight(height uint32) (*util.Header, error)
+
+	// Get headers on the best chain from start to end height, both inclusive
+	GetRange(start, end uint32) ([]*util.Header, error)
+
+	// Iterator returns an iterator walks the best chain from start to end
+	// height, both inclusive
+	Iterator(start, end uint32) HeaderIterator
+
+	// Get the first header on the best chain with timestamp at or after the
+	// given unix timestamp
+	GetByTime(timestamp uint32) (*util.Header, error)
+
+	// Get the median timestamp of the last blocks ended with the given height
+	MedianTimePast(height uint32) (uint32, error)
+}
+
+// HeaderIterator walks the headers on the best chain by height order.
+type HeaderIterator interface {
+	// Next moves the iterator to the next header, returns false if there is
+	// no more header or an error occurred.
+	Next() bool
+
+	// Header returns the current header.
+	Header() *util.Header
+
+	// Error returns the error occurred while iterating.
+	Error() error
+}
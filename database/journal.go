@@ -0,0 +1,219 @@
+package database
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/elastos/Elastos.ELA/common"
+)
+
+// ReorgStage is the stage of a chain reorganize recorded in the journal.
+type ReorgStage uint8
+
+const (
+	// ReorgDisconnect copies the previous main chain transactions to fork.
+	ReorgDisconnect ReorgStage = iota
+
+	// ReorgDelete removes the previous main chain transactions.
+	ReorgDelete
+
+	// ReorgConnect moves the new best chain transactions from fork to main
+	// chain, and sets the new chain tip at last.
+	ReorgConnect
+)
+
+func (s ReorgStage) String() string {
+	switch s {
+	case ReorgDisconnect:
+		return "disconnect"
+	case ReorgDelete:
+		return "delete"
+	case ReorgConnect:
+		return "connect"
+	default:
+		return "unknown"
+	}
+}
+
+// ReorgRecord is the progress of a chain reorganize, every stage can be
+// replayed from the beginning except ReorgConnect, which resumes from Height.
+type ReorgRecord struct {
+	CommonAncestor common.Uint256
+	PrevTip        common.Uint256
+	NewTip         common.Uint256
+	Stage          ReorgStage
+
+	// Height is the next block height to connect in the ReorgConnect stage.
+	Height uint32
+}
+
+func (r *ReorgRecord) Serialize(w io.Writer) error {
+	if err := r.CommonAncestor.Serialize(w); err != nil {
+		return err
+	}
+	if err := r.PrevTip.Serialize(w); err != nil {
+		return err
+	}
+	if err := r.NewTip.Serialize(w); err != nil {
+		return err
+	}
+	if err := common.WriteUint8(w, uint8(r.Stage)); err != nil {
+		return err
+	}
+	return common.WriteUint32(w, r.Height)
+}
+
+func (r *ReorgRecord) Deserialize(reader io.Reader) error {
+	if err := r.CommonAncestor.Deserialize(reader); err != nil {
+		return err
+	}
+	if err := r.PrevTip.Deserialize(reader); err != nil {
+		return err
+	}
+	if err := r.NewTip.Deserialize(reader); err != nil {
+		return err
+	}
+	stage, err := common.ReadUint8(reader)
+	if err != nil {
+		return err
+	}
+	r.Stage = ReorgStage(stage)
+	r.Height, err = common.ReadUint32(reader)
+	return err
+}
+
+// ReorgJournal is the write-ahead journal of chain reorganize, a record left
+// in the journal means the reorganize was interrupted and must be resumed
+// before using the chain store.
+type ReorgJournal interface {
+	// Put saves the record and replaces the previous one, the record must be
+	// durable when Put returns.
+	Put(record *ReorgRecord) error
+
+	// Get returns the record in journal, or nil if there is no record.
+	Get() (*ReorgRecord, error)
+
+	// Delete removes the record from journal.
+	Delete() error
+}
+
+// fileJournal is the ReorgJournal implementation keeps the record in a file.
+type fileJournal struct {
+	path string
+}
+
+// NewFileJournal creates a reorganize journal keeps the record in the file
+// with the given path.
+func NewFileJournal(path string) ReorgJournal {
+	return &fileJournal{path: path}
+}
+
+func (j *fileJournal) Put(record *ReorgRecord) error {
+	buf := new(bytes.Buffer)
+	if err := record.Serialize(buf); err != nil {
+		return err
+	}
+
+	// Write to a temporary file and rename it, so the journal file is always
+	// a complete record.
+	tmp := j.path + ".tmp"
+	file, err := os.OpenFile(tmp, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	if _, err := file.Write(buf.Bytes()); err != nil {
+		file.Close()
+		return err
+	}
+	if err := file.Sync(); err != nil {
+		file.Close()
+		return err
+	}
+	if err := file.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, j.path); err != nil {
+		return err
+	}
+	return syncDir(filepath.Dir(j.path))
+}
+
+func (j *fileJournal) Get() (*ReorgRecord, error) {
+	data, err := ioutil.ReadFile(j.path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var record ReorgRecord
+	if err := record.Deserialize(bytes.NewReader(data)); err != nil {
+		return nil, err
+	}
+	return &record, nil
+}
+
+func (j *fileJournal) Delete() error {
+	err := os.Remove(j.path)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return syncDir(filepath.Dir(j.path))
+}
+
+// syncDir flushes the directory entries, so the renamed or removed journal
+// file survives a crash.
+func syncDir(dir string) error {
+	d, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+	// Some platforms do not support sync on directories.
+	d.Sync()
+	return nil
+}
+
+// memoryJournal is the ReorgJournal implementation keeps the record in
+// memory, it does not survive a restart.
+type memoryJournal struct {
+	sync.Mutex
+	record *ReorgRecord
+}
+
+// NewMemoryJournal creates a reorganize journal keeps the record in memory.
+func NewMemoryJournal() ReorgJournal {
+	return &memoryJournal{}
+}
+
+func (j *memoryJournal) Put(record *ReorgRecord) error {
+	j.Lock()
+	defer j.Unlock()
+
+	r := *record
+	j.record = &r
+	return nil
+}
+
+func (j *memoryJournal) Get() (*ReorgRecord, error) {
+	j.Lock()
+	defer j.Unlock()
+
+	if j.record == nil {
+		return nil, nil
+	}
+	r := *j.record
+	return &r, nil
+}
+
+func (j *memoryJournal) Delete() error {
+	j.Lock()
+	defer j.Unlock()
+
+	j.record = nil
+	return nil
+}
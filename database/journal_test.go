@@ -0,0 +1,45 @@
+package database_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/elastos/Elastos.ELA.SPV/database"
+
+	"github.com/elastos/Elastos.ELA/common"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFileJournal(t *testing.T) {
+	dataDir := "journal_test"
+	os.RemoveAll(dataDir)
+	defer os.RemoveAll(dataDir)
+	assert.NoError(t, os.MkdirAll(dataDir, os.ModePerm))
+
+	path := filepath.Join(dataDir, "reorg.journal")
+	journal := database.NewFileJournal(path)
+	record, err := journal.Get()
+	assert.NoError(t, err)
+	assert.Nil(t, record)
+
+	expected := &database.ReorgRecord{
+		CommonAncestor: common.Uint256{1},
+		PrevTip:        common.Uint256{2},
+		NewTip:         common.Uint256{3},
+		Stage:          database.ReorgConnect,
+		Height:         100,
+	}
+	assert.NoError(t, journal.Put(expected))
+
+	// the record survives restart.
+	record, err = database.NewFileJournal(path).Get()
+	assert.NoError(t, err)
+	assert.Equal(t, expected, record)
+
+	assert.NoError(t, journal.Delete())
+	record, err = journal.Get()
+	assert.NoError(t, err)
+	assert.Nil(t, record)
+	assert.NoError(t, journal.Delete())
+}
@@ -0,0 +1,243 @@
+package database
+
+import (
+	"errors"
+	"sync"
+
+	"github.com/elastos/Elastos.ELA.SPV/util"
+
+	"github.com/elastos/Elastos.ELA/common"
+)
+
+// Ensure memoryHeaders implement ChainHeaders interface.
+var _ ChainHeaders = (*memoryHeaders)(nil)
+
+// Ensure memoryTxs implement TxsDB interface.
+var _ TxsDB = (*memoryTxs)(nil)
+
+// memoryHeaders is the ChainHeaders implementation keeps all headers in
+// memory.
+type memoryHeaders struct {
+	sync.RWMutex
+	headers    map[common.Uint256]*util.Header
+	index      []common.Uint256
+	tip        *util.Header
+	headerTime func(header *util.Header) (uint32, error)
+}
+
+// NewMemoryHeaders creates a headers database keeps all headers in memory,
+// headerTime returns the timestamp of a header to support GetByTime and
+// MedianTimePast queries.
+func NewMemoryHeaders(headerTime func(header *util.Header) (uint32, error)) ChainHeaders {
+	return &memoryHeaders{
+		headers:    make(map[common.Uint256]*util.Header),
+		headerTime: headerTime,
+	}
+}
+
+func (h *memoryHeaders) Put(header *util.Header, newTip bool) error {
+	h.Lock()
+	defer h.Unlock()
+
+	h.headers[header.Hash()] = header
+	if !newTip {
+		return nil
+	}
+	h.tip = header
+
+	// Re-index the best chain until the header already indexed, the headers
+	// above the new tip will be removed from index.
+	if int(header.Height) < len(h.index) {
+		h.index = h.index[:header.Height+1]
+	}
+	for {
+		hash := header.Hash()
+		if int(header.Height) < len(h.index) && h.index[header.Height] == hash {
+			break
+		}
+		for int(header.Height) >= len(h.index) {
+			h.index = append(h.index, common.Uint256{})
+		}
+		h.index[header.Height] = hash
+
+		if header.Height == 0 {
+			break
+		}
+		previous, ok := h.headers[header.Previous()]
+		if !ok {
+			break
+		}
+		header = previous
+	}
+	return nil
+}
+
+func (h *memoryHeaders) GetPrevious(header *util.Header) (*util.Header, error) {
+	hash := header.Previous()
+	return h.Get(&hash)
+}
+
+func (h *memoryHeaders) Get(hash *common.Uint256) (*util.Header, error) {
+	h.RLock()
+	defer h.RUnlock()
+
+	header, ok := h.headers[*hash]
+	if !ok {
+		return nil, ErrHeaderNotFound
+	}
+	return header, nil
+}
+
+func (h *memoryHeaders) GetBest() (*util.Header, error) {
+	h.RLock()
+	defer h.RUnlock()
+
+	if h.tip == nil {
+		return nil, errors.New("no chain tip")
+	}
+	return h.tip, nil
+}
+
+func (h *memoryHeaders) GetByHeight(height uint32) (*util.Header, error) {
+	h.RLock()
+	defer h.RUnlock()
+
+	if int(height) >= len(h.index) {
+		return nil, ErrHeaderNotFound
+	}
+	header, ok := h.headers[h.index[height]]
+	if !ok {
+		return nil, ErrHeaderNotFound
+	}
+	return header, nil
+}
+
+func (h *memoryHeaders) GetRange(start, end uint32) ([]*util.Header, error) {
+	return GetHeaderRange(h.Iterator(start, end))
+}
+
+func (h *memoryHeaders) Iterator(start, end uint32) HeaderIterator {
+	return NewHeaderIterator(h.GetByHeight, start, end)
+}
+
+func (h *memoryHeaders) GetByTime(timestamp uint32) (*util.Header, error) {
+	best, err := h.GetBest()
+	if err != nil {
+		return nil, err
+	}
+	return SearchHeaderByTime(h.GetByHeight, h.headerTime, best.Height,
+		timestamp)
+}
+
+func (h *memoryHeaders) MedianTimePast(height uint32) (uint32, error) {
+	return CalcMedianTimePast(h.GetByHeight, h.headerTime, height)
+}
+
+func (h *memoryHeaders) Clear() error {
+	h.Lock()
+	defer h.Unlock()
+
+	h.headers = make(map[common.Uint256]*util.Header)
+	h.index = nil
+	h.tip = nil
+	return nil
+}
+
+func (h *memoryHeaders) Close() error {
+	return nil
+}
+
+// memoryTxs is the TxsDB implementation keeps all transactions in memory,
+// all transactions are saved so there will be no false positive ones.
+type memoryTxs struct {
+	sync.RWMutex
+	txIds   map[common.Uint256]struct{}
+	txs     map[uint32][]util.Transaction
+	forkTxs map[common.Uint256][]util.Transaction
+}
+
+// NewMemoryTxsDB creates a transactions database keeps all transactions in
+// memory.
+func NewMemoryTxsDB() TxsDB {
+	return &memoryTxs{
+		txIds:   make(map[common.Uint256]struct{}),
+		txs:     make(map[uint32][]util.Transaction),
+		forkTxs: make(map[common.Uint256][]util.Transaction),
+	}
+}
+
+func (t *memoryTxs) PutTxs(txs []util.Transaction, height uint32) (uint32, error) {
+	t.Lock()
+	defer t.Unlock()
+
+	for _, tx := range txs {
+		t.txIds[tx.Hash()] = struct{}{}
+	}
+	t.txs[height] = append(t.txs[height], txs...)
+	return 0, nil
+}
+
+func (t *memoryTxs) PutForkTxs(txs []util.Transaction, hash *common.Uint256) error {
+	t.Lock()
+	defer t.Unlock()
+
+	t.forkTxs[*hash] = append([]util.Transaction{}, txs...)
+	return nil
+}
+
+func (t *memoryTxs) HaveTx(txId *common.Uint256) (bool, error) {
+	t.RLock()
+	defer t.RUnlock()
+
+	_, ok := t.txIds[*txId]
+	return ok, nil
+}
+
+func (t *memoryTxs) GetTxs(height uint32) ([]util.Transaction, error) {
+	t.RLock()
+	defer t.RUnlock()
+
+	return append([]util.Transaction{}, t.txs[height]...), nil
+}
+
+func (t *memoryTxs) GetForkTxs(hash *common.Uint256) ([]util.Transaction, error) {
+	t.RLock()
+	defer t.RUnlock()
+
+	txs, ok := t.forkTxs[*hash]
+	if !ok {
+		return nil, errors.New("fork transactions not found")
+	}
+	return append([]util.Transaction{}, txs...), nil
+}
+
+func (t *memoryTxs) DelTxs(height uint32) error {
+	t.Lock()
+	defer t.Unlock()
+
+	for _, tx := range t.txs[height] {
+		delete(t.txIds, tx.Hash())
+	}
+	delete(t.txs, height)
+	return nil
+}
+
+func (t *memoryTxs) Clear() error {
+	t.Lock()
+	defer t.Unlock()
+
+	t.txIds = make(map[common.Uint256]struct{})
+	t.txs = make(map[uint32][]util.Transaction)
+	t.forkTxs = make(map[common.Uint256][]util.Transaction)
+	return nil
+}
+
+func (t *memoryTxs) Close() error {
+	return nil
+}
+
+// NewMemoryChainDB creates a chain store keeps headers and transactions in
+// memory, see NewMemoryHeaders for headerTime.
+func NewMemoryChainDB(headerTime func(header *util.Header) (uint32, error)) ChainStore {
+	return NewChainDB(NewMemoryHeaders(headerTime), NewMemoryTxsDB())
+}
@@ -0,0 +1,79 @@
+package database_test
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"io"
+	"math/big"
+	"testing"
+
+	"github.com/elastos/Elastos.ELA.SPV/database"
+	"github.com/elastos/Elastos.ELA.SPV/database/dbtest"
+	"github.com/elastos/Elastos.ELA.SPV/util"
+
+	"github.com/elastos/Elastos.ELA/common"
+)
+
+// header is a minimal util.BlockHeader for testing.
+type header struct {
+	previous  common.Uint256
+	timestamp uint32
+	nonce     uint32
+}
+
+func (h *header) Previous() common.Uint256 { return h.previous }
+
+func (h *header) Bits() uint32 { return 0 }
+
+func (h *header) MerkleRoot() common.Uint256 { return common.Uint256{} }
+
+func (h *header) Hash() common.Uint256 {
+	var buf [40]byte
+	copy(buf[:], h.previous[:])
+	binary.BigEndian.PutUint32(buf[32:], h.timestamp)
+	binary.BigEndian.PutUint32(buf[36:], h.nonce)
+	return sha256.Sum256(buf[:])
+}
+
+func (h *header) PowHash() common.Uint256 { return h.Hash() }
+
+func (h *header) Serialize(w io.Writer) error { return nil }
+
+func (h *header) Deserialize(r io.Reader) error { return nil }
+
+func headerTime(h *util.Header) (uint32, error) {
+	return h.BlockHeader.(*header).timestamp, nil
+}
+
+func newHeader(previous *util.Header, timestamp, nonce uint32) *util.Header {
+	var height uint32
+	var previousHash common.Uint256
+	if previous != nil {
+		height = previous.Height + 1
+		previousHash = previous.Hash()
+	}
+	return &util.Header{
+		BlockHeader: &header{
+			previous:  previousHash,
+			timestamp: timestamp,
+			nonce:     nonce,
+		},
+		Height:    height,
+		TotalWork: new(big.Int),
+	}
+}
+
+func TestMemoryHeaders(t *testing.T) {
+	dbtest.TestChainHeaders(t, database.NewMemoryHeaders(headerTime), newHeader)
+}
+
+func TestMemoryChainStore(t *testing.T) {
+	dbtest.TestChainStore(t, database.NewMemoryHeaders(headerTime),
+		database.NewMemoryTxsDB(), newHeader)
+}
+
+func TestMemoryChainStoreRecovery(t *testing.T) {
+	dbtest.TestChainStoreRecovery(t, func() (database.Headers, database.TxsDB) {
+		return database.NewMemoryHeaders(headerTime), database.NewMemoryTxsDB()
+	}, newHeader)
+}
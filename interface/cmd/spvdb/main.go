@@ -0,0 +1,264 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/elastos/Elastos.ELA.SPV/interface"
+	"github.com/elastos/Elastos.ELA.SPV/interface/iutil"
+	"github.com/elastos/Elastos.ELA.SPV/interface/store"
+	"github.com/elastos/Elastos.ELA.SPV/util"
+
+	elacommon "github.com/elastos/Elastos.ELA/core/types/common"
+	"github.com/urfave/cli"
+)
+
+var Version string
+
+var dataDirFlag = cli.StringFlag{
+	Name:  "datadir, d",
+	Usage: "data directory of the SPV service",
+	Value: "./data_spv",
+}
+
+func newBlockHeader() util.BlockHeader {
+	return iutil.NewHeader(&elacommon.Header{})
+}
+
+// openStores opens the databases of a stopped service.
+func openStores(dataDir string) (store.HeaderStore, store.DataStore, error) {
+	headers, err := store.NewHeaderStore(dataDir, newBlockHeader)
+	if err != nil {
+		return nil, nil, err
+	}
+	data, err := store.NewDataStore(dataDir, nil, 0, "")
+	if err != nil {
+		headers.Close()
+		return nil, nil, err
+	}
+	return headers, data, nil
+}
+
+// exportSnapshot exports the snapshot from the databases of a stopped
+// service, use SPVService.ExportSnapshot to export from a running service.
+func exportSnapshot(c *cli.Context) error {
+	file := c.String("file")
+	if len(file) == 0 {
+		return errors.New("snapshot file not specified")
+	}
+	if err := _interface.CheckReorgJournal(c.String("datadir")); err != nil {
+		return err
+	}
+	headers, data, err := openStores(c.String("datadir"))
+	if err != nil {
+		return err
+	}
+	defer headers.Close()
+	defer data.Close()
+
+	height := uint32(c.Uint("height"))
+	if !c.IsSet("height") {
+		best, err := headers.GetBest()
+		if err != nil {
+			return err
+		}
+		height = best.Height
+	}
+
+	f, err := os.Create(file)
+	if err != nil {
+		return err
+	}
+	w := bufio.NewWriter(f)
+	info, err := store.ExportSnapshot(w, headers, data, height)
+	if err == nil {
+		err = w.Flush()
+	}
+	if err == nil {
+		err = f.Close()
+	} else {
+		f.Close()
+	}
+	if err != nil {
+		os.Remove(file)
+		return err
+	}
+
+	fmt.Println("snapshot exported,", info)
+	return nil
+}
+
+func importSnapshot(c *cli.Context) error {
+	file := c.String("file")
+	if len(file) == 0 {
+		return errors.New("snapshot file not specified")
+	}
+
+	f, err := os.Open(file)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	info, err := _interface.ImportSnapshot(bufio.NewReader(f),
+		c.String("datadir"))
+	if err != nil {
+		return err
+	}
+
+	fmt.Println("snapshot imported,", info)
+	return nil
+}
+
+func verifyStores(c *cli.Context) error {
+	if c.Bool("repair") {
+		if err := _interface.CheckReorgJournal(c.String("datadir")); err != nil {
+			return err
+		}
+	}
+	headers, data, err := openStores(c.String("datadir"))
+	if err != nil {
+		return err
+	}
+	defer headers.Close()
+	defer data.Close()
+
+	report, err := store.VerifyStores(headers, data, c.Bool("repair"))
+	if err != nil {
+		return err
+	}
+	for _, issue := range report.Issues {
+		fmt.Println(issue)
+	}
+	fmt.Println("verify finished,", report)
+	return nil
+}
+
+func pruneStores(c *cli.Context) error {
+	opts := store.PruneOptions{
+		Depth:      uint32(c.Uint("depth")),
+		Checkpoint: uint32(c.Uint("checkpoint")),
+	}
+	if !opts.Enabled() {
+		return errors.New("depth or checkpoint not specified")
+	}
+	headers, data, err := openStores(c.String("datadir"))
+	if err != nil {
+		return err
+	}
+	defer headers.Close()
+	defer data.Close()
+
+	report, err := store.PruneStores(headers, data, opts)
+	if err != nil {
+		return err
+	}
+	fmt.Println("prune finished,", report)
+	return nil
+}
+
+func compactStores(c *cli.Context) error {
+	opts := store.CompactOptions{
+		KeepRecent: uint32(c.Uint("keep")),
+	}
+	if !opts.Enabled() {
+		return errors.New("recent blocks to keep not specified")
+	}
+	headers, data, err := openStores(c.String("datadir"))
+	if err != nil {
+		return err
+	}
+	defer headers.Close()
+	defer data.Close()
+
+	report, err := store.CompactStores(headers, data, opts)
+	if err != nil {
+		return err
+	}
+	fmt.Println("compact finished,", report)
+	return nil
+}
+
+func main() {
+	app := cli.NewApp()
+	app.Name = "ELASTOS SPV DATABASE TOOL"
+	app.Version = Version
+	app.Usage = "export, import, verify, prune and compact the SPV service databases"
+	app.Commands = []cli.Command{
+		{
+			Name:  "export",
+			Usage: "export a snapshot, the service must be stopped",
+			Flags: []cli.Flag{
+				dataDirFlag,
+				cli.StringFlag{
+					Name:  "file, f",
+					Usage: "snapshot file to write",
+				},
+				cli.UintFlag{
+					Name:  "height",
+					Usage: "export headers up to the height, must be the best height if specified",
+				},
+			},
+			Action: exportSnapshot,
+		},
+		{
+			Name:  "import",
+			Usage: "import a snapshot into an empty data directory",
+			Flags: []cli.Flag{
+				dataDirFlag,
+				cli.StringFlag{
+					Name:  "file, f",
+					Usage: "snapshot file to read",
+				},
+			},
+			Action: importSnapshot,
+		},
+		{
+			Name:  "verify",
+			Usage: "verify the databases consistency, the service must be stopped",
+			Flags: []cli.Flag{
+				dataDirFlag,
+				cli.BoolFlag{
+					Name:  "repair",
+					Usage: "repair the issues found",
+				},
+			},
+			Action: verifyStores,
+		},
+		{
+			Name:  "prune",
+			Usage: "remove side branches and their fork transactions",
+			Flags: []cli.Flag{
+				dataDirFlag,
+				cli.UintFlag{
+					Name:  "depth",
+					Usage: "prune side branches more than depth blocks below the best header",
+				},
+				cli.UintFlag{
+					Name:  "checkpoint",
+					Usage: "prune side branches below the checkpoint height",
+				},
+			},
+			Action: pruneStores,
+		},
+		{
+			Name:  "compact",
+			Usage: "remove merkle data of old headers not containing stored transactions",
+			Flags: []cli.Flag{
+				dataDirFlag,
+				cli.UintFlag{
+					Name:  "keep",
+					Usage: "keep merkle data of the last blocks",
+				},
+			},
+			Action: compactStores,
+		},
+	}
+
+	if err := app.Run(os.Args); err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+}
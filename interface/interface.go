@@ -1,6 +1,9 @@
 package _interface
 
 import (
+	"io"
+	"time"
+
 	"github.com/elastos/Elastos.ELA.SPV/bloom"
 	"github.com/elastos/Elastos.ELA.SPV/interface/store"
 	"github.com/elastos/Elastos.ELA.SPV/util"
@@ -8,6 +11,7 @@ import (
 	"github.com/elastos/Elastos.ELA/common/config"
 	elacommon "github.com/elastos/Elastos.ELA/core/types/common"
 	it "github.com/elastos/Elastos.ELA/core/types/interfaces"
+	"github.com/elastos/Elastos.ELA/core/types/payload"
 )
 
 // SPV service config
@@ -33,6 +37,43 @@ type Config struct {
 
 	//this spv GenesisBlockAddress
 	GenesisBlockAddress string
+
+	// InMemory keeps block headers and service data in memory, they will be
+	// lost after the service stopped.
+	InMemory bool
+
+	// MigrateDryRun checks the migrations to upgrade the databases to the
+	// current schema version without changing them, NewSPVService returns
+	// ErrMigrateDryRun after the check.
+	MigrateDryRun bool
+
+	// MigrateBackupDir is the directory to back up the databases before
+	// upgrading them, no backup will be made if it's empty.
+	MigrateBackupDir string
+
+	// PruneDepth prunes the side branches more than PruneDepth blocks below
+	// the best header in background, zero disables pruning by depth.
+	PruneDepth uint32
+
+	// PruneCheckpoint prunes the side branches below the checkpoint height
+	// in background, zero disables pruning by checkpoint.
+	PruneCheckpoint uint32
+
+	// CompactKeepRecent enables the pruned mode, the merkle data of the best
+	// chain headers are kept only for the last CompactKeepRecent blocks and
+	// the blocks containing stored transactions, zero disables the pruned
+	// mode. It should be larger than the deepest reorganize.
+	CompactKeepRecent uint32
+
+	// PruneInterval is the interval to prune side branches and compact
+	// headers, ten minutes by default.
+	PruneInterval time.Duration
+
+	// TrackUTXOs tracks the unspent and spent outputs of the registered
+	// addresses for GetBalance and ListUnspent.  Only the transactions
+	// synced after it's enabled are tracked, clear the data store to rescan
+	// the history.
+	TrackUTXOs bool
 }
 
 /*
@@ -53,6 +94,10 @@ type SPVService interface {
 	// listeners must be registered before call Start() method, or some notifications will go missing.
 	RegisterRevertListener(listener RevertListener) error
 
+	// RegisterConsensusModeListener register the listener to receive consensus mode change notifications.
+	// listeners must be registered before call Start() method, or some notifications will go missing.
+	RegisterConsensusModeListener(listener ConsensusModeListener) error
+
 	// After receive the transaction callback, call this method
 	// to confirm that the transaction with the given ID was handled,
 	// so the transaction will be removed from the notify queue.
@@ -63,6 +108,24 @@ type SPVService interface {
 	// This method is useful when receive a transaction from other peer
 	VerifyTransaction(bloom.MerkleProof, it.Transaction) error
 
+	// VerifyTransactionWithPolicy verifies the transaction like
+	// VerifyTransaction, and also checks the block is on the best chain with
+	// the confirmations required by the policy.
+	VerifyTransactionWithPolicy(bloom.MerkleProof, it.Transaction, VerifyPolicy) (*VerifyResult, error)
+
+	// VerifyTransactionsWithPolicy verifies transactions with their proofs in
+	// the same order by the policy, the verification error of each
+	// transaction is returned in it's result.
+	VerifyTransactionsWithPolicy([]bloom.MerkleProof, []it.Transaction, VerifyPolicy) ([]*VerifyResult, error)
+
+	// GetTransactionMerkleBranch returns the merkle branch of a stored
+	// transaction, which is more compact than the merkle proof.
+	GetTransactionMerkleBranch(txId common.Uint256) (*bloom.MerkleBranch, error)
+
+	// VerifyMerkleBranch verifies the transaction is packed in the block by
+	// the merkle branch.
+	VerifyMerkleBranch(branch *bloom.MerkleBranch, txId common.Uint256, blockHash common.Uint256) error
+
 	// Send a transaction to the P2P network
 	SendTransaction(it.Transaction) error
 
@@ -81,6 +144,10 @@ type SPVService interface {
 	// Get consensus algorithm by height.
 	GetConsensusAlgorithm(height uint32) (ConsensusAlgorithm, error)
 
+	// GetConsensusModeTimeline query the consensus mode intervals between
+	// startHeight and endHeight, both inclusive.
+	GetConsensusModeTimeline(startHeight, endHeight uint32) ([]ConsensusModeInterval, error)
+
 	// GetReservedCustomIDs query all controversial reserved custom ID.
 	// height need to be the height of main chain.
 	GetReservedCustomIDs(height uint32) (map[string]struct{}, error)
@@ -89,6 +156,66 @@ type SPVService interface {
 	// height need to be the height of main chain.
 	GetReceivedCustomIDs(height uint32) (map[string]common.Uint168, error)
 
+	// GetCustomIDRecord query the current state of the given custom ID,
+	// including status, owner DID and the proposal which set it.
+	GetCustomIDRecord(customID string) (*store.CustomIDRecord, error)
+
+	// GetCustomIDsByOwner query all custom IDs received by the given DID.
+	GetCustomIDsByOwner(did common.Uint168) ([]*store.CustomIDRecord, error)
+
+	// GetCustomIDFeeRateHistory query all custom ID fee rate changes ordered
+	// by working height.
+	GetCustomIDFeeRateHistory() ([]store.CustomIDFeeRate, error)
+
+	// GetProposal query the CR proposal by it's proposal hash.
+	GetProposal(hash common.Uint256) (*store.ProposalInfo, error)
+
+	// GetProposalsByType query all CR proposals of the given proposal type.
+	GetProposalsByType(proposalType payload.CRCProposalType) ([]*store.ProposalInfo, error)
+
+	// GetProposalsByStatus query all CR proposals in the given status.
+	GetProposalsByStatus(status store.ProposalStatus) ([]*store.ProposalInfo, error)
+
+	// GetProposalsByHeight query all CR proposals packed between startHeight
+	// and endHeight, both inclusive.
+	GetProposalsByHeight(startHeight, endHeight uint32) ([]*store.ProposalInfo, error)
+
+	// GetCrossChainDeposit query the deposit to the side chain identified by
+	// genesisAddress within the given transaction.
+	GetCrossChainDeposit(genesisAddress string, txHash common.Uint256) (*CrossChainDeposit, error)
+
+	// GetCrossChainDeposits query deposits to the side chain identified by
+	// genesisAddress in height order, skip the first offset deposits and
+	// return at most limit deposits.
+	GetCrossChainDeposits(genesisAddress string, offset, limit uint32) ([]*CrossChainDeposit, error)
+
+	// GetTransactionsByAddress query the transactions sent from or received
+	// by the watched address from fromHeight to toHeight, both inclusive, in
+	// height order.  At most limit records are returned, zero limit returns
+	// all records.  Pass the returned cursor to the next query to continue,
+	// the cursor is empty if there are no more records.
+	GetTransactionsByAddress(addr string, fromHeight, toHeight, limit uint32,
+		cursor string) ([]*store.AddressTx, string, error)
+
+	// GetBalance query the balance of the registered address, UTXO tracking
+	// must be enabled by Config.TrackUTXOs.
+	GetBalance(addr string) (*Balance, error)
+
+	// ListUnspent query the unspent outputs of the registered address, UTXO
+	// tracking must be enabled by Config.TrackUTXOs.
+	ListUnspent(addr string) ([]*store.UTXO, error)
+
+	// GetProducer query the producer registered by the owner public key.
+	GetProducer(ownerKey []byte) (*store.ProducerInfo, error)
+
+	// GetProducerByNodeKey query the producer by it's node public key.
+	GetProducerByNodeKey(nodeKey []byte) (*store.ProducerInfo, error)
+
+	// GetProducers query all producers in the given state. Producer related
+	// transaction types should be included in the filter to keep the producer
+	// registry up to date.
+	GetProducers(state store.ProducerState) ([]*store.ProducerInfo, error)
+
 	//HaveRetSideChainDepositCoinTx query tx data by tx hash
 	HaveRetSideChainDepositCoinTx(txHash common.Uint256) bool
 
@@ -101,6 +228,33 @@ type SPVService interface {
 	// Get headers database
 	HeaderStore() store.HeaderStore
 
+	// ExportSnapshot writes a checksummed snapshot of the best chain headers
+	// up to height and the service data to w, it can be called while the
+	// service is running. Height must be the best height, and the export is
+	// refused while a chain reorganize is interrupted. The snapshot can be
+	// imported by ImportSnapshot.
+	ExportSnapshot(w io.Writer, height uint32) (*store.SnapshotInfo, error)
+
+	// VerifyIntegrity walks the header chain from the best header back to
+	// genesis and cross-checks the service data against it, the issues found
+	// are repaired if repair is true. The block processing is paused while
+	// repairing, and repair is refused while a chain reorganize is
+	// interrupted.
+	VerifyIntegrity(repair bool) (*store.IntegrityReport, error)
+
+	// PruneForks removes the side branch headers and fork transactions
+	// selected by the prune config immediately.
+	PruneForks() (*store.PruneReport, error)
+
+	// CompactHeaders removes the merkle data selected by the pruned mode
+	// config immediately, the bare headers are kept so GetByHeight and
+	// VerifyTransaction keep working.
+	CompactHeaders() (*store.CompactReport, error)
+
+	// GetPruneStats returns the metrics of pruning and compacting since the
+	// service started.
+	GetPruneStats() PruneStats
+
 	// Start the SPV service
 	Start()
 
@@ -157,6 +311,16 @@ type RevertListener interface {
 	NotifyRollbackRevertToDPOS(tx it.Transaction)
 }
 
+/*
+Register this listener to IService RegisterConsensusModeListener() method
+to receive consensus mode change notifications.
+*/
+type ConsensusModeListener interface {
+	// NotifyConsensusModeChanged is the method to callback when the consensus
+	// mode changed from previous to current on the given height.
+	NotifyConsensusModeChanged(height uint32, previous, current ConsensusAlgorithm)
+}
+
 /*
 Register this listener to IService RegisterBlockListener() method
 to receive block notifications.
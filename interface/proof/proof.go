@@ -0,0 +1,147 @@
+package proof
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/elastos/Elastos.ELA.SPV/bloom"
+	"github.com/elastos/Elastos.ELA.SPV/interface/iutil"
+
+	elacommon "github.com/elastos/Elastos.ELA/core/types/common"
+	"github.com/elastos/Elastos.ELA/core/types/functions"
+	it "github.com/elastos/Elastos.ELA/core/types/interfaces"
+	"github.com/elastos/Elastos.ELA/p2p/msg"
+)
+
+var (
+	// ErrBlockHashMismatch indicates the proof is not created from the
+	// given block header.
+	ErrBlockHashMismatch = errors.New("block hash not match proof")
+
+	// ErrHeightMismatch indicates the proof height is not the height of
+	// the given block header.
+	ErrHeightMismatch = errors.New("block height not match proof")
+
+	// ErrNoTransactions indicates no transaction is matched in the proof.
+	ErrNoTransactions = errors.New("invalid transaction proof, no transactions found")
+
+	// ErrTxHashMismatch indicates the transaction is not proved by the proof.
+	ErrTxHashMismatch = errors.New("transaction hash not match proof")
+)
+
+// TransactionProof bundles the merkle proof with the transaction and the
+// block header it is packed in, so it can be verified without a database.
+type TransactionProof struct {
+	Proof       bloom.MerkleProof
+	Header      *elacommon.Header
+	Transaction it.Transaction
+}
+
+// transactionProofJSON is the JSON format of TransactionProof, the header
+// and the transaction are encoded as hex string of their raw data.
+type transactionProofJSON struct {
+	Proof       bloom.MerkleProof `json:"proof"`
+	Header      string            `json:"header"`
+	Transaction string            `json:"transaction"`
+}
+
+func (p TransactionProof) MarshalJSON() ([]byte, error) {
+	if p.Header == nil || p.Transaction == nil {
+		return nil, errors.New("header or transaction not set")
+	}
+	header := new(bytes.Buffer)
+	if err := p.Header.Serialize(header); err != nil {
+		return nil, err
+	}
+	tx := new(bytes.Buffer)
+	if err := p.Transaction.Serialize(tx); err != nil {
+		return nil, err
+	}
+	return json.Marshal(transactionProofJSON{
+		Proof:       p.Proof,
+		Header:      hex.EncodeToString(header.Bytes()),
+		Transaction: hex.EncodeToString(tx.Bytes()),
+	})
+}
+
+func (p *TransactionProof) UnmarshalJSON(data []byte) error {
+	var proof transactionProofJSON
+	if err := json.Unmarshal(data, &proof); err != nil {
+		return err
+	}
+
+	headerData, err := hex.DecodeString(proof.Header)
+	if err != nil {
+		return err
+	}
+	var header elacommon.Header
+	if err := header.Deserialize(bytes.NewReader(headerData)); err != nil {
+		return fmt.Errorf("invalid header, %s", err.Error())
+	}
+
+	txData, err := hex.DecodeString(proof.Transaction)
+	if err != nil {
+		return err
+	}
+	r := bytes.NewReader(txData)
+	tx, err := functions.GetTransactionByBytes(r)
+	if err != nil {
+		return errors.New("invalid transaction")
+	}
+	if err := tx.Deserialize(r); err != nil {
+		return fmt.Errorf("invalid transaction, %s", err.Error())
+	}
+
+	p.Proof = proof.Proof
+	p.Header = &header
+	p.Transaction = tx
+	return nil
+}
+
+// Verify checks the proof, the transaction and the header are consistent.
+func (p *TransactionProof) Verify() error {
+	if p.Header == nil || p.Transaction == nil {
+		return errors.New("header or transaction not set")
+	}
+	return VerifyTransaction(p.Proof, p.Header, p.Transaction)
+}
+
+// VerifyTransaction checks the proof is created from the header and the
+// transaction is proved by the proof. It only checks the consistency of the
+// given data, callers should make sure the header is on the best chain.
+func VerifyTransaction(proof bloom.MerkleProof, header *elacommon.Header,
+	tx it.Transaction) error {
+	if proof.BlockHash != header.Hash() {
+		return ErrBlockHashMismatch
+	}
+	if proof.Height != header.Height {
+		return ErrHeightMismatch
+	}
+
+	// Check if merkleroot is match
+	merkleBlock := msg.MerkleBlock{
+		Header:       iutil.NewHeader(header),
+		Transactions: proof.Transactions,
+		Hashes:       proof.Hashes,
+		Flags:        proof.Flags,
+	}
+	txIds, err := bloom.CheckMerkleBlock(merkleBlock)
+	if err != nil {
+		return fmt.Errorf("check merkle branch failed, %s", err.Error())
+	}
+	if len(txIds) == 0 {
+		return ErrNoTransactions
+	}
+
+	// Check if transaction hash is match
+	txHash := tx.Hash()
+	for _, txId := range txIds {
+		if *txId == txHash {
+			return nil
+		}
+	}
+	return ErrTxHashMismatch
+}
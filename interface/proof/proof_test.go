@@ -0,0 +1,79 @@
+package proof
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/elastos/Elastos.ELA.SPV/bloom"
+
+	"github.com/elastos/Elastos.ELA/common"
+	elatx "github.com/elastos/Elastos.ELA/core/transaction"
+	elacommon "github.com/elastos/Elastos.ELA/core/types/common"
+	it "github.com/elastos/Elastos.ELA/core/types/interfaces"
+	"github.com/elastos/Elastos.ELA/core/types/payload"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newTx(lockTime uint32) it.Transaction {
+	return elatx.CreateTransaction(
+		elacommon.TxVersion09,
+		elacommon.TransferAsset,
+		0,
+		&payload.TransferAsset{},
+		[]*elacommon.Attribute{},
+		[]*elacommon.Input{},
+		[]*elacommon.Output{},
+		lockTime,
+		nil,
+	)
+}
+
+func TestTransactionProof(t *testing.T) {
+	tx0, tx1 := newTx(0), newTx(1)
+	hash0, hash1 := tx0.Hash(), tx1.Hash()
+	header := &elacommon.Header{
+		Height:     100,
+		MerkleRoot: *bloom.HashMerkleBranches(&hash0, &hash1),
+	}
+
+	// proof of tx1, flags 101 indicates root is parent, tx0 not matched and
+	// tx1 matched.
+	p := TransactionProof{
+		Proof: bloom.MerkleProof{
+			BlockHash:    header.Hash(),
+			Height:       header.Height,
+			Transactions: 2,
+			Hashes:       []*common.Uint256{&hash0, &hash1},
+			Flags:        []byte{0x05},
+		},
+		Header:      header,
+		Transaction: tx1,
+	}
+	assert.NoError(t, p.Verify())
+	assert.Equal(t, ErrTxHashMismatch, VerifyTransaction(p.Proof, header, tx0))
+
+	invalid := p.Proof
+	invalid.Height = 101
+	assert.Equal(t, ErrHeightMismatch, VerifyTransaction(invalid, header, tx1))
+	invalid = p.Proof
+	invalid.BlockHash = common.Uint256{}
+	assert.Equal(t, ErrBlockHashMismatch, VerifyTransaction(invalid, header, tx1))
+	invalid = p.Proof
+	invalid.Hashes = []*common.Uint256{&hash1, &hash0}
+	assert.Error(t, VerifyTransaction(invalid, header, tx1))
+
+	// JSON round trip.
+	data, err := json.Marshal(p)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	var decoded TransactionProof
+	if !assert.NoError(t, json.Unmarshal(data, &decoded)) {
+		t.FailNow()
+	}
+	assert.Equal(t, p.Proof, decoded.Proof)
+	assert.Equal(t, header.Hash(), decoded.Header.Hash())
+	assert.Equal(t, tx1.Hash(), decoded.Transaction.Hash())
+	assert.NoError(t, decoded.Verify())
+}
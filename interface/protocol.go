@@ -2,4 +2,7 @@ package _interface
 
 const (
 	DefaultConfirmations = 6
+
+	// coinbaseMaturity is the blocks coinbase outputs locked for.
+	coinbaseMaturity = 100
 )
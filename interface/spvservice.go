@@ -10,12 +10,16 @@ import (
 	"io"
 	"math"
 	"os"
+	"path/filepath"
+	"sync"
 	"time"
 
 	"github.com/elastos/Elastos.ELA.SPV/bloom"
 	"github.com/elastos/Elastos.ELA.SPV/database"
 	"github.com/elastos/Elastos.ELA.SPV/interface/iutil"
+	"github.com/elastos/Elastos.ELA.SPV/interface/proof"
 	"github.com/elastos/Elastos.ELA.SPV/interface/store"
+	"github.com/elastos/Elastos.ELA.SPV/interface/store/kv"
 	"github.com/elastos/Elastos.ELA.SPV/sdk"
 	"github.com/elastos/Elastos.ELA.SPV/util"
 	"github.com/elastos/Elastos.ELA/common"
@@ -32,9 +36,15 @@ import (
 const (
 	defaultDataDir = "./data_spv"
 
+	// reorgJournal is the file name of the chain reorganize journal.
+	reorgJournal = "reorg.journal"
+
 	// notifyTimeout is the duration to timeout a notify to the listener, and
 	// resend the notify to the listener.
 	notifyTimeout = 10 * time.Second // 10 second
+
+	// defaultPruneInterval is the default interval to prune side branches.
+	defaultPruneInterval = 10 * time.Minute
 )
 
 type ConsensusAlgorithm byte
@@ -44,6 +54,106 @@ const (
 	POW  ConsensusAlgorithm = 0x01
 )
 
+// ConsensusModeInterval is a range of main chain heights running on the same
+// consensus mode, TxHash is the revert transaction started this interval.
+type ConsensusModeInterval struct {
+	StartHeight uint32
+	EndHeight   uint32
+	Mode        ConsensusAlgorithm
+	TxHash      common.Uint256
+}
+
+// CrossChainDeposit is the deposit to side chain with the merkle proof of
+// the deposit transaction and it's confirmation status.
+type CrossChainDeposit struct {
+	store.CrossChainDeposit
+	Proof         bloom.MerkleProof
+	Confirmations uint32
+	Confirmed     bool
+}
+
+var (
+	// ErrNotOnBestChain indicates the block of the proof is not on the
+	// current best chain, maybe a stale fork block.
+	ErrNotOnBestChain = errors.New("block not on the best chain")
+
+	// ErrNotEnoughConfirmations indicates the block of the proof has not
+	// reach the confirmations required by the policy.
+	ErrNotEnoughConfirmations = errors.New("not enough confirmations")
+
+	// ErrUTXOsNotTracked indicates the UTXO tracking is not enabled by
+	// Config.TrackUTXOs.
+	ErrUTXOsNotTracked = errors.New("UTXO tracking not enabled")
+
+	// ErrMigrateDryRun is returned by NewSPVService after the migrations
+	// checked with Config.MigrateDryRun set.
+	ErrMigrateDryRun = errors.New("database migration dry run finished")
+
+	// ErrReorgInterrupted indicates a chain reorganize was interrupted and
+	// not finished, the databases are in the middle of switching chains.
+	ErrReorgInterrupted = errors.New("chain reorganize interrupted, restart the service to resume it")
+)
+
+// Balance is the balance of an address split by the spendable state of it's
+// unspent outputs.
+type Balance struct {
+	// Confirmed is the value of outputs with enough confirmations.
+	Confirmed common.Fixed64
+
+	// Pending is the value of outputs in blocks without enough
+	// confirmations.
+	Pending common.Fixed64
+
+	// Locked is the value of outputs locked by output lock or coinbase
+	// maturity on the best height.
+	Locked common.Fixed64
+}
+
+// PruneStats is the metrics of pruning side branches and compacting headers
+// since the service started.
+type PruneStats struct {
+	// Runs is the number of prunes and compactions finished.
+	Runs int
+
+	// Headers is the number of side branch headers removed.
+	Headers int
+
+	// ForkTxs is the number of fork blocks transactions removed.
+	ForkTxs int
+
+	// Compacted is the number of best chain headers with merkle data
+	// removed.
+	Compacted int
+
+	// Reclaimed is the bytes of keys and values removed.
+	Reclaimed uint64
+
+	// LastPrune is the time the last prune or compaction finished.
+	LastPrune time.Time
+
+	// LastError is the error of the last prune or compaction, nil if
+	// succeeded.
+	LastError error
+}
+
+// VerifyPolicy is the policy to verify a transaction with.
+type VerifyPolicy struct {
+	// Confirmations is the minimum confirmations of the block the
+	// transaction packed in.
+	Confirmations uint32
+}
+
+// VerifyResult is the result of verifying a transaction with policy, Err is
+// nil if the transaction passed the verification.
+type VerifyResult struct {
+	TxHash        common.Uint256
+	BlockHash     common.Uint256
+	Height        uint32
+	BlockTime     uint32
+	Confirmations uint32
+	Err           error
+}
+
 type spvservice struct {
 	sdk.IService
 	headers        store.HeaderStore
@@ -52,10 +162,87 @@ type spvservice struct {
 	listeners      map[common.Uint256]TransactionListener
 	revertListener RevertListener
 	blockListener  BlockListener
+	modeListener   ConsensusModeListener
+	// the consensus mode of the last committed block
+	consensusMode *ConsensusAlgorithm
 	//FilterType is the filter type .(FTBloom, FTDPOS  and so on )
 	filterType uint8
 	// p2p  Protocol version height  use to change version msg content
 	NewP2PProtocolVersionHeight uint64
+
+	trackUTXOs bool
+
+	journal database.ReorgJournal
+
+	pruneOpts     store.PruneOptions
+	compactOpts   store.CompactOptions
+	pruneInterval time.Duration
+	pruneLock     sync.Mutex
+	pruneStats    PruneStats
+	quit          chan struct{}
+	wg            sync.WaitGroup
+}
+
+// migrateStores upgrades the header store and the data store in dataDir to
+// the current schema version.
+func migrateStores(dataDir string, cfg *Config) error {
+	opts := store.MigrateOptions{DryRun: cfg.MigrateDryRun}
+	if len(cfg.MigrateBackupDir) > 0 {
+		opts.BackupDir = filepath.Join(cfg.MigrateBackupDir, "header")
+	}
+	result, err := store.MigrateHeaderStore(dataDir, newBlockHeader, opts)
+	if err != nil {
+		return fmt.Errorf("migrate header store failed, %s", err)
+	}
+	log.Infof("header store migration: %s %v", result, result.Applied)
+
+	if len(cfg.MigrateBackupDir) > 0 {
+		opts.BackupDir = filepath.Join(cfg.MigrateBackupDir, "store")
+	}
+	result, err = store.MigrateDataStore(dataDir, opts)
+	if err != nil {
+		return fmt.Errorf("migrate data store failed, %s", err)
+	}
+	log.Infof("data store migration: %s %v", result, result.Applied)
+
+	if cfg.MigrateDryRun {
+		return ErrMigrateDryRun
+	}
+	return nil
+}
+
+// CheckReorgJournal returns ErrReorgInterrupted if a chain reorganize of the
+// stopped service in dataDir was interrupted, the databases must not be
+// exported or repaired before the service resumed the reorganize.
+func CheckReorgJournal(dataDir string) error {
+	if len(dataDir) == 0 {
+		dataDir = defaultDataDir
+	}
+	return checkReorgJournal(database.NewFileJournal(
+		filepath.Join(dataDir, reorgJournal)))
+}
+
+// checkReorgJournal returns ErrReorgInterrupted if there is a record in the
+// reorganize journal.
+func checkReorgJournal(journal database.ReorgJournal) error {
+	record, err := journal.Get()
+	if err != nil {
+		return err
+	}
+	if record != nil {
+		return ErrReorgInterrupted
+	}
+	return nil
+}
+
+// ImportSnapshot creates the service databases in dataDir from the snapshot
+// exported by SPVService.ExportSnapshot, dataDir must not contain databases.
+// The header chain and checksum of the snapshot are verified.
+func ImportSnapshot(r io.Reader, dataDir string) (*store.SnapshotInfo, error) {
+	if len(dataDir) == 0 {
+		dataDir = defaultDataDir
+	}
+	return store.ImportSnapshot(r, dataDir, newBlockHeader)
 }
 
 // NewSPVService creates a new SPV service instance.
@@ -72,7 +259,15 @@ func NewSPVService(cfg *Config) (*spvservice, error) {
 		}
 	}
 
-	headerStore, err := store.NewHeaderStore(dataDir, newBlockHeader)
+	var headerStore store.HeaderStore
+	if cfg.InMemory {
+		headerStore, err = store.NewMemoryHeaderStore(newBlockHeader)
+	} else {
+		if err := migrateStores(dataDir, cfg); err != nil {
+			return nil, err
+		}
+		headerStore, err = store.NewHeaderStore(dataDir, newBlockHeader)
+	}
 	if err != nil {
 		return nil, err
 	}
@@ -85,8 +280,15 @@ func NewSPVService(cfg *Config) (*spvservice, error) {
 		}
 		originArbiters = append(originArbiters, v)
 	}
-	dataStore, err := store.NewDataStore(dataDir, originArbiters,
-		len(cfg.ChainParams.DPoSConfiguration.CRCArbiters)*3, cfg.GenesisBlockAddress)
+	var dataStore store.DataStore
+	arbitersCount := len(cfg.ChainParams.DPoSConfiguration.CRCArbiters) * 3
+	if cfg.InMemory {
+		dataStore, err = store.NewMemoryDataStore(originArbiters,
+			arbitersCount, cfg.GenesisBlockAddress)
+	} else {
+		dataStore, err = store.NewDataStore(dataDir, originArbiters,
+			arbitersCount, cfg.GenesisBlockAddress)
+	}
 	if err != nil {
 		return nil, err
 	}
@@ -98,9 +300,30 @@ func NewSPVService(cfg *Config) (*spvservice, error) {
 		listeners:                   make(map[common.Uint256]TransactionListener),
 		filterType:                  cfg.FilterType,
 		NewP2PProtocolVersionHeight: cfg.ChainParams.CRConfiguration.NewP2PProtocolVersionHeight,
+		pruneOpts: store.PruneOptions{
+			Depth:      cfg.PruneDepth,
+			Checkpoint: cfg.PruneCheckpoint,
+		},
+		compactOpts: store.CompactOptions{
+			KeepRecent: cfg.CompactKeepRecent,
+		},
+		pruneInterval: cfg.PruneInterval,
+		trackUTXOs:    cfg.TrackUTXOs,
+	}
+	if service.pruneInterval <= 0 {
+		service.pruneInterval = defaultPruneInterval
 	}
 
-	chainStore := database.NewChainDB(headerStore, service)
+	if cfg.InMemory {
+		service.journal = database.NewMemoryJournal()
+	} else {
+		service.journal = database.NewFileJournal(filepath.Join(dataDir, reorgJournal))
+	}
+	chainStore, err := database.NewChainDBWithJournal(headerStore, service,
+		service.journal)
+	if err != nil {
+		return nil, err
+	}
 
 	serviceCfg := &sdk.Config{
 		DataDir:        dataDir,
@@ -158,44 +381,134 @@ func (s *spvservice) RegisterBlockListener(listener BlockListener) error {
 	return nil
 }
 
+func (s *spvservice) RegisterConsensusModeListener(listener ConsensusModeListener) error {
+	s.modeListener = listener
+	return nil
+}
+
 func (s *spvservice) SubmitTransactionReceipt(notifyId, txHash common.Uint256) error {
 	return s.db.Que().Del(&notifyId, &txHash)
 }
 
-func (s *spvservice) VerifyTransaction(proof bloom.MerkleProof, tx it.Transaction) error {
+func (s *spvservice) VerifyTransaction(p bloom.MerkleProof, tx it.Transaction) error {
 	// Get Header from main chain
-	header, err := s.headers.Get(&proof.BlockHash)
+	header, err := s.headers.Get(&p.BlockHash)
 	if err != nil {
 		return errors.New("can not get block from main chain")
 	}
-
-	// Check if merkleroot is match
-	merkleBlock := msg.MerkleBlock{
-		Header:       header.BlockHeader,
-		Transactions: proof.Transactions,
-		Hashes:       proof.Hashes,
-		Flags:        proof.Flags,
+	h, ok := header.BlockHeader.(*iutil.Header)
+	if !ok {
+		return errors.New("invalid block header")
 	}
-	txIds, err := bloom.CheckMerkleBlock(merkleBlock)
+
+	return proof.VerifyTransaction(p, h.Header, tx)
+}
+
+// VerifyTransactionWithPolicy verifies the transaction is packed in a block
+// on the best chain with the confirmations required by the policy.
+func (s *spvservice) VerifyTransactionWithPolicy(p bloom.MerkleProof,
+	tx it.Transaction, policy VerifyPolicy) (*VerifyResult, error) {
+	results, err := s.VerifyTransactionsWithPolicy(
+		[]bloom.MerkleProof{p}, []it.Transaction{tx}, policy)
 	if err != nil {
-		return fmt.Errorf("check merkle branch failed, %s", err.Error())
+		return nil, err
+	}
+	return results[0], results[0].Err
+}
+
+// VerifyTransactionsWithPolicy verifies transactions with their proofs by
+// the policy, the best header and the headers shared by proofs will be read
+// only once. The returned error is not nil only if the best header can not be
+// read, the verification error of each transaction is in it's result,
+// including the error reading the header on it's height.
+func (s *spvservice) VerifyTransactionsWithPolicy(proofs []bloom.MerkleProof,
+	txs []it.Transaction, policy VerifyPolicy) ([]*VerifyResult, error) {
+	if len(proofs) != len(txs) {
+		return nil, errors.New("proofs and transactions count not match")
 	}
-	if len(txIds) == 0 {
-		return fmt.Errorf("invalid transaction proof, no transactions found")
+
+	best, err := s.headers.GetBest()
+	if err != nil {
+		return nil, err
 	}
 
-	// Check if transaction hash is match
-	match := false
-	for _, txId := range txIds {
-		if *txId == tx.Hash() {
-			match = true
-			break
+	headers := make(map[uint32]*util.Header)
+	results := make([]*VerifyResult, 0, len(proofs))
+	for i, p := range proofs {
+		result := &VerifyResult{
+			TxHash:    txs[i].Hash(),
+			BlockHash: p.BlockHash,
+			Height:    p.Height,
+		}
+		results = append(results, result)
+
+		if p.Height > best.Height {
+			result.Err = ErrNotOnBestChain
+			continue
+		}
+		header, ok := headers[p.Height]
+		if !ok {
+			header, err = s.headers.GetByHeight(p.Height)
+			if err != nil {
+				result.Err = err
+				continue
+			}
+			headers[p.Height] = header
+		}
+		if header.Hash() != p.BlockHash {
+			result.Err = ErrNotOnBestChain
+			continue
+		}
+
+		h, ok := header.BlockHeader.(*iutil.Header)
+		if !ok {
+			result.Err = errors.New("invalid block header")
+			continue
+		}
+		result.BlockTime = h.Timestamp
+		result.Confirmations = best.Height - p.Height
+		if err := proof.VerifyTransaction(p, h.Header, txs[i]); err != nil {
+			result.Err = err
+			continue
 		}
+		if result.Confirmations < policy.Confirmations {
+			result.Err = ErrNotEnoughConfirmations
+		}
+	}
+	return results, nil
+}
+
+// GetTransactionMerkleBranch returns the merkle branch of the stored
+// transaction, derived from the merkle proof stored with the block header.
+func (s *spvservice) GetTransactionMerkleBranch(txId common.Uint256) (*bloom.MerkleBranch, error) {
+	utx, err := s.db.Txs().Get(&txId)
+	if err != nil {
+		return nil, err
 	}
-	if !match {
-		return fmt.Errorf("transaction hash not match proof")
+	header, err := s.headers.GetByHeight(utx.Height)
+	if err != nil {
+		return nil, err
 	}
+	return bloom.GetTxMerkleBranch(msg.MerkleBlock{
+		Header:       header.BlockHeader,
+		Transactions: header.NumTxs,
+		Hashes:       header.Hashes,
+		Flags:        header.Flags,
+	}, &txId)
+}
 
+// VerifyMerkleBranch verifies the transaction is packed in the block by the
+// merkle branch, the block header must be stored.
+func (s *spvservice) VerifyMerkleBranch(branch *bloom.MerkleBranch,
+	txId common.Uint256, blockHash common.Uint256) error {
+	header, err := s.headers.Get(&blockHash)
+	if err != nil {
+		return errors.New("can not get block from main chain")
+	}
+	if branch.MerkleRoot(txId) != header.MerkleRoot() {
+		return fmt.Errorf("merkle root not match, transaction %s not in"+
+			" block %s", txId.String(), blockHash.String())
+	}
 	return nil
 }
 
@@ -208,7 +521,11 @@ func (s *spvservice) GetTransaction(txId *common.Uint256) (it.Transaction, error
 	if err != nil {
 		return nil, err
 	}
+	return decodeTransaction(utx)
+}
 
+// decodeTransaction decodes the transaction stored in database.
+func decodeTransaction(utx *util.Tx) (it.Transaction, error) {
 	r := bytes.NewReader(utx.RawData)
 	tx, err := functions.GetTransactionByBytes(r)
 	if err != nil {
@@ -238,6 +555,25 @@ func (s *spvservice) GetConsensusAlgorithm(height uint32) (ConsensusAlgorithm, e
 	return ConsensusAlgorithm(mode), err
 }
 
+// Get consensus mode intervals between startHeight and endHeight.
+func (s *spvservice) GetConsensusModeTimeline(startHeight, endHeight uint32) ([]ConsensusModeInterval, error) {
+	intervals, err := s.db.Arbiters().GetConsensusModeTimeline(startHeight, endHeight)
+	if err != nil {
+		return nil, err
+	}
+
+	timeline := make([]ConsensusModeInterval, 0, len(intervals))
+	for _, i := range intervals {
+		timeline = append(timeline, ConsensusModeInterval{
+			StartHeight: i.StartHeight,
+			EndHeight:   i.EndHeight,
+			Mode:        ConsensusAlgorithm(i.Mode),
+			TxHash:      i.TxHash,
+		})
+	}
+	return timeline, nil
+}
+
 // Get reserved custom ID.
 func (s *spvservice) GetReservedCustomIDs(height uint32) (map[string]struct{}, error) {
 	return s.db.CID().GetReservedCustomIDs(height, s.db.Arbiters().GetRevertInfo())
@@ -253,6 +589,195 @@ func (s *spvservice) GetRateOfCustomIDFee(height uint32) (common.Fixed64, error)
 	return s.db.CID().GetCustomIDFeeRate(height)
 }
 
+// Get current state of the custom ID.
+func (s *spvservice) GetCustomIDRecord(customID string) (*store.CustomIDRecord, error) {
+	return s.db.CID().GetCustomIDRecord(customID)
+}
+
+// Get custom IDs received by the DID.
+func (s *spvservice) GetCustomIDsByOwner(did common.Uint168) ([]*store.CustomIDRecord, error) {
+	return s.db.CID().GetCustomIDsByOwner(did)
+}
+
+// Get all changes of custom ID fee rate.
+func (s *spvservice) GetCustomIDFeeRateHistory() ([]store.CustomIDFeeRate, error) {
+	return s.db.CID().GetCustomIDFeeRateHistory()
+}
+
+// Get CR proposal by proposal hash.
+func (s *spvservice) GetProposal(hash common.Uint256) (*store.ProposalInfo, error) {
+	return s.db.Proposals().Get(hash)
+}
+
+// Get CR proposals by proposal type.
+func (s *spvservice) GetProposalsByType(proposalType payload.CRCProposalType) ([]*store.ProposalInfo, error) {
+	return s.db.Proposals().GetByType(proposalType)
+}
+
+// Get CR proposals by proposal status.
+func (s *spvservice) GetProposalsByStatus(status store.ProposalStatus) ([]*store.ProposalInfo, error) {
+	return s.db.Proposals().GetByStatus(status)
+}
+
+// Get CR proposals packed between startHeight and endHeight.
+func (s *spvservice) GetProposalsByHeight(startHeight, endHeight uint32) ([]*store.ProposalInfo, error) {
+	return s.db.Proposals().GetByHeight(startHeight, endHeight)
+}
+
+// Get cross chain deposit to the side chain by transaction hash.
+func (s *spvservice) GetCrossChainDeposit(genesisAddress string,
+	txHash common.Uint256) (*CrossChainDeposit, error) {
+	deposit, err := s.db.Deposits().Get(genesisAddress, txHash)
+	if err != nil {
+		return nil, err
+	}
+	return s.newCrossChainDeposit(deposit)
+}
+
+// Get cross chain deposits to the side chain in height order.
+func (s *spvservice) GetCrossChainDeposits(genesisAddress string,
+	offset, limit uint32) ([]*CrossChainDeposit, error) {
+	deposits, err := s.db.Deposits().List(genesisAddress, offset, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([]*CrossChainDeposit, 0, len(deposits))
+	for _, d := range deposits {
+		deposit, err := s.newCrossChainDeposit(d)
+		if err != nil {
+			return nil, err
+		}
+		results = append(results, deposit)
+	}
+	return results, nil
+}
+
+// Get the transaction history of the watched address in height order.
+func (s *spvservice) GetTransactionsByAddress(addr string, fromHeight,
+	toHeight, limit uint32, cursor string) ([]*store.AddressTx, string, error) {
+	programHash, err := common.Uint168FromAddress(addr)
+	if err != nil {
+		return nil, "", err
+	}
+	return s.db.AddrTxs().List(*programHash, fromHeight, toHeight, limit, cursor)
+}
+
+// Get the balance of the registered address on the best height.
+func (s *spvservice) GetBalance(addr string) (*Balance, error) {
+	utxos, err := s.ListUnspent(addr)
+	if err != nil {
+		return nil, err
+	}
+	best, err := s.headers.GetBest()
+	if err != nil {
+		return nil, err
+	}
+
+	var balance Balance
+	for _, utxo := range utxos {
+		var confirmations uint32
+		if best.Height > utxo.AtHeight {
+			confirmations = best.Height - utxo.AtHeight
+		}
+		switch {
+		case utxo.LockTime > best.Height:
+			balance.Locked += utxo.Value
+		case confirmations < DefaultConfirmations:
+			balance.Pending += utxo.Value
+		default:
+			balance.Confirmed += utxo.Value
+		}
+	}
+	return &balance, nil
+}
+
+// Get the unspent outputs of the registered address.
+func (s *spvservice) ListUnspent(addr string) ([]*store.UTXO, error) {
+	if !s.trackUTXOs {
+		return nil, ErrUTXOsNotTracked
+	}
+	programHash, err := common.Uint168FromAddress(addr)
+	if err != nil {
+		return nil, err
+	}
+	return s.db.UTXOs().List(*programHash)
+}
+
+// Get producer by owner public key.
+func (s *spvservice) GetProducer(ownerKey []byte) (*store.ProducerInfo, error) {
+	info, err := s.db.Producers().Get(ownerKey)
+	if err != nil {
+		return nil, err
+	}
+	return s.updateProducerState(info)
+}
+
+// Get producer by node public key.
+func (s *spvservice) GetProducerByNodeKey(nodeKey []byte) (*store.ProducerInfo, error) {
+	info, err := s.db.Producers().GetByNodeKey(nodeKey)
+	if err != nil {
+		return nil, err
+	}
+	return s.updateProducerState(info)
+}
+
+// Get producers in the given state.
+func (s *spvservice) GetProducers(state store.ProducerState) ([]*store.ProducerInfo, error) {
+	infos, err := s.db.Producers().GetAll()
+	if err != nil {
+		return nil, err
+	}
+
+	var producers []*store.ProducerInfo
+	for _, info := range infos {
+		if _, err := s.updateProducerState(info); err != nil {
+			return nil, err
+		}
+		if info.State == state {
+			producers = append(producers, info)
+		}
+	}
+	return producers, nil
+}
+
+// updateProducerState sets the producer state on current best height.
+func (s *spvservice) updateProducerState(info *store.ProducerInfo) (*store.ProducerInfo, error) {
+	best, err := s.headers.GetBest()
+	if err != nil {
+		return nil, err
+	}
+	info.State = info.StateAt(best.Height)
+	return info, nil
+}
+
+func (s *spvservice) newCrossChainDeposit(d *store.CrossChainDeposit) (*CrossChainDeposit, error) {
+	header, err := s.headers.GetByHeight(d.Height)
+	if err != nil {
+		return nil, err
+	}
+	best, err := s.headers.GetBest()
+	if err != nil {
+		return nil, err
+	}
+
+	deposit := &CrossChainDeposit{
+		CrossChainDeposit: *d,
+		Proof: bloom.MerkleProof{
+			BlockHash:    header.Hash(),
+			Height:       header.Height,
+			Transactions: header.NumTxs,
+			Hashes:       header.Hashes,
+			Flags:        header.Flags,
+		},
+	}
+	if best.Height > d.Height {
+		deposit.Confirmations = best.Height - d.Height
+	}
+	deposit.Confirmed = deposit.Confirmations >= DefaultConfirmations
+	return deposit, nil
+}
+
 //GetReturnSideChainDepositCoin query tx data by tx hash
 func (s *spvservice) HaveRetSideChainDepositCoinTx(txHash common.Uint256) bool {
 	return s.db.CID().HaveRetSideChainDepositCoinTx(txHash)
@@ -270,6 +795,134 @@ func (s *spvservice) HeaderStore() store.HeaderStore {
 	return s.headers
 }
 
+func (s *spvservice) ExportSnapshot(w io.Writer, height uint32) (*store.SnapshotInfo, error) {
+	// Blocks are committed headers first, pause the block processing so the
+	// data snapshot matches the header snapshot.
+	unpause := s.IService.Pause()
+	snapshot, err := store.NewStoreSnapshot(s.headers, s.db)
+	if err == nil {
+		err = checkReorgJournal(s.journal)
+	}
+	close(unpause)
+	if err != nil {
+		if snapshot != nil {
+			snapshot.Release()
+		}
+		return nil, err
+	}
+	defer snapshot.Release()
+
+	return snapshot.Export(w, height)
+}
+
+func (s *spvservice) VerifyIntegrity(repair bool) (*store.IntegrityReport, error) {
+	if !repair {
+		return store.VerifyStores(s.headers, s.db, false)
+	}
+
+	// Repair prunes the data above the best header, pause the block
+	// processing so no block is committed while repairing.
+	unpause := s.IService.Pause()
+	defer close(unpause)
+	if err := checkReorgJournal(s.journal); err != nil {
+		return nil, err
+	}
+	return store.VerifyStores(s.headers, s.db, true)
+}
+
+func (s *spvservice) PruneForks() (*store.PruneReport, error) {
+	s.pruneLock.Lock()
+	defer s.pruneLock.Unlock()
+
+	report, err := store.PruneStores(s.headers, s.db, s.pruneOpts)
+	s.pruneStats.LastPrune = time.Now()
+	s.pruneStats.LastError = err
+	if err != nil {
+		return nil, err
+	}
+	s.pruneStats.Runs++
+	s.pruneStats.Headers += report.Headers
+	s.pruneStats.ForkTxs += report.ForkTxs
+	s.pruneStats.Reclaimed += report.Reclaimed
+	return report, nil
+}
+
+func (s *spvservice) CompactHeaders() (*store.CompactReport, error) {
+	s.pruneLock.Lock()
+	defer s.pruneLock.Unlock()
+
+	report, err := store.CompactStores(s.headers, s.db, s.compactOpts)
+	s.pruneStats.LastPrune = time.Now()
+	s.pruneStats.LastError = err
+	if err != nil {
+		return nil, err
+	}
+	s.pruneStats.Runs++
+	s.pruneStats.Compacted += report.Compacted
+	s.pruneStats.Reclaimed += report.Reclaimed
+	return report, nil
+}
+
+func (s *spvservice) GetPruneStats() PruneStats {
+	s.pruneLock.Lock()
+	defer s.pruneLock.Unlock()
+	return s.pruneStats
+}
+
+// pruneHandler prunes side branches and compacts headers by the prune
+// interval until the service stopped.
+func (s *spvservice) pruneHandler() {
+	defer s.wg.Done()
+
+	ticker := time.NewTicker(s.pruneInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			if s.pruneOpts.Enabled() {
+				report, err := s.PruneForks()
+				if err != nil {
+					log.Warnf("Prune side branches error %s", err)
+				} else {
+					log.Debugf("Prune side branches finished, %s", report)
+				}
+			}
+			if s.compactOpts.Enabled() {
+				report, err := s.CompactHeaders()
+				if err != nil {
+					log.Warnf("Compact headers error %s", err)
+				} else {
+					log.Debugf("Compact headers finished, %s", report)
+				}
+			}
+
+		case <-s.quit:
+			return
+		}
+	}
+}
+
+// Start the SPV service and the background prune if enabled.
+func (s *spvservice) Start() {
+	s.IService.Start()
+	enabled := s.pruneOpts.Enabled() || s.compactOpts.Enabled()
+	if enabled && s.quit == nil {
+		s.quit = make(chan struct{})
+		s.wg.Add(1)
+		go s.pruneHandler()
+	}
+}
+
+// Stop the background prune and the SPV service.
+func (s *spvservice) Stop() {
+	if s.quit != nil {
+		close(s.quit)
+		s.wg.Wait()
+		s.quit = nil
+	}
+	s.IService.Stop()
+}
+
 func (s *spvservice) GetFilter() *msg.TxFilterLoad {
 	addrs := s.db.Addrs().GetAll()
 	txTypes := s.db.TxTypes().GetAll()
@@ -289,15 +942,15 @@ func (s *spvservice) putTx(batch store.DataBatch, utx util.Transaction,
 		revertToPOW := tx.Payload().(*payload.RevertToPOW)
 		nakedBatch := batch.GetNakedBatch()
 		err := s.db.Arbiters().BatchPutRevertTransaction(
-			nakedBatch, revertToPOW.WorkingHeight, byte(POW))
+			nakedBatch, revertToPOW.WorkingHeight, byte(POW), tx.Hash(), height)
 		if err != nil {
 			return false, err
 		}
 	case elacommon.RevertToDPOS:
 		revertToDPOS := tx.Payload().(*payload.RevertToDPOS)
 		nakedBatch := batch.GetNakedBatch()
-		err := s.db.Arbiters().BatchPutRevertTransaction(
-			nakedBatch, height+revertToDPOS.WorkHeightInterval, byte(DPOS))
+		err := s.db.Arbiters().BatchPutRevertTransaction(nakedBatch,
+			height+revertToDPOS.WorkHeightInterval, byte(DPOS), tx.Hash(), height)
 		if err != nil {
 			return false, err
 		}
@@ -319,12 +972,45 @@ func (s *spvservice) putTx(batch store.DataBatch, utx util.Transaction,
 		if err != nil {
 			return false, err
 		}
+	case elacommon.TransferCrossChainAsset:
+		deposits, err := store.GetCrossChainDeposits(tx.Transaction, height)
+		if err != nil {
+			return false, err
+		}
+		nakedBatch := batch.GetNakedBatch()
+		for _, d := range deposits {
+			if err := s.db.Deposits().BatchPut(d, nakedBatch); err != nil {
+				return false, err
+			}
+		}
+	case elacommon.RegisterProducer, elacommon.UpdateProducer,
+		elacommon.CancelProducer, elacommon.ActivateProducer,
+		elacommon.InactiveArbitrators, elacommon.IllegalProposalEvidence,
+		elacommon.IllegalVoteEvidence, elacommon.IllegalBlockEvidence:
+		nakedBatch := batch.GetNakedBatch()
+		err := s.db.Producers().BatchPutTx(tx.Transaction, height, nakedBatch)
+		if err != nil {
+			return false, err
+		}
 	case elacommon.CRCProposal:
 		p, ok := tx.Payload().(*payload.CRCProposal)
 		if !ok {
 			return false, errors.New("invalid crc proposal tx")
 		}
 		nakedBatch := batch.GetNakedBatch()
+		err := s.db.Proposals().BatchPut(&store.ProposalInfo{
+			Hash:         p.Hash(tx.PayloadVersion()),
+			TxHash:       tx.Hash(),
+			ProposalType: p.ProposalType,
+			DraftHash:    p.DraftHash,
+			Budgets:      p.Budgets,
+			Recipient:    p.Recipient,
+			Height:       height,
+			Status:       store.ProposalRegistered,
+		}, nakedBatch)
+		if err != nil {
+			return false, err
+		}
 		switch p.ProposalType {
 		case payload.ReserveCustomID:
 			err := s.db.CID().BatchPutControversialReservedCustomIDs(
@@ -354,6 +1040,10 @@ func (s *spvservice) putTx(batch store.DataBatch, utx util.Transaction,
 		if err != nil {
 			return false, err
 		}
+		err = s.db.Proposals().BatchPutResults(p.ProposalResults, height, nakedBatch)
+		if err != nil {
+			return false, err
+		}
 	}
 
 	hits := make(map[common.Uint168]struct{})
@@ -368,10 +1058,25 @@ func (s *spvservice) putTx(batch store.DataBatch, utx util.Transaction,
 
 	for _, input := range tx.Inputs() {
 		op := input.Previous
-		addr := s.db.Ops().HaveOp(util.NewOutPoint(op.TxID, op.Index))
+		addr, err := batch.Ops().HaveOp(util.NewOutPoint(op.TxID, op.Index))
+		if err != nil {
+			return false, err
+		}
 		if addr != nil {
 			hits[*addr] = struct{}{}
 		}
+		if !s.trackUTXOs {
+			continue
+		}
+		// outputs created in the same block can be spent in the batch.
+		stxo, err := s.db.UTXOs().BatchSpend(util.NewOutPoint(op.TxID,
+			op.Index), height, tx.Hash(), batch.GetNakedBatch())
+		if err != nil {
+			return false, err
+		}
+		if stxo != nil {
+			hits[stxo.Address] = struct{}{}
+		}
 	}
 
 	var txTypesHit, addrsHit bool
@@ -392,6 +1097,42 @@ func (s *spvservice) putTx(batch store.DataBatch, utx util.Transaction,
 		}
 	}
 
+	if s.trackUTXOs {
+		for op, addr := range ops {
+			output := tx.Outputs()[op.Index]
+			lockTime := output.OutputLock
+			if tx.TxType() == elacommon.CoinBase {
+				lockTime = height + coinbaseMaturity
+			}
+			err := s.db.UTXOs().BatchPut(&store.UTXO{
+				Op:       *op,
+				Address:  addr,
+				Value:    output.Value,
+				LockTime: lockTime,
+				AtHeight: height,
+			}, batch.GetNakedBatch())
+			if err != nil {
+				return false, err
+			}
+		}
+	}
+
+	// record the transaction in the history of watched addresses.
+	records, err := store.GetAddressTxs(tx.Transaction, height,
+		func(op *util.OutPoint, addr common.Uint168) bool {
+			return s.db.Addrs().GetFilter().ContainAddr(addr)
+		}, func(op *util.OutPoint) (*common.Uint168, common.Fixed64, error) {
+			return spentOutput(batch, op)
+		})
+	if err != nil {
+		return false, err
+	}
+	for _, record := range records {
+		if err := s.db.AddrTxs().BatchPut(record, batch.GetNakedBatch()); err != nil {
+			return false, err
+		}
+	}
+
 	for _, listener := range s.listeners {
 		// skip transactions that not match the require type
 		if listener.Type() != tx.TxType() {
@@ -416,6 +1157,33 @@ func (s *spvservice) putTx(batch store.DataBatch, utx util.Transaction,
 	return false, batch.Txs().Put(util.NewTx(utx, height))
 }
 
+// spentOutput returns the watched address and value of the output spent by
+// an input, the value is zero if the previous transaction is not stored.  The
+// outpoint is read through the batch, so the outputs put earlier in the same
+// block are found.
+func spentOutput(batch store.DataBatch, op *util.OutPoint) (*common.Uint168, common.Fixed64, error) {
+	addr, err := batch.Ops().HaveOp(op)
+	if err != nil || addr == nil {
+		return nil, 0, err
+	}
+	utx, err := batch.Txs().Get(&op.TxID)
+	if err == kv.ErrNotFound {
+		return addr, 0, nil
+	}
+	if err != nil {
+		return nil, 0, err
+	}
+	tx, err := decodeTransaction(utx)
+	if err != nil {
+		return nil, 0, err
+	}
+	if int(op.Index) >= len(tx.Outputs()) {
+		return nil, 0, fmt.Errorf("output %s:%d not found in transaction",
+			op.TxID, op.Index)
+	}
+	return addr, tx.Outputs()[op.Index].Value, nil
+}
+
 // PutTxs persists the main chain transactions into database and can be
 // queried by GetTxs(height).  Returns the false positive transaction count
 // and error.
@@ -515,6 +1283,12 @@ func (s *spvservice) DelTxs(height uint32) error {
 	if s.rollback != nil {
 		s.rollback(height)
 	}
+
+	// Revert transactions on the height are removed, the consensus mode of
+	// the new best height may change back.
+	if height > 0 {
+		s.notifyConsensusMode(height - 1)
+	}
 	return nil
 }
 
@@ -605,6 +1379,7 @@ func (s *spvservice) BlockCommitted(block *util.Block) {
 		s.blockListener.NotifyBlock(block)
 	}
 
+	s.notifyConsensusMode(block.Height)
 }
 
 func (s *spvservice) ClearData() error {
@@ -664,6 +1439,29 @@ func (s *spvservice) notifyTransaction(notifyId common.Uint256,
 	return nil, false
 }
 
+func (s *spvservice) notifyConsensusMode(height uint32) {
+	if s.modeListener == nil {
+		return
+	}
+
+	mode, err := s.GetConsensusAlgorithm(height)
+	if err != nil {
+		log.Errorf("query consensus mode at height %d failed, %s", height, err.Error())
+		return
+	}
+	if s.consensusMode == nil {
+		s.consensusMode = &mode
+		return
+	}
+	if *s.consensusMode == mode {
+		return
+	}
+
+	previous := *s.consensusMode
+	s.consensusMode = &mode
+	s.modeListener.NotifyConsensusModeChanged(height, previous, mode)
+}
+
 func getListenerKey(listener TransactionListener) common.Uint256 {
 	buf := new(bytes.Buffer)
 	if len(listener.Address()) == 0 {
@@ -0,0 +1,358 @@
+package _interface
+
+import (
+	"errors"
+	"math/big"
+	"testing"
+
+	"github.com/elastos/Elastos.ELA.SPV/bloom"
+	"github.com/elastos/Elastos.ELA.SPV/interface/iutil"
+	"github.com/elastos/Elastos.ELA.SPV/interface/proof"
+	"github.com/elastos/Elastos.ELA.SPV/interface/store"
+	"github.com/elastos/Elastos.ELA.SPV/util"
+
+	"github.com/elastos/Elastos.ELA/common"
+	elatx "github.com/elastos/Elastos.ELA/core/transaction"
+	elacommon "github.com/elastos/Elastos.ELA/core/types/common"
+	it "github.com/elastos/Elastos.ELA/core/types/interfaces"
+	"github.com/elastos/Elastos.ELA/core/types/payload"
+	"github.com/stretchr/testify/assert"
+)
+
+func newPolicyTx(lockTime uint32) it.Transaction {
+	return elatx.CreateTransaction(
+		elacommon.TxVersion09,
+		elacommon.TransferAsset,
+		0,
+		&payload.TransferAsset{},
+		[]*elacommon.Attribute{},
+		[]*elacommon.Input{},
+		[]*elacommon.Output{},
+		lockTime,
+		nil,
+	)
+}
+
+// newPolicyBlock returns the header of a block packed two transactions and
+// the proof of the second transaction.
+func newPolicyBlock(previous *util.Header, nonce uint32) (*util.Header,
+	bloom.MerkleProof, it.Transaction) {
+	var height uint32
+	var previousHash common.Uint256
+	if previous != nil {
+		height = previous.Height + 1
+		previousHash = previous.Hash()
+	}
+	tx0, tx1 := newPolicyTx(height*2+nonce*1000), newPolicyTx(height*2+nonce*1000+1)
+	hash0, hash1 := tx0.Hash(), tx1.Hash()
+	header := &util.Header{
+		BlockHeader: iutil.NewHeader(&elacommon.Header{
+			Previous:   previousHash,
+			MerkleRoot: *bloom.HashMerkleBranches(&hash0, &hash1),
+			Timestamp:  1000 + height*10,
+			Height:     height,
+			Nonce:      nonce,
+		}),
+		Height:    height,
+		TotalWork: new(big.Int),
+	}
+
+	// flags 101 indicates root is parent, tx0 not matched and tx1 matched.
+	p := bloom.MerkleProof{
+		BlockHash:    header.Hash(),
+		Height:       height,
+		Transactions: 2,
+		Hashes:       []*common.Uint256{&hash0, &hash1},
+		Flags:        []byte{0x05},
+	}
+	return header, p, tx1
+}
+
+// missingHeaders fails to read the header on the given height.
+type missingHeaders struct {
+	store.HeaderStore
+	height uint32
+}
+
+func (h *missingHeaders) GetByHeight(height uint32) (*util.Header, error) {
+	if height == h.height {
+		return nil, errors.New("header not found")
+	}
+	return h.HeaderStore.GetByHeight(height)
+}
+
+func TestSPVService_VerifyTransactionsWithPolicy(t *testing.T) {
+	headers, err := store.NewMemoryHeaderStore(func() util.BlockHeader {
+		return iutil.NewHeader(&elacommon.Header{})
+	})
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	defer headers.Close()
+	s := &spvservice{headers: headers}
+
+	// best chain from height 0 to 10.
+	var proofs []bloom.MerkleProof
+	var txs []it.Transaction
+	var previous *util.Header
+	for i := 0; i <= 10; i++ {
+		header, p, tx := newPolicyBlock(previous, 0)
+		assert.NoError(t, headers.Put(header, true))
+		proofs = append(proofs, p)
+		txs = append(txs, tx)
+		previous = header
+	}
+
+	// fork block on height 8 and block above the best height.
+	bestAt7, _ := headers.GetByHeight(7)
+	fork, forkProof, forkTx := newPolicyBlock(bestAt7, 1)
+	assert.NoError(t, headers.Put(fork, false))
+	best, _ := headers.GetBest()
+	_, aboveProof, aboveTx := newPolicyBlock(best, 0)
+
+	tests := []struct {
+		name          string
+		proof         bloom.MerkleProof
+		tx            it.Transaction
+		confirmations uint32
+		expected      uint32
+		err           error
+	}{
+		{"fork block", forkProof, forkTx, 0, 0, ErrNotOnBestChain},
+		{"above best", aboveProof, aboveTx, 0, 0, ErrNotOnBestChain},
+		{"not enough confirmations", proofs[7], txs[7], 4, 3, ErrNotEnoughConfirmations},
+		{"confirmation boundary", proofs[7], txs[7], 3, 3, nil},
+		{"best block", proofs[10], txs[10], 0, 0, nil},
+	}
+	for _, test := range tests {
+		result, err := s.VerifyTransactionWithPolicy(test.proof, test.tx,
+			VerifyPolicy{Confirmations: test.confirmations})
+		assert.Equal(t, test.err, err, test.name)
+		if assert.NotNil(t, result, test.name) {
+			assert.Equal(t, test.tx.Hash(), result.TxHash, test.name)
+			assert.Equal(t, test.expected, result.Confirmations, test.name)
+		}
+	}
+
+	// mixed batch, only the failed proofs have errors in results.
+	results, err := s.VerifyTransactionsWithPolicy(
+		[]bloom.MerkleProof{proofs[2], forkProof, proofs[9], proofs[4]},
+		[]it.Transaction{txs[2], forkTx, txs[9], txs[4]},
+		VerifyPolicy{Confirmations: 2})
+	if !assert.NoError(t, err) || !assert.Equal(t, 4, len(results)) {
+		t.FailNow()
+	}
+	assert.NoError(t, results[0].Err)
+	assert.Equal(t, uint32(8), results[0].Confirmations)
+	assert.Equal(t, uint32(1020), results[0].BlockTime)
+	assert.Equal(t, ErrNotOnBestChain, results[1].Err)
+	assert.Equal(t, ErrNotEnoughConfirmations, results[2].Err)
+	assert.Equal(t, uint32(1), results[2].Confirmations)
+	assert.NoError(t, results[3].Err)
+
+	// a header can not be read only fails the proof on that height.
+	s.headers = &missingHeaders{HeaderStore: headers, height: 5}
+	results, err = s.VerifyTransactionsWithPolicy(
+		[]bloom.MerkleProof{proofs[2], proofs[5], proofs[9]},
+		[]it.Transaction{txs[2], txs[5], txs[9]}, VerifyPolicy{})
+	if !assert.NoError(t, err) || !assert.Equal(t, 3, len(results)) {
+		t.FailNow()
+	}
+	assert.NoError(t, results[0].Err)
+	assert.Error(t, results[1].Err)
+	assert.NoError(t, results[2].Err)
+	s.headers = headers
+
+	// proofs and transactions count not match.
+	_, err = s.VerifyTransactionsWithPolicy(proofs[:2], txs[:1], VerifyPolicy{})
+	assert.Error(t, err)
+}
+
+func newTransferTx(inputs []*elacommon.Input, outputs []*elacommon.Output) it.Transaction {
+	return elatx.CreateTransaction(
+		elacommon.TxVersion09,
+		elacommon.TransferAsset,
+		0,
+		&payload.TransferAsset{},
+		[]*elacommon.Attribute{},
+		inputs,
+		outputs,
+		0,
+		nil,
+	)
+}
+
+func TestSPVService_PutTxsSpentInBlock(t *testing.T) {
+	data, err := store.NewMemoryDataStore(nil, 36, "")
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	defer data.Close()
+	s := &spvservice{db: data}
+
+	watched := common.Uint168{0x21, 1}
+	other := common.Uint168{0x21, 2}
+	assert.NoError(t, data.Addrs().Put(&watched))
+
+	// tx2 spends the output of tx1 in the same block.
+	tx1 := newTransferTx(nil, []*elacommon.Output{
+		{ProgramHash: watched, Value: 10},
+	})
+	tx2 := newTransferTx([]*elacommon.Input{{
+		Previous: *elacommon.NewOutPoint(tx1.Hash(), 0),
+	}}, []*elacommon.Output{
+		{ProgramHash: other, Value: 6},
+		{ProgramHash: watched, Value: 3},
+	})
+	_, err = s.PutTxs([]util.Transaction{iutil.NewTx(tx1), iutil.NewTx(tx2)}, 100)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+
+	// records on the same height are ordered by transaction hash.
+	records, _, err := data.AddrTxs().List(watched, 0, 100, 0, "")
+	assert.NoError(t, err)
+	assert.Equal(t, 2, len(records))
+	for _, record := range records {
+		if record.TxHash.IsEqual(tx2.Hash()) {
+			assert.Equal(t, store.TxSent|store.TxReceived, record.Direction)
+			assert.Equal(t, common.Fixed64(10), record.Sent)
+			assert.Equal(t, common.Fixed64(3), record.Received)
+			return
+		}
+	}
+	t.Errorf("history of transaction %s not found", tx2.Hash())
+}
+
+type modeChange struct {
+	height            uint32
+	previous, current ConsensusAlgorithm
+}
+
+type modeListener []modeChange
+
+func (l *modeListener) NotifyConsensusModeChanged(height uint32, previous,
+	current ConsensusAlgorithm) {
+	*l = append(*l, modeChange{height, previous, current})
+}
+
+func TestSPVService_ConsensusModeRollback(t *testing.T) {
+	data, err := store.NewMemoryDataStore(nil, 36, "")
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	defer data.Close()
+	var listener modeListener
+	s := &spvservice{db: data, modeListener: &listener}
+
+	// RevertToPOW packed on height 100 works from height 100.
+	s.notifyConsensusMode(99)
+	batch := data.Batch()
+	assert.NoError(t, data.Arbiters().BatchPutRevertTransaction(
+		batch.GetNakedBatch(), 100, byte(POW), common.Uint256{1}, 100))
+	assert.NoError(t, batch.Commit())
+	s.notifyConsensusMode(100)
+	assert.Equal(t, modeListener{{100, DPOS, POW}}, listener)
+
+	// rolling back height 100 changes the mode back.
+	assert.NoError(t, s.DelTxs(100))
+	assert.Equal(t, modeListener{{100, DPOS, POW}, {99, POW, DPOS}}, listener)
+}
+
+func TestSPVService_GetTransactionMerkleBranch(t *testing.T) {
+	headers, err := store.NewMemoryHeaderStore(func() util.BlockHeader {
+		return iutil.NewHeader(&elacommon.Header{})
+	})
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	defer headers.Close()
+	data, err := store.NewMemoryDataStore(nil, 36, "")
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	defer data.Close()
+	s := &spvservice{headers: headers, db: data}
+
+	genesis, _, _ := newPolicyBlock(nil, 0)
+	assert.NoError(t, headers.Put(genesis, true))
+	header, p, tx := newPolicyBlock(genesis, 0)
+	header.NumTxs = p.Transactions
+	header.Hashes = p.Hashes
+	header.Flags = p.Flags
+	assert.NoError(t, headers.Put(header, true))
+	assert.NoError(t, data.Txs().Put(util.NewTx(iutil.NewTx(tx), header.Height)))
+
+	branch, err := s.GetTransactionMerkleBranch(tx.Hash())
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	assert.NoError(t, s.VerifyMerkleBranch(branch, tx.Hash(), header.Hash()))
+
+	// other transaction or block does not match the branch.
+	assert.Error(t, s.VerifyMerkleBranch(branch, newPolicyTx(100).Hash(),
+		header.Hash()))
+	assert.Error(t, s.VerifyMerkleBranch(branch, tx.Hash(), genesis.Hash()))
+	assert.Error(t, s.VerifyMerkleBranch(branch, tx.Hash(), common.Uint256{1}))
+
+	// transaction not stored.
+	_, err = s.GetTransactionMerkleBranch(newPolicyTx(100).Hash())
+	assert.Error(t, err)
+}
+
+func TestSPVService_CompactKeepsDepositProof(t *testing.T) {
+	headers, err := store.NewMemoryHeaderStore(func() util.BlockHeader {
+		return iutil.NewHeader(&elacommon.Header{})
+	})
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	defer headers.Close()
+	data, err := store.NewMemoryDataStore(nil, 36, "")
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	defer data.Close()
+	s := &spvservice{headers: headers, db: data,
+		compactOpts: store.CompactOptions{KeepRecent: 5}}
+
+	// best chain from height 0 to 10 with merkle data, the deposit is packed
+	// on height 3 and not stored as a transaction.
+	var deposit *store.CrossChainDeposit
+	var depositTx it.Transaction
+	var previous *util.Header
+	for i := uint32(0); i <= 10; i++ {
+		header, p, tx := newPolicyBlock(previous, 0)
+		header.NumTxs = p.Transactions
+		header.Hashes = p.Hashes
+		header.Flags = p.Flags
+		assert.NoError(t, headers.Put(header, true))
+		if i == 3 {
+			genesis, _ := common.Uint168{0x4b, 1}.ToAddress()
+			deposit = &store.CrossChainDeposit{TxHash: tx.Hash(),
+				GenesisAddress: genesis, Height: i}
+			depositTx = tx
+		}
+		previous = header
+	}
+	batch := data.Batch()
+	assert.NoError(t, data.Deposits().BatchPut(deposit, batch.GetNakedBatch()))
+	assert.NoError(t, batch.Commit())
+
+	report, err := s.CompactHeaders()
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	assert.Equal(t, 4, report.Compacted)
+
+	result, err := s.GetCrossChainDeposit(deposit.GenesisAddress, deposit.TxHash)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	header, err := headers.GetByHeight(3)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	assert.NoError(t, proof.VerifyTransaction(result.Proof,
+		header.BlockHeader.(*iutil.Header).Header, depositTx))
+}
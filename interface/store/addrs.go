@@ -3,11 +3,10 @@ package store
 import (
 	"sync"
 
+	"github.com/elastos/Elastos.ELA.SPV/interface/store/kv"
 	"github.com/elastos/Elastos.ELA.SPV/sdk"
 
 	"github.com/elastos/Elastos.ELA/common"
-	"github.com/syndtr/goleveldb/leveldb"
-	"github.com/syndtr/goleveldb/leveldb/util"
 )
 
 // Ensure addrs implement Addrs interface.
@@ -15,11 +14,11 @@ var _ Addrs = (*addrs)(nil)
 
 type addrs struct {
 	sync.RWMutex
-	db     *leveldb.DB
+	db     kv.DB
 	filter *sdk.AddrFilter
 }
 
-func NewAddrs(db *leveldb.DB) (*addrs, error) {
+func NewAddrs(db kv.DB) (*addrs, error) {
 	store := addrs{db: db}
 
 	addrs, err := store.getAll()
@@ -46,7 +45,7 @@ func (a *addrs) Put(addr *common.Uint168) error {
 	}
 
 	a.filter.AddAddr(addr)
-	return a.db.Put(toKey(BKTAddrs, addr[:]...), addr[:], nil)
+	return a.db.Put(toKey(BKTAddrs, addr[:]...), addr[:])
 }
 
 func (a *addrs) GetAll() []*common.Uint168 {
@@ -56,7 +55,7 @@ func (a *addrs) GetAll() []*common.Uint168 {
 }
 
 func (a *addrs) getAll() (addrs []*common.Uint168, err error) {
-	it := a.db.NewIterator(util.BytesPrefix(BKTAddrs), nil)
+	it := a.db.NewIterator(kv.BytesPrefix(BKTAddrs))
 	defer it.Release()
 	for it.Next() {
 		addr, err := common.Uint168FromBytes(it.Value())
@@ -73,13 +72,13 @@ func (a *addrs) Clear() error {
 	a.Lock()
 	defer a.Unlock()
 
-	it := a.db.NewIterator(util.BytesPrefix(BKTAddrs), nil)
+	it := a.db.NewIterator(kv.BytesPrefix(BKTAddrs))
 	defer it.Release()
-	batch := new(leveldb.Batch)
+	batch := a.db.NewBatch()
 	for it.Next() {
 		batch.Delete(it.Key())
 	}
-	return a.db.Write(batch, nil)
+	return a.db.Write(batch)
 }
 
 func (a *addrs) Close() error {
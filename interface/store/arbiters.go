@@ -5,13 +5,13 @@ import (
 	"crypto/sha256"
 	"encoding/binary"
 	"errors"
+	"math"
 	"sort"
 	"sync"
 
-	"github.com/elastos/Elastos.ELA/common"
+	"github.com/elastos/Elastos.ELA.SPV/interface/store/kv"
 
-	"github.com/syndtr/goleveldb/leveldb"
-	dbutil "github.com/syndtr/goleveldb/leveldb/util"
+	"github.com/elastos/Elastos.ELA/common"
 )
 
 // Ensure arbiters implement arbiters interface.
@@ -22,11 +22,29 @@ type RevertInfo struct {
 	Mode          byte
 }
 
+// ConsensusModeInterval is a range of main chain heights that running on the
+// same consensus mode, EndHeight is math.MaxUint32 if the interval is still
+// open. TxHash is the revert transaction which started the interval, and it
+// will be empty for the interval started from the genesis block.
+type ConsensusModeInterval struct {
+	StartHeight uint32
+	EndHeight   uint32
+	Mode        byte
+	TxHash      common.Uint256
+}
+
+// revertTx is the revert transaction which changed consensus mode on the
+// working height, Height is the block height the transaction packed in.
+type revertTx struct {
+	TxHash common.Uint256
+	Height uint32
+}
+
 type arbiters struct {
 	batch
 	sync.RWMutex
-	db             *leveldb.DB
-	b              *leveldb.Batch
+	db             kv.DB
+	b              kv.Batch
 	posCache       []uint32
 	revertPOSCache []RevertInfo
 	cache          map[common.Uint256]uint32
@@ -34,10 +52,10 @@ type arbiters struct {
 	arbitersCount  int
 }
 
-func NewArbiters(db *leveldb.DB, originArbiters [][]byte, arbitersCount int) *arbiters {
+func NewArbiters(db kv.DB, originArbiters [][]byte, arbitersCount int) *arbiters {
 	return &arbiters{
 		db:             db,
-		b:              new(leveldb.Batch),
+		b:              db.NewBatch(),
 		posCache:       make([]uint32, 0),
 		cache:          make(map[common.Uint256]uint32),
 		originArbiters: originArbiters,
@@ -51,11 +69,11 @@ func (c *arbiters) Put(height uint32, crcArbiters [][]byte, normalArbiters [][]b
 	if err := c.batchPut(height, crcArbiters, normalArbiters, c.b); err != nil {
 		return err
 	}
-	c.db.Write(c.b, nil)
+	c.db.Write(c.b)
 	return nil
 }
 
-func (c *arbiters) batchPut(height uint32, crcArbiters [][]byte, normalArbiters [][]byte, batch *leveldb.Batch) error {
+func (c *arbiters) batchPut(height uint32, crcArbiters [][]byte, normalArbiters [][]byte, batch kv.Batch) error {
 	batch.Put(BKTArbPosition, uint32toBytes(height))
 
 	// update positions
@@ -79,12 +97,12 @@ func (c *arbiters) batchPut(height uint32, crcArbiters [][]byte, normalArbiters
 	val, ok := c.cache[*key]
 	index := getIndex(height)
 	if !ok {
-		existHeight, err := c.db.Get(toKey(BKTTransactionHeight, hash[:]...), nil)
+		existHeight, err := c.db.Get(toKey(BKTTransactionHeight, hash[:]...))
 		if err == nil {
 			c.cache[*key] = bytesToUint32(existHeight)
 			batch.Put(toKey(BKTArbitersData, index...), existHeight)
 			return nil
-		} else if err == leveldb.ErrNotFound {
+		} else if err == kv.ErrNotFound {
 			c.cache[*key] = height
 			batch.Put(toKey(BKTArbitersData, index...), data)
 			batch.Put(toKey(BKTTransactionHeight, hash[:]...), uint32toBytes(height))
@@ -98,7 +116,7 @@ func (c *arbiters) batchPut(height uint32, crcArbiters [][]byte, normalArbiters
 	return nil
 }
 
-func (c *arbiters) BatchPut(height uint32, crcArbiters [][]byte, normalArbiters [][]byte, batch *leveldb.Batch) error {
+func (c *arbiters) BatchPut(height uint32, crcArbiters [][]byte, normalArbiters [][]byte, batch kv.Batch) error {
 	c.Lock()
 	defer c.Unlock()
 	return c.batchPut(height, crcArbiters, normalArbiters, batch)
@@ -120,12 +138,12 @@ func (c *arbiters) GetNext() (workingHeight uint32, crcArbiters [][]byte, normal
 
 func (c *arbiters) get(height uint32) (crcArbiters [][]byte, normalArbiters [][]byte, err error) {
 	var val []byte
-	val, err = c.db.Get(toKey(BKTArbitersData, getIndex(height)...), nil)
+	val, err = c.db.Get(toKey(BKTArbitersData, getIndex(height)...))
 	if err != nil {
 		return
 	}
 	if len(val) == 4 {
-		val, err = c.db.Get(toKey(BKTArbitersData, getIndex(bytesToUint32(val))...), nil)
+		val, err = c.db.Get(toKey(BKTArbitersData, getIndex(bytesToUint32(val))...))
 		if err != nil {
 			return
 		}
@@ -185,17 +203,17 @@ func (c *arbiters) Close() error {
 func (c *arbiters) Clear() error {
 	c.Lock()
 	defer c.Unlock()
-	it := c.db.NewIterator(dbutil.BytesPrefix(BKTArbiters), nil)
+	it := c.db.NewIterator(kv.BytesPrefix(BKTArbiters))
 	defer it.Release()
 	for it.Next() {
 		c.b.Delete(it.Key())
 	}
 	c.b.Delete(BKTArbPosition)
-	return c.db.Write(c.b, nil)
+	return c.db.Write(c.b)
 }
 
 func (c *arbiters) getCurrentPosition() uint32 {
-	pos, err := c.db.Get(BKTArbPosition, nil)
+	pos, err := c.db.Get(BKTArbPosition)
 	if err == nil {
 		return bytesToUint32(pos)
 	}
@@ -204,7 +222,7 @@ func (c *arbiters) getCurrentPosition() uint32 {
 }
 
 func (c *arbiters) getCurrentPositions() []uint32 {
-	pos, err := c.db.Get(BKTArbPositions, nil)
+	pos, err := c.db.Get(BKTArbPositions)
 	if err == nil {
 		return bytesToUint32Array(pos)
 	}
@@ -212,7 +230,7 @@ func (c *arbiters) getCurrentPositions() []uint32 {
 }
 
 func (c *arbiters) getCurrentRevertPosition() uint32 {
-	pos, err := c.db.Get(BKTRevertPosition, nil)
+	pos, err := c.db.Get(BKTRevertPosition)
 	if err == nil {
 		return bytesToUint32(pos)
 	}
@@ -221,7 +239,7 @@ func (c *arbiters) getCurrentRevertPosition() uint32 {
 }
 
 func (c *arbiters) getCurrentRevertPositions() ([]RevertInfo, error) {
-	pos, err := c.db.Get(BKTRevertPositions, nil)
+	pos, err := c.db.Get(BKTRevertPositions)
 	if err != nil {
 		return nil, err
 	}
@@ -229,7 +247,7 @@ func (c *arbiters) getCurrentRevertPositions() ([]RevertInfo, error) {
 }
 
 func (c *arbiters) Commit() error {
-	return c.db.Write(c.b, nil)
+	return c.db.Write(c.b)
 }
 
 func (c *arbiters) Rollback() error {
@@ -237,11 +255,24 @@ func (c *arbiters) Rollback() error {
 	return nil
 }
 
-func (c *arbiters) CommitBatch(batch *leveldb.Batch) error {
-	return c.db.Write(batch, nil)
+func (c *arbiters) CommitBatch(batch kv.Batch) error {
+	if err := c.db.Write(batch); err != nil {
+		return err
+	}
+	c.resetRevertCache()
+	return nil
+}
+
+// resetRevertCache drops the cached consensus mode changes, it must be called
+// after the revert transaction changes are written, the cache will be filled
+// from the database again on next query.
+func (c *arbiters) resetRevertCache() {
+	c.Lock()
+	c.revertPOSCache = nil
+	c.Unlock()
 }
 
-func (c *arbiters) RollbackBatch(batch *leveldb.Batch) error {
+func (c *arbiters) RollbackBatch(batch kv.Batch) error {
 	batch.Reset()
 	return nil
 }
@@ -375,7 +406,7 @@ func (c *arbiters) GetRevertInfo() []RevertInfo {
 	if len(c.revertPOSCache) == 0 {
 		var err error
 		pos, err = c.getCurrentRevertPositions()
-		if err != nil && err != leveldb.ErrNotFound {
+		if err != nil && err != kv.ErrNotFound {
 			return pos
 		}
 		c.revertPOSCache = pos
@@ -393,7 +424,7 @@ func (c *arbiters) GetConsensusAlgorithmByHeight(height uint32) (byte, error) {
 	if len(c.revertPOSCache) == 0 {
 		var err error
 		pos, err = c.getCurrentRevertPositions()
-		if err != nil && err != leveldb.ErrNotFound {
+		if err != nil && err != kv.ErrNotFound {
 			return 0, err
 		}
 		c.revertPOSCache = pos
@@ -412,7 +443,8 @@ func (c *arbiters) GetConsensusAlgorithmByHeight(height uint32) (byte, error) {
 	return revertInfo.Mode, nil
 }
 
-func (c *arbiters) BatchPutRevertTransaction(batch *leveldb.Batch, workingHeight uint32, mode byte) error {
+func (c *arbiters) BatchPutRevertTransaction(batch kv.Batch, workingHeight uint32,
+	mode byte, txHash common.Uint256, height uint32) error {
 	c.Lock()
 	defer c.Unlock()
 
@@ -424,8 +456,11 @@ func (c *arbiters) BatchPutRevertTransaction(batch *leveldb.Batch, workingHeight
 	batch.Put(BKTRevertPosition, uint32toBytes(workingHeight))
 
 	if !isRollback {
+		tx := revertTx{TxHash: txHash, Height: height}
+		batch.Put(toKey(BKTRevertTxs, uint32toBytes(workingHeight)...), tx.bytes())
+
 		posCache, err := c.getCurrentRevertPositions()
-		if err != nil && err != leveldb.ErrNotFound {
+		if err != nil && err != kv.ErrNotFound {
 			return err
 		}
 		newPosCache := make([]RevertInfo, 0)
@@ -438,8 +473,7 @@ func (c *arbiters) BatchPutRevertTransaction(batch *leveldb.Batch, workingHeight
 			WorkingHeight: workingHeight,
 			Mode:          mode,
 		})
-		c.revertPOSCache = newPosCache
-		data, err := revertInfoArrayToBytes(c.revertPOSCache)
+		data, err := revertInfoArrayToBytes(newPosCache)
 		if err != nil {
 			return err
 		}
@@ -448,3 +482,140 @@ func (c *arbiters) BatchPutRevertTransaction(batch *leveldb.Batch, workingHeight
 
 	return nil
 }
+
+// BatchDeleteRevertTransactions removes the consensus mode changes caused by
+// the revert transactions packed on the given height.
+func (c *arbiters) BatchDeleteRevertTransactions(batch kv.Batch, height uint32) error {
+	c.Lock()
+	defer c.Unlock()
+
+	posCache, err := c.getCurrentRevertPositions()
+	if err == kv.ErrNotFound {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	var changed bool
+	newPosCache := make([]RevertInfo, 0, len(posCache))
+	for _, p := range posCache {
+		tx, err := c.getRevertTx(p.WorkingHeight)
+		if err == nil && tx.Height == height {
+			batch.Delete(toKey(BKTRevertTxs, uint32toBytes(p.WorkingHeight)...))
+			changed = true
+			continue
+		}
+		newPosCache = append(newPosCache, p)
+	}
+	if !changed {
+		return nil
+	}
+
+	data, err := revertInfoArrayToBytes(newPosCache)
+	if err != nil {
+		return err
+	}
+	batch.Put(BKTRevertPositions, data)
+	if len(newPosCache) == 0 {
+		batch.Delete(BKTRevertPosition)
+	} else {
+		last := newPosCache[len(newPosCache)-1]
+		batch.Put(BKTRevertPosition, uint32toBytes(last.WorkingHeight))
+	}
+
+	return nil
+}
+
+// GetConsensusModeTimeline returns the consensus mode intervals overlapping
+// the given height range, the first and last interval will be cut to fit the
+// range.
+func (c *arbiters) GetConsensusModeTimeline(startHeight, endHeight uint32) ([]ConsensusModeInterval, error) {
+	if startHeight > endHeight {
+		return nil, errors.New("invalid height range")
+	}
+
+	// The cache is filled on first use, so the write lock is required.
+	c.Lock()
+	defer c.Unlock()
+	var pos []RevertInfo
+	if len(c.revertPOSCache) == 0 {
+		var err error
+		pos, err = c.getCurrentRevertPositions()
+		if err != nil && err != kv.ErrNotFound {
+			return nil, err
+		}
+		c.revertPOSCache = pos
+	} else {
+		pos = c.revertPOSCache
+	}
+
+	intervals := []ConsensusModeInterval{{
+		StartHeight: 0,
+		EndHeight:   math.MaxUint32,
+	}}
+	for _, p := range pos {
+		var txHash common.Uint256
+		if tx, err := c.getRevertTx(p.WorkingHeight); err == nil {
+			txHash = tx.TxHash
+		}
+
+		last := &intervals[len(intervals)-1]
+		if p.WorkingHeight == last.StartHeight {
+			last.Mode = p.Mode
+			last.TxHash = txHash
+			continue
+		}
+		last.EndHeight = p.WorkingHeight - 1
+		intervals = append(intervals, ConsensusModeInterval{
+			StartHeight: p.WorkingHeight,
+			EndHeight:   math.MaxUint32,
+			Mode:        p.Mode,
+			TxHash:      txHash,
+		})
+	}
+
+	results := make([]ConsensusModeInterval, 0, len(intervals))
+	for _, i := range intervals {
+		if i.EndHeight < startHeight || i.StartHeight > endHeight {
+			continue
+		}
+		if i.StartHeight < startHeight {
+			i.StartHeight = startHeight
+		}
+		if i.EndHeight > endHeight {
+			i.EndHeight = endHeight
+		}
+		results = append(results, i)
+	}
+	return results, nil
+}
+
+func (c *arbiters) getRevertTx(workingHeight uint32) (*revertTx, error) {
+	data, err := c.db.Get(toKey(BKTRevertTxs, uint32toBytes(workingHeight)...))
+	if err != nil {
+		return nil, err
+	}
+	var tx revertTx
+	if err := tx.deserialize(data); err != nil {
+		return nil, err
+	}
+	return &tx, nil
+}
+
+func (t *revertTx) bytes() []byte {
+	buf := new(bytes.Buffer)
+	t.TxHash.Serialize(buf)
+	common.WriteUint32(buf, t.Height)
+	return buf.Bytes()
+}
+
+func (t *revertTx) deserialize(data []byte) error {
+	r := bytes.NewReader(data)
+	if err := t.TxHash.Deserialize(r); err != nil {
+		return err
+	}
+	var err error
+	t.Height, err = common.ReadUint32(r)
+	return err
+}
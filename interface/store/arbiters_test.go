@@ -3,19 +3,21 @@ package store
 import (
 	"bytes"
 	"encoding/hex"
+
+	"github.com/elastos/Elastos.ELA.SPV/interface/store/kv"
 	"github.com/stretchr/testify/assert"
 	"os"
 	"path/filepath"
 	"testing"
 
-	"github.com/syndtr/goleveldb/leveldb"
+	"github.com/elastos/Elastos.ELA/common"
 )
 
 func TestArbiters(t *testing.T) {
 	dataDir := "spv_test"
 	os.RemoveAll(dataDir)
 
-	db, err := leveldb.OpenFile(filepath.Join(dataDir, "store"), nil)
+	db, err := kv.OpenLevelDB(filepath.Join(dataDir, "store"))
 	if err != nil {
 		println(err.Error())
 	}
@@ -135,7 +137,7 @@ func TestArbiters(t *testing.T) {
 	}
 
 	// batch put
-	batch := new(leveldb.Batch)
+	batch := db.NewBatch()
 	err = arbiters.BatchPut(602, crcs, normal, batch)
 	if err != nil {
 		t.Errorf("put arbiter error %s", err.Error())
@@ -192,3 +194,110 @@ func checkExist(target [][]byte, src [][]byte) bool {
 	}
 	return true
 }
+
+func TestArbiters_ConsensusModeTimeline(t *testing.T) {
+	dataDir := "spv_test"
+	os.RemoveAll(dataDir)
+	defer os.RemoveAll(dataDir)
+
+	db, err := kv.OpenLevelDB(filepath.Join(dataDir, "store"))
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	defer db.Close()
+	arbiters := NewArbiters(db, nil, 36)
+
+	powTx := common.Uint256{1}
+	dposTx := common.Uint256{2}
+	batch := db.NewBatch()
+	assert.NoError(t, arbiters.BatchPutRevertTransaction(batch, 100, 0x01, powTx, 90))
+	assert.NoError(t, arbiters.CommitBatch(batch))
+	batch = db.NewBatch()
+	assert.NoError(t, arbiters.BatchPutRevertTransaction(batch, 200, 0x00, dposTx, 150))
+	assert.NoError(t, arbiters.CommitBatch(batch))
+
+	timeline, err := arbiters.GetConsensusModeTimeline(0, 300)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	assert.Equal(t, []ConsensusModeInterval{
+		{StartHeight: 0, EndHeight: 99, Mode: 0x00},
+		{StartHeight: 100, EndHeight: 199, Mode: 0x01, TxHash: powTx},
+		{StartHeight: 200, EndHeight: 300, Mode: 0x00, TxHash: dposTx},
+	}, timeline)
+
+	timeline, err = arbiters.GetConsensusModeTimeline(120, 130)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	assert.Equal(t, []ConsensusModeInterval{
+		{StartHeight: 120, EndHeight: 130, Mode: 0x01, TxHash: powTx},
+	}, timeline)
+
+	_, err = arbiters.GetConsensusModeTimeline(130, 120)
+	assert.Error(t, err)
+
+	// rollback the RevertToDPOS transaction.
+	batch = db.NewBatch()
+	assert.NoError(t, arbiters.BatchDeleteRevertTransactions(batch, 150))
+	assert.NoError(t, arbiters.CommitBatch(batch))
+
+	timeline, err = arbiters.GetConsensusModeTimeline(0, 300)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	assert.Equal(t, []ConsensusModeInterval{
+		{StartHeight: 0, EndHeight: 99, Mode: 0x00},
+		{StartHeight: 100, EndHeight: 300, Mode: 0x01, TxHash: powTx},
+	}, timeline)
+	mode, err := arbiters.GetConsensusAlgorithmByHeight(250)
+	assert.NoError(t, err)
+	assert.Equal(t, byte(0x01), mode)
+}
+
+func TestArbiters_RevertTransactionRollback(t *testing.T) {
+	dataDir := "spv_test"
+	os.RemoveAll(dataDir)
+	defer os.RemoveAll(dataDir)
+
+	db, err := kv.OpenLevelDB(filepath.Join(dataDir, "store"))
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	defer db.Close()
+	arbiters := NewArbiters(db, nil, 36)
+
+	powTx := common.Uint256{1}
+	batch := db.NewBatch()
+	assert.NoError(t, arbiters.BatchPutRevertTransaction(batch, 100, 0x01, powTx, 90))
+	assert.NoError(t, arbiters.CommitBatch(batch))
+
+	// a revert transaction working on passed height does not replace the
+	// recorded one.
+	batch = db.NewBatch()
+	assert.NoError(t, arbiters.BatchPutRevertTransaction(batch, 100, 0x00,
+		common.Uint256{2}, 95))
+	assert.NoError(t, arbiters.CommitBatch(batch))
+	timeline, err := arbiters.GetConsensusModeTimeline(0, 200)
+	assert.NoError(t, err)
+	assert.Equal(t, []ConsensusModeInterval{
+		{StartHeight: 0, EndHeight: 99, Mode: 0x00},
+		{StartHeight: 100, EndHeight: 200, Mode: 0x01, TxHash: powTx},
+	}, timeline)
+
+	// changes in a discarded batch are not cached.
+	batch = db.NewBatch()
+	assert.NoError(t, arbiters.BatchDeleteRevertTransactions(batch, 90))
+	assert.NoError(t, arbiters.RollbackBatch(batch))
+	mode, err := arbiters.GetConsensusAlgorithmByHeight(150)
+	assert.NoError(t, err)
+	assert.Equal(t, byte(0x01), mode)
+
+	batch = db.NewBatch()
+	assert.NoError(t, arbiters.BatchPutRevertTransaction(batch, 200, 0x00,
+		common.Uint256{3}, 180))
+	assert.NoError(t, arbiters.RollbackBatch(batch))
+	mode, err = arbiters.GetConsensusAlgorithmByHeight(250)
+	assert.NoError(t, err)
+	assert.Equal(t, byte(0x01), mode)
+}
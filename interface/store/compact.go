@@ -0,0 +1,170 @@
+package store
+
+import (
+	"encoding/binary"
+	"fmt"
+
+	"github.com/elastos/Elastos.ELA.SPV/interface/store/kv"
+)
+
+// compactBatchSize is the number of heights compacted in one batch, the
+// headers store is locked while writing a batch.
+const compactBatchSize = 1000
+
+// CompactOptions selects the best chain headers to compact in pruned mode.
+type CompactOptions struct {
+	// KeepRecent keeps the full merkle data of the last KeepRecent blocks,
+	// it should be larger than the deepest reorganize.  Zero disables
+	// compaction.
+	KeepRecent uint32
+}
+
+// Enabled returns if compaction is enabled by the options.
+func (o CompactOptions) Enabled() bool {
+	return o.KeepRecent > 0
+}
+
+// CompactReport is the result of compacting headers.
+type CompactReport struct {
+	// Height is the compact height, headers below it are compacted.
+	Height uint32
+
+	// Compacted is the number of headers with merkle data removed.
+	Compacted int
+
+	// Reclaimed is the bytes of keys and values removed, the disk space is
+	// reclaimed after the database compaction.
+	Reclaimed uint64
+}
+
+func (r *CompactReport) String() string {
+	return fmt.Sprintf("compact height %d, %d headers compacted, %d bytes"+
+		" reclaimed", r.Height, r.Compacted, r.Reclaimed)
+}
+
+// CompactStores removes the merkle data of the best chain headers below the
+// recent blocks, except the blocks containing transactions or cross chain
+// deposits in the data store, so the merkle proofs served for them can still
+// be rebuilt.  The compacted headers and their height indexes are kept, so
+// the best chain stays connected for the readers walking it.  It can be
+// called while the service is running.
+func CompactStores(headers HeaderStore, data DataStore, opts CompactOptions) (*CompactReport, error) {
+	if !opts.Enabled() {
+		return &CompactReport{}, nil
+	}
+	best, err := headers.GetBest()
+	if err != nil {
+		return nil, err
+	}
+	if best.Height <= opts.KeepRecent {
+		return &CompactReport{}, nil
+	}
+	return headers.Compact(best.Height-opts.KeepRecent,
+		func(height uint32) (bool, error) {
+			txIds, err := data.Txs().GetIds(height)
+			if err != nil || len(txIds) > 0 {
+				return len(txIds) > 0, err
+			}
+			return data.Deposits().HaveHeight(height)
+		})
+}
+
+// Compact removes the merkle data of the best chain headers below height
+// unless keep returns true with the header height. Headers compacted by
+// previous calls are skipped.
+func (h *headers) Compact(height uint32,
+	keep func(height uint32) (bool, error)) (*CompactReport, error) {
+	report := &CompactReport{Height: height}
+	start, err := compactHeight(h.db)
+	if err != nil {
+		return nil, err
+	}
+	for start < height {
+		end := start + compactBatchSize
+		if end > height || end < start {
+			end = height
+		}
+		if err := h.compact(start, end, keep, report); err != nil {
+			return nil, err
+		}
+		start = end
+	}
+	return report, nil
+}
+
+// compact compacts the headers from start to end height, end exclusive.
+func (h *headers) compact(start, end uint32,
+	keep func(height uint32) (bool, error), report *CompactReport) error {
+	// Query the data store before locking the headers store.
+	kept := make(map[uint32]bool)
+	for height := start; height < end; height++ {
+		ok, err := keep(height)
+		if err != nil {
+			return err
+		}
+		kept[height] = ok
+	}
+
+	h.Lock()
+	defer h.Unlock()
+
+	batch := h.db.NewBatch()
+	for height := start; height < end; height++ {
+		if kept[height] {
+			continue
+		}
+		index := indexKey(height)
+		hash, err := h.db.Get(index)
+		if err == kv.ErrNotFound {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		key := toKey(BKTHeaders, hash...)
+		data, err := h.db.Get(key)
+		if err == kv.ErrNotFound {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		header, err := h.decodeHeader(data)
+		if err != nil {
+			return err
+		}
+		if len(header.Hashes) == 0 && len(header.Flags) == 0 {
+			continue
+		}
+		h.cache.headers.Delete(header.Hash().String())
+		header.Hashes = nil
+		header.Flags = nil
+		compacted, err := header.Serialize()
+		if err != nil {
+			return err
+		}
+		batch.Put(key, compacted)
+		report.Compacted++
+		report.Reclaimed += uint64(len(data) - len(compacted))
+	}
+
+	var value [4]byte
+	binary.LittleEndian.PutUint32(value[:], end)
+	batch.Put(BKTCompactHeight, value[:])
+	return h.db.Write(batch)
+}
+
+// compactHeight returns the height headers below have been compacted.
+func compactHeight(r kv.Reader) (uint32, error) {
+	data, err := r.Get(BKTCompactHeight)
+	if err == kv.ErrNotFound {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	if len(data) != 4 {
+		return 0, fmt.Errorf("invalid compact height %x", data)
+	}
+	return binary.LittleEndian.Uint32(data), nil
+}
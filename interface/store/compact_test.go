@@ -0,0 +1,124 @@
+package store
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/elastos/Elastos.ELA.SPV/util"
+
+	"github.com/elastos/Elastos.ELA/common"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// newCompactStores creates memory stores with main chain from height 0 to 9,
+// every header has merkle data, and a transaction on height 3.
+func newCompactStores(t *testing.T) (*headers, *dataStore) {
+	headers, err := NewMemoryHeaderStore(newEmptyHeader)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	data, err := NewMemoryDataStore(nil, 36, "")
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+
+	var previous *util.Header
+	for i := uint32(0); i <= 9; i++ {
+		header := newHeader(previous, 1000+i*10, 0)
+		header.NumTxs = 2
+		header.Hashes = []*common.Uint256{{1}, {2}}
+		header.Flags = []byte{0x05}
+		assert.NoError(t, headers.Put(header, true))
+		previous = header
+	}
+	assert.NoError(t, data.Txs().Put(newStoreTx(1, 3)))
+	return headers, data
+}
+
+func TestCompactStores(t *testing.T) {
+	headers, data := newCompactStores(t)
+	defer headers.Close()
+	defer data.Close()
+
+	report, err := CompactStores(headers, data, CompactOptions{})
+	assert.NoError(t, err)
+	assert.Equal(t, &CompactReport{}, report)
+
+	report, err = CompactStores(headers, data, CompactOptions{KeepRecent: 5})
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	assert.Equal(t, uint32(4), report.Height)
+	assert.Equal(t, 3, report.Compacted)
+	assert.True(t, report.Reclaimed > 0)
+
+	for height := uint32(0); height <= 9; height++ {
+		header, err := headers.GetByHeight(height)
+		if !assert.NoError(t, err) {
+			continue
+		}
+		assert.Equal(t, uint32(2), header.NumTxs)
+		if height < 4 && height != 3 {
+			assert.Equal(t, 0, len(header.Hashes), "height %d", height)
+			assert.Equal(t, 0, len(header.Flags), "height %d", height)
+		} else {
+			assert.Equal(t, 2, len(header.Hashes), "height %d", height)
+		}
+	}
+
+	// compacted headers are skipped.
+	report, err = CompactStores(headers, data, CompactOptions{KeepRecent: 5})
+	assert.NoError(t, err)
+	assert.Equal(t, 0, report.Compacted)
+
+	report, err = VerifyStores(headers, data, false)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, len(report.Issues))
+}
+
+func TestCompactStores_ChainReaders(t *testing.T) {
+	headers, data := newCompactStores(t)
+	defer headers.Close()
+	defer data.Close()
+
+	report, err := CompactStores(headers, data, CompactOptions{KeepRecent: 2})
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	assert.Equal(t, uint32(7), report.Height)
+	assert.Equal(t, 6, report.Compacted)
+
+	// the compacted headers are kept on the best chain.
+	result, err := headers.GetRange(0, 9)
+	if assert.NoError(t, err) {
+		assert.Equal(t, 10, len(result))
+	}
+	header, err := headers.GetByTime(1015)
+	if assert.NoError(t, err) {
+		assert.Equal(t, uint32(2), header.Height)
+	}
+	mtp, err := headers.MedianTimePast(9)
+	assert.NoError(t, err)
+	assert.Equal(t, uint32(1050), mtp)
+	best, err := headers.GetBest()
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	for header = best; header.Height > 0; {
+		header, err = headers.GetPrevious(header)
+		if !assert.NoError(t, err) {
+			t.FailNow()
+		}
+	}
+
+	integrity, err := VerifyStores(headers, data, false)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, len(integrity.Issues))
+
+	// the snapshot contains all headers.
+	info, err := ExportSnapshot(new(bytes.Buffer), headers, data, 9)
+	if assert.NoError(t, err) {
+		assert.Equal(t, 10, info.Headers)
+	}
+}
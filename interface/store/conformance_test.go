@@ -0,0 +1,179 @@
+package store
+
+import (
+	"bytes"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/elastos/Elastos.ELA.SPV/database"
+	"github.com/elastos/Elastos.ELA.SPV/database/dbtest"
+	"github.com/elastos/Elastos.ELA.SPV/util"
+
+	"github.com/elastos/Elastos.ELA/common"
+	elatx "github.com/elastos/Elastos.ELA/core/transaction"
+	elacommon "github.com/elastos/Elastos.ELA/core/types/common"
+	"github.com/elastos/Elastos.ELA/core/types/payload"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// backend creates the header store and data store of a storage backend.
+type backend struct {
+	name       string
+	newHeaders func(t *testing.T) HeaderStore
+	newData    func(t *testing.T) DataStore
+}
+
+func backends(dataDir string) []backend {
+	return []backend{
+		{
+			name: "leveldb",
+			newHeaders: func(t *testing.T) HeaderStore {
+				headers, err := NewHeaderStore(dataDir, newEmptyHeader)
+				if !assert.NoError(t, err) {
+					t.FailNow()
+				}
+				return headers
+			},
+			newData: func(t *testing.T) DataStore {
+				db, err := NewDataStore(dataDir, nil, 36, "")
+				if !assert.NoError(t, err) {
+					t.FailNow()
+				}
+				return db
+			},
+		},
+		{
+			name: "memory",
+			newHeaders: func(t *testing.T) HeaderStore {
+				headers, err := NewMemoryHeaderStore(newEmptyHeader)
+				if !assert.NoError(t, err) {
+					t.FailNow()
+				}
+				return headers
+			},
+			newData: func(t *testing.T) DataStore {
+				db, err := NewMemoryDataStore(nil, 36, "")
+				if !assert.NoError(t, err) {
+					t.FailNow()
+				}
+				return db
+			},
+		},
+	}
+}
+
+func newStoreTx(lockTime, height uint32) *util.Tx {
+	tx := elatx.CreateTransaction(
+		elacommon.TxVersion09,
+		elacommon.TransferAsset,
+		0,
+		&payload.TransferAsset{},
+		nil,
+		nil,
+		nil,
+		lockTime,
+		nil,
+	)
+	buf := new(bytes.Buffer)
+	tx.Serialize(buf)
+	return &util.Tx{
+		Hash:      tx.Hash(),
+		Height:    height,
+		Timestamp: time.Unix(0, 0),
+		RawData:   buf.Bytes(),
+	}
+}
+
+func TestConformance(t *testing.T) {
+	dataDir := "spv_test"
+
+	for _, b := range backends(dataDir) {
+		t.Run(b.name+"/headers", func(t *testing.T) {
+			os.RemoveAll(dataDir)
+			defer os.RemoveAll(dataDir)
+
+			headers := b.newHeaders(t)
+			defer headers.Close()
+			dbtest.TestChainHeaders(t, headers, newHeader)
+		})
+
+		t.Run(b.name+"/chainstore", func(t *testing.T) {
+			os.RemoveAll(dataDir)
+			defer os.RemoveAll(dataDir)
+
+			headers := b.newHeaders(t)
+			defer headers.Close()
+			dbtest.TestChainStore(t, headers, database.NewMemoryTxsDB(),
+				newHeader)
+		})
+
+		t.Run(b.name+"/recovery", func(t *testing.T) {
+			defer os.RemoveAll(dataDir)
+
+			dbtest.TestChainStoreRecovery(t,
+				func() (database.Headers, database.TxsDB) {
+					os.RemoveAll(dataDir)
+					return b.newHeaders(t), database.NewMemoryTxsDB()
+				}, newHeader)
+		})
+
+		t.Run(b.name+"/datastore", func(t *testing.T) {
+			os.RemoveAll(dataDir)
+			defer os.RemoveAll(dataDir)
+
+			db := b.newData(t)
+			defer db.Close()
+			testDataStore(t, db)
+		})
+	}
+}
+
+// testDataStore checks the batch commit, rollback and delete by height
+// semantics of the data store.
+func testDataStore(t *testing.T, db DataStore) {
+	tx1, tx2 := newStoreTx(1, 100), newStoreTx(2, 101)
+
+	// changes in a rolled back batch are discarded.
+	batch := db.Batch()
+	assert.NoError(t, batch.Txs().Put(tx1))
+	assert.NoError(t, batch.Rollback())
+	_, err := db.Txs().Get(&tx1.Hash)
+	assert.Error(t, err)
+
+	// changes are visible after commit.
+	batch = db.Batch()
+	assert.NoError(t, batch.Txs().Put(tx1))
+	op := util.NewOutPoint(tx1.Hash, 0)
+	assert.NoError(t, batch.Ops().Put(op, common.Uint168{1}))
+	if !assert.NoError(t, batch.Commit()) {
+		t.FailNow()
+	}
+	tx, err := db.Txs().Get(&tx1.Hash)
+	assert.NoError(t, err)
+	assert.Equal(t, tx1.RawData, tx.RawData)
+	assert.Equal(t, &common.Uint168{1}, db.Ops().HaveOp(op))
+
+	// transactions are indexed by height.
+	txsBatch := db.Txs().Batch()
+	assert.NoError(t, txsBatch.Put(tx2))
+	assert.NoError(t, txsBatch.Commit())
+	ids, err := db.Txs().GetIds(101)
+	assert.NoError(t, err)
+	assert.Equal(t, []*common.Uint256{&tx2.Hash}, ids)
+
+	// delete all data on height 101.
+	batch = db.Batch()
+	assert.NoError(t, batch.DelAll(101))
+	assert.NoError(t, batch.Commit())
+	_, err = db.Txs().Get(&tx2.Hash)
+	assert.Error(t, err)
+	_, err = db.Txs().Get(&tx1.Hash)
+	assert.NoError(t, err)
+
+	assert.NoError(t, db.Clear())
+	_, err = db.Txs().Get(&tx1.Hash)
+	assert.Error(t, err)
+	assert.Nil(t, db.Ops().HaveOp(op))
+}
@@ -5,15 +5,14 @@ import (
 	"errors"
 	"sync"
 
+	"github.com/elastos/Elastos.ELA.SPV/interface/store/kv"
+
 	"github.com/elastos/Elastos.ELA/common"
 	elacommon "github.com/elastos/Elastos.ELA/core/types/common"
 	it "github.com/elastos/Elastos.ELA/core/types/interfaces"
 	"github.com/elastos/Elastos.ELA/core/types/outputpayload"
 	"github.com/elastos/Elastos.ELA/core/types/payload"
 	"github.com/elastos/Elastos.ELA/dpos/state"
-
-	"github.com/syndtr/goleveldb/leveldb"
-	"github.com/syndtr/goleveldb/leveldb/util"
 )
 
 // Ensure customID implement CustomID interface.
@@ -32,8 +31,8 @@ type CustomIDInfo struct {
 type customID struct {
 	batch
 	sync.RWMutex
-	db                  *leveldb.DB
-	b                   *leveldb.Batch
+	db                  kv.DB
+	b                   kv.Batch
 	cache               map[common.Uint256]uint32
 	reservedCustomIDs   map[string]uint32
 	receivedCustomIDs   map[string]CustomIDInfo // key: customID
@@ -43,10 +42,10 @@ type customID struct {
 	GenesisBlockAddress string
 }
 
-func NewCustomID(db *leveldb.DB, GenesisBlockAddress string) *customID {
+func NewCustomID(db kv.DB, GenesisBlockAddress string) *customID {
 	return &customID{
 		db:                  db,
-		b:                   new(leveldb.Batch),
+		b:                   db.NewBatch(),
 		cache:               make(map[common.Uint256]uint32),
 		reservedCustomIDs:   make(map[string]uint32, 0),
 		receivedCustomIDs:   make(map[string]CustomIDInfo, 0),
@@ -58,49 +57,49 @@ func (c *customID) PutControversialReservedCustomIDs(
 	reservedCustomIDs []string, proposalHash common.Uint256) error {
 	c.Lock()
 	defer c.Unlock()
-	batch := new(leveldb.Batch)
+	batch := c.db.NewBatch()
 	if err := c.batchPutControversialReservedCustomIDs(
 		reservedCustomIDs, proposalHash, batch); err != nil {
 		return err
 	}
-	return c.db.Write(batch, nil)
+	return c.db.Write(batch)
 }
 
 func (c *customID) PutControversialReceivedCustomIDs(receivedCustomIDs []string,
 	did common.Uint168, proposalHash common.Uint256) error {
 	c.Lock()
 	defer c.Unlock()
-	batch := new(leveldb.Batch)
+	batch := c.db.NewBatch()
 	if err := c.batchPutControversialReceivedCustomIDs(
 		receivedCustomIDs, did, proposalHash, batch); err != nil {
 		return err
 	}
-	return c.db.Write(batch, nil)
+	return c.db.Write(batch)
 }
 
 func (c *customID) PutControversialChangeCustomIDFee(rate common.Fixed64, proposalHash common.Uint256, workingHeight uint32) error {
 	c.Lock()
 	defer c.Unlock()
-	batch := new(leveldb.Batch)
+	batch := c.db.NewBatch()
 	if err := c.batchPutControversialChangeCustomIDFee(rate, workingHeight, proposalHash, batch); err != nil {
 		return err
 	}
-	return c.db.Write(batch, nil)
+	return c.db.Write(batch)
 }
 
 func (c *customID) PutCustomIDProposalResults(
 	results []payload.ProposalResult, height uint32) error {
 	c.Lock()
 	defer c.Unlock()
-	batch := new(leveldb.Batch)
+	batch := c.db.NewBatch()
 	if err := c.batchPutCustomIDProposalResults(results, height, batch); err != nil {
 		return err
 	}
-	return c.db.Write(batch, nil)
+	return c.db.Write(batch)
 }
 
 func (c *customID) BatchPutControversialReservedCustomIDs(
-	reservedCustomIDs []string, proposalHash common.Uint256, batch *leveldb.Batch) error {
+	reservedCustomIDs []string, proposalHash common.Uint256, batch kv.Batch) error {
 	c.Lock()
 	defer c.Unlock()
 
@@ -108,14 +107,14 @@ func (c *customID) BatchPutControversialReservedCustomIDs(
 }
 
 func (c *customID) BatchDeleteControversialReservedCustomIDs(
-	proposalHash common.Uint256, batch *leveldb.Batch) {
+	proposalHash common.Uint256, batch kv.Batch) {
 	c.Lock()
 	defer c.Unlock()
 	batch.Delete(toKey(BKTReservedCustomID, proposalHash.Bytes()...))
 }
 
 func (c *customID) BatchPutControversialReceivedCustomIDs(receivedCustomIDs []string,
-	did common.Uint168, proposalHash common.Uint256, batch *leveldb.Batch) error {
+	did common.Uint168, proposalHash common.Uint256, batch kv.Batch) error {
 	c.Lock()
 	defer c.Unlock()
 
@@ -123,7 +122,7 @@ func (c *customID) BatchPutControversialReceivedCustomIDs(receivedCustomIDs []st
 }
 
 func (c *customID) BatchDeleteControversialReceivedCustomIDs(
-	proposalHash common.Uint256, batch *leveldb.Batch) {
+	proposalHash common.Uint256, batch kv.Batch) {
 	c.Lock()
 	defer c.Unlock()
 
@@ -131,7 +130,7 @@ func (c *customID) BatchDeleteControversialReceivedCustomIDs(
 }
 
 func (c *customID) BatchPutControversialChangeCustomIDFee(rate common.Fixed64,
-	proposalHash common.Uint256, workingHeight uint32, batch *leveldb.Batch) error {
+	proposalHash common.Uint256, workingHeight uint32, batch kv.Batch) error {
 	c.Lock()
 	defer c.Unlock()
 
@@ -139,7 +138,7 @@ func (c *customID) BatchPutControversialChangeCustomIDFee(rate common.Fixed64,
 }
 
 func (c *customID) BatchDeleteControversialChangeCustomIDFee(
-	proposalHash common.Uint256, batch *leveldb.Batch) {
+	proposalHash common.Uint256, batch kv.Batch) {
 	c.Lock()
 	defer c.Unlock()
 
@@ -147,7 +146,7 @@ func (c *customID) BatchDeleteControversialChangeCustomIDFee(
 }
 
 func (c *customID) BatchPutCustomIDProposalResults(
-	results []payload.ProposalResult, height uint32, batch *leveldb.Batch) error {
+	results []payload.ProposalResult, height uint32, batch kv.Batch) error {
 	c.Lock()
 	defer c.Unlock()
 
@@ -155,7 +154,7 @@ func (c *customID) BatchPutCustomIDProposalResults(
 }
 
 func (c *customID) batchPutCustomIDProposalResults(
-	results []payload.ProposalResult, height uint32, batch *leveldb.Batch) error {
+	results []payload.ProposalResult, height uint32, batch kv.Batch) error {
 	// add new reserved custom ID into cache.
 	for _, r := range results {
 		switch r.ProposalType {
@@ -177,6 +176,14 @@ func (c *customID) batchPutCustomIDProposalResults(
 				}
 				for k, _ := range reservedCustomIDs {
 					c.reservedCustomIDs[k] = height
+					if err := c.batchPutCustomIDRecord(&CustomIDRecord{
+						CustomID:     k,
+						Status:       CustomIDReserved,
+						ProposalHash: r.ProposalHash,
+						Height:       height,
+					}, batch); err != nil {
+						return err
+					}
 				}
 				// update db.
 				if err := c.batchPutReservedCustomIDs(batch); err != nil {
@@ -208,6 +215,15 @@ func (c *customID) batchPutCustomIDProposalResults(
 						DID:    v,
 						Height: height,
 					}
+					if err := c.batchPutCustomIDRecord(&CustomIDRecord{
+						CustomID:     k,
+						Status:       CustomIDReceived,
+						DID:          v,
+						ProposalHash: r.ProposalHash,
+						Height:       height,
+					}, batch); err != nil {
+						return err
+					}
 				}
 				// update db.
 				if err := c.batchPutReceivedCustomIDs(batch); err != nil {
@@ -228,6 +244,10 @@ func (c *customID) batchPutCustomIDProposalResults(
 				if err := c.batchPutChangeCustomIDFee(batch, rate, workingHeight); err != nil {
 					return err
 				}
+				if err := c.batchPutCustomIDFeeProposal(workingHeight,
+					r.ProposalHash, height, batch); err != nil {
+					return err
+				}
 			} else {
 				// if you need to remove data from db, you need to consider rollback.
 				//c.removeControversialCustomIDFeeRate(r.ProposalHash, batch)
@@ -238,7 +258,7 @@ func (c *customID) batchPutCustomIDProposalResults(
 }
 
 func (c *customID) batchPutControversialReservedCustomIDs(
-	reservedCustomIDs []string, proposalHash common.Uint256, batch *leveldb.Batch) error {
+	reservedCustomIDs []string, proposalHash common.Uint256, batch kv.Batch) error {
 	// store reserved custom ID.
 	w := new(bytes.Buffer)
 	err := common.WriteVarUint(w, uint64(len(reservedCustomIDs)))
@@ -254,7 +274,7 @@ func (c *customID) batchPutControversialReservedCustomIDs(
 	return nil
 }
 
-func (c *customID) batchPutReservedCustomIDs(batch *leveldb.Batch) error {
+func (c *customID) batchPutReservedCustomIDs(batch kv.Batch) error {
 	// store reserved custom ID.
 	w := new(bytes.Buffer)
 	err := common.WriteVarUint(w, uint64(len(c.reservedCustomIDs)))
@@ -275,7 +295,7 @@ func (c *customID) batchPutReservedCustomIDs(batch *leveldb.Batch) error {
 
 func (c *customID) batchPutControversialReceivedCustomIDs(
 	receivedCustomIDs []string, did common.Uint168,
-	proposalHash common.Uint256, batch *leveldb.Batch) error {
+	proposalHash common.Uint256, batch kv.Batch) error {
 	w := new(bytes.Buffer)
 	err := common.WriteUint32(w, uint32(len(receivedCustomIDs)))
 	if err != nil {
@@ -293,7 +313,7 @@ func (c *customID) batchPutControversialReceivedCustomIDs(
 	return nil
 }
 
-func (c *customID) batchPutReceivedCustomIDs(batch *leveldb.Batch) error {
+func (c *customID) batchPutReceivedCustomIDs(batch kv.Batch) error {
 	w := new(bytes.Buffer)
 	err := common.WriteUint32(w, uint32(len(c.receivedCustomIDs)))
 	if err != nil {
@@ -315,7 +335,7 @@ func (c *customID) batchPutReceivedCustomIDs(batch *leveldb.Batch) error {
 }
 
 func (c *customID) batchPutControversialChangeCustomIDFee(rate common.Fixed64,
-	workingHeight uint32, proposalHash common.Uint256, batch *leveldb.Batch) error {
+	workingHeight uint32, proposalHash common.Uint256, batch kv.Batch) error {
 	w := new(bytes.Buffer)
 	if err := rate.Serialize(w); err != nil {
 		return err
@@ -328,14 +348,14 @@ func (c *customID) batchPutControversialChangeCustomIDFee(rate common.Fixed64,
 }
 
 func (c *customID) getCurrentCustomIDFeePositions() []uint32 {
-	pos, err := c.db.Get(BKTCustomIDFeePositions, nil)
+	pos, err := c.db.Get(BKTCustomIDFeePositions)
 	if err == nil {
 		return bytesToUint32Array(pos)
 	}
 	return nil
 }
 
-func (c *customID) batchPutChangeCustomIDFee(batch *leveldb.Batch, feeRate common.Fixed64, workingHeight uint32) error {
+func (c *customID) batchPutChangeCustomIDFee(batch kv.Batch, feeRate common.Fixed64, workingHeight uint32) error {
 	posCache := c.getCurrentCustomIDFeePositions()
 	newPosCache := make([]uint32, 0)
 	for _, p := range posCache {
@@ -468,7 +488,7 @@ func maxUint32(first, second uint32) uint32 {
 
 func (c *customID) getControversialReservedCustomIDsFromDB(proposalHash common.Uint256) (map[string]struct{}, error) {
 	var val []byte
-	val, err := c.db.Get(toKey(BKTReservedCustomID, proposalHash.Bytes()...), nil)
+	val, err := c.db.Get(toKey(BKTReservedCustomID, proposalHash.Bytes()...))
 	if err != nil {
 		return nil, err
 	}
@@ -489,7 +509,7 @@ func (c *customID) getControversialReservedCustomIDsFromDB(proposalHash common.U
 }
 
 func (c *customID) removeControversialReservedCustomIDsFromDB(
-	proposalHash common.Uint256, batch *leveldb.Batch) {
+	proposalHash common.Uint256, batch kv.Batch) {
 	batch.Delete(toKey(BKTReservedCustomID, proposalHash.Bytes()...))
 }
 
@@ -497,9 +517,9 @@ func (c *customID) getReservedCustomIDsFromDB() (map[string]uint32, error) {
 	var val []byte
 	//if return no err,reservedCustomIDs also allocated
 	reservedCustomIDs := make(map[string]uint32, 0)
-	val, err := c.db.Get(BKTReservedCustomID, nil)
+	val, err := c.db.Get(BKTReservedCustomID)
 	if err != nil {
-		if err.Error() == leveldb.ErrNotFound.Error() {
+		if err.Error() == kv.ErrNotFound.Error() {
 			return reservedCustomIDs, nil
 		}
 		return nil, err
@@ -526,7 +546,7 @@ func (c *customID) getReservedCustomIDsFromDB() (map[string]uint32, error) {
 func (c *customID) getControversialReceivedCustomIDsFromDB(
 	proposalHash common.Uint256) (map[string]common.Uint168, error) {
 	var val []byte
-	val, err := c.db.Get(toKey(BKTReceivedCustomID, proposalHash.Bytes()...), nil)
+	val, err := c.db.Get(toKey(BKTReceivedCustomID, proposalHash.Bytes()...))
 	if err != nil {
 		return nil, err
 	}
@@ -551,7 +571,7 @@ func (c *customID) getControversialReceivedCustomIDsFromDB(
 }
 
 func (c *customID) removeControversialReceivedCustomIDsFromDB(
-	proposalHash common.Uint256, batch *leveldb.Batch) {
+	proposalHash common.Uint256, batch kv.Batch) {
 	batch.Delete(toKey(BKTReceivedCustomID, proposalHash.Bytes()...))
 }
 
@@ -559,9 +579,9 @@ func (c *customID) getReceivedCustomIDsFromDB() (map[string]CustomIDInfo, error)
 	var val []byte
 	receiedCustomIDs := make(map[string]CustomIDInfo, 0)
 
-	val, err := c.db.Get(BKTReceivedCustomID, nil)
+	val, err := c.db.Get(BKTReceivedCustomID)
 	if err != nil {
-		if err.Error() == leveldb.ErrNotFound.Error() {
+		if err.Error() == kv.ErrNotFound.Error() {
 			return receiedCustomIDs, nil
 		}
 		return nil, err
@@ -646,7 +666,7 @@ func (c *customID) findCustomIDWorkingHeightByCurrentHeight(height uint32) (uint
 
 func (c *customID) getControversialCustomIDFeeRateByProposalHash(proposalHash common.Uint256) (common.Fixed64, uint32, error) {
 	var val []byte
-	val, err := c.db.Get(toKey(BKTChangeCustomIDFee, proposalHash.Bytes()...), nil)
+	val, err := c.db.Get(toKey(BKTChangeCustomIDFee, proposalHash.Bytes()...))
 	if err != nil {
 		return 0, 0, err
 	}
@@ -668,7 +688,7 @@ func (c *customID) getControversialCustomIDFeeRateByHeight(workingHeight uint32)
 		return 0, err
 	}
 	var val []byte
-	val, err := c.db.Get(toKey(BKTChangeCustomIDFee, buf.Bytes()...), nil)
+	val, err := c.db.Get(toKey(BKTChangeCustomIDFee, buf.Bytes()...))
 	if err != nil {
 		return 0, err
 	}
@@ -681,7 +701,7 @@ func (c *customID) getControversialCustomIDFeeRateByHeight(workingHeight uint32)
 }
 
 func (c *customID) removeControversialCustomIDFeeRate(
-	proposalHash common.Uint256, batch *leveldb.Batch) {
+	proposalHash common.Uint256, batch kv.Batch) {
 	batch.Delete(toKey(BKTChangeCustomIDFee, proposalHash.Bytes()...))
 }
 
@@ -694,29 +714,29 @@ func (c *customID) Clear() error {
 	c.Lock()
 	defer c.Unlock()
 
-	batch := new(leveldb.Batch)
-	it := c.db.NewIterator(util.BytesPrefix(BKTReservedCustomID), nil)
+	batch := c.db.NewBatch()
+	it := c.db.NewIterator(kv.BytesPrefix(BKTReservedCustomID))
 	defer it.Release()
 	for it.Next() {
 		batch.Delete(it.Key())
 	}
 
-	it = c.db.NewIterator(util.BytesPrefix(BKTReceivedCustomID), nil)
+	it = c.db.NewIterator(kv.BytesPrefix(BKTReceivedCustomID))
 	defer it.Release()
 	for it.Next() {
 		batch.Delete(it.Key())
 	}
 
-	it = c.db.NewIterator(util.BytesPrefix(BKTChangeCustomIDFee), nil)
+	it = c.db.NewIterator(kv.BytesPrefix(BKTChangeCustomIDFee))
 	defer it.Release()
 	for it.Next() {
 		batch.Delete(it.Key())
 	}
-	return c.db.Write(c.b, nil)
+	return c.db.Write(c.b)
 }
 
 func (c *customID) Commit() error {
-	return c.db.Write(c.b, nil)
+	return c.db.Write(c.b)
 }
 
 func (c *customID) Rollback() error {
@@ -724,16 +744,16 @@ func (c *customID) Rollback() error {
 	return nil
 }
 
-func (c *customID) CommitBatch(batch *leveldb.Batch) error {
-	return c.db.Write(batch, nil)
+func (c *customID) CommitBatch(batch kv.Batch) error {
+	return c.db.Write(batch)
 }
 
-func (c *customID) RollbackBatch(batch *leveldb.Batch) error {
+func (c *customID) RollbackBatch(batch kv.Batch) error {
 	batch.Reset()
 	return nil
 }
 
-func (c *customID) BatchPutRetSideChainDepositCoinTx(tx it.Transaction, batch *leveldb.Batch) error {
+func (c *customID) BatchPutRetSideChainDepositCoinTx(tx it.Transaction, batch kv.Batch) error {
 	c.Lock()
 	defer c.Unlock()
 	for _, output := range tx.Outputs() {
@@ -755,7 +775,7 @@ func (c *customID) BatchPutRetSideChainDepositCoinTx(tx it.Transaction, batch *l
 	return nil
 }
 
-func (c *customID) BatchDeleteRetSideChainDepositCoinTx(tx it.Transaction, batch *leveldb.Batch) error {
+func (c *customID) BatchDeleteRetSideChainDepositCoinTx(tx it.Transaction, batch kv.Batch) error {
 	c.Lock()
 	defer c.Unlock()
 	for _, output := range tx.Outputs() {
@@ -777,7 +797,7 @@ func (c *customID) BatchDeleteRetSideChainDepositCoinTx(tx it.Transaction, batch
 }
 
 func (c *customID) HaveRetSideChainDepositCoinTx(txHash common.Uint256) bool {
-	_, err := c.db.Get(toKey(BKTReturnSideChainDepositCoin, txHash.Bytes()...), nil)
+	_, err := c.db.Get(toKey(BKTReturnSideChainDepositCoin, txHash.Bytes()...))
 	if err == nil {
 		return true
 	}
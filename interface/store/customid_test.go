@@ -1,8 +1,14 @@
 package store
 
 import (
+	"os"
+	"path/filepath"
 	"testing"
 
+	"github.com/elastos/Elastos.ELA.SPV/interface/store/kv"
+
+	"github.com/elastos/Elastos.ELA/common"
+	"github.com/elastos/Elastos.ELA/core/types/payload"
 	"github.com/elastos/Elastos.ELA/dpos/state"
 
 	"github.com/stretchr/testify/assert"
@@ -302,3 +308,133 @@ func TestCustomID_GetConfirmCount(t *testing.T) {
 	}
 	assert.Equal(t, false, isProposalConfirmed(currenHeight, proposalHeight, revertInfo))
 }
+
+func TestCustomID_Records(t *testing.T) {
+	dataDir := "spv_test"
+	os.RemoveAll(dataDir)
+	defer os.RemoveAll(dataDir)
+
+	db, err := kv.OpenLevelDB(filepath.Join(dataDir, "store"))
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	defer db.Close()
+	cid := NewCustomID(db, "")
+
+	reserveProposal := common.Uint256{1}
+	receiveProposal := common.Uint256{2}
+	feeProposal := common.Uint256{3}
+	did := common.Uint168{0x67}
+	assert.NoError(t, cid.PutControversialReservedCustomIDs(
+		[]string{"alice", "bob"}, reserveProposal))
+	assert.NoError(t, cid.PutControversialReceivedCustomIDs(
+		[]string{"alice"}, did, receiveProposal))
+	assert.NoError(t, cid.PutControversialChangeCustomIDFee(
+		2e8, feeProposal, 500))
+
+	assert.NoError(t, cid.PutCustomIDProposalResults([]payload.ProposalResult{
+		{ProposalHash: reserveProposal, ProposalType: payload.ReserveCustomID, Result: true},
+	}, 100))
+	record, err := cid.GetCustomIDRecord("alice")
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	assert.Equal(t, &CustomIDRecord{
+		CustomID:     "alice",
+		Status:       CustomIDReserved,
+		ProposalHash: reserveProposal,
+		Height:       100,
+	}, record)
+
+	assert.NoError(t, cid.PutCustomIDProposalResults([]payload.ProposalResult{
+		{ProposalHash: receiveProposal, ProposalType: payload.ReceiveCustomID, Result: true},
+		{ProposalHash: feeProposal, ProposalType: payload.ChangeCustomIDFee, Result: true},
+	}, 200))
+	records, err := cid.GetCustomIDsByOwner(did)
+	assert.NoError(t, err)
+	assert.Equal(t, []*CustomIDRecord{{
+		CustomID:     "alice",
+		Status:       CustomIDReceived,
+		DID:          did,
+		ProposalHash: receiveProposal,
+		Height:       200,
+	}}, records)
+
+	history, err := cid.GetCustomIDFeeRateHistory()
+	assert.NoError(t, err)
+	assert.Equal(t, []CustomIDFeeRate{{
+		WorkingHeight: 500,
+		Rate:          2e8,
+		ProposalHash:  feeProposal,
+		Height:        200,
+	}}, history)
+
+	// rollback the proposal results on height 200.
+	batch := db.NewBatch()
+	assert.NoError(t, cid.BatchDeleteCustomIDHistory(200, batch))
+	assert.NoError(t, cid.CommitBatch(batch))
+
+	record, err = cid.GetCustomIDRecord("alice")
+	assert.NoError(t, err)
+	assert.Equal(t, CustomIDReserved, record.Status)
+	records, err = cid.GetCustomIDsByOwner(did)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, len(records))
+	history, err = cid.GetCustomIDFeeRateHistory()
+	assert.NoError(t, err)
+	assert.Equal(t, 0, len(history))
+
+	// rollback the proposal results on height 100.
+	batch = db.NewBatch()
+	assert.NoError(t, cid.BatchDeleteCustomIDHistory(100, batch))
+	assert.NoError(t, cid.CommitBatch(batch))
+	_, err = cid.GetCustomIDRecord("bob")
+	assert.Equal(t, kv.ErrNotFound, err)
+}
+
+func TestCustomID_OwnerChangedInBatch(t *testing.T) {
+	dataDir := "spv_test"
+	os.RemoveAll(dataDir)
+	defer os.RemoveAll(dataDir)
+
+	db, err := kv.OpenLevelDB(filepath.Join(dataDir, "store"))
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	defer db.Close()
+	cid := NewCustomID(db, "")
+
+	first := common.Uint256{1}
+	second := common.Uint256{2}
+	did1 := common.Uint168{0x67, 1}
+	did2 := common.Uint168{0x67, 2}
+	assert.NoError(t, cid.PutControversialReceivedCustomIDs(
+		[]string{"alice"}, did1, first))
+	assert.NoError(t, cid.PutControversialReceivedCustomIDs(
+		[]string{"alice"}, did2, second))
+
+	// the custom ID changes owner twice on the same height.
+	assert.NoError(t, cid.PutCustomIDProposalResults([]payload.ProposalResult{
+		{ProposalHash: first, ProposalType: payload.ReceiveCustomID, Result: true},
+		{ProposalHash: second, ProposalType: payload.ReceiveCustomID, Result: true},
+	}, 100))
+
+	records, err := cid.GetCustomIDsByOwner(did1)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, len(records))
+	records, err = cid.GetCustomIDsByOwner(did2)
+	assert.NoError(t, err)
+	if assert.Equal(t, 1, len(records)) {
+		assert.Equal(t, second, records[0].ProposalHash)
+	}
+
+	// rollback restores the record before the height.
+	batch := db.NewBatch()
+	assert.NoError(t, cid.BatchDeleteCustomIDHistory(100, batch))
+	assert.NoError(t, cid.CommitBatch(batch))
+	_, err = cid.GetCustomIDRecord("alice")
+	assert.Equal(t, kv.ErrNotFound, err)
+	records, err = cid.GetCustomIDsByOwner(did2)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, len(records))
+}
@@ -0,0 +1,295 @@
+package store
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+
+	"github.com/elastos/Elastos.ELA.SPV/interface/store/kv"
+
+	"github.com/elastos/Elastos.ELA/common"
+)
+
+type CustomIDStatus byte
+
+const (
+	// CustomIDReserved indicates the custom ID has been reserved by the
+	// ReserveCustomID proposal.
+	CustomIDReserved CustomIDStatus = 0x01
+
+	// CustomIDReceived indicates the custom ID has been received by the DID
+	// from the ReceiveCustomID proposal.
+	CustomIDReceived CustomIDStatus = 0x02
+)
+
+func (s CustomIDStatus) String() string {
+	switch s {
+	case CustomIDReserved:
+		return "Reserved"
+	case CustomIDReceived:
+		return "Received"
+	default:
+		return "Unknown"
+	}
+}
+
+// CustomIDRecord is the current state of a custom ID.
+type CustomIDRecord struct {
+	CustomID string
+	Status   CustomIDStatus
+
+	// DID is the owner of the custom ID, only available if the
+	// custom ID has been received.
+	DID common.Uint168
+
+	// ProposalHash is the proposal which set the current state.
+	ProposalHash common.Uint256
+
+	// Height is the height of the proposal result packed in, the state
+	// takes effect after the proposal result confirmed.
+	Height uint32
+}
+
+func (r *CustomIDRecord) Serialize(w io.Writer) error {
+	if err := common.WriteVarString(w, r.CustomID); err != nil {
+		return err
+	}
+	if err := common.WriteUint8(w, byte(r.Status)); err != nil {
+		return err
+	}
+	if err := r.DID.Serialize(w); err != nil {
+		return err
+	}
+	if err := r.ProposalHash.Serialize(w); err != nil {
+		return err
+	}
+	return common.WriteUint32(w, r.Height)
+}
+
+func (r *CustomIDRecord) Deserialize(rd io.Reader) error {
+	var err error
+	if r.CustomID, err = common.ReadVarString(rd); err != nil {
+		return err
+	}
+	status, err := common.ReadUint8(rd)
+	if err != nil {
+		return err
+	}
+	r.Status = CustomIDStatus(status)
+	if err := r.DID.Deserialize(rd); err != nil {
+		return err
+	}
+	if err := r.ProposalHash.Deserialize(rd); err != nil {
+		return err
+	}
+	r.Height, err = common.ReadUint32(rd)
+	return err
+}
+
+// CustomIDFeeRate is a change of the custom ID fee rate.
+type CustomIDFeeRate struct {
+	WorkingHeight uint32
+	Rate          common.Fixed64
+	ProposalHash  common.Uint256
+
+	// Height is the height of the proposal result packed in.
+	Height uint32
+}
+
+func (c *customID) GetCustomIDRecord(customID string) (*CustomIDRecord, error) {
+	c.RLock()
+	defer c.RUnlock()
+	return c.getCustomIDRecord(customID)
+}
+
+func (c *customID) GetCustomIDsByOwner(did common.Uint168) ([]*CustomIDRecord, error) {
+	c.RLock()
+	defer c.RUnlock()
+
+	prefix := toKey(BKTCustomIDOwners, did[:]...)
+	it := c.db.NewIterator(kv.BytesPrefix(prefix))
+	defer it.Release()
+
+	var records []*CustomIDRecord
+	for it.Next() {
+		record, err := c.getCustomIDRecord(string(subKey(prefix, it.Key())))
+		if err != nil {
+			return nil, err
+		}
+		records = append(records, record)
+	}
+	return records, it.Error()
+}
+
+func (c *customID) GetCustomIDFeeRateHistory() ([]CustomIDFeeRate, error) {
+	c.RLock()
+	defer c.RUnlock()
+
+	positions := c.getCurrentCustomIDFeePositions()
+	history := make([]CustomIDFeeRate, 0, len(positions))
+	for _, p := range positions {
+		rate, err := c.getControversialCustomIDFeeRateByHeight(p)
+		if err != nil {
+			return nil, err
+		}
+		feeRate := CustomIDFeeRate{WorkingHeight: p, Rate: rate}
+		if data, err := c.db.Get(toKey(BKTCustomIDFeeProposals,
+			uint32toBytes(p)...)); err == nil {
+			r := bytes.NewReader(data)
+			if err := feeRate.ProposalHash.Deserialize(r); err != nil {
+				return nil, err
+			}
+			if feeRate.Height, err = common.ReadUint32(r); err != nil {
+				return nil, err
+			}
+		}
+		history = append(history, feeRate)
+	}
+	return history, nil
+}
+
+// BatchDeleteCustomIDHistory restores custom ID records and fee rate changes
+// made by the proposal results packed on the given height.
+func (c *customID) BatchDeleteCustomIDHistory(height uint32, batch kv.Batch) error {
+	c.Lock()
+	defer c.Unlock()
+
+	var key [4]byte
+	binary.BigEndian.PutUint32(key[:], height)
+	prefix := toKey(BKTCustomIDUndo, key[:]...)
+	it := c.db.NewIterator(kv.BytesPrefix(prefix))
+	for it.Next() {
+		id := string(subKey(prefix, it.Key()))
+		current, err := c.getCustomIDRecord(id)
+		if err == nil && current.Status == CustomIDReceived {
+			batch.Delete(customIDOwnerKey(current.DID, id))
+		}
+
+		if len(it.Value()) == 0 {
+			batch.Delete(toKey(BKTCustomIDRecords, []byte(id)...))
+		} else {
+			var previous CustomIDRecord
+			err := previous.Deserialize(bytes.NewReader(it.Value()))
+			if err != nil {
+				it.Release()
+				return err
+			}
+			batch.Put(toKey(BKTCustomIDRecords, []byte(id)...), it.Value())
+			if previous.Status == CustomIDReceived {
+				batch.Put(customIDOwnerKey(previous.DID, id), empty)
+			}
+		}
+		batch.Delete(it.Key())
+	}
+	it.Release()
+	if err := it.Error(); err != nil {
+		return err
+	}
+
+	// Remove fee rate changes recorded on this height.
+	var changed bool
+	positions := c.getCurrentCustomIDFeePositions()
+	newPositions := make([]uint32, 0, len(positions))
+	for _, p := range positions {
+		data, err := c.db.Get(toKey(BKTCustomIDFeeProposals, uint32toBytes(p)...))
+		if err == nil && len(data) == 36 && binary.LittleEndian.Uint32(data[32:]) == height {
+			batch.Delete(toKey(BKTCustomIDFeeProposals, uint32toBytes(p)...))
+			changed = true
+			continue
+		}
+		newPositions = append(newPositions, p)
+	}
+	if changed {
+		c.customIDFeePosCache = newPositions
+		batch.Put(BKTCustomIDFeePositions, uint32ArrayToBytes(newPositions))
+	}
+	return nil
+}
+
+func (c *customID) batchPutCustomIDRecord(record *CustomIDRecord, batch kv.Batch) error {
+	recordKey := toKey(BKTCustomIDRecords, []byte(record.CustomID)...)
+
+	// Keep the record before this height so it can be restored on rollback,
+	// only the first change on the height need to be kept.
+	var key [4]byte
+	binary.BigEndian.PutUint32(key[:], record.Height)
+	undoKey := toKey(BKTCustomIDUndo, append(key[:], record.CustomID...)...)
+	if _, err := batchGet(c.db, batch, undoKey); err == kv.ErrNotFound {
+		previous, err := batchGet(c.db, batch, recordKey)
+		switch err {
+		case nil:
+		case kv.ErrNotFound:
+			previous = empty
+		default:
+			return err
+		}
+		batch.Put(undoKey, previous)
+	} else if err != nil {
+		return err
+	}
+
+	// The record may be changed earlier within the same batch, read it
+	// through the batch so the owner index of it is removed.
+	current, err := c.batchGetCustomIDRecord(record.CustomID, batch)
+	switch err {
+	case nil:
+		if current.Status == CustomIDReceived {
+			batch.Delete(customIDOwnerKey(current.DID, current.CustomID))
+		}
+	case kv.ErrNotFound:
+	default:
+		return err
+	}
+
+	buf := new(bytes.Buffer)
+	if err := record.Serialize(buf); err != nil {
+		return err
+	}
+	batch.Put(recordKey, buf.Bytes())
+	if record.Status == CustomIDReceived {
+		batch.Put(customIDOwnerKey(record.DID, record.CustomID), empty)
+	}
+	return nil
+}
+
+func (c *customID) batchPutCustomIDFeeProposal(workingHeight uint32,
+	proposalHash common.Uint256, height uint32, batch kv.Batch) error {
+	buf := new(bytes.Buffer)
+	if err := proposalHash.Serialize(buf); err != nil {
+		return err
+	}
+	if err := common.WriteUint32(buf, height); err != nil {
+		return err
+	}
+	batch.Put(toKey(BKTCustomIDFeeProposals, uint32toBytes(workingHeight)...), buf.Bytes())
+	return nil
+}
+
+func (c *customID) getCustomIDRecord(customID string) (*CustomIDRecord, error) {
+	data, err := c.db.Get(toKey(BKTCustomIDRecords, []byte(customID)...))
+	if err != nil {
+		return nil, err
+	}
+	var record CustomIDRecord
+	if err := record.Deserialize(bytes.NewReader(data)); err != nil {
+		return nil, err
+	}
+	return &record, nil
+}
+
+func (c *customID) batchGetCustomIDRecord(customID string, batch kv.Batch) (*CustomIDRecord, error) {
+	data, err := batchGet(c.db, batch, toKey(BKTCustomIDRecords, []byte(customID)...))
+	if err != nil {
+		return nil, err
+	}
+	var record CustomIDRecord
+	if err := record.Deserialize(bytes.NewReader(data)); err != nil {
+		return nil, err
+	}
+	return &record, nil
+}
+
+func customIDOwnerKey(did common.Uint168, customID string) []byte {
+	key := append(append([]byte{}, BKTCustomIDOwners...), did[:]...)
+	return append(key, customID...)
+}
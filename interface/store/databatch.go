@@ -7,13 +7,13 @@ import (
 	"fmt"
 	"sync"
 
+	"github.com/elastos/Elastos.ELA.SPV/interface/store/kv"
 	"github.com/elastos/Elastos.ELA.SPV/util"
 
 	elatx "github.com/elastos/Elastos.ELA/core/transaction"
 	elacommon "github.com/elastos/Elastos.ELA/core/types/common"
 	it "github.com/elastos/Elastos.ELA/core/types/interfaces"
 	"github.com/elastos/Elastos.ELA/core/types/payload"
-	"github.com/syndtr/goleveldb/leveldb"
 )
 
 // Ensure dataBatch implement DataBatch interface.
@@ -21,9 +21,15 @@ var _ DataBatch = (*dataBatch)(nil)
 
 type dataBatch struct {
 	mutex sync.Mutex
-	*leveldb.DB
+	kv.DB
 	*customID
-	*leveldb.Batch
+	kv.Batch
+	ars   *arbiters
+	prps  *proposals
+	deps  *deposits
+	prds  *producers
+	atxs  *addrTxs
+	utxos *utxos
 }
 
 func (b *dataBatch) Txs() TxsBatch {
@@ -38,7 +44,7 @@ func (b *dataBatch) Que() QueBatch {
 	return &queBatch{DB: b.DB, Batch: b.Batch}
 }
 
-func (b *dataBatch) GetNakedBatch() *leveldb.Batch {
+func (b *dataBatch) GetNakedBatch() kv.Batch {
 	return b.Batch
 }
 
@@ -49,10 +55,14 @@ func (b *dataBatch) DelAll(height uint32) error {
 
 	var key [4]byte
 	binary.BigEndian.PutUint32(key[:], height)
-	data, _ := b.DB.Get(toKey(BKTHeightTxs, key[:]...), nil)
+	data, _ := b.DB.Get(toKey(BKTHeightTxs, key[:]...))
 	for _, txId := range getTxIds(data) {
 		var utx util.Tx
-		data, err := b.DB.Get(toKey(BKTTxs, txId.Bytes()...), nil)
+		data, err := b.DB.Get(toKey(BKTTxs, txId.Bytes()...))
+		if err == kv.ErrNotFound {
+			// the transaction may be pruned by store verification.
+			continue
+		}
 		if err != nil {
 			return err
 		}
@@ -86,6 +96,41 @@ func (b *dataBatch) DelAll(height uint32) error {
 
 	b.Batch.Delete(toKey(BKTHeightTxs, key[:]...))
 
+	// remove consensus mode changes caused on this height.
+	if err := b.ars.BatchDeleteRevertTransactions(b.Batch, height); err != nil {
+		return err
+	}
+
+	// restore custom ID records changed on this height.
+	if err := b.customID.BatchDeleteCustomIDHistory(height, b.Batch); err != nil {
+		return err
+	}
+
+	// remove proposals and proposal results recorded on this height.
+	if err := b.prps.BatchDeleteAll(height, b.Batch); err != nil {
+		return err
+	}
+
+	// remove cross chain deposits packed on this height.
+	if err := b.deps.BatchDeleteAll(height, b.Batch); err != nil {
+		return err
+	}
+
+	// restore producers changed on this height.
+	if err := b.prds.BatchDeleteAll(height, b.Batch); err != nil {
+		return err
+	}
+
+	// remove address history recorded on this height.
+	if err := b.atxs.BatchDeleteAll(height, b.Batch); err != nil {
+		return err
+	}
+
+	// restore outputs spent and remove outputs created on this height.
+	if err := b.utxos.BatchDeleteAll(height, b.Batch); err != nil {
+		return err
+	}
+
 	return b.Que().DelAll(height)
 }
 
@@ -179,10 +224,51 @@ func (b *dataBatch) DeleteCustomID(tx it.Transaction) error {
 }
 
 func (b *dataBatch) Commit() error {
-	return b.DB.Write(b.Batch, nil)
+	if err := b.DB.Write(b.Batch); err != nil {
+		return err
+	}
+	// consensus mode changes may be written within the batch.
+	b.ars.resetRevertCache()
+	return nil
 }
 
 func (b *dataBatch) Rollback() error {
 	b.Batch.Reset()
 	return nil
 }
+
+// batchGet returns the value of key with the changes within batch applied.
+func batchGet(db kv.DB, batch kv.Batch, key []byte) ([]byte, error) {
+	r := batchReader{key: key}
+	if err := batch.Replay(&r); err != nil {
+		return nil, err
+	}
+	if !r.found {
+		return db.Get(key)
+	}
+	if r.value == nil {
+		return nil, kv.ErrNotFound
+	}
+	return r.value, nil
+}
+
+// batchReader finds the last change of key within a batch.
+type batchReader struct {
+	key   []byte
+	value []byte
+	found bool
+}
+
+func (r *batchReader) Put(key, value []byte) {
+	if bytes.Equal(key, r.key) {
+		r.found = true
+		r.value = append([]byte{}, value...)
+	}
+}
+
+func (r *batchReader) Delete(key []byte) {
+	if bytes.Equal(key, r.key) {
+		r.found = true
+		r.value = nil
+	}
+}
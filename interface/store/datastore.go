@@ -4,7 +4,7 @@ import (
 	"path/filepath"
 	"sync"
 
-	"github.com/syndtr/goleveldb/leveldb"
+	"github.com/elastos/Elastos.ELA.SPV/interface/store/kv"
 )
 
 // Ensure dataStore implement DataStore interface.
@@ -12,7 +12,7 @@ var _ DataStore = (*dataStore)(nil)
 
 type dataStore struct {
 	sync.RWMutex
-	db    *leveldb.DB
+	db    kv.DB
 	addrs *addrs
 	tps   *txTypes
 	txs   *txs
@@ -20,18 +20,37 @@ type dataStore struct {
 	que   *que
 	ars   *arbiters
 	cid   *customID
+	prps  *proposals
+	deps  *deposits
+	prds  *producers
+	atxs  *addrTxs
+	utxos *utxos
 }
 
 ////this spv GenesisBlockAddress
 //	GenesisBlockAddress    string
 func NewDataStore(dataDir string, originArbiters [][]byte, arbitersCount int, GenesisBlockAddress string) (*dataStore, error) {
-	db, err := leveldb.OpenFile(filepath.Join(dataDir, "store"), nil)
+	db, err := kv.OpenLevelDB(filepath.Join(dataDir, "store"))
 	if err != nil {
 		return nil, err
 	}
 	if err != nil {
 		return nil, err
 	}
+	dataStore, err := NewDataStoreWithDB(db, originArbiters, arbitersCount, GenesisBlockAddress)
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+	return dataStore, nil
+}
+
+// NewDataStoreWithDB creates a data store on the given KV backend, the
+// database must be on the latest schema version or empty.
+func NewDataStoreWithDB(db kv.DB, originArbiters [][]byte, arbitersCount int, GenesisBlockAddress string) (*dataStore, error) {
+	if err := checkSchema(db, dataMigrations); err != nil {
+		return nil, err
+	}
 
 	addrs, err := NewAddrs(db)
 	if err != nil {
@@ -52,6 +71,11 @@ func NewDataStore(dataDir string, originArbiters [][]byte, arbitersCount int, Ge
 		que:   NewQue(db),
 		ars:   NewArbiters(db, originArbiters, arbitersCount),
 		cid:   NewCustomID(db, GenesisBlockAddress),
+		prps:  NewProposals(db),
+		deps:  NewDeposits(db),
+		prds:  NewProducers(db),
+		atxs:  NewAddrTxs(db),
+		utxos: NewUTXOs(db),
 	}, nil
 }
 
@@ -83,28 +107,59 @@ func (d *dataStore) CID() CustomID {
 	return d.cid
 }
 
+func (d *dataStore) Proposals() Proposals {
+	return d.prps
+}
+
+func (d *dataStore) Deposits() Deposits {
+	return d.deps
+}
+
+func (d *dataStore) Producers() Producers {
+	return d.prds
+}
+
+func (d *dataStore) AddrTxs() AddrTxs {
+	return d.atxs
+}
+
+func (d *dataStore) UTXOs() UTXOs {
+	return d.utxos
+}
+
 func (d *dataStore) Batch() DataBatch {
 	return &dataBatch{
 		DB:       d.db,
 		customID: d.cid,
-		Batch:    new(leveldb.Batch),
+		Batch:    d.db.NewBatch(),
+		ars:      d.ars,
+		prps:     d.prps,
+		deps:     d.deps,
+		prds:     d.prds,
+		atxs:     d.atxs,
+		utxos:    d.utxos,
 	}
 }
 
+func (d *dataStore) NewSnapshot() (kv.Snapshot, error) {
+	return d.db.NewSnapshot()
+}
+
 func (d *dataStore) Clear() error {
 	d.Lock()
 	defer d.Unlock()
 
 	d.que.Clear()
 
-	it := d.db.NewIterator(nil, nil)
-	batch := new(leveldb.Batch)
+	it := d.db.NewIterator(nil)
+	batch := d.db.NewBatch()
 	for it.Next() {
 		batch.Delete(it.Key())
 	}
 	it.Release()
+	putSchemaVersion(batch, latestVersion(dataMigrations))
 
-	return d.db.Write(batch, nil)
+	return d.db.Write(batch)
 }
 
 // Close db
@@ -118,5 +173,10 @@ func (d *dataStore) Close() error {
 	d.que.Close()
 	d.ars.Close()
 	d.cid.Close()
+	d.prps.Close()
+	d.deps.Close()
+	d.prds.Close()
+	d.atxs.Close()
+	d.utxos.Close()
 	return d.db.Close()
 }
@@ -0,0 +1,344 @@
+package store
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"sort"
+	"sync"
+
+	"github.com/elastos/Elastos.ELA.SPV/interface/store/kv"
+
+	"github.com/elastos/Elastos.ELA/common"
+	elacommon "github.com/elastos/Elastos.ELA/core/types/common"
+	it "github.com/elastos/Elastos.ELA/core/types/interfaces"
+	"github.com/elastos/Elastos.ELA/core/types/outputpayload"
+	"github.com/elastos/Elastos.ELA/core/types/payload"
+)
+
+// Ensure deposits implement Deposits interface.
+var _ Deposits = (*deposits)(nil)
+
+// CrossChainOutput is a deposit to side chain within the cross chain
+// transaction.
+type CrossChainOutput struct {
+	// Index of the output in the transaction.
+	Index uint16
+
+	// TargetAddress is the receiver address on side chain.
+	TargetAddress string
+
+	// Amount is the value of the output send to side chain genesis address.
+	Amount common.Fixed64
+
+	// TargetAmount is the amount side chain receiver will get.
+	TargetAmount common.Fixed64
+}
+
+// CrossChainDeposit is the deposits from main chain to the side chain
+// identified by it's genesis address.
+type CrossChainDeposit struct {
+	TxHash         common.Uint256
+	GenesisAddress string
+	Height         uint32
+	Outputs        []CrossChainOutput
+}
+
+func (d *CrossChainDeposit) Serialize(w io.Writer) error {
+	if err := d.TxHash.Serialize(w); err != nil {
+		return err
+	}
+	if err := common.WriteVarString(w, d.GenesisAddress); err != nil {
+		return err
+	}
+	if err := common.WriteUint32(w, d.Height); err != nil {
+		return err
+	}
+	if err := common.WriteVarUint(w, uint64(len(d.Outputs))); err != nil {
+		return err
+	}
+	for _, o := range d.Outputs {
+		if err := common.WriteUint16(w, o.Index); err != nil {
+			return err
+		}
+		if err := common.WriteVarString(w, o.TargetAddress); err != nil {
+			return err
+		}
+		if err := o.Amount.Serialize(w); err != nil {
+			return err
+		}
+		if err := o.TargetAmount.Serialize(w); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (d *CrossChainDeposit) Deserialize(r io.Reader) error {
+	if err := d.TxHash.Deserialize(r); err != nil {
+		return err
+	}
+	var err error
+	if d.GenesisAddress, err = common.ReadVarString(r); err != nil {
+		return err
+	}
+	if d.Height, err = common.ReadUint32(r); err != nil {
+		return err
+	}
+	count, err := common.ReadVarUint(r, 0)
+	if err != nil {
+		return err
+	}
+	d.Outputs = nil
+	for i := uint64(0); i < count; i++ {
+		var o CrossChainOutput
+		if o.Index, err = common.ReadUint16(r); err != nil {
+			return err
+		}
+		if o.TargetAddress, err = common.ReadVarString(r); err != nil {
+			return err
+		}
+		if err := o.Amount.Deserialize(r); err != nil {
+			return err
+		}
+		if err := o.TargetAmount.Deserialize(r); err != nil {
+			return err
+		}
+		d.Outputs = append(d.Outputs, o)
+	}
+	return nil
+}
+
+// GetCrossChainDeposits returns the deposits within the TransferCrossChainAsset
+// transaction grouped by side chain genesis address.
+func GetCrossChainDeposits(tx it.Transaction, height uint32) ([]*CrossChainDeposit, error) {
+	if tx.TxType() != elacommon.TransferCrossChainAsset {
+		return nil, nil
+	}
+
+	deposits := make(map[common.Uint168]*CrossChainDeposit)
+	addDeposit := func(index int, output CrossChainOutput) error {
+		if index < 0 || index >= len(tx.Outputs()) {
+			return errors.New("invalid cross chain output index")
+		}
+		txOutput := tx.Outputs()[index]
+		deposit, ok := deposits[txOutput.ProgramHash]
+		if !ok {
+			address, err := txOutput.ProgramHash.ToAddress()
+			if err != nil {
+				return err
+			}
+			deposit = &CrossChainDeposit{
+				TxHash:         tx.Hash(),
+				GenesisAddress: address,
+				Height:         height,
+			}
+			deposits[txOutput.ProgramHash] = deposit
+		}
+		output.Index = uint16(index)
+		output.Amount = txOutput.Value
+		deposit.Outputs = append(deposit.Outputs, output)
+		return nil
+	}
+
+	// Cross chain outputs since transaction version 09.
+	for i, output := range tx.Outputs() {
+		if output.Type != elacommon.OTCrossChain {
+			continue
+		}
+		p, ok := output.Payload.(*outputpayload.CrossChainOutput)
+		if !ok {
+			return nil, errors.New("invalid cross chain output payload")
+		}
+		err := addDeposit(i, CrossChainOutput{
+			TargetAddress: p.TargetAddress,
+			TargetAmount:  p.TargetAmount,
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// Cross chain outputs described by the transaction payload.
+	if len(deposits) == 0 {
+		p, ok := tx.Payload().(*payload.TransferCrossChainAsset)
+		if !ok {
+			return nil, errors.New("invalid TransferCrossChainAsset tx")
+		}
+		if len(p.CrossChainAddresses) != len(p.OutputIndexes) ||
+			len(p.CrossChainAddresses) != len(p.CrossChainAmounts) {
+			return nil, errors.New("invalid TransferCrossChainAsset payload")
+		}
+		for i, address := range p.CrossChainAddresses {
+			err := addDeposit(int(p.OutputIndexes[i]), CrossChainOutput{
+				TargetAddress: address,
+				TargetAmount:  p.CrossChainAmounts[i],
+			})
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	results := make([]*CrossChainDeposit, 0, len(deposits))
+	for _, d := range deposits {
+		results = append(results, d)
+	}
+	sort.Slice(results, func(i, j int) bool {
+		return results[i].GenesisAddress < results[j].GenesisAddress
+	})
+	return results, nil
+}
+
+type deposits struct {
+	sync.RWMutex
+	db kv.DB
+}
+
+func NewDeposits(db kv.DB) *deposits {
+	return &deposits{db: db}
+}
+
+func (d *deposits) BatchPut(deposit *CrossChainDeposit, batch kv.Batch) error {
+	d.Lock()
+	defer d.Unlock()
+
+	genesis, err := common.Uint168FromAddress(deposit.GenesisAddress)
+	if err != nil {
+		return err
+	}
+	buf := new(bytes.Buffer)
+	if err := deposit.Serialize(buf); err != nil {
+		return err
+	}
+
+	var height [4]byte
+	binary.BigEndian.PutUint32(height[:], deposit.Height)
+	batch.Put(joinKey(BKTDeposits, genesis[:], height[:], deposit.TxHash[:]), buf.Bytes())
+	batch.Put(joinKey(BKTDepositTxs, deposit.TxHash[:], genesis[:]), height[:])
+	batch.Put(joinKey(BKTDepositHeights, height[:], genesis[:], deposit.TxHash[:]), empty)
+	return nil
+}
+
+// BatchDeleteAll removes all deposits packed on the given height.
+func (d *deposits) BatchDeleteAll(height uint32, batch kv.Batch) error {
+	d.Lock()
+	defer d.Unlock()
+
+	var key [4]byte
+	binary.BigEndian.PutUint32(key[:], height)
+	prefix := joinKey(BKTDepositHeights, key[:])
+	it := d.db.NewIterator(kv.BytesPrefix(prefix))
+	defer it.Release()
+	for it.Next() {
+		value := subKey(prefix, it.Key())
+		genesis, txHash := value[:21], value[21:]
+		batch.Delete(joinKey(BKTDeposits, genesis, key[:], txHash))
+		batch.Delete(joinKey(BKTDepositTxs, txHash, genesis))
+		batch.Delete(it.Key())
+	}
+	return it.Error()
+}
+
+func (d *deposits) Get(genesisAddress string, txHash common.Uint256) (*CrossChainDeposit, error) {
+	d.RLock()
+	defer d.RUnlock()
+
+	genesis, err := common.Uint168FromAddress(genesisAddress)
+	if err != nil {
+		return nil, err
+	}
+	height, err := d.db.Get(joinKey(BKTDepositTxs, txHash[:], genesis[:]))
+	if err != nil {
+		return nil, err
+	}
+	data, err := d.db.Get(joinKey(BKTDeposits, genesis[:], height, txHash[:]))
+	if err != nil {
+		return nil, err
+	}
+	var deposit CrossChainDeposit
+	if err := deposit.Deserialize(bytes.NewReader(data)); err != nil {
+		return nil, err
+	}
+	return &deposit, nil
+}
+
+// List returns deposits to the side chain in height order, skip the first
+// offset deposits and return at most limit deposits.
+func (d *deposits) List(genesisAddress string, offset, limit uint32) ([]*CrossChainDeposit, error) {
+	d.RLock()
+	defer d.RUnlock()
+
+	genesis, err := common.Uint168FromAddress(genesisAddress)
+	if err != nil {
+		return nil, err
+	}
+	it := d.db.NewIterator(kv.BytesPrefix(joinKey(BKTDeposits, genesis[:])))
+	defer it.Release()
+
+	var deposits []*CrossChainDeposit
+	for skipped := uint32(0); uint32(len(deposits)) < limit && it.Next(); {
+		if skipped < offset {
+			skipped++
+			continue
+		}
+		var deposit CrossChainDeposit
+		if err := deposit.Deserialize(bytes.NewReader(it.Value())); err != nil {
+			return nil, err
+		}
+		deposits = append(deposits, &deposit)
+	}
+	return deposits, it.Error()
+}
+
+// Count returns the count of deposits to the side chain.
+func (d *deposits) Count(genesisAddress string) (uint32, error) {
+	d.RLock()
+	defer d.RUnlock()
+
+	genesis, err := common.Uint168FromAddress(genesisAddress)
+	if err != nil {
+		return 0, err
+	}
+	it := d.db.NewIterator(kv.BytesPrefix(joinKey(BKTDeposits, genesis[:])))
+	defer it.Release()
+
+	var count uint32
+	for it.Next() {
+		count++
+	}
+	return count, it.Error()
+}
+
+func (d *deposits) Clear() error {
+	d.Lock()
+	defer d.Unlock()
+
+	batch := d.db.NewBatch()
+	for _, prefix := range [][]byte{BKTDeposits, BKTDepositTxs, BKTDepositHeights} {
+		it := d.db.NewIterator(kv.BytesPrefix(prefix))
+		for it.Next() {
+			batch.Delete(it.Key())
+		}
+		it.Release()
+	}
+	return d.db.Write(batch)
+}
+
+// HaveHeight returns if any deposit is packed on the given height.
+func (d *deposits) HaveHeight(height uint32) (bool, error) {
+	d.RLock()
+	defer d.RUnlock()
+
+	var key [4]byte
+	binary.BigEndian.PutUint32(key[:], height)
+	it := d.db.NewIterator(kv.BytesPrefix(joinKey(BKTDepositHeights, key[:])))
+	defer it.Release()
+	return it.Next(), it.Error()
+}
+
+func (d *deposits) Close() error {
+	d.Lock()
+	return nil
+}
@@ -0,0 +1,93 @@
+package store
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/elastos/Elastos.ELA.SPV/interface/store/kv"
+
+	"github.com/elastos/Elastos.ELA/common"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDeposits(t *testing.T) {
+	dataDir := "spv_test"
+	os.RemoveAll(dataDir)
+	defer os.RemoveAll(dataDir)
+
+	db, err := kv.OpenLevelDB(filepath.Join(dataDir, "store"))
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	defer db.Close()
+	deposits := NewDeposits(db)
+
+	genesisHash := common.Uint168{0x4b, 1}
+	genesis, err := genesisHash.ToAddress()
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	otherHash := common.Uint168{0x4b, 2}
+	other, err := otherHash.ToAddress()
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+
+	var all []*CrossChainDeposit
+	batch := db.NewBatch()
+	for i := uint32(0); i < 10; i++ {
+		deposit := &CrossChainDeposit{
+			TxHash:         common.Uint256{byte(i)},
+			GenesisAddress: genesis,
+			Height:         100 + i,
+			Outputs: []CrossChainOutput{{
+				Index:         0,
+				TargetAddress: "EZxSX4hp8hfHDTKSkpqzgCy1cTgZd7ZGyK",
+				Amount:        common.Fixed64(i + 1),
+				TargetAmount:  common.Fixed64(i),
+			}},
+		}
+		all = append(all, deposit)
+		assert.NoError(t, deposits.BatchPut(deposit, batch))
+	}
+	assert.NoError(t, deposits.BatchPut(&CrossChainDeposit{
+		TxHash:         common.Uint256{0xff},
+		GenesisAddress: other,
+		Height:         105,
+	}, batch))
+	assert.NoError(t, db.Write(batch))
+
+	deposit, err := deposits.Get(genesis, common.Uint256{3})
+	assert.NoError(t, err)
+	assert.Equal(t, all[3], deposit)
+	_, err = deposits.Get(other, common.Uint256{3})
+	assert.Equal(t, kv.ErrNotFound, err)
+
+	count, err := deposits.Count(genesis)
+	assert.NoError(t, err)
+	assert.Equal(t, uint32(10), count)
+
+	page, err := deposits.List(genesis, 0, 4)
+	assert.NoError(t, err)
+	assert.Equal(t, all[:4], page)
+	page, err = deposits.List(genesis, 8, 4)
+	assert.NoError(t, err)
+	assert.Equal(t, all[8:], page)
+
+	// rollback deposits on height 109 and 105.
+	batch = db.NewBatch()
+	assert.NoError(t, deposits.BatchDeleteAll(109, batch))
+	assert.NoError(t, deposits.BatchDeleteAll(105, batch))
+	assert.NoError(t, db.Write(batch))
+
+	count, err = deposits.Count(genesis)
+	assert.NoError(t, err)
+	assert.Equal(t, uint32(8), count)
+	count, err = deposits.Count(other)
+	assert.NoError(t, err)
+	assert.Equal(t, uint32(0), count)
+	_, err = deposits.Get(genesis, common.Uint256{5})
+	assert.Equal(t, kv.ErrNotFound, err)
+}
@@ -1,6 +1,7 @@
 package store
 
 import (
+	"bytes"
 	"encoding/binary"
 	"encoding/hex"
 	"errors"
@@ -8,11 +9,13 @@ import (
 	"path/filepath"
 	"sync"
 
+	"github.com/elastos/Elastos.ELA.SPV/database"
+	"github.com/elastos/Elastos.ELA.SPV/interface/iutil"
+	"github.com/elastos/Elastos.ELA.SPV/interface/store/kv"
 	"github.com/elastos/Elastos.ELA.SPV/util"
 
 	"github.com/cevaris/ordered_map"
 	"github.com/elastos/Elastos.ELA/common"
-	"github.com/syndtr/goleveldb/leveldb"
 )
 
 // Ensure headers implement database.Headers interface.
@@ -20,16 +23,30 @@ var _ HeaderStore = (*headers)(nil)
 
 type headers struct {
 	*sync.RWMutex
-	db        *leveldb.DB
+	db        kv.DB
 	cache     *cache
 	newHeader func() util.BlockHeader
 }
 
 func NewHeaderStore(dataDir string, newHeader func() util.BlockHeader) (*headers, error) {
-	db, err := leveldb.OpenFile(filepath.Join(dataDir, "header"), nil)
+	db, err := kv.OpenLevelDB(filepath.Join(dataDir, "header"))
 	if err != nil {
 		return nil, err
 	}
+	headers, err := NewHeaderStoreWithDB(db, newHeader)
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+	return headers, nil
+}
+
+// NewHeaderStoreWithDB creates a header store on the given KV backend, the
+// database must be on the latest schema version or empty.
+func NewHeaderStoreWithDB(db kv.DB, newHeader func() util.BlockHeader) (*headers, error) {
+	if err := checkSchema(db, headerMigrations(newHeader)); err != nil {
+		return nil, err
+	}
 
 	headers := &headers{
 		RWMutex:   new(sync.RWMutex),
@@ -77,22 +94,56 @@ func (h *headers) Put(header *util.Header, newTip bool) error {
 		return err
 	}
 
-	err = h.db.Put(key, bytes, nil)
+	err = h.db.Put(key, bytes)
 	if err != nil {
 		return err
 	}
 
 	if newTip {
-		err = h.db.Put(BKTChainTip, bytes, nil)
+		err = h.db.Put(BKTChainTip, bytes)
 		if err != nil {
 			return err
 		}
+		return h.putIndexes(header)
 	}
+	return nil
+}
+
+// putIndexes indexes the new tip and it's previous headers by height, until
+// the height already indexed with the same header. Fork headers replaced by
+// the new best chain will be re-indexed.
+func (h *headers) putIndexes(tip *util.Header) error {
+	batch := h.db.NewBatch()
+
+	// Remove indexes above the new tip if the best chain becomes shorter.
+	for height := tip.Height + 1; ; height++ {
+		key := indexKey(height)
+		if ok, err := h.db.Has(key); err != nil || !ok {
+			break
+		}
+		batch.Delete(key)
+	}
+
+	header := tip
+	for {
+		hash := header.Hash()
+		key := indexKey(header.Height)
+		indexed, err := h.db.Get(key)
+		if err == nil && bytes.Equal(indexed, hash.Bytes()) {
+			break
+		}
+		batch.Put(key, hash.Bytes())
 
-	var height [4]byte
-	binary.LittleEndian.PutUint32(height[:], header.Height)
-	index := toKey(BKTIndexes, height[:]...)
-	return h.db.Put(index, header.Hash().Bytes(), nil)
+		if header.Height == 0 {
+			break
+		}
+		previous := header.Previous()
+		header, err = h.get(&previous, false)
+		if err != nil {
+			break
+		}
+	}
+	return h.db.Write(batch)
 }
 
 func (h *headers) GetPrevious(header *util.Header) (*util.Header, error) {
@@ -103,13 +154,7 @@ func (h *headers) GetPrevious(header *util.Header) (*util.Header, error) {
 func (h *headers) Get(hash *common.Uint256) (header *util.Header, err error) {
 	h.RLock()
 	defer h.RUnlock()
-
-	header, err = h.cache.get(hash)
-	if err == nil {
-		return header, nil
-	}
-
-	return h.getHeader(toKey(BKTHeaders, hash.Bytes()...))
+	return h.get(hash, false)
 }
 
 func (h *headers) GetBest() (header *util.Header, err error) {
@@ -120,39 +165,66 @@ func (h *headers) GetBest() (header *util.Header, err error) {
 		return h.cache.tip, nil
 	}
 
-	return h.getHeader(BKTChainTip)
+	return h.getHeader(BKTChainTip, false)
 }
 
 func (h *headers) GetByHeight(height uint32) (header *util.Header, err error) {
 	h.RLock()
 	defer h.RUnlock()
+	return h.getByHeight(height, false)
+}
 
-	var key [4]byte
-	binary.LittleEndian.PutUint32(key[:], height)
-	hashBytes, err := h.db.Get(toKey(BKTIndexes, key[:]...), nil)
-	if err != nil {
-		return nil, err
-	}
+// GetRange returns headers on the best chain from start to end height, both
+// inclusive.
+func (h *headers) GetRange(start, end uint32) ([]*util.Header, error) {
+	return database.GetHeaderRange(h.Iterator(start, end))
+}
 
-	header, err = h.getHeader(toKey(BKTHeaders, hashBytes...))
+// Iterator returns an iterator walks the best chain from start to end height,
+// both inclusive. Headers read by the iterator will not fill the caches, so
+// a long scan will not evict the recent headers.
+func (h *headers) Iterator(start, end uint32) database.HeaderIterator {
+	return database.NewHeaderIterator(func(height uint32) (*util.Header, error) {
+		h.RLock()
+		defer h.RUnlock()
+		return h.getByHeight(height, true)
+	}, start, end)
+}
+
+// GetByTime returns the first header on the best chain with timestamp at or
+// after the given unix timestamp.
+func (h *headers) GetByTime(timestamp uint32) (*util.Header, error) {
+	best, err := h.GetBest()
 	if err != nil {
 		return nil, err
 	}
+	return database.SearchHeaderByTime(h.GetByHeight, headerTime,
+		best.Height, timestamp)
+}
+
+// MedianTimePast returns the median timestamp of the last blocks ended with
+// the given height.
+func (h *headers) MedianTimePast(height uint32) (uint32, error) {
+	return database.CalcMedianTimePast(h.GetByHeight, headerTime, height)
+}
 
-	return header, err
+func (h *headers) NewSnapshot() (kv.Snapshot, error) {
+	return h.db.NewSnapshot()
 }
 
 func (h *headers) Clear() error {
 	h.Lock()
 	defer h.Unlock()
 
-	batch := new(leveldb.Batch)
-	inter := h.db.NewIterator(nil, nil)
+	batch := h.db.NewBatch()
+	inter := h.db.NewIterator(nil)
 	for inter.Next() {
 		batch.Delete(inter.Key())
 	}
 	inter.Release()
-	return h.db.Write(batch, nil)
+	putSchemaVersion(batch, latestVersion(headerMigrations(h.newHeader)))
+	h.cache = newCache(100)
+	return h.db.Write(batch)
 }
 
 // Close db
@@ -162,8 +234,29 @@ func (h *headers) Close() error {
 	return h.db.Close()
 }
 
-func (h *headers) getHeader(key []byte) (*util.Header, error) {
-	data, err := h.db.Get(key, nil)
+func (h *headers) get(hash *common.Uint256, uncached bool) (*util.Header, error) {
+	header, err := h.cache.get(hash)
+	if err == nil {
+		return header, nil
+	}
+
+	return h.getHeader(toKey(BKTHeaders, hash.Bytes()...), uncached)
+}
+
+func (h *headers) getByHeight(height uint32, uncached bool) (*util.Header, error) {
+	hashBytes, err := h.read(indexKey(height), uncached)
+	if err != nil {
+		return nil, err
+	}
+	hash, err := common.Uint256FromBytes(hashBytes)
+	if err != nil {
+		return nil, err
+	}
+	return h.get(hash, uncached)
+}
+
+func (h *headers) getHeader(key []byte, uncached bool) (*util.Header, error) {
+	data, err := h.read(key, uncached)
 	if err != nil {
 		return nil, fmt.Errorf("header %s does not exist in database",
 			hex.EncodeToString(key))
@@ -179,6 +272,31 @@ func (h *headers) getHeader(key []byte) (*util.Header, error) {
 	return &header, nil
 }
 
+// read returns the value of key, the value will not fill the database caches
+// if uncached is true.
+func (h *headers) read(key []byte, uncached bool) ([]byte, error) {
+	if uncached {
+		return h.db.GetUncached(key)
+	}
+	return h.db.Get(key)
+}
+
+// indexKey returns the key of the height index.
+func indexKey(height uint32) []byte {
+	var key [4]byte
+	binary.LittleEndian.PutUint32(key[:], height)
+	return append(append([]byte{}, BKTIndexes...), key[:]...)
+}
+
+// headerTime returns the timestamp of the main chain header.
+func headerTime(header *util.Header) (uint32, error) {
+	h, ok := header.BlockHeader.(*iutil.Header)
+	if !ok {
+		return 0, errors.New("invalid block header")
+	}
+	return h.Timestamp, nil
+}
+
 type cache struct {
 	size    int
 	tip     *util.Header
@@ -0,0 +1,144 @@
+package store
+
+import (
+	"math/big"
+	"os"
+	"testing"
+
+	"github.com/elastos/Elastos.ELA.SPV/database"
+	"github.com/elastos/Elastos.ELA.SPV/interface/iutil"
+	"github.com/elastos/Elastos.ELA.SPV/util"
+
+	"github.com/elastos/Elastos.ELA/common"
+	elacommon "github.com/elastos/Elastos.ELA/core/types/common"
+	"github.com/stretchr/testify/assert"
+)
+
+func newEmptyHeader() util.BlockHeader {
+	return iutil.NewHeader(&elacommon.Header{})
+}
+
+func newHeader(previous *util.Header, timestamp, nonce uint32) *util.Header {
+	var height uint32
+	var previousHash common.Uint256
+	if previous != nil {
+		height = previous.Height + 1
+		previousHash = previous.Hash()
+	}
+	return &util.Header{
+		BlockHeader: iutil.NewHeader(&elacommon.Header{
+			Previous:  previousHash,
+			Timestamp: timestamp,
+			Height:    height,
+			Nonce:     nonce,
+		}),
+		Height:    height,
+		TotalWork: new(big.Int),
+	}
+}
+
+func TestHeaders_Queries(t *testing.T) {
+	dataDir := "spv_test"
+	os.RemoveAll(dataDir)
+	defer os.RemoveAll(dataDir)
+
+	headers, err := NewHeaderStore(dataDir, newEmptyHeader)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	defer headers.Close()
+
+	// main chain from height 0 to 20, timestamps increase by 10 seconds.
+	var chain []*util.Header
+	var previous *util.Header
+	for i := uint32(0); i <= 20; i++ {
+		header := newHeader(previous, 1000+i*10, 0)
+		assert.NoError(t, headers.Put(header, true))
+		chain = append(chain, header)
+		previous = header
+	}
+
+	// fork header is not indexed.
+	fork := newHeader(chain[14], 1150, 1)
+	assert.NoError(t, headers.Put(fork, false))
+	header, err := headers.GetByHeight(15)
+	assert.NoError(t, err)
+	assert.Equal(t, chain[15].Hash(), header.Hash())
+
+	// range and iterator.
+	result, err := headers.GetRange(5, 9)
+	assert.NoError(t, err)
+	if assert.Equal(t, 5, len(result)) {
+		for i, header := range result {
+			assert.Equal(t, chain[5+i].Hash(), header.Hash())
+		}
+	}
+	it := headers.Iterator(18, 25)
+	var heights []uint32
+	for it.Next() {
+		heights = append(heights, it.Header().Height)
+	}
+	assert.Error(t, it.Error())
+	assert.Equal(t, []uint32{18, 19, 20}, heights)
+
+	// time search.
+	header, err = headers.GetByTime(1055)
+	assert.NoError(t, err)
+	assert.Equal(t, uint32(6), header.Height)
+	header, err = headers.GetByTime(1060)
+	assert.NoError(t, err)
+	assert.Equal(t, uint32(6), header.Height)
+	header, err = headers.GetByTime(0)
+	assert.NoError(t, err)
+	assert.Equal(t, uint32(0), header.Height)
+	_, err = headers.GetByTime(1201)
+	assert.Equal(t, database.ErrHeaderNotFound, err)
+
+	// median time past of heights 10 to 20 and 0 to 3.
+	mtp, err := headers.MedianTimePast(20)
+	assert.NoError(t, err)
+	assert.Equal(t, uint32(1150), mtp)
+	mtp, err = headers.MedianTimePast(3)
+	assert.NoError(t, err)
+	assert.Equal(t, uint32(1020), mtp)
+
+	// reorganize to a shorter fork chain from height 15.
+	fork16 := newHeader(fork, 1160, 1)
+	assert.NoError(t, headers.Put(fork16, false))
+	fork17 := newHeader(fork16, 1170, 1)
+	assert.NoError(t, headers.Put(fork17, true))
+
+	result, err = headers.GetRange(14, 17)
+	assert.NoError(t, err)
+	if assert.Equal(t, 4, len(result)) {
+		assert.Equal(t, chain[14].Hash(), result[0].Hash())
+		assert.Equal(t, fork.Hash(), result[1].Hash())
+		assert.Equal(t, fork16.Hash(), result[2].Hash())
+		assert.Equal(t, fork17.Hash(), result[3].Hash())
+	}
+	_, err = headers.GetByHeight(18)
+	assert.Error(t, err)
+}
+
+func TestHeaders_Clear(t *testing.T) {
+	dataDir := "spv_test"
+	os.RemoveAll(dataDir)
+	defer os.RemoveAll(dataDir)
+
+	headers, err := NewHeaderStore(dataDir, newEmptyHeader)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	defer headers.Close()
+
+	header := newHeader(nil, 1000, 0)
+	assert.NoError(t, headers.Put(header, true))
+	assert.NoError(t, headers.Clear())
+
+	// cached headers are removed with the database.
+	_, err = headers.GetBest()
+	assert.Error(t, err)
+	hash := header.Hash()
+	_, err = headers.Get(&hash)
+	assert.Error(t, err)
+}
@@ -0,0 +1,355 @@
+package store
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"io"
+	"math"
+	"sort"
+	"sync"
+
+	"github.com/elastos/Elastos.ELA.SPV/interface/store/kv"
+	"github.com/elastos/Elastos.ELA.SPV/util"
+
+	"github.com/elastos/Elastos.ELA/common"
+	elatx "github.com/elastos/Elastos.ELA/core/transaction"
+	it "github.com/elastos/Elastos.ELA/core/types/interfaces"
+)
+
+// Ensure addrTxs implement AddrTxs interface.
+var _ AddrTxs = (*addrTxs)(nil)
+
+// ErrInvalidCursor indicates the history cursor is not returned by List.
+var ErrInvalidCursor = errors.New("invalid history cursor")
+
+// TxDirection is the direction of a transaction to an address, a transaction
+// can both spend from and pay to the same address.
+type TxDirection uint8
+
+const (
+	// TxReceived means the transaction has outputs paid to the address.
+	TxReceived TxDirection = 1 << iota
+
+	// TxSent means the transaction spends outputs of the address.
+	TxSent
+)
+
+func (d TxDirection) String() string {
+	switch d {
+	case TxReceived:
+		return "received"
+	case TxSent:
+		return "sent"
+	case TxReceived | TxSent:
+		return "sent and received"
+	default:
+		return "unknown"
+	}
+}
+
+// AddressTx is a transaction in the history of a watched address.
+type AddressTx struct {
+	Address   common.Uint168
+	TxHash    common.Uint256
+	Height    uint32
+	Direction TxDirection
+
+	// Sent is the value of the address outputs spent by the transaction, the
+	// spent outputs of transactions not stored are not counted.
+	Sent common.Fixed64
+
+	// Received is the value of the transaction outputs paid to the address.
+	Received common.Fixed64
+}
+
+func (t *AddressTx) Serialize(w io.Writer) error {
+	if err := t.Address.Serialize(w); err != nil {
+		return err
+	}
+	if err := t.TxHash.Serialize(w); err != nil {
+		return err
+	}
+	if err := common.WriteUint32(w, t.Height); err != nil {
+		return err
+	}
+	if err := common.WriteUint8(w, uint8(t.Direction)); err != nil {
+		return err
+	}
+	if err := t.Sent.Serialize(w); err != nil {
+		return err
+	}
+	return t.Received.Serialize(w)
+}
+
+func (t *AddressTx) Deserialize(r io.Reader) error {
+	if err := t.Address.Deserialize(r); err != nil {
+		return err
+	}
+	if err := t.TxHash.Deserialize(r); err != nil {
+		return err
+	}
+	var err error
+	if t.Height, err = common.ReadUint32(r); err != nil {
+		return err
+	}
+	direction, err := common.ReadUint8(r)
+	if err != nil {
+		return err
+	}
+	t.Direction = TxDirection(direction)
+	if err := t.Sent.Deserialize(r); err != nil {
+		return err
+	}
+	return t.Received.Deserialize(r)
+}
+
+// GetAddressTxs returns the history records of addresses within the
+// transaction ordered by address. received returns if the output is paid to a
+// watched address, spent returns the watched address and value of the output
+// spent by an input, or nil address if it's not watched.
+func GetAddressTxs(tx it.Transaction, height uint32,
+	received func(op *util.OutPoint, addr common.Uint168) bool,
+	spent func(op *util.OutPoint) (*common.Uint168, common.Fixed64, error)) ([]*AddressTx, error) {
+	records := make(map[common.Uint168]*AddressTx)
+	record := func(addr common.Uint168) *AddressTx {
+		r, ok := records[addr]
+		if !ok {
+			r = &AddressTx{Address: addr, TxHash: tx.Hash(), Height: height}
+			records[addr] = r
+		}
+		return r
+	}
+
+	for index, output := range tx.Outputs() {
+		op := util.NewOutPoint(tx.Hash(), uint16(index))
+		if !received(op, output.ProgramHash) {
+			continue
+		}
+		r := record(output.ProgramHash)
+		r.Direction |= TxReceived
+		r.Received += output.Value
+	}
+
+	for _, input := range tx.Inputs() {
+		addr, value, err := spent(util.NewOutPoint(input.Previous.TxID,
+			input.Previous.Index))
+		if err != nil {
+			return nil, err
+		}
+		if addr == nil {
+			continue
+		}
+		r := record(*addr)
+		r.Direction |= TxSent
+		r.Sent += value
+	}
+
+	results := make([]*AddressTx, 0, len(records))
+	for _, r := range records {
+		results = append(results, r)
+	}
+	sort.Slice(results, func(i, j int) bool {
+		return bytes.Compare(results[i].Address[:], results[j].Address[:]) < 0
+	})
+	return results, nil
+}
+
+type addrTxs struct {
+	sync.RWMutex
+	db kv.DB
+}
+
+func NewAddrTxs(db kv.DB) *addrTxs {
+	return &addrTxs{db: db}
+}
+
+func (a *addrTxs) BatchPut(record *AddressTx, batch kv.Batch) error {
+	a.Lock()
+	defer a.Unlock()
+
+	buf := new(bytes.Buffer)
+	if err := record.Serialize(buf); err != nil {
+		return err
+	}
+
+	var height [4]byte
+	binary.BigEndian.PutUint32(height[:], record.Height)
+	batch.Put(joinKey(BKTAddrTxs, record.Address[:], height[:], record.TxHash[:]), buf.Bytes())
+	batch.Put(joinKey(BKTAddrTxHeights, height[:], record.Address[:], record.TxHash[:]), empty)
+	return nil
+}
+
+// BatchDeleteAll removes all history records on the given height.
+func (a *addrTxs) BatchDeleteAll(height uint32, batch kv.Batch) error {
+	a.Lock()
+	defer a.Unlock()
+
+	var key [4]byte
+	binary.BigEndian.PutUint32(key[:], height)
+	prefix := joinKey(BKTAddrTxHeights, key[:])
+	it := a.db.NewIterator(kv.BytesPrefix(prefix))
+	defer it.Release()
+	for it.Next() {
+		value := subKey(prefix, it.Key())
+		addr, txHash := value[:21], value[21:]
+		batch.Delete(joinKey(BKTAddrTxs, addr, key[:], txHash))
+		batch.Delete(it.Key())
+	}
+	return it.Error()
+}
+
+// List returns the history of the address from fromHeight to toHeight, both
+// inclusive, in height order.  At most limit records are returned, zero limit
+// returns all records.  The returned cursor is passed to the next List call
+// to continue after the last returned record, it's empty if there are no more
+// records.
+func (a *addrTxs) List(addr common.Uint168, fromHeight, toHeight, limit uint32,
+	cursor string) ([]*AddressTx, string, error) {
+	a.RLock()
+	defer a.RUnlock()
+
+	if fromHeight > toHeight {
+		return nil, "", nil
+	}
+
+	prefix := joinKey(BKTAddrTxs, addr[:])
+	var height [4]byte
+	binary.BigEndian.PutUint32(height[:], fromHeight)
+	r := &kv.Range{Start: joinKey(prefix, height[:])}
+	if cursor != "" {
+		position, err := hex.DecodeString(cursor)
+		if err != nil || len(position) != 4+common.UINT256SIZE {
+			return nil, "", ErrInvalidCursor
+		}
+		// Start after the last returned record.
+		start := append(joinKey(prefix, position), 0)
+		if bytes.Compare(start, r.Start) > 0 {
+			r.Start = start
+		}
+	}
+	if toHeight == math.MaxUint32 {
+		r.Limit = kv.BytesPrefix(prefix).Limit
+	} else {
+		binary.BigEndian.PutUint32(height[:], toHeight+1)
+		r.Limit = joinKey(prefix, height[:])
+	}
+
+	it := a.db.NewIterator(r)
+	defer it.Release()
+
+	var records []*AddressTx
+	for it.Next() {
+		if limit > 0 && uint32(len(records)) == limit {
+			last := records[len(records)-1]
+			binary.BigEndian.PutUint32(height[:], last.Height)
+			return records, hex.EncodeToString(joinKey(height[:],
+				last.TxHash[:])), it.Error()
+		}
+		var record AddressTx
+		if err := record.Deserialize(bytes.NewReader(it.Value())); err != nil {
+			return nil, "", err
+		}
+		records = append(records, &record)
+	}
+	return records, "", it.Error()
+}
+
+func (a *addrTxs) Clear() error {
+	a.Lock()
+	defer a.Unlock()
+
+	batch := a.db.NewBatch()
+	for _, prefix := range [][]byte{BKTAddrTxs, BKTAddrTxHeights} {
+		it := a.db.NewIterator(kv.BytesPrefix(prefix))
+		for it.Next() {
+			batch.Delete(it.Key())
+		}
+		it.Release()
+	}
+	return a.db.Write(batch)
+}
+
+func (a *addrTxs) Close() error {
+	a.Lock()
+	return nil
+}
+
+// buildAddrTxs puts the history records of the stored transactions, the
+// watched outputs are found by the stored outpoints.
+func buildAddrTxs(db kv.DB, batch kv.Batch) error {
+	a := NewAddrTxs(db)
+	received := func(op *util.OutPoint, addr common.Uint168) bool {
+		ok, _ := db.Has(toKey(BKTOps, op.Bytes()...))
+		return ok
+	}
+	spent := func(op *util.OutPoint) (*common.Uint168, common.Fixed64, error) {
+		data, err := db.Get(toKey(BKTOps, op.Bytes()...))
+		if err == kv.ErrNotFound {
+			return nil, 0, nil
+		}
+		if err != nil {
+			return nil, 0, err
+		}
+		addr, err := common.Uint168FromBytes(data)
+		if err != nil {
+			return nil, 0, err
+		}
+		tx, _, err := getStoredTx(db, &op.TxID)
+		if err == kv.ErrNotFound {
+			return addr, 0, nil
+		}
+		if err != nil {
+			return nil, 0, err
+		}
+		if int(op.Index) >= len(tx.Outputs()) {
+			return addr, 0, nil
+		}
+		return addr, tx.Outputs()[op.Index].Value, nil
+	}
+
+	iter := db.NewIterator(kv.BytesPrefix(BKTTxs))
+	defer iter.Release()
+	for iter.Next() {
+		txId, err := common.Uint256FromBytes(subKey(BKTTxs, iter.Key()))
+		if err != nil {
+			return err
+		}
+		tx, height, err := getStoredTx(db, txId)
+		if err != nil {
+			return err
+		}
+		records, err := GetAddressTxs(tx, height, received, spent)
+		if err != nil {
+			return err
+		}
+		for _, record := range records {
+			if err := a.BatchPut(record, batch); err != nil {
+				return err
+			}
+		}
+	}
+	return iter.Error()
+}
+
+// getStoredTx returns the stored transaction and it's height.
+func getStoredTx(r kv.Reader, txId *common.Uint256) (it.Transaction, uint32, error) {
+	data, err := r.Get(toKey(BKTTxs, txId.Bytes()...))
+	if err != nil {
+		return nil, 0, err
+	}
+	var utx util.Tx
+	if err := utx.Deserialize(bytes.NewReader(data)); err != nil {
+		return nil, 0, err
+	}
+	reader := bytes.NewReader(utx.RawData)
+	tx, err := elatx.GetTransactionByBytes(reader)
+	if err != nil {
+		return nil, 0, err
+	}
+	if err := tx.Deserialize(reader); err != nil {
+		return nil, 0, err
+	}
+	return tx, utx.Height, nil
+}
@@ -0,0 +1,132 @@
+package store
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/elastos/Elastos.ELA.SPV/util"
+
+	"github.com/elastos/Elastos.ELA/common"
+	elatx "github.com/elastos/Elastos.ELA/core/transaction"
+	elacommon "github.com/elastos/Elastos.ELA/core/types/common"
+	it "github.com/elastos/Elastos.ELA/core/types/interfaces"
+	"github.com/elastos/Elastos.ELA/core/types/payload"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newTransferTx(inputs []*elacommon.Input, outputs []*elacommon.Output) it.Transaction {
+	return elatx.CreateTransaction(
+		elacommon.TxVersion09,
+		elacommon.TransferAsset,
+		0,
+		&payload.TransferAsset{},
+		nil,
+		inputs,
+		outputs,
+		0,
+		nil,
+	)
+}
+
+func putTransferTx(t *testing.T, data *dataStore, tx it.Transaction, height uint32) {
+	buf := new(bytes.Buffer)
+	assert.NoError(t, tx.Serialize(buf))
+	assert.NoError(t, data.Txs().Put(&util.Tx{
+		Hash:      tx.Hash(),
+		Height:    height,
+		Timestamp: time.Unix(0, 0),
+		RawData:   buf.Bytes(),
+	}))
+}
+
+func TestAddrTxs(t *testing.T) {
+	data, err := NewMemoryDataStore(nil, 36, "")
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	defer data.Close()
+
+	watched := common.Uint168{0x21, 1}
+	other := common.Uint168{0x21, 2}
+
+	// tx1 pays 10 to the watched address, tx2 spends it with 3 change.
+	tx1 := newTransferTx(nil, []*elacommon.Output{
+		{ProgramHash: watched, Value: 10},
+		{ProgramHash: other, Value: 5},
+	})
+	tx2 := newTransferTx([]*elacommon.Input{{
+		Previous: *elacommon.NewOutPoint(tx1.Hash(), 0),
+	}}, []*elacommon.Output{
+		{ProgramHash: other, Value: 6},
+		{ProgramHash: watched, Value: 3},
+	})
+	putTransferTx(t, data, tx1, 100)
+	putTransferTx(t, data, tx2, 101)
+	assert.NoError(t, data.Ops().Put(util.NewOutPoint(tx1.Hash(), 0), watched))
+	assert.NoError(t, data.Ops().Put(util.NewOutPoint(tx2.Hash(), 1), watched))
+
+	// build the history from stored transactions like the migration.
+	batch := data.db.NewBatch()
+	assert.NoError(t, buildAddrTxs(data.db, batch))
+	assert.NoError(t, data.db.Write(batch))
+
+	records, cursor, err := data.AddrTxs().List(watched, 0, 200, 0, "")
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	assert.Equal(t, "", cursor)
+	assert.Equal(t, []*AddressTx{{
+		Address:   watched,
+		TxHash:    tx1.Hash(),
+		Height:    100,
+		Direction: TxReceived,
+		Received:  10,
+	}, {
+		Address:   watched,
+		TxHash:    tx2.Hash(),
+		Height:    101,
+		Direction: TxReceived | TxSent,
+		Sent:      10,
+		Received:  3,
+	}}, records)
+
+	records, _, err = data.AddrTxs().List(other, 0, 200, 0, "")
+	assert.NoError(t, err)
+	assert.Equal(t, 0, len(records))
+
+	// page through the history with cursor.
+	page, cursor, err := data.AddrTxs().List(watched, 0, 200, 1, "")
+	assert.NoError(t, err)
+	if assert.Equal(t, 1, len(page)) {
+		assert.Equal(t, tx1.Hash(), page[0].TxHash)
+	}
+	assert.NotEqual(t, "", cursor)
+	page, cursor, err = data.AddrTxs().List(watched, 0, 200, 1, cursor)
+	assert.NoError(t, err)
+	if assert.Equal(t, 1, len(page)) {
+		assert.Equal(t, tx2.Hash(), page[0].TxHash)
+	}
+	assert.Equal(t, "", cursor)
+	_, _, err = data.AddrTxs().List(watched, 0, 200, 1, "invalid")
+	assert.Equal(t, ErrInvalidCursor, err)
+
+	// height range is inclusive.
+	page, _, err = data.AddrTxs().List(watched, 101, 101, 0, "")
+	assert.NoError(t, err)
+	assert.Equal(t, 1, len(page))
+	page, _, err = data.AddrTxs().List(watched, 102, 0xffffffff, 0, "")
+	assert.NoError(t, err)
+	assert.Equal(t, 0, len(page))
+
+	// history is rolled back with the block.
+	dataBatch := data.Batch()
+	assert.NoError(t, dataBatch.DelAll(101))
+	assert.NoError(t, dataBatch.Commit())
+	page, _, err = data.AddrTxs().List(watched, 0, 0xffffffff, 0, "")
+	assert.NoError(t, err)
+	if assert.Equal(t, 1, len(page)) {
+		assert.Equal(t, tx1.Hash(), page[0].TxHash)
+	}
+}
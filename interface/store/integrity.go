@@ -0,0 +1,861 @@
+package store
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+
+	"github.com/elastos/Elastos.ELA.SPV/interface/store/kv"
+	"github.com/elastos/Elastos.ELA.SPV/util"
+
+	"github.com/elastos/Elastos.ELA/common"
+)
+
+// IssueType is the type of an inconsistency found in the stores.
+type IssueType byte
+
+const (
+	// IssueHeaderChain indicates the best header or the best chain headers
+	// are missing or not connected.
+	IssueHeaderChain IssueType = 0x00
+
+	// IssueHeaderIndex indicates the height index of headers not point to
+	// the best chain.
+	IssueHeaderIndex IssueType = 0x01
+
+	// IssueTxIndex indicates the height index of transactions references
+	// missing transactions, or transactions not indexed or above the best
+	// height.
+	IssueTxIndex IssueType = 0x02
+
+	// IssueOrphanOp indicates an outpoint of a missing transaction.
+	IssueOrphanOp IssueType = 0x03
+
+	// IssueOrphanQue indicates a queued notify of a missing transaction, or
+	// the queue index not match the queued item.
+	IssueOrphanQue IssueType = 0x04
+
+	// IssueArbiterPosition indicates the arbiters or revert positions not
+	// match the stored arbiters and revert transactions.
+	IssueArbiterPosition IssueType = 0x05
+
+	// IssueCustomIDPosition indicates the custom ID fee rate positions not
+	// match the stored fee rates.
+	IssueCustomIDPosition IssueType = 0x06
+)
+
+func (t IssueType) String() string {
+	switch t {
+	case IssueHeaderChain:
+		return "HeaderChain"
+	case IssueHeaderIndex:
+		return "HeaderIndex"
+	case IssueTxIndex:
+		return "TxIndex"
+	case IssueOrphanOp:
+		return "OrphanOp"
+	case IssueOrphanQue:
+		return "OrphanQue"
+	case IssueArbiterPosition:
+		return "ArbiterPosition"
+	case IssueCustomIDPosition:
+		return "CustomIDPosition"
+	default:
+		return "Unknown"
+	}
+}
+
+// IntegrityIssue is an inconsistency found in the stores.
+type IntegrityIssue struct {
+	Type   IssueType
+	Height uint32
+	Detail string
+}
+
+func (i *IntegrityIssue) String() string {
+	return fmt.Sprintf("[%s] height %d: %s", i.Type, i.Height, i.Detail)
+}
+
+// IntegrityReport is the result of a store verification.
+type IntegrityReport struct {
+	// BestHeight is the height of the best header, it's lowered if the best
+	// chain is broken, headers and data above it will be synced again after
+	// repair.
+	BestHeight uint32
+
+	// Headers is the number of best chain headers checked.
+	Headers int
+
+	// Issues is the inconsistencies found.
+	Issues []*IntegrityIssue
+
+	// Repaired indicates the issues have been repaired.
+	Repaired bool
+}
+
+func (r *IntegrityReport) add(t IssueType, height uint32, format string,
+	a ...interface{}) {
+	r.Issues = append(r.Issues, &IntegrityIssue{
+		Type:   t,
+		Height: height,
+		Detail: fmt.Sprintf(format, a...),
+	})
+}
+
+func (r *IntegrityReport) String() string {
+	return fmt.Sprintf("best height %d, %d headers checked, %d issues found,"+
+		" repaired %v", r.BestHeight, r.Headers, len(r.Issues), r.Repaired)
+}
+
+// VerifyStores walks the header chain from the best header back to genesis
+// or the sync start point, and cross-checks the data store against it. The
+// issues found are repaired if repair is true, by pruning broken entries or
+// removing the affected range to sync it again. Repair must not run while
+// blocks are committing, SPVService.VerifyIntegrity pauses the block
+// processing for it.
+func VerifyStores(headers HeaderStore, data DataStore, repair bool) (*IntegrityReport, error) {
+	report, err := headers.Verify(repair)
+	if err != nil {
+		return nil, err
+	}
+	dataReport, err := data.Verify(report.BestHeight, repair)
+	if err != nil {
+		return nil, err
+	}
+	report.Issues = append(report.Issues, dataReport.Issues...)
+	report.Repaired = repair && len(report.Issues) > 0
+	return report, nil
+}
+
+// Verify checks the best chain headers and their height indexes.
+func (h *headers) Verify(repair bool) (*IntegrityReport, error) {
+	if !repair {
+		return h.verify(false)
+	}
+
+	h.Lock()
+	report, err := h.verify(true)
+	h.cache = newCache(100)
+	h.Unlock()
+	h.initCache()
+	return report, err
+}
+
+func (h *headers) verify(repair bool) (*IntegrityReport, error) {
+	snapshot, err := h.db.NewSnapshot()
+	if err != nil {
+		return nil, err
+	}
+	defer snapshot.Release()
+
+	report := &IntegrityReport{}
+	data, err := snapshot.Get(BKTChainTip)
+	if err == kv.ErrNotFound {
+		return report, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	tip, err := h.decodeHeader(data)
+	if err != nil {
+		return nil, err
+	}
+
+	batch := h.db.NewBatch()
+	hash := tip.Hash()
+	ok, err := snapshot.Has(toKey(BKTHeaders, hash.Bytes()...))
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		report.add(IssueHeaderChain, tip.Height, "best header %s not stored", hash)
+		batch.Put(toKey(BKTHeaders, hash.Bytes()...), data)
+	}
+
+	// Walk the chain from the best header, the best chain is truncated to
+	// the header below if it's broken, and walk again from there.
+	var start uint32
+	for {
+		var broken bool
+		start, broken, err = h.walkChain(snapshot, tip, report, batch)
+		if err != nil {
+			return nil, err
+		}
+		if !broken {
+			break
+		}
+		header, data, err := h.findIndexedHeader(snapshot, start-1)
+		if err != nil {
+			return nil, err
+		}
+		if header == nil {
+			// No headers below, keep the broken point as the start.
+			break
+		}
+		report.add(IssueHeaderChain, header.Height,
+			"best chain truncated from height %d", tip.Height)
+		batch.Reset()
+		batch.Put(BKTChainTip, data)
+		tip = header
+	}
+	report.BestHeight = tip.Height
+
+	// Remove height indexes outside the best chain.
+	it := snapshot.NewIterator(kv.BytesPrefix(BKTIndexes))
+	for it.Next() {
+		if len(it.Key()) != len(BKTIndexes)+4 {
+			continue
+		}
+		height := binary.LittleEndian.Uint32(it.Key()[len(BKTIndexes):])
+		if height < start || height > tip.Height {
+			report.add(IssueHeaderIndex, height,
+				"height index outside best chain [%d, %d]", start, tip.Height)
+			batch.Delete(append([]byte{}, it.Key()...))
+		}
+	}
+	it.Release()
+	if err := it.Error(); err != nil {
+		return nil, err
+	}
+
+	if repair && batch.Len() > 0 {
+		if err := h.db.Write(batch); err != nil {
+			return nil, err
+		}
+	}
+	report.Repaired = repair && len(report.Issues) > 0
+	return report, nil
+}
+
+// walkChain checks headers from tip back to genesis or the sync start point,
+// and returns the lowest height reached. broken is true if the previous
+// header of the lowest header is indexed but missing or not connected.
+func (h *headers) walkChain(r kv.Reader, tip *util.Header,
+	report *IntegrityReport, batch kv.Batch) (start uint32, broken bool, err error) {
+	report.Headers = 0
+	header := tip
+	for {
+		hash := header.Hash()
+		index, err := r.Get(indexKey(header.Height))
+		if err != nil && err != kv.ErrNotFound {
+			return 0, false, err
+		}
+		if !bytes.Equal(index, hash.Bytes()) {
+			report.add(IssueHeaderIndex, header.Height,
+				"height index not point to best chain header %s", hash)
+			batch.Put(indexKey(header.Height), hash.Bytes())
+		}
+		report.Headers++
+		if header.Height == 0 {
+			return 0, false, nil
+		}
+
+		previous := header.Previous()
+		data, err := r.Get(toKey(BKTHeaders, previous.Bytes()...))
+		if err == kv.ErrNotFound {
+			// Headers before the sync start point are not stored.
+			ok, err := r.Has(indexKey(header.Height - 1))
+			if err != nil {
+				return 0, false, err
+			}
+			if !ok {
+				return header.Height, false, nil
+			}
+			report.add(IssueHeaderChain, header.Height-1,
+				"previous header %s of height %d not stored", previous,
+				header.Height)
+			return header.Height, true, nil
+		}
+		if err != nil {
+			return 0, false, err
+		}
+		prev, err := h.decodeHeader(data)
+		if err != nil || prev.Height+1 != header.Height {
+			report.add(IssueHeaderChain, header.Height-1,
+				"previous header %s of height %d is invalid", previous,
+				header.Height)
+			return header.Height, true, nil
+		}
+		header = prev
+	}
+}
+
+// findIndexedHeader returns the highest stored header indexed on or below
+// height, or nil if not found.
+func (h *headers) findIndexedHeader(r kv.Reader,
+	height uint32) (*util.Header, []byte, error) {
+	for {
+		hash, err := r.Get(indexKey(height))
+		if err == kv.ErrNotFound {
+			return nil, nil, nil
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+		data, err := r.Get(toKey(BKTHeaders, hash...))
+		if err == nil {
+			if header, err := h.decodeHeader(data); err == nil &&
+				header.Height == height {
+				return header, data, nil
+			}
+		} else if err != kv.ErrNotFound {
+			return nil, nil, err
+		}
+		if height == 0 {
+			return nil, nil, nil
+		}
+		height--
+	}
+}
+
+func (h *headers) decodeHeader(data []byte) (*util.Header, error) {
+	header := &util.Header{BlockHeader: h.newHeader()}
+	if err := header.Deserialize(data); err != nil {
+		return nil, err
+	}
+	return header, nil
+}
+
+// Verify checks the transactions height indexes, ops, queued items, arbiter
+// positions and custom ID fee positions against the best height.
+func (d *dataStore) Verify(bestHeight uint32, repair bool) (*IntegrityReport, error) {
+	report := &IntegrityReport{BestHeight: bestHeight}
+
+	// Remove data above the best height first, so the following checks and
+	// repairs work on the remaining data.
+	if repair {
+		if err := d.pruneAboveBest(bestHeight, report); err != nil {
+			return nil, err
+		}
+	}
+
+	snapshot, err := d.db.NewSnapshot()
+	if err != nil {
+		return nil, err
+	}
+	defer snapshot.Release()
+
+	v := &dataVerifier{
+		r:       snapshot,
+		best:    bestHeight,
+		report:  report,
+		batch:   d.db.NewBatch(),
+		removed: make(map[common.Uint256]struct{}),
+		indexes: make(map[uint32][]byte),
+	}
+	for _, verify := range []func() error{
+		v.verifyTxs,
+		v.verifyOps,
+		v.verifyQue,
+		v.verifyArbiters,
+		v.verifyRevertPositions,
+		v.verifyCustomIDFee,
+	} {
+		if err := verify(); err != nil {
+			return nil, err
+		}
+	}
+
+	if repair && v.batch.Len() > 0 {
+		if err := d.db.Write(v.batch); err != nil {
+			return nil, err
+		}
+		d.ars.Lock()
+		d.ars.posCache = make([]uint32, 0)
+		d.ars.revertPOSCache = nil
+		d.ars.Unlock()
+		d.cid.Lock()
+		d.cid.customIDFeePosCache = nil
+		d.cid.Unlock()
+	}
+	report.Repaired = repair && len(report.Issues) > 0
+	return report, nil
+}
+
+// pruneAboveBest removes all data on the heights above the best height.
+func (d *dataStore) pruneAboveBest(bestHeight uint32, report *IntegrityReport) error {
+	heights, err := txHeightsAbove(d.db, bestHeight)
+	if err != nil {
+		return err
+	}
+	for _, height := range heights {
+		report.add(IssueTxIndex, height,
+			"transactions above best height %d removed", bestHeight)
+		batch := d.Batch()
+		if err := batch.DelAll(height); err != nil {
+			batch.Rollback()
+			return err
+		}
+		if err := batch.Commit(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// txHeightsAbove returns the heights above height in the transactions height
+// index.
+func txHeightsAbove(r kv.Reader, height uint32) ([]uint32, error) {
+	if height == math.MaxUint32 {
+		return nil, nil
+	}
+	var start [4]byte
+	binary.BigEndian.PutUint32(start[:], height+1)
+	it := r.NewIterator(&kv.Range{
+		Start: toKey(append([]byte{}, BKTHeightTxs...), start[:]...),
+		Limit: kv.BytesPrefix(BKTHeightTxs).Limit,
+	})
+	defer it.Release()
+
+	var heights []uint32
+	for it.Next() {
+		if len(it.Key()) != len(BKTHeightTxs)+4 {
+			continue
+		}
+		heights = append(heights,
+			binary.BigEndian.Uint32(it.Key()[len(BKTHeightTxs):]))
+	}
+	return heights, it.Error()
+}
+
+// dataVerifier checks the data store snapshot, repairs are put into batch.
+type dataVerifier struct {
+	r      kv.Reader
+	best   uint32
+	report *IntegrityReport
+	batch  kv.Batch
+
+	// removed is the transactions missing or removed by repair.
+	removed map[common.Uint256]struct{}
+
+	// indexes is the transactions height indexes changed by repair.
+	indexes map[uint32][]byte
+}
+
+// hasTx returns if the transaction is stored and not removed.
+func (v *dataVerifier) hasTx(txId *common.Uint256) (bool, error) {
+	if _, ok := v.removed[*txId]; ok {
+		return false, nil
+	}
+	return v.r.Has(toKey(BKTTxs, txId.Bytes()...))
+}
+
+func (v *dataVerifier) getIndex(height uint32) ([]byte, error) {
+	if data, ok := v.indexes[height]; ok {
+		return data, nil
+	}
+	var key [4]byte
+	binary.BigEndian.PutUint32(key[:], height)
+	data, err := v.r.Get(toKey(BKTHeightTxs, key[:]...))
+	if err == kv.ErrNotFound {
+		return nil, nil
+	}
+	return data, err
+}
+
+func (v *dataVerifier) verifyTxs() error {
+	it := v.r.NewIterator(kv.BytesPrefix(BKTHeightTxs))
+	for it.Next() {
+		if len(it.Key()) != len(BKTHeightTxs)+4 {
+			continue
+		}
+		height := binary.BigEndian.Uint32(it.Key()[len(BKTHeightTxs):])
+		if height > v.best {
+			v.report.add(IssueTxIndex, height,
+				"transactions above best height %d", v.best)
+			for _, txId := range getTxIds(it.Value()) {
+				v.removed[*txId] = struct{}{}
+			}
+			continue
+		}
+
+		data := append([]byte{}, it.Value()...)
+		var changed bool
+		for _, txId := range getTxIds(it.Value()) {
+			value, err := v.r.Get(toKey(BKTTxs, txId.Bytes()...))
+			if err == kv.ErrNotFound {
+				v.report.add(IssueTxIndex, height,
+					"indexed transaction %s not found", txId)
+				data, changed = delTxId(data, txId), true
+				continue
+			}
+			if err != nil {
+				it.Release()
+				return err
+			}
+			var tx util.Tx
+			if err := tx.Deserialize(bytes.NewReader(value)); err == nil &&
+				tx.Height != height {
+				v.report.add(IssueTxIndex, height,
+					"indexed transaction %s packed on height %d", txId,
+					tx.Height)
+				data, changed = delTxId(data, txId), true
+			}
+		}
+		if changed {
+			v.indexes[height] = data
+		}
+	}
+	it.Release()
+	if err := it.Error(); err != nil {
+		return err
+	}
+
+	it = v.r.NewIterator(kv.BytesPrefix(BKTTxs))
+	defer it.Release()
+	for it.Next() {
+		if len(it.Key()) != len(BKTTxs)+common.UINT256SIZE {
+			continue
+		}
+		var txId common.Uint256
+		copy(txId[:], it.Key()[len(BKTTxs):])
+		if _, ok := v.removed[txId]; ok {
+			continue
+		}
+		var tx util.Tx
+		if err := tx.Deserialize(bytes.NewReader(it.Value())); err != nil {
+			v.report.add(IssueTxIndex, 0, "invalid transaction %s, %s",
+				txId, err)
+			v.removed[txId] = struct{}{}
+			v.batch.Delete(append([]byte{}, it.Key()...))
+			continue
+		}
+		if tx.Height > v.best {
+			v.report.add(IssueTxIndex, tx.Height,
+				"transaction %s above best height %d", txId, v.best)
+			v.removed[txId] = struct{}{}
+			v.batch.Delete(append([]byte{}, it.Key()...))
+			continue
+		}
+		index, err := v.getIndex(tx.Height)
+		if err != nil {
+			return err
+		}
+		if !containsTxId(index, &txId) {
+			v.report.add(IssueTxIndex, tx.Height,
+				"transaction %s not indexed", txId)
+			v.indexes[tx.Height] = putTxId(append([]byte{}, index...), &txId)
+		}
+	}
+	if err := it.Error(); err != nil {
+		return err
+	}
+
+	for height, data := range v.indexes {
+		var key [4]byte
+		binary.BigEndian.PutUint32(key[:], height)
+		if len(getTxIds(data)) == 0 {
+			v.batch.Delete(toKey(BKTHeightTxs, key[:]...))
+		} else {
+			v.batch.Put(toKey(BKTHeightTxs, key[:]...), data)
+		}
+	}
+	return nil
+}
+
+func containsTxId(data []byte, txId *common.Uint256) bool {
+	for _, id := range getTxIds(data) {
+		if id.IsEqual(*txId) {
+			return true
+		}
+	}
+	return false
+}
+
+func (v *dataVerifier) verifyOps() error {
+	it := v.r.NewIterator(kv.BytesPrefix(BKTOps))
+	defer it.Release()
+	for it.Next() {
+		var op util.OutPoint
+		key := subKey(BKTOps, it.Key())
+		if len(key) != common.UINT256SIZE+2 ||
+			op.Deserialize(bytes.NewReader(key)) != nil {
+			continue
+		}
+		ok, err := v.hasTx(&op.TxID)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			v.report.add(IssueOrphanOp, 0, "outpoint %s:%d of missing"+
+				" transaction", op.TxID, op.Index)
+			v.batch.Delete(append([]byte{}, it.Key()...))
+		}
+	}
+	return it.Error()
+}
+
+func (v *dataVerifier) verifyQue() error {
+	it := v.r.NewIterator(kv.BytesPrefix(BKTQue))
+	for it.Next() {
+		value := subKey(BKTQue, it.Key())
+		if len(value) != common.UINT256SIZE*2 || len(it.Value()) < 4 {
+			continue
+		}
+		var txId common.Uint256
+		copy(txId[:], value[common.UINT256SIZE:])
+		height := binary.BigEndian.Uint32(it.Value())
+		idxKey := toKey(append([]byte{}, BKTQueIdx...),
+			append(append([]byte{}, it.Value()[:4]...), value...)...)
+
+		data, err := v.r.Get(toKey(BKTTxs, txId.Bytes()...))
+		if _, ok := v.removed[txId]; ok || err == kv.ErrNotFound {
+			v.report.add(IssueOrphanQue, height,
+				"queued notify of missing transaction %s", txId)
+			v.batch.Delete(append([]byte{}, it.Key()...))
+			v.batch.Delete(idxKey)
+			continue
+		}
+		if err != nil {
+			it.Release()
+			return err
+		}
+
+		var tx util.Tx
+		if err := tx.Deserialize(bytes.NewReader(data)); err != nil {
+			continue
+		}
+		if tx.Height != height {
+			v.report.add(IssueOrphanQue, height,
+				"queued notify of transaction %s packed on height %d",
+				txId, tx.Height)
+			var buf [4]byte
+			binary.BigEndian.PutUint32(buf[:], tx.Height)
+			v.batch.Delete(idxKey)
+			v.batch.Put(toKey(append([]byte{}, BKTQueIdx...),
+				append(buf[:], value...)...), empty)
+			v.batch.Put(append([]byte{}, it.Key()...),
+				append(buf[:], it.Value()[4:]...))
+			continue
+		}
+		ok, err := v.r.Has(idxKey)
+		if err != nil {
+			it.Release()
+			return err
+		}
+		if !ok {
+			v.report.add(IssueOrphanQue, height,
+				"queued notify of transaction %s not indexed", txId)
+			v.batch.Put(idxKey, empty)
+		}
+	}
+	it.Release()
+	if err := it.Error(); err != nil {
+		return err
+	}
+
+	it = v.r.NewIterator(kv.BytesPrefix(BKTQueIdx))
+	defer it.Release()
+	for it.Next() {
+		key := subKey(BKTQueIdx, it.Key())
+		if len(key) != 4+common.UINT256SIZE*2 {
+			continue
+		}
+		height := binary.BigEndian.Uint32(key)
+		data, err := v.r.Get(toKey(append([]byte{}, BKTQue...), key[4:]...))
+		if err != nil && err != kv.ErrNotFound {
+			return err
+		}
+		if err == kv.ErrNotFound || len(data) < 4 ||
+			!bytes.Equal(data[:4], key[:4]) {
+			v.report.add(IssueOrphanQue, height,
+				"queue index not match the queued notify")
+			v.batch.Delete(append([]byte{}, it.Key()...))
+		}
+	}
+	return it.Error()
+}
+
+// positions returns the positions stored in key, nil if not exists.
+func (v *dataVerifier) positions(key []byte) ([]uint32, error) {
+	data, err := v.r.Get(key)
+	if err == kv.ErrNotFound {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if len(data) < 4 || len(data) != int(bytesToUint32(data))*4+4 {
+		return nil, errors.New("invalid positions data")
+	}
+	return bytesToUint32Array(data), nil
+}
+
+func (v *dataVerifier) hasArbiters(height uint32) (bool, error) {
+	data, err := v.r.Get(toKey(BKTArbitersData, getIndex(height)...))
+	if err == kv.ErrNotFound {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	if len(data) == 4 {
+		return v.r.Has(toKey(BKTArbitersData, getIndex(bytesToUint32(data))...))
+	}
+	return true, nil
+}
+
+func (v *dataVerifier) verifyArbiters() error {
+	positions, err := v.positions(BKTArbPositions)
+	if err != nil {
+		v.report.add(IssueArbiterPosition, 0, "arbiter positions: %s", err)
+		v.batch.Delete(BKTArbPositions)
+		v.batch.Delete(BKTArbPosition)
+		return nil
+	}
+	if positions == nil {
+		return nil
+	}
+
+	valid := make([]uint32, 0, len(positions))
+	for _, p := range positions {
+		if len(valid) > 0 && p <= valid[len(valid)-1] {
+			v.report.add(IssueArbiterPosition, p,
+				"arbiter position not in ascending order")
+			continue
+		}
+		ok, err := v.hasArbiters(p)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			v.report.add(IssueArbiterPosition, p,
+				"arbiters of the position not found")
+			continue
+		}
+		valid = append(valid, p)
+	}
+
+	current, err := v.r.Get(BKTArbPosition)
+	if err != nil && err != kv.ErrNotFound {
+		return err
+	}
+	changed := len(valid) != len(positions)
+	switch {
+	case len(valid) == 0:
+		if changed || err == nil {
+			v.batch.Delete(BKTArbPositions)
+			v.batch.Delete(BKTArbPosition)
+		}
+		return nil
+	case len(current) != 4 || bytesToUint32(current) != valid[len(valid)-1]:
+		v.report.add(IssueArbiterPosition, valid[len(valid)-1],
+			"current arbiter position not match the last position")
+		changed = true
+	}
+	if changed {
+		v.batch.Put(BKTArbPositions, uint32ArrayToBytes(valid))
+		v.batch.Put(BKTArbPosition, uint32toBytes(valid[len(valid)-1]))
+	}
+	return nil
+}
+
+func (v *dataVerifier) verifyRevertPositions() error {
+	data, err := v.r.Get(BKTRevertPositions)
+	if err == kv.ErrNotFound {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	positions, err := bytesToRevertInfoArray(data)
+	if err != nil {
+		v.report.add(IssueArbiterPosition, 0, "revert positions: %s", err)
+		v.batch.Delete(BKTRevertPositions)
+		v.batch.Delete(BKTRevertPosition)
+		return nil
+	}
+
+	valid := make([]RevertInfo, 0, len(positions))
+	for _, p := range positions {
+		if len(valid) > 0 &&
+			p.WorkingHeight <= valid[len(valid)-1].WorkingHeight {
+			v.report.add(IssueArbiterPosition, p.WorkingHeight,
+				"revert position not in ascending order")
+			continue
+		}
+		key := toKey(BKTRevertTxs, uint32toBytes(p.WorkingHeight)...)
+		data, err := v.r.Get(key)
+		if err != nil && err != kv.ErrNotFound {
+			return err
+		}
+		var tx revertTx
+		if err == kv.ErrNotFound || tx.deserialize(data) != nil {
+			v.report.add(IssueArbiterPosition, p.WorkingHeight,
+				"revert transaction of the position not found")
+			continue
+		}
+		if tx.Height > v.best {
+			v.report.add(IssueArbiterPosition, p.WorkingHeight,
+				"revert transaction %s above best height %d", tx.TxHash,
+				v.best)
+			v.batch.Delete(key)
+			continue
+		}
+		valid = append(valid, p)
+	}
+	if len(valid) == len(positions) {
+		return nil
+	}
+
+	data, err = revertInfoArrayToBytes(valid)
+	if err != nil {
+		return err
+	}
+	v.batch.Put(BKTRevertPositions, data)
+	if len(valid) == 0 {
+		v.batch.Delete(BKTRevertPosition)
+	} else {
+		last := valid[len(valid)-1]
+		v.batch.Put(BKTRevertPosition, uint32toBytes(last.WorkingHeight))
+	}
+	return nil
+}
+
+func (v *dataVerifier) verifyCustomIDFee() error {
+	positions, err := v.positions(BKTCustomIDFeePositions)
+	if err != nil {
+		v.report.add(IssueCustomIDPosition, 0, "custom ID fee positions: %s",
+			err)
+		v.batch.Delete(BKTCustomIDFeePositions)
+		return nil
+	}
+
+	valid := make([]uint32, 0, len(positions))
+	for _, p := range positions {
+		if len(valid) > 0 && p <= valid[len(valid)-1] {
+			v.report.add(IssueCustomIDPosition, p,
+				"custom ID fee position not in ascending order")
+			continue
+		}
+		feeKey := toKey(BKTChangeCustomIDFee, uint32toBytes(p)...)
+		ok, err := v.r.Has(feeKey)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			v.report.add(IssueCustomIDPosition, p,
+				"custom ID fee rate of the position not found")
+			continue
+		}
+		proposalKey := toKey(BKTCustomIDFeeProposals, uint32toBytes(p)...)
+		data, err := v.r.Get(proposalKey)
+		if err != nil && err != kv.ErrNotFound {
+			return err
+		}
+		if len(data) == 36 && binary.LittleEndian.Uint32(data[32:]) > v.best {
+			v.report.add(IssueCustomIDPosition, p,
+				"custom ID fee rate changed above best height %d", v.best)
+			v.batch.Delete(feeKey)
+			v.batch.Delete(proposalKey)
+			continue
+		}
+		valid = append(valid, p)
+	}
+	if len(valid) != len(positions) {
+		v.batch.Put(BKTCustomIDFeePositions, uint32ArrayToBytes(valid))
+	}
+	return nil
+}
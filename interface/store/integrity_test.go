@@ -0,0 +1,150 @@
+package store
+
+import (
+	"testing"
+
+	"github.com/elastos/Elastos.ELA.SPV/util"
+
+	"github.com/elastos/Elastos.ELA/common"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// newTestStores creates memory stores with main chain from height 0 to 5.
+func newTestStores(t *testing.T) (*headers, *dataStore, []*util.Header) {
+	headers, err := NewMemoryHeaderStore(newEmptyHeader)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	data, err := NewMemoryDataStore(nil, 36, "")
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+
+	var chain []*util.Header
+	var previous *util.Header
+	for i := uint32(0); i <= 5; i++ {
+		header := newHeader(previous, 1000+i*10, 0)
+		assert.NoError(t, headers.Put(header, true))
+		chain = append(chain, header)
+		previous = header
+	}
+	return headers, data, chain
+}
+
+func TestVerifyStores_Headers(t *testing.T) {
+	headers, data, chain := newTestStores(t)
+	defer headers.Close()
+	defer data.Close()
+
+	report, err := VerifyStores(headers, data, false)
+	assert.NoError(t, err)
+	assert.Equal(t, uint32(5), report.BestHeight)
+	assert.Equal(t, 6, report.Headers)
+	assert.Equal(t, 0, len(report.Issues))
+
+	// height index points to a fork header and above the best header.
+	fork := newHeader(chain[1], 1020, 1)
+	assert.NoError(t, headers.Put(fork, false))
+	forkHash := fork.Hash()
+	assert.NoError(t, headers.db.Put(indexKey(2), forkHash.Bytes()))
+	assert.NoError(t, headers.db.Put(indexKey(7), forkHash.Bytes()))
+
+	report, err = VerifyStores(headers, data, false)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, len(report.Issues))
+	assert.False(t, report.Repaired)
+
+	report, err = VerifyStores(headers, data, true)
+	assert.NoError(t, err)
+	assert.True(t, report.Repaired)
+	header, err := headers.GetByHeight(2)
+	assert.NoError(t, err)
+	assert.Equal(t, chain[2].Hash(), header.Hash())
+	_, err = headers.GetByHeight(7)
+	assert.Error(t, err)
+
+	// the best chain is truncated below the missing header.
+	hash := chain[3].Hash()
+	assert.NoError(t, headers.db.Delete(toKey(BKTHeaders, hash.Bytes()...)))
+	report, err = VerifyStores(headers, data, true)
+	assert.NoError(t, err)
+	assert.Equal(t, uint32(2), report.BestHeight)
+	best, err := headers.GetBest()
+	assert.NoError(t, err)
+	assert.Equal(t, chain[2].Hash(), best.Hash())
+	_, err = headers.GetByHeight(4)
+	assert.Error(t, err)
+
+	report, err = VerifyStores(headers, data, false)
+	assert.NoError(t, err)
+	assert.Equal(t, uint32(2), report.BestHeight)
+	assert.Equal(t, 3, report.Headers)
+	assert.Equal(t, 0, len(report.Issues))
+}
+
+func TestVerifyStores_Data(t *testing.T) {
+	headers, data, _ := newTestStores(t)
+	defer headers.Close()
+	defer data.Close()
+
+	tx1, tx2, tx3 := newStoreTx(1, 3), newStoreTx(2, 4), newStoreTx(3, 7)
+	for _, tx := range []*util.Tx{tx1, tx2, tx3} {
+		assert.NoError(t, data.Txs().Put(tx))
+	}
+	op := util.NewOutPoint(tx1.Hash, 0)
+	assert.NoError(t, data.Ops().Put(op, common.Uint168{1}))
+	assert.NoError(t, data.Que().Put(&QueItem{
+		NotifyId: common.Uint256{1}, TxId: tx1.Hash, Height: 3}))
+	assert.NoError(t, data.Que().Put(&QueItem{
+		NotifyId: common.Uint256{1}, TxId: tx2.Hash, Height: 4}))
+	assert.NoError(t, data.Arbiters().Put(100, [][]byte{{1}}, nil))
+
+	report, err := VerifyStores(headers, data, false)
+	assert.NoError(t, err)
+	if assert.Equal(t, 1, len(report.Issues)) {
+		assert.Equal(t, IssueTxIndex, report.Issues[0].Type)
+		assert.Equal(t, uint32(7), report.Issues[0].Height)
+	}
+
+	// tx1 is lost with it's op and queued notify, and the arbiter positions
+	// reference missing arbiters.
+	assert.NoError(t, data.db.Delete(toKey(BKTTxs, tx1.Hash.Bytes()...)))
+	assert.NoError(t, data.db.Put(BKTArbPositions,
+		uint32ArrayToBytes([]uint32{100, 200})))
+	report, err = VerifyStores(headers, data, false)
+	assert.NoError(t, err)
+	types := make(map[IssueType]int)
+	for _, issue := range report.Issues {
+		types[issue.Type]++
+	}
+	assert.Equal(t, map[IssueType]int{
+		IssueTxIndex:         2,
+		IssueOrphanOp:        1,
+		IssueOrphanQue:       1,
+		IssueArbiterPosition: 1,
+	}, types)
+
+	report, err = VerifyStores(headers, data, true)
+	assert.NoError(t, err)
+	assert.True(t, report.Repaired)
+
+	_, err = data.Txs().Get(&tx3.Hash)
+	assert.Error(t, err)
+	ids, err := data.Txs().GetIds(3)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, len(ids))
+	assert.Nil(t, data.Ops().HaveOp(op))
+	items, err := data.Que().GetAll()
+	assert.NoError(t, err)
+	if assert.Equal(t, 1, len(items)) {
+		assert.Equal(t, tx2.Hash, items[0].TxId)
+	}
+	workingHeight, _, _, err := data.Arbiters().GetNext()
+	assert.NoError(t, err)
+	assert.Equal(t, uint32(100), workingHeight)
+
+	report, err = VerifyStores(headers, data, false)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, len(report.Issues))
+}
@@ -4,18 +4,32 @@ import (
 	"time"
 
 	"github.com/elastos/Elastos.ELA.SPV/database"
+	"github.com/elastos/Elastos.ELA.SPV/interface/store/kv"
 	"github.com/elastos/Elastos.ELA.SPV/sdk"
 	"github.com/elastos/Elastos.ELA.SPV/util"
 	"github.com/elastos/Elastos.ELA/common"
 	it "github.com/elastos/Elastos.ELA/core/types/interfaces"
 	"github.com/elastos/Elastos.ELA/core/types/payload"
-
-	"github.com/syndtr/goleveldb/leveldb"
 )
 
 type HeaderStore interface {
-	database.Headers
-	GetByHeight(height uint32) (header *util.Header, err error)
+	database.ChainHeaders
+
+	// NewSnapshot returns a snapshot of the current headers database.
+	NewSnapshot() (kv.Snapshot, error)
+
+	// Verify checks the best chain headers and their height indexes, and
+	// repairs the issues found if repair is true.
+	Verify(repair bool) (*IntegrityReport, error)
+
+	// PruneForks removes the side branch headers with branch tips below
+	// height, returns the number of headers and bytes removed.
+	PruneForks(height uint32) (int, uint64, error)
+
+	// Compact removes the merkle data of the best chain headers below
+	// height unless keep returns true with the header height.
+	Compact(height uint32,
+		keep func(height uint32) (bool, error)) (*CompactReport, error)
 }
 
 type DataStore interface {
@@ -27,7 +41,19 @@ type DataStore interface {
 	Que() Que
 	Arbiters() Arbiters
 	CID() CustomID
+	Proposals() Proposals
+	Deposits() Deposits
+	Producers() Producers
+	AddrTxs() AddrTxs
+	UTXOs() UTXOs
 	Batch() DataBatch
+
+	// NewSnapshot returns a snapshot of the current data database.
+	NewSnapshot() (kv.Snapshot, error)
+
+	// Verify checks the data against the best height, and repairs the
+	// issues found if repair is true.
+	Verify(bestHeight uint32, repair bool) (*IntegrityReport, error)
 }
 
 type DataBatch interface {
@@ -35,7 +61,7 @@ type DataBatch interface {
 	Txs() TxsBatch
 	Ops() OpsBatch
 	Que() QueBatch
-	GetNakedBatch() *leveldb.Batch
+	GetNakedBatch() kv.Batch
 	// Delete all transactions, ops, queued items on
 	// the given height.
 	DelAll(height uint32) error
@@ -68,6 +94,9 @@ type Txs interface {
 	GetIds(height uint32) ([]*common.Uint256, error)
 	PutForkTxs(txs []*util.Tx, hash *common.Uint256) error
 	GetForkTxs(hash *common.Uint256) ([]*util.Tx, error)
+	// Remove fork transactions of the blocks that prune returns true,
+	// returns the number of fork blocks and bytes removed.
+	PruneForkTxs(prune func(hash *common.Uint256) bool) (int, uint64, error)
 	Del(txId *common.Uint256) error
 	Batch() TxsBatch
 }
@@ -75,6 +104,8 @@ type Txs interface {
 type TxsBatch interface {
 	batch
 	Put(tx *util.Tx) error
+	// Get returns the transaction with the changes within the batch applied.
+	Get(txId *common.Uint256) (*util.Tx, error)
 	Del(txId *common.Uint256) error
 	DelAll(height uint32) error
 }
@@ -90,6 +121,9 @@ type Ops interface {
 type OpsBatch interface {
 	batch
 	Put(*util.OutPoint, common.Uint168) error
+	// HaveOp returns the address of the outpoint with the changes within the
+	// batch applied, or nil if the outpoint is not stored.
+	HaveOp(*util.OutPoint) (*common.Uint168, error)
 	Del(*util.OutPoint) error
 }
 
@@ -132,12 +166,15 @@ type QueItem struct {
 type Arbiters interface {
 	database.DB
 	Put(height uint32, crcArbiters [][]byte, normalArbiters [][]byte) error
-	BatchPut(height uint32, crcArbiters [][]byte, normalArbiters [][]byte, batch *leveldb.Batch) error
+	BatchPut(height uint32, crcArbiters [][]byte, normalArbiters [][]byte, batch kv.Batch) error
 	Get() (crcArbiters [][]byte, normalArbiters [][]byte, err error)
 	GetNext() (workingHeight uint32, crcArbiters [][]byte, normalArbiters [][]byte, err error)
 	GetByHeight(height uint32) (crcArbiters [][]byte, normalArbiters [][]byte, err error)
-	BatchPutRevertTransaction(batch *leveldb.Batch, workingHeight uint32, mode byte) error
+	BatchPutRevertTransaction(batch kv.Batch, workingHeight uint32, mode byte,
+		txHash common.Uint256, height uint32) error
+	BatchDeleteRevertTransactions(batch kv.Batch, height uint32) error
 	GetConsensusAlgorithmByHeight(height uint32) (byte, error)
+	GetConsensusModeTimeline(startHeight, endHeight uint32) ([]ConsensusModeInterval, error)
 	GetRevertInfo() []RevertInfo
 }
 
@@ -146,34 +183,102 @@ type CustomID interface {
 	PutControversialReservedCustomIDs(
 		reservedCustomIDs []string, proposalHash common.Uint256) error
 	BatchPutControversialReservedCustomIDs(reservedCustomIDs []string,
-		proposalHash common.Uint256, batch *leveldb.Batch) error
+		proposalHash common.Uint256, batch kv.Batch) error
 	BatchDeleteControversialReservedCustomIDs(
-		proposalHash common.Uint256, batch *leveldb.Batch)
+		proposalHash common.Uint256, batch kv.Batch)
 
 	PutControversialReceivedCustomIDs(reservedCustomIDs []string,
 		did common.Uint168, proposalHash common.Uint256) error
 	BatchPutControversialReceivedCustomIDs(receivedCustomIDs []string,
-		did common.Uint168, proposalHash common.Uint256, batch *leveldb.Batch) error
+		did common.Uint168, proposalHash common.Uint256, batch kv.Batch) error
 	BatchDeleteControversialReceivedCustomIDs(
-		proposalHash common.Uint256, batch *leveldb.Batch)
+		proposalHash common.Uint256, batch kv.Batch)
 
-	BatchPutRetSideChainDepositCoinTx(tx it.Transaction, batch *leveldb.Batch) error
-	BatchDeleteRetSideChainDepositCoinTx(tx it.Transaction, batch *leveldb.Batch) error
+	BatchPutRetSideChainDepositCoinTx(tx it.Transaction, batch kv.Batch) error
+	BatchDeleteRetSideChainDepositCoinTx(tx it.Transaction, batch kv.Batch) error
 
 	PutControversialChangeCustomIDFee(rate common.Fixed64,
 		proposalHash common.Uint256, workingHeight uint32) error
 
 	BatchPutControversialChangeCustomIDFee(rate common.Fixed64,
-		proposalHash common.Uint256, workingHeight uint32, batch *leveldb.Batch) error
+		proposalHash common.Uint256, workingHeight uint32, batch kv.Batch) error
 	BatchDeleteControversialChangeCustomIDFee(
-		proposalHash common.Uint256, batch *leveldb.Batch)
+		proposalHash common.Uint256, batch kv.Batch)
 
 	PutCustomIDProposalResults(results []payload.ProposalResult, height uint32) error
-	BatchPutCustomIDProposalResults(results []payload.ProposalResult, height uint32, batch *leveldb.Batch) error
+	BatchPutCustomIDProposalResults(results []payload.ProposalResult, height uint32, batch kv.Batch) error
 
 	GetReservedCustomIDs(height uint32, info []RevertInfo) (map[string]struct{}, error)
 	GetReceivedCustomIDs(height uint32, info []RevertInfo) (map[string]common.Uint168, error)
 	GetCustomIDFeeRate(height uint32) (common.Fixed64, error)
+	GetCustomIDRecord(customID string) (*CustomIDRecord, error)
+	GetCustomIDsByOwner(did common.Uint168) ([]*CustomIDRecord, error)
+	GetCustomIDFeeRateHistory() ([]CustomIDFeeRate, error)
+	// Restore custom ID records and fee rates changed on the given height.
+	BatchDeleteCustomIDHistory(height uint32, batch kv.Batch) error
 	//Is this RetSideChainDepositCoin tx exist
 	HaveRetSideChainDepositCoinTx(txHash common.Uint256) bool
 }
+
+type Proposals interface {
+	database.DB
+	BatchPut(info *ProposalInfo, batch kv.Batch) error
+	BatchPutResults(results []payload.ProposalResult, height uint32, batch kv.Batch) error
+	// Delete all proposals and proposal results recorded on the given height.
+	BatchDeleteAll(height uint32, batch kv.Batch) error
+
+	Get(hash common.Uint256) (*ProposalInfo, error)
+	GetByType(proposalType payload.CRCProposalType) ([]*ProposalInfo, error)
+	GetByStatus(status ProposalStatus) ([]*ProposalInfo, error)
+	GetByHeight(startHeight, endHeight uint32) ([]*ProposalInfo, error)
+}
+
+type Deposits interface {
+	database.DB
+	BatchPut(deposit *CrossChainDeposit, batch kv.Batch) error
+	// Delete all deposits packed on the given height.
+	BatchDeleteAll(height uint32, batch kv.Batch) error
+
+	Get(genesisAddress string, txHash common.Uint256) (*CrossChainDeposit, error)
+	List(genesisAddress string, offset, limit uint32) ([]*CrossChainDeposit, error)
+	Count(genesisAddress string) (uint32, error)
+	// Returns if any deposit is packed on the given height.
+	HaveHeight(height uint32) (bool, error)
+}
+
+type AddrTxs interface {
+	database.DB
+	BatchPut(record *AddressTx, batch kv.Batch) error
+	// Delete all history records on the given height.
+	BatchDeleteAll(height uint32, batch kv.Batch) error
+
+	List(addr common.Uint168, fromHeight, toHeight, limit uint32,
+		cursor string) ([]*AddressTx, string, error)
+}
+
+type UTXOs interface {
+	database.DB
+	BatchPut(utxo *UTXO, batch kv.Batch) error
+	// Move the UTXO of the outpoint to STXO, returns nil if the outpoint is
+	// not a UTXO.
+	BatchSpend(op *util.OutPoint, height uint32, txId common.Uint256,
+		batch kv.Batch) (*STXO, error)
+	// Restore outputs spent and remove UTXOs created on the given height.
+	BatchDeleteAll(height uint32, batch kv.Batch) error
+
+	Get(op *util.OutPoint) (*UTXO, error)
+	GetSpent(op *util.OutPoint) (*STXO, error)
+	List(addr common.Uint168) ([]*UTXO, error)
+}
+
+type Producers interface {
+	database.DB
+	// BatchPutTx updates producers by the producer related transaction.
+	BatchPutTx(tx it.Transaction, height uint32, batch kv.Batch) error
+	// Restore all producers changed on the given height.
+	BatchDeleteAll(height uint32, batch kv.Batch) error
+
+	Get(ownerKey []byte) (*ProducerInfo, error)
+	GetByNodeKey(nodeKey []byte) (*ProducerInfo, error)
+	GetAll() ([]*ProducerInfo, error)
+}
@@ -0,0 +1,124 @@
+package kv
+
+import (
+	"errors"
+)
+
+// ErrNotFound is returned when the key is not found in the database.
+var ErrNotFound = errors.New("kv: not found")
+
+// Range is a key range, Start is inclusive and Limit is exclusive. A nil
+// Start means from the first key and a nil Limit means to the last key.
+type Range struct {
+	Start []byte
+	Limit []byte
+}
+
+// BytesPrefix returns the key range that satisfy the given prefix.
+func BytesPrefix(prefix []byte) *Range {
+	var limit []byte
+	for i := len(prefix) - 1; i >= 0; i-- {
+		c := prefix[i]
+		if c < 0xff {
+			limit = make([]byte, i+1)
+			copy(limit, prefix)
+			limit[i] = c + 1
+			break
+		}
+	}
+	return &Range{Start: prefix, Limit: limit}
+}
+
+// Iterator iterates over key/value pairs in key order. The key and value
+// returned are only valid until the next call of Next.
+type Iterator interface {
+	// Next moves the iterator to the next key/value pair, returns false if
+	// the iterator is exhausted.
+	Next() bool
+
+	// Key returns the key of the current key/value pair.
+	Key() []byte
+
+	// Value returns the value of the current key/value pair.
+	Value() []byte
+
+	// Release releases the resources of the iterator.
+	Release()
+
+	// Error returns the error occurred while iterating.
+	Error() error
+}
+
+// BatchReplay is used to replay the changes within a batch.
+type BatchReplay interface {
+	Put(key, value []byte)
+	Delete(key []byte)
+}
+
+// Batch is a write batch applied to the database atomically.
+type Batch interface {
+	// Put appends a put operation to the batch.
+	Put(key, value []byte)
+
+	// Delete appends a delete operation to the batch.
+	Delete(key []byte)
+
+	// Len returns the number of operations in the batch.
+	Len() int
+
+	// Reset resets the batch.
+	Reset()
+
+	// Replay replays the operations of the batch in order.
+	Replay(r BatchReplay) error
+}
+
+// Reader is the read methods shared by DB and Snapshot.
+type Reader interface {
+	// Get returns the value of the key, or ErrNotFound if not exists.
+	Get(key []byte) ([]byte, error)
+
+	// GetUncached is the same as Get, but the value read will not fill the
+	// caches of the backend, so a long sequential scan will not evict the
+	// recent data.
+	GetUncached(key []byte) ([]byte, error)
+
+	// Has returns if the key exists.
+	Has(key []byte) (bool, error)
+
+	// NewIterator returns an iterator over the given key range, a nil range
+	// iterates over the whole database.
+	NewIterator(r *Range) Iterator
+}
+
+// Snapshot is a frozen view of the database, changes after the snapshot
+// taken are not visible.
+type Snapshot interface {
+	Reader
+
+	// Release releases the snapshot.
+	Release()
+}
+
+// DB is the key/value storage backend of the stores.
+type DB interface {
+	Reader
+
+	// Put sets the value of the key.
+	Put(key, value []byte) error
+
+	// Delete removes the key, it's not an error if the key not exists.
+	Delete(key []byte) error
+
+	// NewSnapshot returns a snapshot of the current database state.
+	NewSnapshot() (Snapshot, error)
+
+	// NewBatch returns a new write batch.
+	NewBatch() Batch
+
+	// Write applies the batch to the database atomically.
+	Write(batch Batch) error
+
+	// Close closes the database.
+	Close() error
+}
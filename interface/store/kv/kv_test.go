@@ -0,0 +1,117 @@
+package kv
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func testDB(t *testing.T, db DB) {
+	assert.NoError(t, db.Put([]byte("a1"), []byte("v1")))
+	value, err := db.Get([]byte("a1"))
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("v1"), value)
+	_, err = db.Get([]byte("a2"))
+	assert.Equal(t, ErrNotFound, err)
+	value, err = db.GetUncached([]byte("a1"))
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("v1"), value)
+	_, err = db.GetUncached([]byte("a2"))
+	assert.Equal(t, ErrNotFound, err)
+
+	batch := db.NewBatch()
+	batch.Put([]byte("a2"), []byte("v2"))
+	batch.Put([]byte("b1"), []byte("v3"))
+	batch.Delete([]byte("a1"))
+	assert.Equal(t, 3, batch.Len())
+	ok, err := db.Has([]byte("a2"))
+	assert.NoError(t, err)
+	assert.False(t, ok)
+	assert.NoError(t, db.Write(batch))
+
+	ok, err = db.Has([]byte("a1"))
+	assert.NoError(t, err)
+	assert.False(t, ok)
+
+	// batch from another backend.
+	foreign := NewMemoryDB().NewBatch()
+	foreign.Put([]byte("a3"), []byte("v4"))
+	assert.NoError(t, db.Write(foreign))
+
+	it := db.NewIterator(BytesPrefix([]byte("a")))
+	var keys []string
+	for it.Next() {
+		keys = append(keys, string(it.Key()))
+	}
+	it.Release()
+	assert.NoError(t, it.Error())
+	assert.Equal(t, []string{"a2", "a3"}, keys)
+
+	it = db.NewIterator(nil)
+	keys = nil
+	for it.Next() {
+		keys = append(keys, string(it.Key()))
+	}
+	it.Release()
+	assert.Equal(t, []string{"a2", "a3", "b1"}, keys)
+
+	// changes after the snapshot taken are not visible.
+	snapshot, err := db.NewSnapshot()
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	assert.NoError(t, db.Put([]byte("a4"), []byte("v5")))
+
+	batch.Reset()
+	assert.Equal(t, 0, batch.Len())
+	assert.NoError(t, db.Delete([]byte("b1")))
+	_, err = db.Get([]byte("b1"))
+	assert.Equal(t, ErrNotFound, err)
+
+	value, err = snapshot.Get([]byte("b1"))
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("v3"), value)
+	value, err = snapshot.GetUncached([]byte("b1"))
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("v3"), value)
+	ok, err = snapshot.Has([]byte("a4"))
+	assert.NoError(t, err)
+	assert.False(t, ok)
+	it = snapshot.NewIterator(nil)
+	keys = nil
+	for it.Next() {
+		keys = append(keys, string(it.Key()))
+	}
+	it.Release()
+	assert.Equal(t, []string{"a2", "a3", "b1"}, keys)
+	snapshot.Release()
+}
+
+func TestLevelDB(t *testing.T) {
+	dataDir := "kv_test"
+	os.RemoveAll(dataDir)
+	defer os.RemoveAll(dataDir)
+
+	db, err := OpenLevelDB(dataDir)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	defer db.Close()
+	testDB(t, db)
+}
+
+func TestMemoryDB(t *testing.T) {
+	db := NewMemoryDB()
+	testDB(t, db)
+	assert.NoError(t, db.Close())
+	_, err := db.Get([]byte("a2"))
+	assert.Error(t, err)
+}
+
+func TestBytesPrefix(t *testing.T) {
+	r := BytesPrefix([]byte{0x01, 0xff})
+	assert.Equal(t, []byte{0x02}, r.Limit)
+	r = BytesPrefix([]byte{0xff})
+	assert.Nil(t, r.Limit)
+}
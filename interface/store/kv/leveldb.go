@@ -0,0 +1,135 @@
+package kv
+
+import (
+	"github.com/syndtr/goleveldb/leveldb"
+	"github.com/syndtr/goleveldb/leveldb/opt"
+	"github.com/syndtr/goleveldb/leveldb/util"
+)
+
+// Ensure levelDB implement DB interface.
+var _ DB = (*levelDB)(nil)
+
+// dontFillCache is the read options of GetUncached.
+var dontFillCache = &opt.ReadOptions{DontFillCache: true}
+
+// levelDB is the DB implementation backed by goleveldb.
+type levelDB struct {
+	db *leveldb.DB
+}
+
+// OpenLevelDB opens or creates a LevelDB database in the given path.
+func OpenLevelDB(path string) (DB, error) {
+	db, err := leveldb.OpenFile(path, nil)
+	if err != nil {
+		return nil, err
+	}
+	return &levelDB{db: db}, nil
+}
+
+func (d *levelDB) Get(key []byte) ([]byte, error) {
+	value, err := d.db.Get(key, nil)
+	if err == leveldb.ErrNotFound {
+		return nil, ErrNotFound
+	}
+	return value, err
+}
+
+func (d *levelDB) GetUncached(key []byte) ([]byte, error) {
+	value, err := d.db.Get(key, dontFillCache)
+	if err == leveldb.ErrNotFound {
+		return nil, ErrNotFound
+	}
+	return value, err
+}
+
+func (d *levelDB) Has(key []byte) (bool, error) {
+	return d.db.Has(key, nil)
+}
+
+func (d *levelDB) Put(key, value []byte) error {
+	return d.db.Put(key, value, nil)
+}
+
+func (d *levelDB) Delete(key []byte) error {
+	return d.db.Delete(key, nil)
+}
+
+func (d *levelDB) NewIterator(r *Range) Iterator {
+	return d.db.NewIterator(levelRange(r), nil)
+}
+
+func (d *levelDB) NewSnapshot() (Snapshot, error) {
+	snapshot, err := d.db.GetSnapshot()
+	if err != nil {
+		return nil, err
+	}
+	return &levelSnapshot{snapshot: snapshot}, nil
+}
+
+func (d *levelDB) NewBatch() Batch {
+	return new(levelBatch)
+}
+
+func (d *levelDB) Write(batch Batch) error {
+	b, ok := batch.(*levelBatch)
+	if !ok {
+		b = new(levelBatch)
+		if err := batch.Replay(b); err != nil {
+			return err
+		}
+	}
+	return d.db.Write(&b.Batch, nil)
+}
+
+func (d *levelDB) Close() error {
+	return d.db.Close()
+}
+
+// levelBatch is the Batch implementation backed by goleveldb.
+type levelBatch struct {
+	leveldb.Batch
+}
+
+func (b *levelBatch) Replay(r BatchReplay) error {
+	return b.Batch.Replay(r)
+}
+
+// levelSnapshot is the Snapshot implementation backed by goleveldb.
+type levelSnapshot struct {
+	snapshot *leveldb.Snapshot
+}
+
+func (s *levelSnapshot) Get(key []byte) ([]byte, error) {
+	value, err := s.snapshot.Get(key, nil)
+	if err == leveldb.ErrNotFound {
+		return nil, ErrNotFound
+	}
+	return value, err
+}
+
+func (s *levelSnapshot) GetUncached(key []byte) ([]byte, error) {
+	value, err := s.snapshot.Get(key, dontFillCache)
+	if err == leveldb.ErrNotFound {
+		return nil, ErrNotFound
+	}
+	return value, err
+}
+
+func (s *levelSnapshot) Has(key []byte) (bool, error) {
+	return s.snapshot.Has(key, nil)
+}
+
+func (s *levelSnapshot) NewIterator(r *Range) Iterator {
+	return s.snapshot.NewIterator(levelRange(r), nil)
+}
+
+func (s *levelSnapshot) Release() {
+	s.snapshot.Release()
+}
+
+func levelRange(r *Range) *util.Range {
+	if r == nil {
+		return nil
+	}
+	return &util.Range{Start: r.Start, Limit: r.Limit}
+}
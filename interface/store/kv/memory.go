@@ -0,0 +1,271 @@
+package kv
+
+import (
+	"bytes"
+	"errors"
+	"sort"
+	"sync"
+)
+
+// Ensure memoryDB implement DB interface.
+var _ DB = (*memoryDB)(nil)
+
+// errClosed is returned when operating a closed memory database.
+var errClosed = errors.New("kv: database closed")
+
+// memoryDB is the DB implementation keeps all data in memory, it's useful for
+// tests and the embedders not need persistence.
+type memoryDB struct {
+	sync.RWMutex
+	data map[string][]byte
+}
+
+// NewMemoryDB creates an empty in-memory database.
+func NewMemoryDB() DB {
+	return &memoryDB{data: make(map[string][]byte)}
+}
+
+func (d *memoryDB) Get(key []byte) ([]byte, error) {
+	d.RLock()
+	defer d.RUnlock()
+
+	if d.data == nil {
+		return nil, errClosed
+	}
+	value, ok := d.data[string(key)]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return append([]byte{}, value...), nil
+}
+
+// GetUncached is the same as Get, memoryDB has no caches.
+func (d *memoryDB) GetUncached(key []byte) ([]byte, error) {
+	return d.Get(key)
+}
+
+func (d *memoryDB) Has(key []byte) (bool, error) {
+	d.RLock()
+	defer d.RUnlock()
+
+	if d.data == nil {
+		return false, errClosed
+	}
+	_, ok := d.data[string(key)]
+	return ok, nil
+}
+
+func (d *memoryDB) Put(key, value []byte) error {
+	d.Lock()
+	defer d.Unlock()
+
+	if d.data == nil {
+		return errClosed
+	}
+	d.data[string(key)] = append([]byte{}, value...)
+	return nil
+}
+
+func (d *memoryDB) Delete(key []byte) error {
+	d.Lock()
+	defer d.Unlock()
+
+	if d.data == nil {
+		return errClosed
+	}
+	delete(d.data, string(key))
+	return nil
+}
+
+// NewIterator returns an iterator over a snapshot of the key range, changes
+// after the iterator created will not be seen by the iterator.
+func (d *memoryDB) NewIterator(r *Range) Iterator {
+	d.RLock()
+	defer d.RUnlock()
+
+	if d.data == nil {
+		return &memoryIterator{err: errClosed}
+	}
+	it := &memoryIterator{index: -1}
+	for key, value := range d.data {
+		k := []byte(key)
+		if r != nil && r.Start != nil && bytes.Compare(k, r.Start) < 0 {
+			continue
+		}
+		if r != nil && r.Limit != nil && bytes.Compare(k, r.Limit) >= 0 {
+			continue
+		}
+		it.keys = append(it.keys, k)
+		it.values = append(it.values, value)
+	}
+	sort.Sort(it)
+	return it
+}
+
+// NewSnapshot returns a snapshot holds a copy of all data.
+func (d *memoryDB) NewSnapshot() (Snapshot, error) {
+	d.RLock()
+	defer d.RUnlock()
+
+	if d.data == nil {
+		return nil, errClosed
+	}
+	data := make(map[string][]byte, len(d.data))
+	for key, value := range d.data {
+		data[key] = value
+	}
+	return &memorySnapshot{memoryDB{data: data}}, nil
+}
+
+func (d *memoryDB) NewBatch() Batch {
+	return new(memoryBatch)
+}
+
+func (d *memoryDB) Write(batch Batch) error {
+	d.Lock()
+	defer d.Unlock()
+
+	if d.data == nil {
+		return errClosed
+	}
+	return batch.Replay(memoryWriter{d.data})
+}
+
+func (d *memoryDB) Close() error {
+	d.Lock()
+	defer d.Unlock()
+	d.data = nil
+	return nil
+}
+
+// memorySnapshot is the Snapshot implementation of memoryDB, values are never
+// modified in place so the snapshot can share them with the database.
+type memorySnapshot struct {
+	db memoryDB
+}
+
+func (s *memorySnapshot) Get(key []byte) ([]byte, error) {
+	return s.db.Get(key)
+}
+
+func (s *memorySnapshot) GetUncached(key []byte) ([]byte, error) {
+	return s.db.GetUncached(key)
+}
+
+func (s *memorySnapshot) Has(key []byte) (bool, error) {
+	return s.db.Has(key)
+}
+
+func (s *memorySnapshot) NewIterator(r *Range) Iterator {
+	return s.db.NewIterator(r)
+}
+
+func (s *memorySnapshot) Release() {
+	s.db.Close()
+}
+
+// memoryWriter applies batch operations to the data map.
+type memoryWriter struct {
+	data map[string][]byte
+}
+
+func (w memoryWriter) Put(key, value []byte) {
+	w.data[string(key)] = append([]byte{}, value...)
+}
+
+func (w memoryWriter) Delete(key []byte) {
+	delete(w.data, string(key))
+}
+
+type memoryIterator struct {
+	keys   [][]byte
+	values [][]byte
+	index  int
+	err    error
+}
+
+func (it *memoryIterator) Len() int {
+	return len(it.keys)
+}
+
+func (it *memoryIterator) Less(i, j int) bool {
+	return bytes.Compare(it.keys[i], it.keys[j]) < 0
+}
+
+func (it *memoryIterator) Swap(i, j int) {
+	it.keys[i], it.keys[j] = it.keys[j], it.keys[i]
+	it.values[i], it.values[j] = it.values[j], it.values[i]
+}
+
+func (it *memoryIterator) Next() bool {
+	if it.index+1 >= len(it.keys) {
+		it.index = len(it.keys)
+		return false
+	}
+	it.index++
+	return true
+}
+
+func (it *memoryIterator) Key() []byte {
+	if it.index < 0 || it.index >= len(it.keys) {
+		return nil
+	}
+	return it.keys[it.index]
+}
+
+func (it *memoryIterator) Value() []byte {
+	if it.index < 0 || it.index >= len(it.values) {
+		return nil
+	}
+	return it.values[it.index]
+}
+
+func (it *memoryIterator) Release() {
+	it.keys = nil
+	it.values = nil
+}
+
+func (it *memoryIterator) Error() error {
+	return it.err
+}
+
+type batchOp struct {
+	key    []byte
+	value  []byte
+	delete bool
+}
+
+// memoryBatch is the Batch implementation records operations in memory.
+type memoryBatch struct {
+	ops []batchOp
+}
+
+func (b *memoryBatch) Put(key, value []byte) {
+	b.ops = append(b.ops, batchOp{
+		key:   append([]byte{}, key...),
+		value: append([]byte{}, value...),
+	})
+}
+
+func (b *memoryBatch) Delete(key []byte) {
+	b.ops = append(b.ops, batchOp{key: append([]byte{}, key...), delete: true})
+}
+
+func (b *memoryBatch) Len() int {
+	return len(b.ops)
+}
+
+func (b *memoryBatch) Reset() {
+	b.ops = b.ops[:0]
+}
+
+func (b *memoryBatch) Replay(r BatchReplay) error {
+	for _, op := range b.ops {
+		if op.delete {
+			r.Delete(op.key)
+		} else {
+			r.Put(op.key, op.value)
+		}
+	}
+	return nil
+}
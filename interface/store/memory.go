@@ -0,0 +1,20 @@
+package store
+
+import (
+	"github.com/elastos/Elastos.ELA.SPV/interface/store/kv"
+	"github.com/elastos/Elastos.ELA.SPV/util"
+)
+
+// NewMemoryHeaderStore creates a header store keeps all headers in memory,
+// headers will be lost after the store closed.
+func NewMemoryHeaderStore(newHeader func() util.BlockHeader) (*headers, error) {
+	return NewHeaderStoreWithDB(kv.NewMemoryDB(), newHeader)
+}
+
+// NewMemoryDataStore creates a data store keeps all data in memory, data will
+// be lost after the store closed.
+func NewMemoryDataStore(originArbiters [][]byte, arbitersCount int,
+	GenesisBlockAddress string) (*dataStore, error) {
+	return NewDataStoreWithDB(kv.NewMemoryDB(), originArbiters, arbitersCount,
+		GenesisBlockAddress)
+}
@@ -0,0 +1,302 @@
+package store
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/elastos/Elastos.ELA.SPV/interface/store/kv"
+	"github.com/elastos/Elastos.ELA.SPV/util"
+)
+
+// copyBatchSize is the number of entries written in one batch when copying
+// a database.
+const copyBatchSize = 10000
+
+var (
+	// ErrSchemaOutdated indicates the database is created by an older version
+	// and must be migrated before use.
+	ErrSchemaOutdated = errors.New("database schema outdated, migration required")
+
+	// ErrSchemaTooNew indicates the database is created by a newer version
+	// which is not supported.
+	ErrSchemaTooNew = errors.New("database schema is newer than supported")
+)
+
+// Migration upgrades a database from the previous schema version to Version.
+// Migrate puts all changes into batch, the batch and the new version will be
+// written to the database together.
+type Migration struct {
+	Version     uint32
+	Description string
+	Migrate     func(db kv.DB, batch kv.Batch) error
+}
+
+// MigrateOptions are the options to upgrade a database.
+type MigrateOptions struct {
+	// DryRun applies migrations on a copy of the database in memory, so the
+	// result can be checked without changing the database.
+	DryRun bool
+
+	// BackupDir is the directory to back up the database before migrate, it
+	// must not exist. No backup will be made if it's empty.
+	BackupDir string
+}
+
+// MigrateResult is the result of a database upgrade.
+type MigrateResult struct {
+	// From is the schema version before migrate.
+	From uint32
+
+	// To is the schema version after migrate.
+	To uint32
+
+	// Applied is the description of migrations applied.
+	Applied []string
+
+	// Changes is the number of entries put or deleted by migrations.
+	Changes int
+}
+
+func (r *MigrateResult) String() string {
+	return fmt.Sprintf("schema version %d to %d, %d migrations applied, %d changes",
+		r.From, r.To, len(r.Applied), r.Changes)
+}
+
+// headerMigrations returns the migrations of the header store.
+func headerMigrations(newHeader func() util.BlockHeader) []Migration {
+	return []Migration{
+		{
+			Version:     1,
+			Description: "rebuild best chain height index",
+			Migrate: func(db kv.DB, batch kv.Batch) error {
+				return rebuildHeaderIndexes(db, batch, newHeader)
+			},
+		},
+	}
+}
+
+// dataMigrations are the migrations of the data store.
+var dataMigrations = []Migration{
+	{
+		Version:     1,
+		Description: "add schema version",
+		Migrate: func(db kv.DB, batch kv.Batch) error {
+			return nil
+		},
+	},
+	{
+		Version:     2,
+		Description: "build address transaction history",
+		Migrate:     buildAddrTxs,
+	},
+}
+
+// latestVersion returns the schema version after all migrations applied.
+func latestVersion(migrations []Migration) uint32 {
+	if len(migrations) == 0 {
+		return 0
+	}
+	return migrations[len(migrations)-1].Version
+}
+
+// GetSchemaVersion returns the schema version of the database, empty is true
+// if there is no data in the database. Databases created before versioning
+// introduced are version 0.
+func GetSchemaVersion(db kv.DB) (version uint32, empty bool, err error) {
+	data, err := db.Get(BKTSchemaVersion)
+	switch err {
+	case nil:
+		if len(data) != 4 {
+			return 0, false, errors.New("invalid schema version")
+		}
+		return binary.BigEndian.Uint32(data), false, nil
+	case kv.ErrNotFound:
+	default:
+		return 0, false, err
+	}
+
+	it := db.NewIterator(nil)
+	defer it.Release()
+	return 0, !it.Next(), it.Error()
+}
+
+func putSchemaVersion(batch kv.Batch, version uint32) {
+	var data [4]byte
+	binary.BigEndian.PutUint32(data[:], version)
+	batch.Put(BKTSchemaVersion, data[:])
+}
+
+// checkSchema makes sure the database is on the latest schema version, an
+// empty database will be marked as the latest version.
+func checkSchema(db kv.DB, migrations []Migration) error {
+	latest := latestVersion(migrations)
+	version, empty, err := GetSchemaVersion(db)
+	if err != nil {
+		return err
+	}
+	switch {
+	case empty:
+		batch := db.NewBatch()
+		putSchemaVersion(batch, latest)
+		return db.Write(batch)
+	case version < latest:
+		return ErrSchemaOutdated
+	case version > latest:
+		return ErrSchemaTooNew
+	}
+	return nil
+}
+
+// Migrate upgrades the database to the latest version by applying migrations
+// in order. Each migration is written together with its version, so an
+// interrupted upgrade continues from the last finished migration.
+func Migrate(db kv.DB, migrations []Migration, opts MigrateOptions) (*MigrateResult, error) {
+	latest := latestVersion(migrations)
+	version, empty, err := GetSchemaVersion(db)
+	if err != nil {
+		return nil, err
+	}
+	result := &MigrateResult{From: version, To: version}
+	if empty {
+		result.From, result.To = latest, latest
+		if opts.DryRun {
+			return result, nil
+		}
+		return result, checkSchema(db, migrations)
+	}
+	if version > latest {
+		return nil, ErrSchemaTooNew
+	}
+	if version == latest {
+		return result, nil
+	}
+
+	target := db
+	if opts.DryRun {
+		target = kv.NewMemoryDB()
+		defer target.Close()
+		if err := copyDB(target, db); err != nil {
+			return nil, err
+		}
+	} else if len(opts.BackupDir) > 0 {
+		if err := backupDB(db, opts.BackupDir); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, m := range migrations {
+		if m.Version <= version {
+			continue
+		}
+		batch := target.NewBatch()
+		if err := m.Migrate(target, batch); err != nil {
+			return result, fmt.Errorf("migrate to version %d failed, %s",
+				m.Version, err)
+		}
+		result.Changes += batch.Len()
+		putSchemaVersion(batch, m.Version)
+		if err := target.Write(batch); err != nil {
+			return result, err
+		}
+		result.To = m.Version
+		result.Applied = append(result.Applied, m.Description)
+	}
+	return result, nil
+}
+
+// MigrateHeaderStore upgrades the header store in dataDir to the latest
+// schema version.
+func MigrateHeaderStore(dataDir string, newHeader func() util.BlockHeader,
+	opts MigrateOptions) (*MigrateResult, error) {
+	return migrateDir(filepath.Join(dataDir, "header"),
+		headerMigrations(newHeader), opts)
+}
+
+// MigrateDataStore upgrades the data store in dataDir to the latest schema
+// version.
+func MigrateDataStore(dataDir string, opts MigrateOptions) (*MigrateResult, error) {
+	return migrateDir(filepath.Join(dataDir, "store"), dataMigrations, opts)
+}
+
+func migrateDir(path string, migrations []Migration, opts MigrateOptions) (*MigrateResult, error) {
+	db, err := kv.OpenLevelDB(path)
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+	return Migrate(db, migrations, opts)
+}
+
+// backupDB copies all data of db into a new LevelDB database in dir.
+func backupDB(db kv.DB, dir string) error {
+	if _, err := os.Stat(dir); !os.IsNotExist(err) {
+		return fmt.Errorf("backup directory %s already exists", dir)
+	}
+	backup, err := kv.OpenLevelDB(dir)
+	if err != nil {
+		return err
+	}
+	defer backup.Close()
+	return copyDB(backup, db)
+}
+
+// copyDB copies all data from src into dst.
+func copyDB(dst, src kv.DB) error {
+	it := src.NewIterator(nil)
+	defer it.Release()
+
+	batch := dst.NewBatch()
+	for it.Next() {
+		batch.Put(it.Key(), it.Value())
+		if batch.Len() >= copyBatchSize {
+			if err := dst.Write(batch); err != nil {
+				return err
+			}
+			batch.Reset()
+		}
+	}
+	if err := it.Error(); err != nil {
+		return err
+	}
+	return dst.Write(batch)
+}
+
+// rebuildHeaderIndexes removes all height indexes and indexes the best chain
+// from the chain tip. Databases before version 1 indexed fork headers too.
+func rebuildHeaderIndexes(db kv.DB, batch kv.Batch,
+	newHeader func() util.BlockHeader) error {
+	it := db.NewIterator(kv.BytesPrefix(BKTIndexes))
+	for it.Next() {
+		batch.Delete(append([]byte{}, it.Key()...))
+	}
+	it.Release()
+	if err := it.Error(); err != nil {
+		return err
+	}
+
+	data, err := db.Get(BKTChainTip)
+	if err == kv.ErrNotFound {
+		return nil
+	}
+	for err == nil {
+		header := util.Header{BlockHeader: newHeader()}
+		if err := header.Deserialize(data); err != nil {
+			return err
+		}
+		hash := header.Hash()
+		batch.Put(indexKey(header.Height), hash.Bytes())
+		if header.Height == 0 {
+			break
+		}
+		previous := header.Previous()
+		data, err = db.Get(toKey(BKTHeaders, previous.Bytes()...))
+	}
+	// Headers before the sync start point are not stored.
+	if err != nil && err != kv.ErrNotFound {
+		return err
+	}
+	return nil
+}
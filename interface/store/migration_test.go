@@ -0,0 +1,316 @@
+package store
+
+import (
+	"bytes"
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/elastos/Elastos.ELA.SPV/interface/store/kv"
+	"github.com/elastos/Elastos.ELA.SPV/util"
+
+	"github.com/elastos/Elastos.ELA/common"
+	elacommon "github.com/elastos/Elastos.ELA/core/types/common"
+	it "github.com/elastos/Elastos.ELA/core/types/interfaces"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// putHeaderV0 writes a header in the layout of schema version 0, the height
+// index is written for fork headers too.
+func putHeaderV0(t *testing.T, db kv.DB, header *util.Header, newTip bool) {
+	data, err := header.Serialize()
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	hash := header.Hash()
+	assert.NoError(t, db.Put(toKey(BKTHeaders, hash.Bytes()...), data))
+	assert.NoError(t, db.Put(indexKey(header.Height), hash.Bytes()))
+	if newTip {
+		assert.NoError(t, db.Put(BKTChainTip, data))
+	}
+}
+
+// headersFixtureV0 creates a header store of schema version 0 with main chain
+// from height 0 to 5, and a fork header on height 4 polluted the index.
+func headersFixtureV0(t *testing.T, dataDir string) []*util.Header {
+	db, err := kv.OpenLevelDB(filepath.Join(dataDir, "header"))
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	defer db.Close()
+
+	var chain []*util.Header
+	var previous *util.Header
+	for i := uint32(0); i <= 5; i++ {
+		header := newHeader(previous, 1000+i*10, 0)
+		putHeaderV0(t, db, header, true)
+		chain = append(chain, header)
+		previous = header
+	}
+	putHeaderV0(t, db, newHeader(chain[3], 1040, 1), false)
+	return chain
+}
+
+// dataFixtureV0 creates a data store of schema version 0.
+func dataFixtureV0(t *testing.T, dataDir string) {
+	db, err := kv.OpenLevelDB(filepath.Join(dataDir, "store"))
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	defer db.Close()
+	assert.NoError(t, db.Put(toKey(BKTTxTypes, 1), []byte{1}))
+}
+
+// putTxV1 writes a transaction in the layout of schema version 1.
+func putTxV1(t *testing.T, db kv.DB, tx it.Transaction, height uint32) {
+	buf := new(bytes.Buffer)
+	assert.NoError(t, tx.Serialize(buf))
+	utx := util.Tx{
+		Hash:      tx.Hash(),
+		Height:    height,
+		Timestamp: time.Unix(0, 0),
+		RawData:   buf.Bytes(),
+	}
+	buf = new(bytes.Buffer)
+	assert.NoError(t, utx.Serialize(buf))
+	assert.NoError(t, db.Put(toKey(BKTTxs, utx.Hash.Bytes()...), buf.Bytes()))
+
+	var key [4]byte
+	binary.BigEndian.PutUint32(key[:], height)
+	data, _ := db.Get(toKey(BKTHeightTxs, key[:]...))
+	assert.NoError(t, db.Put(toKey(BKTHeightTxs, key[:]...), putTxId(data, &utx.Hash)))
+}
+
+// dataFixtureV1 creates a data store of schema version 1 with transactions
+// and outpoints but no address history.  tx1 pays 10 to the watched address,
+// tx2 spends it with 3 change.
+func dataFixtureV1(t *testing.T, dataDir string) (watched common.Uint168,
+	tx1, tx2 it.Transaction) {
+	db, err := kv.OpenLevelDB(filepath.Join(dataDir, "store"))
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	defer db.Close()
+
+	watched = common.Uint168{0x21, 1}
+	other := common.Uint168{0x21, 2}
+	tx1 = newTransferTx(nil, []*elacommon.Output{
+		{ProgramHash: watched, Value: 10},
+		{ProgramHash: other, Value: 5},
+	})
+	tx2 = newTransferTx([]*elacommon.Input{{
+		Previous: *elacommon.NewOutPoint(tx1.Hash(), 0),
+	}}, []*elacommon.Output{
+		{ProgramHash: other, Value: 6},
+		{ProgramHash: watched, Value: 3},
+	})
+	putTxV1(t, db, tx1, 100)
+	putTxV1(t, db, tx2, 101)
+	for _, op := range []*util.OutPoint{
+		util.NewOutPoint(tx1.Hash(), 0),
+		util.NewOutPoint(tx2.Hash(), 1),
+	} {
+		assert.NoError(t, db.Put(toKey(BKTOps, op.Bytes()...), watched.Bytes()))
+	}
+
+	batch := db.NewBatch()
+	putSchemaVersion(batch, 1)
+	assert.NoError(t, db.Write(batch))
+	return watched, tx1, tx2
+}
+
+func TestMigrateHeaderStore(t *testing.T) {
+	dataDir := "spv_test"
+	os.RemoveAll(dataDir)
+	defer os.RemoveAll(dataDir)
+
+	chain := headersFixtureV0(t, dataDir)
+	_, err := NewHeaderStore(dataDir, newEmptyHeader)
+	assert.Equal(t, ErrSchemaOutdated, err)
+
+	// dry run reports the migrations without changing the database.
+	result, err := MigrateHeaderStore(dataDir, newEmptyHeader,
+		MigrateOptions{DryRun: true})
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	assert.Equal(t, uint32(0), result.From)
+	assert.Equal(t, uint32(1), result.To)
+	assert.Equal(t, 1, len(result.Applied))
+	_, err = NewHeaderStore(dataDir, newEmptyHeader)
+	assert.Equal(t, ErrSchemaOutdated, err)
+
+	// migrate with backup.
+	backupDir := filepath.Join(dataDir, "backup")
+	result, err = MigrateHeaderStore(dataDir, newEmptyHeader,
+		MigrateOptions{BackupDir: backupDir})
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	assert.Equal(t, uint32(1), result.To)
+	_, err = MigrateHeaderStore(dataDir, newEmptyHeader,
+		MigrateOptions{BackupDir: backupDir})
+	assert.NoError(t, err)
+
+	backup, err := kv.OpenLevelDB(backupDir)
+	if assert.NoError(t, err) {
+		version, empty, err := GetSchemaVersion(backup)
+		assert.NoError(t, err)
+		assert.False(t, empty)
+		assert.Equal(t, uint32(0), version)
+		backup.Close()
+	}
+
+	headers, err := NewHeaderStore(dataDir, newEmptyHeader)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	defer headers.Close()
+	result2, err := headers.GetRange(0, 5)
+	assert.NoError(t, err)
+	if assert.Equal(t, 6, len(result2)) {
+		for i, header := range result2 {
+			assert.Equal(t, chain[i].Hash(), header.Hash())
+		}
+	}
+}
+
+func TestMigrateDataStore(t *testing.T) {
+	dataDir := "spv_test"
+	os.RemoveAll(dataDir)
+	defer os.RemoveAll(dataDir)
+
+	dataFixtureV0(t, dataDir)
+	_, err := NewDataStore(dataDir, nil, 36, "")
+	assert.Equal(t, ErrSchemaOutdated, err)
+
+	result, err := MigrateDataStore(dataDir, MigrateOptions{})
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	assert.Equal(t, uint32(0), result.From)
+	assert.Equal(t, uint32(2), result.To)
+
+	db, err := NewDataStore(dataDir, nil, 36, "")
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	assert.Equal(t, []uint8{1}, db.TxTypes().GetAll())
+
+	// schema version is kept after clear.
+	assert.NoError(t, db.Clear())
+	db.Close()
+	db, err = NewDataStore(dataDir, nil, 36, "")
+	if assert.NoError(t, err) {
+		db.Close()
+	}
+}
+
+func TestMigrate(t *testing.T) {
+	migrations := []Migration{
+		{Version: 1, Description: "v1", Migrate: func(db kv.DB, batch kv.Batch) error {
+			batch.Put([]byte("v1"), empty)
+			return nil
+		}},
+		{Version: 2, Description: "v2", Migrate: func(db kv.DB, batch kv.Batch) error {
+			// changes of previous migrations are visible.
+			ok, err := db.Has([]byte("v1"))
+			if err != nil || !ok {
+				return kv.ErrNotFound
+			}
+			batch.Put([]byte("v2"), empty)
+			return nil
+		}},
+	}
+
+	// empty database is marked as the latest version.
+	db := kv.NewMemoryDB()
+	result, err := Migrate(db, migrations, MigrateOptions{})
+	assert.NoError(t, err)
+	assert.Equal(t, uint32(2), result.From)
+	version, _, _ := GetSchemaVersion(db)
+	assert.Equal(t, uint32(2), version)
+
+	// continue from version 1.
+	db = kv.NewMemoryDB()
+	batch := db.NewBatch()
+	batch.Put([]byte("v1"), empty)
+	putSchemaVersion(batch, 1)
+	assert.NoError(t, db.Write(batch))
+	result, err = Migrate(db, migrations, MigrateOptions{})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"v2"}, result.Applied)
+	assert.Equal(t, 1, result.Changes)
+
+	// newer version is not supported.
+	_, err = Migrate(db, migrations[:1], MigrateOptions{})
+	assert.Equal(t, ErrSchemaTooNew, err)
+	assert.Equal(t, ErrSchemaTooNew, checkSchema(db, migrations[:1]))
+}
+
+func TestMigrateDataStoreV1(t *testing.T) {
+	dataDir := "spv_test"
+	os.RemoveAll(dataDir)
+	defer os.RemoveAll(dataDir)
+
+	watched, tx1, tx2 := dataFixtureV1(t, dataDir)
+	_, err := NewDataStore(dataDir, nil, 36, "")
+	assert.Equal(t, ErrSchemaOutdated, err)
+
+	result, err := MigrateDataStore(dataDir, MigrateOptions{})
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	assert.Equal(t, uint32(1), result.From)
+	assert.Equal(t, uint32(2), result.To)
+	assert.Equal(t, []string{"build address transaction history"}, result.Applied)
+
+	// history records are keyed by address, height and transaction hash.
+	db, err := kv.OpenLevelDB(filepath.Join(dataDir, "store"))
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	addrTxKey := func(height uint32, txHash common.Uint256) []byte {
+		var key [4]byte
+		binary.BigEndian.PutUint32(key[:], height)
+		return append(append(toKey(BKTAddrTxs, watched[:]...), key[:]...),
+			txHash[:]...)
+	}
+	var keys [][]byte
+	iter := db.NewIterator(kv.BytesPrefix(BKTAddrTxs))
+	for iter.Next() {
+		keys = append(keys, append([]byte{}, iter.Key()...))
+	}
+	iter.Release()
+	assert.NoError(t, iter.Error())
+	assert.Equal(t, [][]byte{
+		addrTxKey(100, tx1.Hash()),
+		addrTxKey(101, tx2.Hash()),
+	}, keys)
+	db.Close()
+
+	data, err := NewDataStore(dataDir, nil, 36, "")
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	defer data.Close()
+	records, _, err := data.AddrTxs().List(watched, 0, 200, 0, "")
+	assert.NoError(t, err)
+	assert.Equal(t, []*AddressTx{{
+		Address:   watched,
+		TxHash:    tx1.Hash(),
+		Height:    100,
+		Direction: TxReceived,
+		Received:  10,
+	}, {
+		Address:   watched,
+		TxHash:    tx2.Hash(),
+		Height:    101,
+		Direction: TxSent | TxReceived,
+		Sent:      10,
+		Received:  3,
+	}}, records)
+}
@@ -3,10 +3,10 @@ package store
 import (
 	"sync"
 
+	"github.com/elastos/Elastos.ELA.SPV/interface/store/kv"
 	"github.com/elastos/Elastos.ELA.SPV/util"
 
 	"github.com/elastos/Elastos.ELA/common"
-	"github.com/syndtr/goleveldb/leveldb"
 )
 
 // Ensure opsBatch implement OpsBatch interface.
@@ -14,8 +14,8 @@ var _ OpsBatch = (*opsBatch)(nil)
 
 type opsBatch struct {
 	sync.Mutex
-	*leveldb.DB
-	*leveldb.Batch
+	kv.DB
+	kv.Batch
 }
 
 func (b *opsBatch) Put(op *util.OutPoint, addr common.Uint168) error {
@@ -25,6 +25,19 @@ func (b *opsBatch) Put(op *util.OutPoint, addr common.Uint168) error {
 	return nil
 }
 
+func (b *opsBatch) HaveOp(op *util.OutPoint) (*common.Uint168, error) {
+	b.Lock()
+	defer b.Unlock()
+	addrBytes, err := batchGet(b.DB, b.Batch, toKey(BKTOps, op.Bytes()...))
+	if err == kv.ErrNotFound {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return common.Uint168FromBytes(addrBytes)
+}
+
 func (b *opsBatch) Del(op *util.OutPoint) error {
 	b.Lock()
 	defer b.Unlock()
@@ -38,5 +51,5 @@ func (b *opsBatch) Rollback() error {
 }
 
 func (b *opsBatch) Commit() error {
-	return b.DB.Write(b.Batch, nil)
+	return b.DB.Write(b.Batch)
 }
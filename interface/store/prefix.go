@@ -1,6 +1,9 @@
 package store
 
 var (
+	// schema version
+	BKTSchemaVersion = []byte("schemaversion")
+
 	// addresses
 	BKTAddrs = []byte("addrs")
 
@@ -12,6 +15,9 @@ var (
 	BKTIndexes  = []byte("indexes")
 	BKTChainTip = []byte("chaintip")
 
+	// the height headers below have been compacted
+	BKTCompactHeight = []byte("compactheight")
+
 	// ops
 	BKTOps = []byte("ops")
 
@@ -34,6 +40,7 @@ var (
 	// revert to pow
 	BKTRevertPosition  = []byte("revertp")
 	BKTRevertPositions = []byte("revertps")
+	BKTRevertTxs       = []byte("reverttxs")
 
 	//ReturnSideChainDepositCoin
 	BKTReturnSideChainDepositCoin = []byte("retschdepositcoin")
@@ -42,4 +49,43 @@ var (
 	BKTReceivedCustomID     = []byte("rccid")
 	BKTChangeCustomIDFee    = []byte("ccidf")
 	BKTCustomIDFeePositions = []byte("cidfps")
+	BKTCustomIDFeeProposals = []byte("cidfpp")
+	BKTCustomIDRecords      = []byte("cidrecord")
+	BKTCustomIDOwners       = []byte("cidowner")
+	BKTCustomIDUndo         = []byte("cidundo")
+
+	// CR proposals
+	BKTProposals       = []byte("prps")
+	BKTProposalHeights = []byte("prpheight")
+	BKTProposalResults = []byte("prpresult")
+
+	// cross chain deposits
+	BKTDeposits       = []byte("ccdeposit")
+	BKTDepositTxs     = []byte("ccdeptx")
+	BKTDepositHeights = []byte("ccdepheight")
+
+	// address transaction history
+	BKTAddrTxs       = []byte("addrtxs")
+	BKTAddrTxHeights = []byte("addrtxheight")
+
+	// unspent and spent outputs
+	BKTUTXOs       = []byte("utxos")
+	BKTAddrUTXOs   = []byte("addrutxos")
+	BKTUTXOHeights = []byte("utxoheight")
+	BKTSTXOs       = []byte("stxos")
+	BKTSTXOHeights = []byte("stxoheight")
+
+	// producers
+	BKTProducers     = []byte("producers")
+	BKTProducerNodes = []byte("prdnodes")
+	BKTProducerUndo  = []byte("prdundo")
 )
+
+// joinKey returns a new key of the bucket followed by the fields.
+func joinKey(bucket []byte, fields ...[]byte) []byte {
+	key := append([]byte{}, bucket...)
+	for _, f := range fields {
+		key = append(key, f...)
+	}
+	return key
+}
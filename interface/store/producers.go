@@ -0,0 +1,481 @@
+package store
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"sync"
+
+	"github.com/elastos/Elastos.ELA.SPV/interface/store/kv"
+
+	"github.com/elastos/Elastos.ELA/common"
+	"github.com/elastos/Elastos.ELA/core/contract"
+	elacommon "github.com/elastos/Elastos.ELA/core/types/common"
+	it "github.com/elastos/Elastos.ELA/core/types/interfaces"
+	"github.com/elastos/Elastos.ELA/core/types/payload"
+)
+
+// Ensure producers implement Producers interface.
+var _ Producers = (*producers)(nil)
+
+type ProducerState byte
+
+const (
+	// ProducerPending indicates the producer registered but not confirmed.
+	ProducerPending ProducerState = 0x00
+
+	// ProducerActive indicates the producer is active to be elected.
+	ProducerActive ProducerState = 0x01
+
+	// ProducerInactive indicates the producer has been set inactive by the
+	// InactiveArbitrators transaction.
+	ProducerInactive ProducerState = 0x02
+
+	// ProducerCanceled indicates the producer has been canceled by it's owner.
+	ProducerCanceled ProducerState = 0x03
+
+	// ProducerIllegal indicates the producer has been punished by the
+	// illegal evidence.
+	ProducerIllegal ProducerState = 0x04
+)
+
+func (s ProducerState) String() string {
+	switch s {
+	case ProducerPending:
+		return "Pending"
+	case ProducerActive:
+		return "Active"
+	case ProducerInactive:
+		return "Inactive"
+	case ProducerCanceled:
+		return "Canceled"
+	case ProducerIllegal:
+		return "Illegal"
+	default:
+		return "Unknown"
+	}
+}
+
+// ProducerInfo is the registered producer tracked from main chain.
+type ProducerInfo struct {
+	OwnerKey       []byte
+	NodeKey        []byte
+	NickName       string
+	URL            string
+	Location       uint64
+	NetAddress     string
+	StakeUntil     uint32
+	DepositAddress string
+
+	State ProducerState
+
+	// RegisterHeight is the height the RegisterProducer transaction packed
+	// in, and StateHeight is the height of the last state change.
+	RegisterHeight uint32
+	StateHeight    uint32
+}
+
+// StateAt returns the state of the producer on the given height, the
+// pending producer will be active after DefaultConfirmations.
+func (p *ProducerInfo) StateAt(height uint32) ProducerState {
+	if p.State == ProducerPending &&
+		height >= p.RegisterHeight+DefaultConfirmations {
+		return ProducerActive
+	}
+	return p.State
+}
+
+func (p *ProducerInfo) Serialize(w io.Writer) error {
+	if err := common.WriteVarBytes(w, p.OwnerKey); err != nil {
+		return err
+	}
+	if err := common.WriteVarBytes(w, p.NodeKey); err != nil {
+		return err
+	}
+	if err := common.WriteVarString(w, p.NickName); err != nil {
+		return err
+	}
+	if err := common.WriteVarString(w, p.URL); err != nil {
+		return err
+	}
+	if err := common.WriteUint64(w, p.Location); err != nil {
+		return err
+	}
+	if err := common.WriteVarString(w, p.NetAddress); err != nil {
+		return err
+	}
+	if err := common.WriteUint32(w, p.StakeUntil); err != nil {
+		return err
+	}
+	if err := common.WriteVarString(w, p.DepositAddress); err != nil {
+		return err
+	}
+	if err := common.WriteUint8(w, byte(p.State)); err != nil {
+		return err
+	}
+	if err := common.WriteUint32(w, p.RegisterHeight); err != nil {
+		return err
+	}
+	return common.WriteUint32(w, p.StateHeight)
+}
+
+func (p *ProducerInfo) Deserialize(r io.Reader) error {
+	var err error
+	if p.OwnerKey, err = common.ReadVarBytes(r, maxProducerKeySize,
+		"producer owner key"); err != nil {
+		return err
+	}
+	if p.NodeKey, err = common.ReadVarBytes(r, maxProducerKeySize,
+		"producer node key"); err != nil {
+		return err
+	}
+	if p.NickName, err = common.ReadVarString(r); err != nil {
+		return err
+	}
+	if p.URL, err = common.ReadVarString(r); err != nil {
+		return err
+	}
+	if p.Location, err = common.ReadUint64(r); err != nil {
+		return err
+	}
+	if p.NetAddress, err = common.ReadVarString(r); err != nil {
+		return err
+	}
+	if p.StakeUntil, err = common.ReadUint32(r); err != nil {
+		return err
+	}
+	if p.DepositAddress, err = common.ReadVarString(r); err != nil {
+		return err
+	}
+	state, err := common.ReadUint8(r)
+	if err != nil {
+		return err
+	}
+	p.State = ProducerState(state)
+	if p.RegisterHeight, err = common.ReadUint32(r); err != nil {
+		return err
+	}
+	p.StateHeight, err = common.ReadUint32(r)
+	return err
+}
+
+// maxProducerKeySize is the max size of producer keys, the owner key can be
+// a multi-sign redeem script.
+const maxProducerKeySize = 4096
+
+type producers struct {
+	sync.RWMutex
+	db kv.DB
+}
+
+func NewProducers(db kv.DB) *producers {
+	return &producers{db: db}
+}
+
+// BatchPutTx updates producer registry by the producer related transaction,
+// other transactions will be ignored.
+func (p *producers) BatchPutTx(tx it.Transaction, height uint32, batch kv.Batch) error {
+	p.Lock()
+	defer p.Unlock()
+
+	switch tx.TxType() {
+	case elacommon.RegisterProducer:
+		info, ok := tx.Payload().(*payload.ProducerInfo)
+		if !ok {
+			return errors.New("invalid RegisterProducer tx")
+		}
+		depositAddress, err := getDepositAddress(info.OwnerKey)
+		if err != nil {
+			return err
+		}
+		return p.batchPut(&ProducerInfo{
+			OwnerKey:       info.OwnerKey,
+			NodeKey:        info.NodePublicKey,
+			NickName:       info.NickName,
+			URL:            info.Url,
+			Location:       info.Location,
+			NetAddress:     info.NetAddress,
+			StakeUntil:     info.StakeUntil,
+			DepositAddress: depositAddress,
+			State:          ProducerPending,
+			RegisterHeight: height,
+			StateHeight:    height,
+		}, height, batch)
+
+	case elacommon.UpdateProducer:
+		info, ok := tx.Payload().(*payload.ProducerInfo)
+		if !ok {
+			return errors.New("invalid UpdateProducer tx")
+		}
+		producer, err := p.batchGet(toKey(BKTProducers, info.OwnerKey...), batch)
+		if err == kv.ErrNotFound {
+			// producer registered before the store created.
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		producer.NodeKey = info.NodePublicKey
+		producer.NickName = info.NickName
+		producer.URL = info.Url
+		producer.Location = info.Location
+		producer.NetAddress = info.NetAddress
+		producer.StakeUntil = info.StakeUntil
+		return p.batchPut(producer, height, batch)
+
+	case elacommon.CancelProducer:
+		processProducer, ok := tx.Payload().(*payload.ProcessProducer)
+		if !ok {
+			return errors.New("invalid CancelProducer tx")
+		}
+		return p.batchSetState(toKey(BKTProducers, processProducer.OwnerKey...),
+			ProducerCanceled, height, batch)
+
+	case elacommon.ActivateProducer:
+		activate, ok := tx.Payload().(*payload.ActivateProducer)
+		if !ok {
+			return errors.New("invalid ActivateProducer tx")
+		}
+		return p.batchSetStateByNodeKey(activate.NodePublicKey,
+			ProducerActive, height, batch)
+
+	case elacommon.InactiveArbitrators:
+		inactive, ok := tx.Payload().(*payload.InactiveArbitrators)
+		if !ok {
+			return errors.New("invalid InactiveArbitrators tx")
+		}
+		for _, nodeKey := range inactive.Arbitrators {
+			err := p.batchSetStateByNodeKey(nodeKey, ProducerInactive, height, batch)
+			if err != nil {
+				return err
+			}
+		}
+
+	case elacommon.IllegalProposalEvidence:
+		evidence, ok := tx.Payload().(*payload.DPOSIllegalProposals)
+		if !ok {
+			return errors.New("invalid IllegalProposalEvidence tx")
+		}
+		return p.batchSetStateByNodeKey(evidence.Evidence.Proposal.Sponsor,
+			ProducerIllegal, height, batch)
+
+	case elacommon.IllegalVoteEvidence:
+		evidence, ok := tx.Payload().(*payload.DPOSIllegalVotes)
+		if !ok {
+			return errors.New("invalid IllegalVoteEvidence tx")
+		}
+		return p.batchSetStateByNodeKey(evidence.Evidence.Vote.Signer,
+			ProducerIllegal, height, batch)
+
+	case elacommon.IllegalBlockEvidence:
+		evidence, ok := tx.Payload().(*payload.DPOSIllegalBlocks)
+		if !ok {
+			return errors.New("invalid IllegalBlockEvidence tx")
+		}
+		// Signers confirmed both blocks are illegal.
+		for _, signer := range evidence.Evidence.Signers {
+			for _, compare := range evidence.CompareEvidence.Signers {
+				if !bytes.Equal(signer, compare) {
+					continue
+				}
+				err := p.batchSetStateByNodeKey(signer, ProducerIllegal, height, batch)
+				if err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// BatchDeleteAll restores producers changed on the given height.
+func (p *producers) BatchDeleteAll(height uint32, batch kv.Batch) error {
+	p.Lock()
+	defer p.Unlock()
+
+	var key [4]byte
+	binary.BigEndian.PutUint32(key[:], height)
+	prefix := toKey(BKTProducerUndo, key[:]...)
+	it := p.db.NewIterator(kv.BytesPrefix(prefix))
+	defer it.Release()
+	for it.Next() {
+		producerKey := toKey(BKTProducers, subKey(prefix, it.Key())...)
+		current, err := p.batchGet(producerKey, batch)
+		if err == nil {
+			batch.Delete(toKey(BKTProducerNodes, current.NodeKey...))
+		}
+
+		if len(it.Value()) == 0 {
+			batch.Delete(producerKey)
+		} else {
+			var previous ProducerInfo
+			err := previous.Deserialize(bytes.NewReader(it.Value()))
+			if err != nil {
+				return err
+			}
+			batch.Put(producerKey, it.Value())
+			batch.Put(toKey(BKTProducerNodes, previous.NodeKey...), previous.OwnerKey)
+		}
+		batch.Delete(it.Key())
+	}
+	return it.Error()
+}
+
+func (p *producers) Get(ownerKey []byte) (*ProducerInfo, error) {
+	p.RLock()
+	defer p.RUnlock()
+	return p.get(toKey(BKTProducers, ownerKey...))
+}
+
+func (p *producers) GetByNodeKey(nodeKey []byte) (*ProducerInfo, error) {
+	p.RLock()
+	defer p.RUnlock()
+
+	ownerKey, err := p.db.Get(toKey(BKTProducerNodes, nodeKey...))
+	if err != nil {
+		return nil, err
+	}
+	return p.get(toKey(BKTProducers, ownerKey...))
+}
+
+func (p *producers) GetAll() ([]*ProducerInfo, error) {
+	p.RLock()
+	defer p.RUnlock()
+
+	it := p.db.NewIterator(kv.BytesPrefix(BKTProducers))
+	defer it.Release()
+
+	var infos []*ProducerInfo
+	for it.Next() {
+		var info ProducerInfo
+		if err := info.Deserialize(bytes.NewReader(it.Value())); err != nil {
+			return nil, err
+		}
+		infos = append(infos, &info)
+	}
+	return infos, it.Error()
+}
+
+func (p *producers) Clear() error {
+	p.Lock()
+	defer p.Unlock()
+
+	batch := p.db.NewBatch()
+	for _, prefix := range [][]byte{BKTProducers, BKTProducerNodes, BKTProducerUndo} {
+		it := p.db.NewIterator(kv.BytesPrefix(prefix))
+		for it.Next() {
+			batch.Delete(it.Key())
+		}
+		it.Release()
+	}
+	return p.db.Write(batch)
+}
+
+func (p *producers) Close() error {
+	p.Lock()
+	return nil
+}
+
+func (p *producers) batchPut(info *ProducerInfo, height uint32, batch kv.Batch) error {
+	producerKey := toKey(BKTProducers, info.OwnerKey...)
+
+	// Keep the state before this height so it can be restored on rollback,
+	// only the first change on the height need to be kept.
+	var key [4]byte
+	binary.BigEndian.PutUint32(key[:], height)
+	undoKey := toKey(BKTProducerUndo, append(key[:], info.OwnerKey...)...)
+	if _, err := batchGet(p.db, batch, undoKey); err == kv.ErrNotFound {
+		previous, err := batchGet(p.db, batch, producerKey)
+		switch err {
+		case nil:
+		case kv.ErrNotFound:
+			previous = empty
+		default:
+			return err
+		}
+		batch.Put(undoKey, previous)
+	} else if err != nil {
+		return err
+	}
+
+	current, err := p.batchGet(producerKey, batch)
+	if err == nil {
+		batch.Delete(toKey(BKTProducerNodes, current.NodeKey...))
+	}
+
+	buf := new(bytes.Buffer)
+	if err := info.Serialize(buf); err != nil {
+		return err
+	}
+	batch.Put(producerKey, buf.Bytes())
+	batch.Put(toKey(BKTProducerNodes, info.NodeKey...), info.OwnerKey)
+	return nil
+}
+
+func (p *producers) batchSetState(producerKey []byte, state ProducerState,
+	height uint32, batch kv.Batch) error {
+	info, err := p.batchGet(producerKey, batch)
+	if err == kv.ErrNotFound {
+		// producer registered before the store created.
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	info.State = state
+	info.StateHeight = height
+	return p.batchPut(info, height, batch)
+}
+
+func (p *producers) batchSetStateByNodeKey(nodeKey []byte, state ProducerState,
+	height uint32, batch kv.Batch) error {
+	ownerKey, err := batchGet(p.db, batch, toKey(BKTProducerNodes, nodeKey...))
+	if err == kv.ErrNotFound {
+		// CR members or producers registered before the store created.
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	return p.batchSetState(toKey(BKTProducers, ownerKey...), state, height, batch)
+}
+
+func (p *producers) batchGet(producerKey []byte, batch kv.Batch) (*ProducerInfo, error) {
+	data, err := batchGet(p.db, batch, producerKey)
+	if err != nil {
+		return nil, err
+	}
+	var info ProducerInfo
+	if err := info.Deserialize(bytes.NewReader(data)); err != nil {
+		return nil, err
+	}
+	return &info, nil
+}
+
+func (p *producers) get(producerKey []byte) (*ProducerInfo, error) {
+	data, err := p.db.Get(producerKey)
+	if err != nil {
+		return nil, err
+	}
+	var info ProducerInfo
+	if err := info.Deserialize(bytes.NewReader(data)); err != nil {
+		return nil, err
+	}
+	return &info, nil
+}
+
+func getDepositAddress(ownerKey []byte) (string, error) {
+	var programHash *common.Uint168
+	if len(ownerKey) == 33 {
+		var err error
+		programHash, err = contract.PublicKeyToDepositProgramHash(ownerKey)
+		if err != nil {
+			return "", err
+		}
+	} else {
+		// multi-sign owner key is the redeem script.
+		programHash = common.ToProgramHash(byte(contract.PrefixDeposit), ownerKey)
+	}
+	return programHash.ToAddress()
+}
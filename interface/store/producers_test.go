@@ -0,0 +1,163 @@
+package store
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/elastos/Elastos.ELA.SPV/interface/store/kv"
+
+	"github.com/elastos/Elastos.ELA/common"
+	elatx "github.com/elastos/Elastos.ELA/core/transaction"
+	elacommon "github.com/elastos/Elastos.ELA/core/types/common"
+	it "github.com/elastos/Elastos.ELA/core/types/interfaces"
+	"github.com/elastos/Elastos.ELA/core/types/payload"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newProducerTx(txType elacommon.TxType, p it.Payload) it.Transaction {
+	return elatx.CreateTransaction(
+		elacommon.TxVersion09,
+		txType,
+		0,
+		p,
+		nil,
+		nil,
+		nil,
+		0,
+		nil,
+	)
+}
+
+func TestProducers(t *testing.T) {
+	dataDir := "spv_test"
+	os.RemoveAll(dataDir)
+	defer os.RemoveAll(dataDir)
+
+	db, err := kv.OpenLevelDB(filepath.Join(dataDir, "store"))
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	defer db.Close()
+	producers := NewProducers(db)
+
+	ownerKey, _ := common.HexStringToBytes(
+		"02089d7e878171240ce0e3633d3ddc8b1128bc221f6b5f0d1551caa717c7493062")
+	nodeKey, _ := common.HexStringToBytes(
+		"0268214956b8421c0621d62cf2f0b20a02c2dc8c2cc89528aff9bd43b45ed34b9f")
+	newNodeKey, _ := common.HexStringToBytes(
+		"03cce325c55057d2c8e3fb03fb5871794e73b85821e8d0f96a7e4510b4a922fad5")
+
+	// register and update producer in the same block.
+	batch := db.NewBatch()
+	assert.NoError(t, producers.BatchPutTx(newProducerTx(elacommon.RegisterProducer,
+		&payload.ProducerInfo{
+			OwnerKey:      ownerKey,
+			NodePublicKey: nodeKey,
+			NickName:      "producer",
+			Url:           "https://elastos.org",
+			NetAddress:    "127.0.0.1:20339",
+		}), 100, batch))
+	assert.NoError(t, producers.BatchPutTx(newProducerTx(elacommon.UpdateProducer,
+		&payload.ProducerInfo{
+			OwnerKey:      ownerKey,
+			NodePublicKey: nodeKey,
+			NickName:      "updated",
+			Url:           "https://elastos.org",
+			NetAddress:    "127.0.0.1:20339",
+		}), 100, batch))
+	assert.NoError(t, db.Write(batch))
+
+	info, err := producers.Get(ownerKey)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	assert.Equal(t, "updated", info.NickName)
+	assert.Equal(t, ProducerPending, info.State)
+	assert.Equal(t, ProducerPending, info.StateAt(105))
+	assert.Equal(t, ProducerActive, info.StateAt(106))
+	assert.NotEmpty(t, info.DepositAddress)
+
+	// change node key and set inactive.
+	batch = db.NewBatch()
+	assert.NoError(t, producers.BatchPutTx(newProducerTx(elacommon.UpdateProducer,
+		&payload.ProducerInfo{
+			OwnerKey:      ownerKey,
+			NodePublicKey: newNodeKey,
+			NickName:      "updated",
+		}), 110, batch))
+	assert.NoError(t, producers.BatchPutTx(newProducerTx(elacommon.InactiveArbitrators,
+		&payload.InactiveArbitrators{
+			Arbitrators: [][]byte{newNodeKey},
+		}), 110, batch))
+	assert.NoError(t, db.Write(batch))
+
+	_, err = producers.GetByNodeKey(nodeKey)
+	assert.Equal(t, kv.ErrNotFound, err)
+	info, err = producers.GetByNodeKey(newNodeKey)
+	if assert.NoError(t, err) {
+		assert.Equal(t, ProducerInactive, info.State)
+		assert.Equal(t, uint32(110), info.StateHeight)
+	}
+
+	// cancel producer.
+	batch = db.NewBatch()
+	assert.NoError(t, producers.BatchPutTx(newProducerTx(elacommon.CancelProducer,
+		&payload.ProcessProducer{OwnerKey: ownerKey}), 120, batch))
+	assert.NoError(t, db.Write(batch))
+	info, err = producers.Get(ownerKey)
+	if assert.NoError(t, err) {
+		assert.Equal(t, ProducerCanceled, info.State)
+	}
+
+	// rollback to height 100.
+	batch = db.NewBatch()
+	assert.NoError(t, producers.BatchDeleteAll(120, batch))
+	assert.NoError(t, producers.BatchDeleteAll(110, batch))
+	assert.NoError(t, db.Write(batch))
+
+	info, err = producers.GetByNodeKey(nodeKey)
+	if assert.NoError(t, err) {
+		assert.Equal(t, "updated", info.NickName)
+		assert.Equal(t, ProducerPending, info.State)
+	}
+	_, err = producers.GetByNodeKey(newNodeKey)
+	assert.Equal(t, kv.ErrNotFound, err)
+
+	// rollback the registration.
+	batch = db.NewBatch()
+	assert.NoError(t, producers.BatchDeleteAll(100, batch))
+	assert.NoError(t, db.Write(batch))
+	infos, err := producers.GetAll()
+	assert.NoError(t, err)
+	assert.Equal(t, 0, len(infos))
+	_, err = producers.GetByNodeKey(nodeKey)
+	assert.Equal(t, kv.ErrNotFound, err)
+}
+
+func TestProducers_UpdateUnknown(t *testing.T) {
+	db := kv.NewMemoryDB()
+	defer db.Close()
+	producers := NewProducers(db)
+
+	ownerKey, _ := common.HexStringToBytes(
+		"02089d7e878171240ce0e3633d3ddc8b1128bc221f6b5f0d1551caa717c7493062")
+	nodeKey, _ := common.HexStringToBytes(
+		"0268214956b8421c0621d62cf2f0b20a02c2dc8c2cc89528aff9bd43b45ed34b9f")
+
+	// producer registered before the store created is ignored.
+	batch := db.NewBatch()
+	assert.NoError(t, producers.BatchPutTx(newProducerTx(elacommon.UpdateProducer,
+		&payload.ProducerInfo{
+			OwnerKey:      ownerKey,
+			NodePublicKey: nodeKey,
+			NickName:      "updated",
+		}), 100, batch))
+	assert.NoError(t, db.Write(batch))
+
+	_, err := producers.Get(ownerKey)
+	assert.Equal(t, kv.ErrNotFound, err)
+	_, err = producers.GetByNodeKey(nodeKey)
+	assert.Equal(t, kv.ErrNotFound, err)
+}
@@ -0,0 +1,376 @@
+package store
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"sync"
+
+	"github.com/elastos/Elastos.ELA.SPV/interface/store/kv"
+
+	"github.com/elastos/Elastos.ELA/common"
+	"github.com/elastos/Elastos.ELA/core/types/payload"
+)
+
+// Ensure proposals implement Proposals interface.
+var _ Proposals = (*proposals)(nil)
+
+type ProposalStatus byte
+
+const (
+	// ProposalRegistered indicates the proposal has been packed into main
+	// chain but no result recorded yet.
+	ProposalRegistered ProposalStatus = 0x00
+
+	// ProposalApproved indicates the proposal result recorded as passed.
+	ProposalApproved ProposalStatus = 0x01
+
+	// ProposalRejected indicates the proposal result recorded as not passed.
+	ProposalRejected ProposalStatus = 0x02
+)
+
+func (s ProposalStatus) String() string {
+	switch s {
+	case ProposalRegistered:
+		return "Registered"
+	case ProposalApproved:
+		return "Approved"
+	case ProposalRejected:
+		return "Rejected"
+	default:
+		return "Unknown"
+	}
+}
+
+// ProposalInfo is the CR proposal information tracked from main chain.
+type ProposalInfo struct {
+	// Hash is the proposal hash.
+	Hash common.Uint256
+
+	// TxHash is the hash of the CRCProposal transaction.
+	TxHash common.Uint256
+
+	ProposalType payload.CRCProposalType
+	DraftHash    common.Uint256
+	Budgets      []payload.Budget
+	Recipient    common.Uint168
+
+	// Height is the block height the proposal packed in.
+	Height uint32
+
+	// Status of the proposal, and the height the result takes effect on.
+	Status       ProposalStatus
+	ResultHeight uint32
+}
+
+func (p *ProposalInfo) Serialize(w io.Writer) error {
+	if err := p.Hash.Serialize(w); err != nil {
+		return err
+	}
+	if err := p.TxHash.Serialize(w); err != nil {
+		return err
+	}
+	if err := common.WriteUint16(w, uint16(p.ProposalType)); err != nil {
+		return err
+	}
+	if err := p.DraftHash.Serialize(w); err != nil {
+		return err
+	}
+	if err := common.WriteVarUint(w, uint64(len(p.Budgets))); err != nil {
+		return err
+	}
+	for _, b := range p.Budgets {
+		if err := common.WriteUint8(w, byte(b.Type)); err != nil {
+			return err
+		}
+		if err := common.WriteUint8(w, b.Stage); err != nil {
+			return err
+		}
+		if err := b.Amount.Serialize(w); err != nil {
+			return err
+		}
+	}
+	if err := p.Recipient.Serialize(w); err != nil {
+		return err
+	}
+	if err := common.WriteUint32(w, p.Height); err != nil {
+		return err
+	}
+	if err := common.WriteUint8(w, byte(p.Status)); err != nil {
+		return err
+	}
+	return common.WriteUint32(w, p.ResultHeight)
+}
+
+func (p *ProposalInfo) Deserialize(r io.Reader) error {
+	if err := p.Hash.Deserialize(r); err != nil {
+		return err
+	}
+	if err := p.TxHash.Deserialize(r); err != nil {
+		return err
+	}
+	proposalType, err := common.ReadUint16(r)
+	if err != nil {
+		return err
+	}
+	p.ProposalType = payload.CRCProposalType(proposalType)
+	if err := p.DraftHash.Deserialize(r); err != nil {
+		return err
+	}
+	count, err := common.ReadVarUint(r, 0)
+	if err != nil {
+		return err
+	}
+	p.Budgets = nil
+	for i := uint64(0); i < count; i++ {
+		var b payload.Budget
+		budgetType, err := common.ReadUint8(r)
+		if err != nil {
+			return err
+		}
+		b.Type = payload.BudgetType(budgetType)
+		if b.Stage, err = common.ReadUint8(r); err != nil {
+			return err
+		}
+		if err := b.Amount.Deserialize(r); err != nil {
+			return err
+		}
+		p.Budgets = append(p.Budgets, b)
+	}
+	if err := p.Recipient.Deserialize(r); err != nil {
+		return err
+	}
+	if p.Height, err = common.ReadUint32(r); err != nil {
+		return err
+	}
+	status, err := common.ReadUint8(r)
+	if err != nil {
+		return err
+	}
+	p.Status = ProposalStatus(status)
+	p.ResultHeight, err = common.ReadUint32(r)
+	return err
+}
+
+type proposals struct {
+	sync.RWMutex
+	db kv.DB
+}
+
+func NewProposals(db kv.DB) *proposals {
+	return &proposals{db: db}
+}
+
+func (p *proposals) BatchPut(info *ProposalInfo, batch kv.Batch) error {
+	p.Lock()
+	defer p.Unlock()
+
+	buf := new(bytes.Buffer)
+	if err := info.Serialize(buf); err != nil {
+		return err
+	}
+	batch.Put(toKey(BKTProposals, info.Hash.Bytes()...), buf.Bytes())
+	batch.Put(toKey(BKTProposalHeights, heightKey(info.Height, info.Hash)...), empty)
+	return nil
+}
+
+func (p *proposals) BatchPutResults(results []payload.ProposalResult,
+	height uint32, batch kv.Batch) error {
+	p.Lock()
+	defer p.Unlock()
+
+	for _, r := range results {
+		info, err := p.get(r.ProposalHash)
+		if err == kv.ErrNotFound {
+			// proposal packed before the store created, nothing to update.
+			continue
+		}
+		if err != nil {
+			return err
+		}
+
+		info.Status = ProposalRejected
+		if r.Result {
+			info.Status = ProposalApproved
+		}
+		info.ResultHeight = height
+
+		buf := new(bytes.Buffer)
+		if err := info.Serialize(buf); err != nil {
+			return err
+		}
+		batch.Put(toKey(BKTProposals, info.Hash.Bytes()...), buf.Bytes())
+		batch.Put(toKey(BKTProposalResults, heightKey(height, info.Hash)...), empty)
+	}
+	return nil
+}
+
+// BatchDeleteAll removes the proposals and proposal results recorded on the
+// given height.
+func (p *proposals) BatchDeleteAll(height uint32, batch kv.Batch) error {
+	p.Lock()
+	defer p.Unlock()
+
+	var key [4]byte
+	binary.BigEndian.PutUint32(key[:], height)
+
+	// Reset status of the proposals which result recorded on this height.
+	prefix := toKey(BKTProposalResults, key[:]...)
+	it := p.db.NewIterator(kv.BytesPrefix(prefix))
+	for it.Next() {
+		hash, err := common.Uint256FromBytes(subKey(prefix, it.Key()))
+		if err != nil {
+			it.Release()
+			return err
+		}
+		info, err := p.get(*hash)
+		if err == nil {
+			info.Status = ProposalRegistered
+			info.ResultHeight = 0
+			buf := new(bytes.Buffer)
+			if err := info.Serialize(buf); err != nil {
+				it.Release()
+				return err
+			}
+			batch.Put(toKey(BKTProposals, hash.Bytes()...), buf.Bytes())
+		}
+		batch.Delete(it.Key())
+	}
+	it.Release()
+	if err := it.Error(); err != nil {
+		return err
+	}
+
+	// Remove proposals packed on this height.
+	prefix = toKey(BKTProposalHeights, key[:]...)
+	it = p.db.NewIterator(kv.BytesPrefix(prefix))
+	defer it.Release()
+	for it.Next() {
+		batch.Delete(toKey(BKTProposals, subKey(prefix, it.Key())...))
+		batch.Delete(it.Key())
+	}
+	return it.Error()
+}
+
+func (p *proposals) Get(hash common.Uint256) (*ProposalInfo, error) {
+	p.RLock()
+	defer p.RUnlock()
+	return p.get(hash)
+}
+
+func (p *proposals) GetByType(proposalType payload.CRCProposalType) ([]*ProposalInfo, error) {
+	p.RLock()
+	defer p.RUnlock()
+	return p.filter(func(info *ProposalInfo) bool {
+		return info.ProposalType == proposalType
+	})
+}
+
+func (p *proposals) GetByStatus(status ProposalStatus) ([]*ProposalInfo, error) {
+	p.RLock()
+	defer p.RUnlock()
+	return p.filter(func(info *ProposalInfo) bool {
+		return info.Status == status
+	})
+}
+
+// GetByHeight returns proposals packed between startHeight and endHeight,
+// both inclusive, in height order.
+func (p *proposals) GetByHeight(startHeight, endHeight uint32) ([]*ProposalInfo, error) {
+	if startHeight > endHeight {
+		return nil, errors.New("invalid height range")
+	}
+
+	p.RLock()
+	defer p.RUnlock()
+
+	var start, limit [4]byte
+	binary.BigEndian.PutUint32(start[:], startHeight)
+	binary.BigEndian.PutUint32(limit[:], endHeight)
+	it := p.db.NewIterator(heightRange(BKTProposalHeights, start[:], limit[:]))
+	defer it.Release()
+
+	var infos []*ProposalInfo
+	for it.Next() {
+		hash, err := common.Uint256FromBytes(subKey(BKTProposalHeights, it.Key())[4:])
+		if err != nil {
+			return nil, err
+		}
+		info, err := p.get(*hash)
+		if err != nil {
+			return nil, err
+		}
+		infos = append(infos, info)
+	}
+	return infos, it.Error()
+}
+
+func (p *proposals) get(hash common.Uint256) (*ProposalInfo, error) {
+	data, err := p.db.Get(toKey(BKTProposals, hash.Bytes()...))
+	if err != nil {
+		return nil, err
+	}
+	var info ProposalInfo
+	if err := info.Deserialize(bytes.NewReader(data)); err != nil {
+		return nil, err
+	}
+	return &info, nil
+}
+
+func (p *proposals) filter(match func(info *ProposalInfo) bool) ([]*ProposalInfo, error) {
+	it := p.db.NewIterator(kv.BytesPrefix(BKTProposals))
+	defer it.Release()
+
+	var infos []*ProposalInfo
+	for it.Next() {
+		var info ProposalInfo
+		if err := info.Deserialize(bytes.NewReader(it.Value())); err != nil {
+			return nil, err
+		}
+		if match(&info) {
+			infos = append(infos, &info)
+		}
+	}
+	return infos, it.Error()
+}
+
+func (p *proposals) Clear() error {
+	p.Lock()
+	defer p.Unlock()
+
+	batch := p.db.NewBatch()
+	for _, prefix := range [][]byte{BKTProposals, BKTProposalHeights,
+		BKTProposalResults} {
+		it := p.db.NewIterator(kv.BytesPrefix(prefix))
+		for it.Next() {
+			batch.Delete(it.Key())
+		}
+		it.Release()
+	}
+	return p.db.Write(batch)
+}
+
+func (p *proposals) Close() error {
+	p.Lock()
+	return nil
+}
+
+// heightKey returns the big endian height followed by the hash, so keys can
+// be iterated by height order.
+func heightKey(height uint32, hash common.Uint256) []byte {
+	var key [4]byte
+	binary.BigEndian.PutUint32(key[:], height)
+	return append(key[:], hash.Bytes()...)
+}
+
+// heightRange returns the key range covers heights from start to limit, both
+// inclusive, under the given bucket.
+func heightRange(bucket []byte, start, limit []byte) *kv.Range {
+	startKey := append(append([]byte{}, bucket...), start...)
+	limitKey := append(append([]byte{}, bucket...), limit...)
+	return &kv.Range{
+		Start: startKey,
+		Limit: kv.BytesPrefix(limitKey).Limit,
+	}
+}
@@ -0,0 +1,107 @@
+package store
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/elastos/Elastos.ELA.SPV/interface/store/kv"
+
+	"github.com/elastos/Elastos.ELA/common"
+	"github.com/elastos/Elastos.ELA/core/types/payload"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestProposals(t *testing.T) {
+	dataDir := "spv_test"
+	os.RemoveAll(dataDir)
+	defer os.RemoveAll(dataDir)
+
+	db, err := kv.OpenLevelDB(filepath.Join(dataDir, "store"))
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	defer db.Close()
+	proposals := NewProposals(db)
+
+	normal := &ProposalInfo{
+		Hash:         common.Uint256{1},
+		TxHash:       common.Uint256{11},
+		ProposalType: payload.Normal,
+		DraftHash:    common.Uint256{21},
+		Budgets: []payload.Budget{
+			{Type: payload.Imprest, Stage: 0, Amount: 100},
+			{Type: payload.FinalPayment, Stage: 1, Amount: 200},
+		},
+		Recipient: common.Uint168{31},
+		Height:    100,
+	}
+	customID := &ProposalInfo{
+		Hash:         common.Uint256{2},
+		TxHash:       common.Uint256{12},
+		ProposalType: payload.ReserveCustomID,
+		Height:       120,
+	}
+	batch := db.NewBatch()
+	assert.NoError(t, proposals.BatchPut(normal, batch))
+	assert.NoError(t, proposals.BatchPut(customID, batch))
+	assert.NoError(t, db.Write(batch))
+
+	info, err := proposals.Get(normal.Hash)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	assert.Equal(t, normal, info)
+
+	infos, err := proposals.GetByType(payload.ReserveCustomID)
+	assert.NoError(t, err)
+	assert.Equal(t, []*ProposalInfo{customID}, infos)
+
+	infos, err = proposals.GetByHeight(100, 119)
+	assert.NoError(t, err)
+	assert.Equal(t, []*ProposalInfo{normal}, infos)
+
+	infos, err = proposals.GetByHeight(100, 120)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, len(infos))
+
+	// record proposal results.
+	batch = db.NewBatch()
+	assert.NoError(t, proposals.BatchPutResults([]payload.ProposalResult{
+		{ProposalHash: normal.Hash, ProposalType: payload.Normal, Result: true},
+		{ProposalHash: customID.Hash, ProposalType: payload.ReserveCustomID, Result: false},
+		{ProposalHash: common.Uint256{3}, ProposalType: payload.Normal, Result: true},
+	}, 200, batch))
+	assert.NoError(t, db.Write(batch))
+
+	infos, err = proposals.GetByStatus(ProposalApproved)
+	assert.NoError(t, err)
+	if assert.Equal(t, 1, len(infos)) {
+		assert.Equal(t, normal.Hash, infos[0].Hash)
+		assert.Equal(t, uint32(200), infos[0].ResultHeight)
+	}
+	infos, err = proposals.GetByStatus(ProposalRejected)
+	assert.NoError(t, err)
+	if assert.Equal(t, 1, len(infos)) {
+		assert.Equal(t, customID.Hash, infos[0].Hash)
+	}
+
+	// rollback proposal results.
+	batch = db.NewBatch()
+	assert.NoError(t, proposals.BatchDeleteAll(200, batch))
+	assert.NoError(t, db.Write(batch))
+	infos, err = proposals.GetByStatus(ProposalRegistered)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, len(infos))
+
+	// rollback proposal.
+	batch = db.NewBatch()
+	assert.NoError(t, proposals.BatchDeleteAll(120, batch))
+	assert.NoError(t, db.Write(batch))
+	_, err = proposals.Get(customID.Hash)
+	assert.Equal(t, kv.ErrNotFound, err)
+	infos, err = proposals.GetByHeight(0, 1000)
+	assert.NoError(t, err)
+	assert.Equal(t, []*ProposalInfo{normal}, infos)
+}
@@ -0,0 +1,219 @@
+package store
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/elastos/Elastos.ELA.SPV/interface/store/kv"
+
+	"github.com/elastos/Elastos.ELA/common"
+)
+
+// PruneOptions selects the side branches to prune, a side branch is pruned
+// if it's tip is below the prune height.
+type PruneOptions struct {
+	// Depth prunes the side branches more than Depth blocks below the best
+	// header, zero disables pruning by depth.
+	Depth uint32
+
+	// Checkpoint prunes the side branches below the checkpoint height, zero
+	// disables pruning by checkpoint.
+	Checkpoint uint32
+}
+
+// Enabled returns if pruning is enabled by the options.
+func (o PruneOptions) Enabled() bool {
+	return o.Depth > 0 || o.Checkpoint > 0
+}
+
+// Height returns the prune height on the given best height, zero means
+// nothing to prune.
+func (o PruneOptions) Height(bestHeight uint32) uint32 {
+	var height uint32
+	if o.Depth > 0 && bestHeight > o.Depth {
+		height = bestHeight - o.Depth
+	}
+	if o.Checkpoint > height && o.Checkpoint <= bestHeight {
+		height = o.Checkpoint
+	}
+	return height
+}
+
+// PruneReport is the result of pruning side branches.
+type PruneReport struct {
+	// Height is the prune height, side branches below it are pruned.
+	Height uint32
+
+	// Headers is the number of side branch headers removed.
+	Headers int
+
+	// ForkTxs is the number of fork blocks transactions removed.
+	ForkTxs int
+
+	// Reclaimed is the bytes of keys and values removed, the disk space is
+	// reclaimed after the database compaction.
+	Reclaimed uint64
+}
+
+func (r *PruneReport) String() string {
+	return fmt.Sprintf("prune height %d, %d headers and %d fork blocks"+
+		" removed, %d bytes reclaimed", r.Height, r.Headers, r.ForkTxs,
+		r.Reclaimed)
+}
+
+// PruneStores removes the side branch headers and fork blocks transactions
+// below the prune height, and the fork transactions of the best chain blocks
+// below the prune height, which are left by previous reorganizes. It can be
+// called while the service is running.
+func PruneStores(headers HeaderStore, data DataStore, opts PruneOptions) (*PruneReport, error) {
+	best, err := headers.GetBest()
+	if err != nil {
+		return nil, err
+	}
+	report := &PruneReport{Height: opts.Height(best.Height)}
+	if report.Height == 0 {
+		return report, nil
+	}
+
+	count, size, err := headers.PruneForks(report.Height)
+	if err != nil {
+		return nil, err
+	}
+	report.Headers = count
+	report.Reclaimed += size
+
+	// Headers are put before fork transactions, so fork transactions without
+	// header are removed with their pruned header.
+	count, size, err = data.Txs().PruneForkTxs(func(hash *common.Uint256) bool {
+		header, err := headers.Get(hash)
+		return err != nil || header.Height < report.Height
+	})
+	if err != nil {
+		return nil, err
+	}
+	report.ForkTxs = count
+	report.Reclaimed += size
+	return report, nil
+}
+
+// sideHeader is a header not on the best chain.
+type sideHeader struct {
+	height   uint32
+	previous common.Uint256
+
+	// tip is the highest branch tip height through this header.
+	tip uint32
+}
+
+// PruneForks removes the side branch headers with all branch tips through
+// them below height, so the remaining side branches are still connected.
+func (h *headers) PruneForks(height uint32) (int, uint64, error) {
+	// Find side headers on snapshot without blocking the headers store.
+	snapshot, err := h.db.NewSnapshot()
+	if err != nil {
+		return 0, 0, err
+	}
+	side := make(map[common.Uint256]*sideHeader)
+	it := snapshot.NewIterator(kv.BytesPrefix(BKTHeaders))
+	for it.Next() {
+		header, err := h.decodeHeader(it.Value())
+		if err != nil {
+			it.Release()
+			snapshot.Release()
+			return 0, 0, err
+		}
+		hash := header.Hash()
+		indexed, err := snapshot.Get(indexKey(header.Height))
+		if err == nil && bytes.Equal(indexed, hash.Bytes()) {
+			continue
+		}
+		side[hash] = &sideHeader{
+			height:   header.Height,
+			previous: header.Previous(),
+		}
+	}
+	it.Release()
+	err = it.Error()
+	snapshot.Release()
+	if err != nil {
+		return 0, 0, err
+	}
+
+	// Walk down from every branch tip to the best chain.
+	parents := make(map[common.Uint256]struct{})
+	for _, header := range side {
+		parents[header.previous] = struct{}{}
+	}
+	for hash, tip := range side {
+		if _, ok := parents[hash]; ok {
+			continue
+		}
+		for header := tip; header != nil && header.tip < tip.height; {
+			header.tip = tip.height
+			header = side[header.previous]
+		}
+	}
+
+	h.Lock()
+	defer h.Unlock()
+
+	var count int
+	var size uint64
+	batch := h.db.NewBatch()
+	for hash, header := range side {
+		if header.tip >= height {
+			continue
+		}
+
+		// The header may be moved to the best chain after the snapshot.
+		indexed, err := h.db.Get(indexKey(header.height))
+		if err == nil && bytes.Equal(indexed, hash.Bytes()) {
+			continue
+		}
+		key := toKey(BKTHeaders, hash.Bytes()...)
+		data, err := h.db.Get(key)
+		if err == kv.ErrNotFound {
+			continue
+		}
+		if err != nil {
+			return 0, 0, err
+		}
+		batch.Delete(key)
+		h.cache.headers.Delete(hash.String())
+		count++
+		size += uint64(len(key) + len(data))
+	}
+	if err := h.db.Write(batch); err != nil {
+		return 0, 0, err
+	}
+	return count, size, nil
+}
+
+// PruneForkTxs removes the fork blocks transactions if prune returns true
+// with the fork block hash.
+func (t *txs) PruneForkTxs(prune func(hash *common.Uint256) bool) (int, uint64, error) {
+	t.Lock()
+	defer t.Unlock()
+
+	var count int
+	var size uint64
+	batch := t.db.NewBatch()
+	it := t.db.NewIterator(kv.BytesPrefix(BKTForkTxs))
+	for it.Next() {
+		hash, err := common.Uint256FromBytes(subKey(BKTForkTxs, it.Key()))
+		if err != nil || !prune(hash) {
+			continue
+		}
+		batch.Delete(append([]byte{}, it.Key()...))
+		count++
+		size += uint64(len(it.Key()) + len(it.Value()))
+	}
+	it.Release()
+	if err := it.Error(); err != nil {
+		return 0, 0, err
+	}
+	if err := t.db.Write(batch); err != nil {
+		return 0, 0, err
+	}
+	return count, size, nil
+}
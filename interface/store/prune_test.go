@@ -0,0 +1,87 @@
+package store
+
+import (
+	"testing"
+
+	"github.com/elastos/Elastos.ELA.SPV/util"
+
+	"github.com/elastos/Elastos.ELA/common"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPruneOptions(t *testing.T) {
+	assert.False(t, PruneOptions{}.Enabled())
+	assert.Equal(t, uint32(0), PruneOptions{}.Height(100))
+	assert.Equal(t, uint32(90), PruneOptions{Depth: 10}.Height(100))
+	assert.Equal(t, uint32(0), PruneOptions{Depth: 100}.Height(100))
+	assert.Equal(t, uint32(95), PruneOptions{Depth: 10, Checkpoint: 95}.Height(100))
+	assert.Equal(t, uint32(90), PruneOptions{Depth: 10, Checkpoint: 80}.Height(100))
+	assert.Equal(t, uint32(0), PruneOptions{Checkpoint: 101}.Height(100))
+}
+
+func TestPruneStores(t *testing.T) {
+	headers, data, chain := newTestStores(t)
+	defer headers.Close()
+	defer data.Close()
+
+	// side branches a2-a3 and a2-c3-c4 forked from height 1, b4 forked from
+	// height 3.
+	a2 := newHeader(chain[1], 1020, 1)
+	a3 := newHeader(a2, 1030, 1)
+	c3 := newHeader(a2, 1030, 2)
+	c4 := newHeader(c3, 1040, 2)
+	b4 := newHeader(chain[3], 1040, 3)
+	for _, header := range []*util.Header{a2, a3, c3, c4, b4} {
+		assert.NoError(t, headers.Put(header, false))
+	}
+
+	// fork transactions of side blocks, a best chain block left by reorganize
+	// and an unknown block.
+	for _, hash := range []common.Uint256{a3.Hash(), c4.Hash(),
+		chain[2].Hash(), {1}} {
+		assert.NoError(t, data.Txs().PutForkTxs(
+			[]*util.Tx{newStoreTx(1, 0)}, &hash))
+	}
+
+	report, err := PruneStores(headers, data, PruneOptions{Depth: 10})
+	assert.NoError(t, err)
+	assert.Equal(t, &PruneReport{}, report)
+
+	// only a3 is pruned, a2 is kept for branch c4.
+	report, err = PruneStores(headers, data, PruneOptions{Depth: 1})
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	assert.Equal(t, uint32(4), report.Height)
+	assert.Equal(t, 1, report.Headers)
+	assert.Equal(t, 3, report.ForkTxs)
+	assert.True(t, report.Reclaimed > 0)
+	for _, header := range []*util.Header{a2, c3, c4, b4} {
+		hash := header.Hash()
+		_, err := headers.Get(&hash)
+		assert.NoError(t, err)
+	}
+	hash := a3.Hash()
+	_, err = headers.Get(&hash)
+	assert.Error(t, err)
+	hash = chain[2].Hash()
+	_, err = data.Txs().GetForkTxs(&hash)
+	assert.Error(t, err)
+	hash = c4.Hash()
+	_, err = data.Txs().GetForkTxs(&hash)
+	assert.NoError(t, err)
+
+	report, err = PruneStores(headers, data, PruneOptions{Checkpoint: 5})
+	assert.NoError(t, err)
+	assert.Equal(t, 4, report.Headers)
+	assert.Equal(t, 1, report.ForkTxs)
+
+	// best chain is not changed.
+	best, err := headers.GetBest()
+	assert.NoError(t, err)
+	assert.Equal(t, chain[5].Hash(), best.Hash())
+	result, err := headers.GetRange(0, 5)
+	assert.NoError(t, err)
+	assert.Equal(t, 6, len(result))
+}
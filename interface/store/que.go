@@ -6,9 +6,9 @@ import (
 	"sync"
 	"time"
 
+	"github.com/elastos/Elastos.ELA.SPV/interface/store/kv"
+
 	"github.com/elastos/Elastos.ELA/common"
-	"github.com/syndtr/goleveldb/leveldb"
-	"github.com/syndtr/goleveldb/leveldb/util"
 )
 
 var (
@@ -20,10 +20,10 @@ var _ Que = (*que)(nil)
 
 type que struct {
 	sync.RWMutex
-	db *leveldb.DB
+	db kv.DB
 }
 
-func NewQue(db *leveldb.DB) *que {
+func NewQue(db kv.DB) *que {
 	return &que{db: db}
 }
 
@@ -32,14 +32,14 @@ func (q *que) Put(item *QueItem) error {
 	q.Lock()
 	defer q.Unlock()
 
-	batch := new(leveldb.Batch)
+	batch := q.db.NewBatch()
 	buf := new(bytes.Buffer)
 	binary.Write(buf, binary.BigEndian, item.Height)
 	value := append(item.NotifyId[:], item.TxId[:]...)
 	batch.Put(toKey(BKTQueIdx, append(buf.Bytes(), value...)...), empty)
 	binary.Write(buf, binary.BigEndian, item.LastNotify.Unix())
 	batch.Put(toKey(BKTQue, value...), buf.Bytes())
-	return q.db.Write(batch, nil)
+	return q.db.Write(batch)
 }
 
 // Get all items in queue
@@ -47,7 +47,7 @@ func (q *que) GetAll() (items []*QueItem, err error) {
 	q.RLock()
 	defer q.RUnlock()
 
-	it := q.db.NewIterator(util.BytesPrefix(BKTQue), nil)
+	it := q.db.NewIterator(kv.BytesPrefix(BKTQue))
 	defer it.Release()
 	for it.Next() {
 		var item QueItem
@@ -70,38 +70,38 @@ func (q *que) Del(notifyId, txHash *common.Uint256) error {
 	defer q.Unlock()
 
 	value := append(notifyId[:], txHash[:]...)
-	height, err := q.db.Get(toKey(BKTQue, value...), nil)
+	height, err := q.db.Get(toKey(BKTQue, value...))
 	if err != nil {
 		return err
 	}
-	batch := new(leveldb.Batch)
+	batch := q.db.NewBatch()
 	batch.Delete(toKey(BKTQue, value...))
 	batch.Delete(toKey(BKTQueIdx, append(height[:], value...)...))
-	return q.db.Write(batch, nil)
+	return q.db.Write(batch)
 }
 
 func (q *que) Batch() QueBatch {
-	return &queBatch{DB: q.db, Batch: new(leveldb.Batch)}
+	return &queBatch{DB: q.db, Batch: q.db.NewBatch()}
 }
 
 func (q *que) Clear() error {
 	q.Lock()
 	defer q.Unlock()
 
-	batch := new(leveldb.Batch)
-	it := q.db.NewIterator(util.BytesPrefix(BKTQue), nil)
+	batch := q.db.NewBatch()
+	it := q.db.NewIterator(kv.BytesPrefix(BKTQue))
 	for it.Next() {
 		batch.Delete(it.Key())
 	}
 	it.Release()
 
-	it = q.db.NewIterator(util.BytesPrefix(BKTQueIdx), nil)
+	it = q.db.NewIterator(kv.BytesPrefix(BKTQueIdx))
 	for it.Next() {
 		batch.Delete(it.Key())
 	}
 	it.Release()
 
-	return q.db.Write(batch, nil)
+	return q.db.Write(batch)
 }
 
 func (q *que) Close() error {
@@ -6,14 +6,15 @@ import (
 	"testing"
 	"time"
 
+	"github.com/elastos/Elastos.ELA.SPV/interface/store/kv"
+
 	"github.com/elastos/Elastos.ELA/common"
 
 	"github.com/stretchr/testify/assert"
-	"github.com/syndtr/goleveldb/leveldb"
 )
 
 func TestQue(t *testing.T) {
-	db, err := leveldb.OpenFile("test", nil)
+	db, err := kv.OpenLevelDB("test")
 	if !assert.NoError(t, err) {
 		t.FailNow()
 	}
@@ -68,7 +69,7 @@ func TestQue(t *testing.T) {
 	binary.BigEndian.PutUint64(data1[4:], uint64(defaultTime.Add(time.Second).Unix()))
 	for i, notifyID := range notifyIDs {
 		value := append(notifyID[:], txHashes[i][:]...)
-		data, err := que.db.Get(toKey(BKTQue, value...), nil)
+		data, err := que.db.Get(toKey(BKTQue, value...))
 		if !assert.NoError(t, err) {
 			t.FailNow()
 		}
@@ -94,7 +95,7 @@ func TestQue(t *testing.T) {
 
 	for i, notifyID := range notifyIDs {
 		value := append(notifyID[:], txHashes[i][:]...)
-		_, err := que.db.Get(toKey(BKTQue, value...), nil)
+		_, err := que.db.Get(toKey(BKTQue, value...))
 		if i < times/2 {
 			if !assert.Error(t, err) {
 				t.FailNow()
@@ -168,7 +169,7 @@ func TestQue(t *testing.T) {
 
 	for i, notifyID := range notifyIDs {
 		value := append(notifyID[:], txHashes[i][:]...)
-		_, err := que.db.Get(toKey(BKTQue, value...), nil)
+		_, err := que.db.Get(toKey(BKTQue, value...))
 		if i < times/2 {
 			if !assert.Error(t, err) {
 				t.FailNow()
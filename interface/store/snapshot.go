@@ -0,0 +1,441 @@
+package store
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/elastos/Elastos.ELA.SPV/interface/store/kv"
+	"github.com/elastos/Elastos.ELA.SPV/util"
+
+	"github.com/elastos/Elastos.ELA/common"
+	"github.com/elastos/Elastos.ELA/elanet/pact"
+)
+
+// snapshotVersion is the version of the snapshot file format.
+const snapshotVersion = 1
+
+// Record types of the snapshot file.
+const (
+	recordEnd    = 0
+	recordHeader = 1
+	recordData   = 2
+)
+
+var (
+	// snapshotMagic is the leading bytes of a snapshot file.
+	snapshotMagic = []byte("SPVSNAP")
+
+	// ErrSnapshotChecksum indicates the snapshot file is corrupted.
+	ErrSnapshotChecksum = errors.New("snapshot checksum mismatch")
+)
+
+// SnapshotInfo is the summary of an exported or imported snapshot.
+type SnapshotInfo struct {
+	// Height is the height of the last header in the snapshot.
+	Height uint32
+
+	// Hash is the hash of the last header in the snapshot.
+	Hash common.Uint256
+
+	// Headers is the number of headers in the snapshot.
+	Headers int
+
+	// Entries is the number of data store entries in the snapshot.
+	Entries int
+
+	// Checksum is the SHA256 checksum of the snapshot content.
+	Checksum common.Uint256
+}
+
+func (i *SnapshotInfo) String() string {
+	return fmt.Sprintf("height %d, hash %s, %d headers, %d entries, checksum %s",
+		i.Height, i.Hash, i.Headers, i.Entries, i.Checksum)
+}
+
+// StoreSnapshot is the database snapshots of the header store and the data
+// store, they must be taken while no block is committing, so the data store
+// matches the best chain of the header store.
+type StoreSnapshot struct {
+	headers kv.Snapshot
+	data    kv.Snapshot
+}
+
+// NewStoreSnapshot takes the snapshots of the header store and the data
+// store, the snapshot must be released after use.
+func NewStoreSnapshot(headers HeaderStore, data DataStore) (*StoreSnapshot, error) {
+	hs, err := headers.NewSnapshot()
+	if err != nil {
+		return nil, err
+	}
+	ds, err := data.NewSnapshot()
+	if err != nil {
+		hs.Release()
+		return nil, err
+	}
+	return &StoreSnapshot{headers: hs, data: ds}, nil
+}
+
+// Release releases the database snapshots.
+func (s *StoreSnapshot) Release() {
+	s.headers.Release()
+	s.data.Release()
+}
+
+// ExportSnapshot writes the best chain headers up to height, and the data
+// store content like arbiters, custom IDs, revert info and transactions to w.
+// The stores must not be changed while exporting, use StoreSnapshot.Export to
+// export from a running service.
+func ExportSnapshot(w io.Writer, headers HeaderStore, data DataStore,
+	height uint32) (*SnapshotInfo, error) {
+	snapshot, err := NewStoreSnapshot(headers, data)
+	if err != nil {
+		return nil, err
+	}
+	defer snapshot.Release()
+	return snapshot.Export(w, height)
+}
+
+// Export writes the best chain headers up to height and the data store
+// content to w.  The data store keeps the state of the best height only, so
+// height must be the best height of the snapshot.
+func (s *StoreSnapshot) Export(w io.Writer, height uint32) (*SnapshotInfo, error) {
+	hs, ds := s.headers, s.data
+	tip, err := hs.Get(indexKey(height))
+	if err != nil {
+		return nil, fmt.Errorf("header on height %d not found, %s", height, err)
+	}
+	// Indexes above the best height are removed when the chain tip changes.
+	ok, err := hs.Has(indexKey(height + 1))
+	if err != nil {
+		return nil, err
+	}
+	if ok {
+		return nil, fmt.Errorf("height %d is not the best height, snapshot"+
+			" can only be exported on the best height", height)
+	}
+	info := &SnapshotInfo{Height: height}
+	copy(info.Hash[:], tip)
+
+	// Headers before the sync start point are not stored.
+	start := height
+	for start > 0 {
+		ok, err := hs.Has(indexKey(start - 1))
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			break
+		}
+		start--
+	}
+
+	sw := newSnapshotWriter(w)
+	sw.write(snapshotMagic)
+	sw.writeUint32(snapshotVersion)
+	sw.writeUint32(height)
+	sw.write(info.Hash[:])
+
+	for h := start; h <= height; h++ {
+		hash, err := hs.Get(indexKey(h))
+		if err != nil {
+			return nil, err
+		}
+		header, err := hs.Get(toKey(BKTHeaders, hash...))
+		if err != nil {
+			return nil, err
+		}
+		sw.writeByte(recordHeader)
+		sw.writeVarBytes(header)
+		info.Headers++
+	}
+
+	it := ds.NewIterator(nil)
+	defer it.Release()
+	for it.Next() {
+		// The schema version is written by import.
+		if bytes.Equal(it.Key(), BKTSchemaVersion) {
+			continue
+		}
+		sw.writeByte(recordData)
+		sw.writeVarBytes(it.Key())
+		sw.writeVarBytes(it.Value())
+		info.Entries++
+	}
+	if err := it.Error(); err != nil {
+		return nil, err
+	}
+	sw.writeByte(recordEnd)
+
+	if sw.err != nil {
+		return nil, sw.err
+	}
+	copy(info.Checksum[:], sw.hash.Sum(nil))
+	if _, err := w.Write(info.Checksum[:]); err != nil {
+		return nil, err
+	}
+	return info, nil
+}
+
+// ImportSnapshot creates the header store and data store in dataDir from the
+// snapshot read from r. The stores must not exist in dataDir. The header
+// chain and the checksum are verified, the created stores will be removed if
+// the snapshot is invalid.
+func ImportSnapshot(r io.Reader, dataDir string,
+	newHeader func() util.BlockHeader) (*SnapshotInfo, error) {
+	headerDir := filepath.Join(dataDir, "header")
+	dataStoreDir := filepath.Join(dataDir, "store")
+	for _, dir := range []string{headerDir, dataStoreDir} {
+		if _, err := os.Stat(dir); !os.IsNotExist(err) {
+			return nil, fmt.Errorf("store directory %s already exists", dir)
+		}
+	}
+
+	info, err := importSnapshotDirs(r, headerDir, dataStoreDir, newHeader)
+	if err != nil {
+		os.RemoveAll(headerDir)
+		os.RemoveAll(dataStoreDir)
+		return nil, err
+	}
+	return info, nil
+}
+
+func importSnapshotDirs(r io.Reader, headerDir, dataStoreDir string,
+	newHeader func() util.BlockHeader) (*SnapshotInfo, error) {
+	headerDB, err := kv.OpenLevelDB(headerDir)
+	if err != nil {
+		return nil, err
+	}
+	defer headerDB.Close()
+
+	dataDB, err := kv.OpenLevelDB(dataStoreDir)
+	if err != nil {
+		return nil, err
+	}
+	defer dataDB.Close()
+
+	return importSnapshot(r, headerDB, dataDB, newHeader)
+}
+
+// importSnapshot reads the snapshot from r into the empty header database and
+// data database.
+func importSnapshot(r io.Reader, headerDB, dataDB kv.DB,
+	newHeader func() util.BlockHeader) (*SnapshotInfo, error) {
+	sr := newSnapshotReader(r)
+	magic := sr.read(len(snapshotMagic))
+	if sr.err == nil && !bytes.Equal(magic, snapshotMagic) {
+		return nil, errors.New("invalid snapshot file")
+	}
+	version := sr.readUint32()
+	if sr.err == nil && version != snapshotVersion {
+		return nil, fmt.Errorf("unsupported snapshot version %d", version)
+	}
+	info := &SnapshotInfo{Height: sr.readUint32()}
+	copy(info.Hash[:], sr.read(common.UINT256SIZE))
+	if sr.err != nil {
+		return nil, sr.err
+	}
+
+	var previous *util.Header
+	var tip []byte
+	headerBatch := headerDB.NewBatch()
+	dataBatch := dataDB.NewBatch()
+	for {
+		recordType := sr.readByte()
+		if sr.err != nil {
+			return nil, sr.err
+		}
+		if recordType == recordEnd {
+			break
+		}
+
+		switch recordType {
+		case recordHeader:
+			data := sr.readVarBytes()
+			if sr.err != nil {
+				return nil, sr.err
+			}
+			header := &util.Header{BlockHeader: newHeader()}
+			if err := header.Deserialize(data); err != nil {
+				return nil, err
+			}
+			if err := verifyNextHeader(previous, header); err != nil {
+				return nil, err
+			}
+			hash := header.Hash()
+			headerBatch.Put(toKey(BKTHeaders, hash.Bytes()...), data)
+			headerBatch.Put(indexKey(header.Height), hash.Bytes())
+			previous, tip = header, data
+			info.Headers++
+
+		case recordData:
+			key, value := sr.readVarBytes(), sr.readVarBytes()
+			if sr.err != nil {
+				return nil, sr.err
+			}
+			if bytes.Equal(key, BKTSchemaVersion) {
+				return nil, errors.New("unexpected schema version in snapshot")
+			}
+			dataBatch.Put(key, value)
+			info.Entries++
+
+		default:
+			return nil, fmt.Errorf("unknown snapshot record type %d", recordType)
+		}
+
+		if err := flushBatch(headerDB, headerBatch); err != nil {
+			return nil, err
+		}
+		if err := flushBatch(dataDB, dataBatch); err != nil {
+			return nil, err
+		}
+	}
+
+	if previous == nil {
+		return nil, errors.New("no headers in snapshot")
+	}
+	if hash := previous.Hash(); previous.Height != info.Height ||
+		!hash.IsEqual(info.Hash) {
+		return nil, fmt.Errorf("snapshot ends with header %s on height %d,"+
+			" expect %s on height %d", hash, previous.Height, info.Hash,
+			info.Height)
+	}
+
+	copy(info.Checksum[:], sr.hash.Sum(nil))
+	var checksum common.Uint256
+	if _, err := io.ReadFull(r, checksum[:]); err != nil {
+		return nil, err
+	}
+	if !checksum.IsEqual(info.Checksum) {
+		return nil, ErrSnapshotChecksum
+	}
+
+	headerBatch.Put(BKTChainTip, tip)
+	putSchemaVersion(headerBatch, latestVersion(headerMigrations(newHeader)))
+	if err := headerDB.Write(headerBatch); err != nil {
+		return nil, err
+	}
+	putSchemaVersion(dataBatch, latestVersion(dataMigrations))
+	if err := dataDB.Write(dataBatch); err != nil {
+		return nil, err
+	}
+	return info, nil
+}
+
+// flushBatch writes the batch into db when it's full.
+func flushBatch(db kv.DB, batch kv.Batch) error {
+	if batch.Len() < copyBatchSize {
+		return nil
+	}
+	if err := db.Write(batch); err != nil {
+		return err
+	}
+	batch.Reset()
+	return nil
+}
+
+// verifyNextHeader checks header is the next header of previous on the chain,
+// the first header of the snapshot can be any header.
+func verifyNextHeader(previous, header *util.Header) error {
+	if previous == nil {
+		return nil
+	}
+	if header.Height != previous.Height+1 {
+		return fmt.Errorf("header height %d not follow height %d",
+			header.Height, previous.Height)
+	}
+	if hash := previous.Hash(); !header.Previous().IsEqual(hash) {
+		return fmt.Errorf("header on height %d not connect to previous header %s",
+			header.Height, hash)
+	}
+	return nil
+}
+
+// snapshotWriter writes snapshot content and computes it's checksum, the
+// first error is kept and the following writes are ignored.
+type snapshotWriter struct {
+	w    io.Writer
+	hash hash.Hash
+	err  error
+}
+
+func newSnapshotWriter(w io.Writer) *snapshotWriter {
+	h := sha256.New()
+	return &snapshotWriter{w: io.MultiWriter(w, h), hash: h}
+}
+
+func (w *snapshotWriter) write(data []byte) {
+	if w.err == nil {
+		_, w.err = w.w.Write(data)
+	}
+}
+
+func (w *snapshotWriter) writeByte(b byte) {
+	w.write([]byte{b})
+}
+
+func (w *snapshotWriter) writeUint32(v uint32) {
+	if w.err == nil {
+		w.err = common.WriteUint32(w.w, v)
+	}
+}
+
+func (w *snapshotWriter) writeVarBytes(data []byte) {
+	if w.err == nil {
+		w.err = common.WriteVarBytes(w.w, data)
+	}
+}
+
+// snapshotReader reads snapshot content and computes it's checksum, the
+// first error is kept and the following reads return zero values.
+type snapshotReader struct {
+	r    io.Reader
+	hash hash.Hash
+	err  error
+}
+
+func newSnapshotReader(r io.Reader) *snapshotReader {
+	h := sha256.New()
+	return &snapshotReader{r: io.TeeReader(r, h), hash: h}
+}
+
+func (r *snapshotReader) read(n int) []byte {
+	if r.err != nil {
+		return nil
+	}
+	data := make([]byte, n)
+	_, r.err = io.ReadFull(r.r, data)
+	return data
+}
+
+func (r *snapshotReader) readByte() byte {
+	data := r.read(1)
+	if r.err != nil {
+		return 0
+	}
+	return data[0]
+}
+
+func (r *snapshotReader) readUint32() uint32 {
+	if r.err != nil {
+		return 0
+	}
+	var v uint32
+	v, r.err = common.ReadUint32(r.r)
+	return v
+}
+
+func (r *snapshotReader) readVarBytes() []byte {
+	if r.err != nil {
+		return nil
+	}
+	var data []byte
+	data, r.err = common.ReadVarBytes(r.r, pact.MaxBlockContextSize,
+		"snapshot record")
+	return data
+}
@@ -0,0 +1,151 @@
+package store
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/elastos/Elastos.ELA.SPV/util"
+
+	"github.com/elastos/Elastos.ELA/common"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSnapshot(t *testing.T) {
+	dataDir := "spv_test"
+	os.RemoveAll(dataDir)
+	defer os.RemoveAll(dataDir)
+
+	headers, err := NewMemoryHeaderStore(newEmptyHeader)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	defer headers.Close()
+	data, err := NewMemoryDataStore(nil, 36, "")
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	defer data.Close()
+
+	// main chain from height 0 to 5 with a fork header on height 4.
+	var chain []*util.Header
+	var previous *util.Header
+	for i := uint32(0); i <= 5; i++ {
+		header := newHeader(previous, 1000+i*10, 0)
+		assert.NoError(t, headers.Put(header, true))
+		chain = append(chain, header)
+		previous = header
+	}
+	assert.NoError(t, headers.Put(newHeader(chain[3], 1040, 1), false))
+
+	tx1, tx2 := newStoreTx(1, 3), newStoreTx(2, 5)
+	assert.NoError(t, data.Txs().Put(tx1))
+	assert.NoError(t, data.Txs().Put(tx2))
+	assert.NoError(t, data.TxTypes().Put(1))
+
+	// export the snapshot on the best height.
+	buf := new(bytes.Buffer)
+	info, err := ExportSnapshot(buf, headers, data, 5)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	assert.Equal(t, uint32(5), info.Height)
+	assert.Equal(t, chain[5].Hash(), info.Hash)
+	assert.Equal(t, 6, info.Headers)
+	snapshot := buf.Bytes()
+
+	// height below or above the best height.
+	_, err = ExportSnapshot(new(bytes.Buffer), headers, data, 4)
+	assert.Error(t, err)
+	_, err = ExportSnapshot(new(bytes.Buffer), headers, data, 6)
+	assert.Error(t, err)
+
+	// corrupted snapshot is rejected and the stores are removed.
+	corrupted := append([]byte{}, snapshot...)
+	corrupted[len(corrupted)-40] ^= 0xff
+	_, err = ImportSnapshot(bytes.NewReader(corrupted), dataDir, newEmptyHeader)
+	assert.Error(t, err)
+	_, err = os.Stat(filepath.Join(dataDir, "header"))
+	assert.True(t, os.IsNotExist(err))
+
+	imported, err := ImportSnapshot(bytes.NewReader(snapshot), dataDir,
+		newEmptyHeader)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	assert.Equal(t, info, imported)
+
+	// import into existing stores is not allowed.
+	_, err = ImportSnapshot(bytes.NewReader(snapshot), dataDir, newEmptyHeader)
+	assert.Error(t, err)
+
+	headers2, err := NewHeaderStore(dataDir, newEmptyHeader)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	defer headers2.Close()
+	best, err := headers2.GetBest()
+	assert.NoError(t, err)
+	assert.Equal(t, chain[5].Hash(), best.Hash())
+	result, err := headers2.GetRange(0, 5)
+	assert.NoError(t, err)
+	if assert.Equal(t, 6, len(result)) {
+		for i, header := range result {
+			assert.Equal(t, chain[i].Hash(), header.Hash())
+		}
+	}
+
+	data2, err := NewDataStore(dataDir, nil, 36, "")
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	defer data2.Close()
+	assert.Equal(t, []uint8{1}, data2.TxTypes().GetAll())
+	_, err = data2.Txs().Get(&tx1.Hash)
+	assert.NoError(t, err)
+	_, err = data2.Txs().Get(&tx2.Hash)
+	assert.NoError(t, err)
+	ids, err := data2.Txs().GetIds(5)
+	assert.NoError(t, err)
+	assert.Equal(t, []*common.Uint256{&tx2.Hash}, ids)
+}
+
+func TestImportSnapshot_BrokenChain(t *testing.T) {
+	dataDir := "spv_test"
+	os.RemoveAll(dataDir)
+	defer os.RemoveAll(dataDir)
+
+	headers, err := NewMemoryHeaderStore(newEmptyHeader)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	defer headers.Close()
+	data, err := NewMemoryDataStore(nil, 36, "")
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	defer data.Close()
+
+	// the header on height 2 is not connected to height 1.
+	h0 := newHeader(nil, 1000, 0)
+	h1 := newHeader(h0, 1010, 0)
+	h2 := newHeader(newHeader(h0, 1010, 1), 1020, 0)
+	for _, header := range []*util.Header{h0, h1, h2} {
+		assert.NoError(t, headers.Put(header, false))
+	}
+	for _, header := range []*util.Header{h0, h1, h2} {
+		hash := header.Hash()
+		assert.NoError(t, headers.db.Put(indexKey(header.Height), hash.Bytes()))
+	}
+
+	buf := new(bytes.Buffer)
+	_, err = ExportSnapshot(buf, headers, data, 2)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	_, err = ImportSnapshot(buf, dataDir, newEmptyHeader)
+	assert.Error(t, err)
+	_, err = os.Stat(filepath.Join(dataDir, "store"))
+	assert.True(t, os.IsNotExist(err))
+}
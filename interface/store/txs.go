@@ -5,11 +5,10 @@ import (
 	"encoding/binary"
 	"sync"
 
+	"github.com/elastos/Elastos.ELA.SPV/interface/store/kv"
 	"github.com/elastos/Elastos.ELA.SPV/util"
 
 	"github.com/elastos/Elastos.ELA/common"
-	"github.com/syndtr/goleveldb/leveldb"
-	dbutil "github.com/syndtr/goleveldb/leveldb/util"
 )
 
 // Ensure txs implement Txs interface.
@@ -17,10 +16,10 @@ var _ Txs = (*txs)(nil)
 
 type txs struct {
 	sync.RWMutex
-	db *leveldb.DB
+	db kv.DB
 }
 
-func NewTxs(db *leveldb.DB) *txs {
+func NewTxs(db kv.DB) *txs {
 	return &txs{db: db}
 }
 
@@ -33,15 +32,15 @@ func (t *txs) Put(txn *util.Tx) error {
 		return err
 	}
 
-	batch := new(leveldb.Batch)
+	batch := t.db.NewBatch()
 	batch.Put(toKey(BKTTxs, txn.Hash.Bytes()...), buf.Bytes())
 
 	var key [4]byte
 	binary.BigEndian.PutUint32(key[:], txn.Height)
-	data, _ := t.db.Get(toKey(BKTHeightTxs, key[:]...), nil)
+	data, _ := t.db.Get(toKey(BKTHeightTxs, key[:]...))
 	batch.Put(toKey(BKTHeightTxs, key[:]...), putTxId(data, &txn.Hash))
 
-	return t.db.Write(batch, nil)
+	return t.db.Write(batch)
 }
 
 func putTxId(data []byte, txId *common.Uint256) []byte {
@@ -62,7 +61,7 @@ func (t *txs) Get(hash *common.Uint256) (txn *util.Tx, err error) {
 	t.RLock()
 	defer t.RUnlock()
 
-	data, err := t.db.Get(toKey(BKTTxs, hash.Bytes()...), nil)
+	data, err := t.db.Get(toKey(BKTTxs, hash.Bytes()...))
 	if err != nil {
 		return nil, err
 	}
@@ -81,7 +80,7 @@ func (t *txs) GetIds(height uint32) (txIds []*common.Uint256, err error) {
 
 	var key [4]byte
 	binary.BigEndian.PutUint32(key[:], height)
-	data, _ := t.db.Get(toKey(BKTHeightTxs, key[:]...), nil)
+	data, _ := t.db.Get(toKey(BKTHeightTxs, key[:]...))
 	return getTxIds(data), nil
 }
 
@@ -117,14 +116,14 @@ func (t *txs) PutForkTxs(txs []*util.Tx, hash *common.Uint256) error {
 			return err
 		}
 	}
-	return t.db.Put(toKey(BKTForkTxs, hash.Bytes()...), buf.Bytes(), nil)
+	return t.db.Put(toKey(BKTForkTxs, hash.Bytes()...), buf.Bytes())
 }
 
 func (t *txs) GetForkTxs(hash *common.Uint256) ([]*util.Tx, error) {
 	t.RLock()
 	defer t.RUnlock()
 
-	data, err := t.db.Get(toKey(BKTForkTxs, hash.Bytes()...), nil)
+	data, err := t.db.Get(toKey(BKTForkTxs, hash.Bytes()...))
 	if err != nil {
 		return nil, err
 	}
@@ -149,7 +148,7 @@ func (t *txs) GetAll() (txs []*util.Tx, err error) {
 	t.RLock()
 	defer t.RUnlock()
 
-	it := t.db.NewIterator(dbutil.BytesPrefix(BKTTxs), nil)
+	it := t.db.NewIterator(kv.BytesPrefix(BKTTxs))
 	defer it.Release()
 	for it.Next() {
 		var txn util.Tx
@@ -167,7 +166,7 @@ func (t *txs) Del(txId *common.Uint256) error {
 	defer t.Unlock()
 
 	var txn util.Tx
-	data, err := t.db.Get(toKey(BKTTxs, txId.Bytes()...), nil)
+	data, err := t.db.Get(toKey(BKTTxs, txId.Bytes()...))
 	if err != nil {
 		return err
 	}
@@ -178,13 +177,13 @@ func (t *txs) Del(txId *common.Uint256) error {
 
 	var key [4]byte
 	binary.BigEndian.PutUint32(key[:], txn.Height)
-	data, _ = t.db.Get(toKey(BKTHeightTxs, key[:]...), nil)
+	data, _ = t.db.Get(toKey(BKTHeightTxs, key[:]...))
 
-	batch := new(leveldb.Batch)
+	batch := t.db.NewBatch()
 	batch.Delete(toKey(BKTTxs, txId.Bytes()...))
 	batch.Put(toKey(BKTHeightTxs, key[:]...), delTxId(data, &txn.Hash))
 
-	return t.db.Write(batch, nil)
+	return t.db.Write(batch)
 }
 
 func delTxId(data []byte, hash *common.Uint256) []byte {
@@ -212,27 +211,27 @@ func delTxId(data []byte, hash *common.Uint256) []byte {
 }
 
 func (t *txs) Batch() TxsBatch {
-	return &txsBatch{DB: t.db, Batch: new(leveldb.Batch)}
+	return &txsBatch{DB: t.db, Batch: t.db.NewBatch()}
 }
 
 func (t *txs) Clear() error {
 	t.Lock()
 	defer t.Unlock()
 
-	it := t.db.NewIterator(dbutil.BytesPrefix(BKTTxs), nil)
-	batch := new(leveldb.Batch)
+	it := t.db.NewIterator(kv.BytesPrefix(BKTTxs))
+	batch := t.db.NewBatch()
 	for it.Next() {
 		batch.Delete(it.Key())
 	}
 	it.Release()
 
-	it = t.db.NewIterator(dbutil.BytesPrefix(BKTHeightTxs), nil)
+	it = t.db.NewIterator(kv.BytesPrefix(BKTHeightTxs))
 	for it.Next() {
 		batch.Delete(it.Key())
 	}
 	it.Release()
 
-	return t.db.Write(batch, nil)
+	return t.db.Write(batch)
 }
 
 func (t *txs) Close() error {
@@ -5,10 +5,10 @@ import (
 	"encoding/binary"
 	"sync"
 
+	"github.com/elastos/Elastos.ELA.SPV/interface/store/kv"
 	"github.com/elastos/Elastos.ELA.SPV/util"
 
 	"github.com/elastos/Elastos.ELA/common"
-	"github.com/syndtr/goleveldb/leveldb"
 )
 
 // Ensure txsBatch implement TxsBatch interface.
@@ -16,8 +16,8 @@ var _ TxsBatch = (*txsBatch)(nil)
 
 type txsBatch struct {
 	sync.Mutex
-	*leveldb.DB
-	*leveldb.Batch
+	kv.DB
+	kv.Batch
 	addTxs []*util.Tx
 	delTxs []*util.Tx
 }
@@ -36,12 +36,27 @@ func (b *txsBatch) Put(tx *util.Tx) error {
 	return nil
 }
 
+func (b *txsBatch) Get(txId *common.Uint256) (*util.Tx, error) {
+	b.Lock()
+	defer b.Unlock()
+
+	data, err := batchGet(b.DB, b.Batch, toKey(BKTTxs, txId.Bytes()...))
+	if err != nil {
+		return nil, err
+	}
+	var tx util.Tx
+	if err := tx.Deserialize(bytes.NewReader(data)); err != nil {
+		return nil, err
+	}
+	return &tx, nil
+}
+
 func (b *txsBatch) Del(txId *common.Uint256) error {
 	b.Lock()
 	defer b.Unlock()
 
 	var tx util.Tx
-	data, err := b.DB.Get(toKey(BKTTxs, txId.Bytes()...), nil)
+	data, err := b.DB.Get(toKey(BKTTxs, txId.Bytes()...))
 	if err != nil {
 		return err
 	}
@@ -60,7 +75,7 @@ func (b *txsBatch) DelAll(height uint32) error {
 
 	var key [4]byte
 	binary.BigEndian.PutUint32(key[:], height)
-	data, _ := b.DB.Get(toKey(BKTHeightTxs, key[:]...), nil)
+	data, _ := b.DB.Get(toKey(BKTHeightTxs, key[:]...))
 	for _, txID := range getTxIds(data) {
 		b.Batch.Delete(toKey(BKTTxs, txID.Bytes()...))
 	}
@@ -86,7 +101,7 @@ func (b *txsBatch) Commit() error {
 		for height, txs := range groups {
 			var key [4]byte
 			binary.BigEndian.PutUint32(key[:], height)
-			data, _ := b.DB.Get(toKey(BKTHeightTxs, key[:]...), nil)
+			data, _ := b.DB.Get(toKey(BKTHeightTxs, key[:]...))
 			for _, tx := range txs {
 				data = putTxId(data, &tx.Hash)
 			}
@@ -100,7 +115,7 @@ func (b *txsBatch) Commit() error {
 		for height, txs := range groups {
 			var key [4]byte
 			binary.BigEndian.PutUint32(key[:], height)
-			data, _ := b.DB.Get(toKey(BKTHeightTxs, key[:]...), nil)
+			data, _ := b.DB.Get(toKey(BKTHeightTxs, key[:]...))
 			for _, tx := range txs {
 				data = delTxId(data, &tx.Hash)
 			}
@@ -108,7 +123,7 @@ func (b *txsBatch) Commit() error {
 		}
 	}
 
-	return b.DB.Write(b.Batch, nil)
+	return b.DB.Write(b.Batch)
 }
 
 func groupByHeight(txs []*util.Tx) map[uint32][]*util.Tx {
@@ -4,10 +4,8 @@ import (
 	"errors"
 	"sync"
 
+	"github.com/elastos/Elastos.ELA.SPV/interface/store/kv"
 	"github.com/elastos/Elastos.ELA.SPV/sdk"
-
-	"github.com/syndtr/goleveldb/leveldb"
-	"github.com/syndtr/goleveldb/leveldb/util"
 )
 
 // Ensure addrs implement Addrs interface.
@@ -15,11 +13,11 @@ var _ TxTypes = (*txTypes)(nil)
 
 type txTypes struct {
 	sync.RWMutex
-	db     *leveldb.DB
+	db     kv.DB
 	filter *sdk.TxTypesFilter
 }
 
-func NewTxTypes(db *leveldb.DB) (*txTypes, error) {
+func NewTxTypes(db kv.DB) (*txTypes, error) {
 	store := txTypes{db: db}
 
 	addrs, err := store.getAll()
@@ -46,7 +44,7 @@ func (a *txTypes) Put(txType uint8) error {
 	}
 
 	a.filter.AddTxType(txType)
-	return a.db.Put(toKey(BKTTxTypes, txType), []byte{txType}, nil)
+	return a.db.Put(toKey(BKTTxTypes, txType), []byte{txType})
 }
 
 func (a *txTypes) GetAll() []uint8 {
@@ -56,7 +54,7 @@ func (a *txTypes) GetAll() []uint8 {
 }
 
 func (a *txTypes) getAll() (txTypes []uint8, err error) {
-	it := a.db.NewIterator(util.BytesPrefix(BKTTxTypes), nil)
+	it := a.db.NewIterator(kv.BytesPrefix(BKTTxTypes))
 	defer it.Release()
 	for it.Next() {
 		if len(it.Value()) != 1 {
@@ -73,13 +71,13 @@ func (a *txTypes) Clear() error {
 	a.Lock()
 	defer a.Unlock()
 
-	it := a.db.NewIterator(util.BytesPrefix(BKTTxTypes), nil)
+	it := a.db.NewIterator(kv.BytesPrefix(BKTTxTypes))
 	defer it.Release()
-	batch := new(leveldb.Batch)
+	batch := a.db.NewBatch()
 	for it.Next() {
 		batch.Delete(it.Key())
 	}
-	return a.db.Write(batch, nil)
+	return a.db.Write(batch)
 }
 
 func (a *txTypes) Close() error {
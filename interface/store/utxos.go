@@ -0,0 +1,295 @@
+package store
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"sync"
+
+	"github.com/elastos/Elastos.ELA.SPV/interface/store/kv"
+	"github.com/elastos/Elastos.ELA.SPV/util"
+
+	"github.com/elastos/Elastos.ELA/common"
+)
+
+// Ensure utxos implement UTXOs interface.
+var _ UTXOs = (*utxos)(nil)
+
+// UTXO is an unspent output paid to a watched address.
+type UTXO struct {
+	Op      util.OutPoint
+	Address common.Uint168
+	Value   common.Fixed64
+
+	// LockTime is the height the output can be spent after, coinbase outputs
+	// are locked until maturity.
+	LockTime uint32
+
+	// AtHeight is the height of the block the output packed in.
+	AtHeight uint32
+}
+
+func (u *UTXO) Serialize(w io.Writer) error {
+	if err := u.Op.TxID.Serialize(w); err != nil {
+		return err
+	}
+	if err := common.WriteUint16(w, u.Op.Index); err != nil {
+		return err
+	}
+	if err := u.Address.Serialize(w); err != nil {
+		return err
+	}
+	if err := u.Value.Serialize(w); err != nil {
+		return err
+	}
+	if err := common.WriteUint32(w, u.LockTime); err != nil {
+		return err
+	}
+	return common.WriteUint32(w, u.AtHeight)
+}
+
+func (u *UTXO) Deserialize(r io.Reader) error {
+	if err := u.Op.TxID.Deserialize(r); err != nil {
+		return err
+	}
+	var err error
+	if u.Op.Index, err = common.ReadUint16(r); err != nil {
+		return err
+	}
+	if err := u.Address.Deserialize(r); err != nil {
+		return err
+	}
+	if err := u.Value.Deserialize(r); err != nil {
+		return err
+	}
+	if u.LockTime, err = common.ReadUint32(r); err != nil {
+		return err
+	}
+	u.AtHeight, err = common.ReadUint32(r)
+	return err
+}
+
+// STXO is a spent output of a watched address.
+type STXO struct {
+	UTXO
+
+	// SpendHeight is the height of the block the spending transaction
+	// packed in.
+	SpendHeight uint32
+
+	// SpendTxId is the hash of the spending transaction.
+	SpendTxId common.Uint256
+}
+
+func (s *STXO) Serialize(w io.Writer) error {
+	if err := s.UTXO.Serialize(w); err != nil {
+		return err
+	}
+	if err := common.WriteUint32(w, s.SpendHeight); err != nil {
+		return err
+	}
+	return s.SpendTxId.Serialize(w)
+}
+
+func (s *STXO) Deserialize(r io.Reader) error {
+	if err := s.UTXO.Deserialize(r); err != nil {
+		return err
+	}
+	var err error
+	if s.SpendHeight, err = common.ReadUint32(r); err != nil {
+		return err
+	}
+	return s.SpendTxId.Deserialize(r)
+}
+
+type utxos struct {
+	sync.RWMutex
+	db kv.DB
+}
+
+func NewUTXOs(db kv.DB) *utxos {
+	return &utxos{db: db}
+}
+
+func (u *utxos) BatchPut(utxo *UTXO, batch kv.Batch) error {
+	u.Lock()
+	defer u.Unlock()
+
+	return batchPutUTXO(utxo, batch)
+}
+
+// BatchSpend moves the UTXO of the outpoint to STXO, returns nil if the
+// outpoint is not a UTXO.  UTXOs put within the same batch can be spent.
+func (u *utxos) BatchSpend(op *util.OutPoint, height uint32,
+	txId common.Uint256, batch kv.Batch) (*STXO, error) {
+	u.Lock()
+	defer u.Unlock()
+
+	data, err := batchGet(u.db, batch, toKey(BKTUTXOs, op.Bytes()...))
+	if err == kv.ErrNotFound {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	stxo := STXO{SpendHeight: height, SpendTxId: txId}
+	if err := stxo.UTXO.Deserialize(bytes.NewReader(data)); err != nil {
+		return nil, err
+	}
+	buf := new(bytes.Buffer)
+	if err := stxo.Serialize(buf); err != nil {
+		return nil, err
+	}
+
+	var key [4]byte
+	binary.BigEndian.PutUint32(key[:], height)
+	batch.Delete(toKey(BKTUTXOs, op.Bytes()...))
+	batch.Delete(joinKey(BKTAddrUTXOs, stxo.Address[:], op.Bytes()))
+	batch.Put(toKey(BKTSTXOs, op.Bytes()...), buf.Bytes())
+	batch.Put(joinKey(BKTSTXOHeights, key[:], op.Bytes()), empty)
+	return &stxo, nil
+}
+
+// BatchDeleteAll restores the outputs spent on the given height to UTXOs,
+// and removes the UTXOs created on the given height.
+func (u *utxos) BatchDeleteAll(height uint32, batch kv.Batch) error {
+	u.Lock()
+	defer u.Unlock()
+
+	var key [4]byte
+	binary.BigEndian.PutUint32(key[:], height)
+
+	// Restore spent outputs first, the outputs created and spent on the same
+	// height are removed later.
+	prefix := joinKey(BKTSTXOHeights, key[:])
+	it := u.db.NewIterator(kv.BytesPrefix(prefix))
+	for it.Next() {
+		op := subKey(prefix, it.Key())
+		data, err := u.db.Get(toKey(BKTSTXOs, op...))
+		if err == nil {
+			var stxo STXO
+			if err := stxo.Deserialize(bytes.NewReader(data)); err != nil {
+				it.Release()
+				return err
+			}
+			if err := batchPutUTXO(&stxo.UTXO, batch); err != nil {
+				it.Release()
+				return err
+			}
+		}
+		batch.Delete(toKey(BKTSTXOs, op...))
+		batch.Delete(append([]byte{}, it.Key()...))
+	}
+	it.Release()
+	if err := it.Error(); err != nil {
+		return err
+	}
+
+	prefix = joinKey(BKTUTXOHeights, key[:])
+	it = u.db.NewIterator(kv.BytesPrefix(prefix))
+	defer it.Release()
+	for it.Next() {
+		op := subKey(prefix, it.Key())
+		data, err := batchGet(u.db, batch, toKey(BKTUTXOs, op...))
+		if err == nil {
+			var utxo UTXO
+			if err := utxo.Deserialize(bytes.NewReader(data)); err != nil {
+				return err
+			}
+			batch.Delete(joinKey(BKTAddrUTXOs, utxo.Address[:], op))
+		}
+		batch.Delete(toKey(BKTUTXOs, op...))
+		batch.Delete(append([]byte{}, it.Key()...))
+	}
+	return it.Error()
+}
+
+func (u *utxos) Get(op *util.OutPoint) (*UTXO, error) {
+	u.RLock()
+	defer u.RUnlock()
+
+	data, err := u.db.Get(toKey(BKTUTXOs, op.Bytes()...))
+	if err != nil {
+		return nil, err
+	}
+	var utxo UTXO
+	if err := utxo.Deserialize(bytes.NewReader(data)); err != nil {
+		return nil, err
+	}
+	return &utxo, nil
+}
+
+func (u *utxos) GetSpent(op *util.OutPoint) (*STXO, error) {
+	u.RLock()
+	defer u.RUnlock()
+
+	data, err := u.db.Get(toKey(BKTSTXOs, op.Bytes()...))
+	if err != nil {
+		return nil, err
+	}
+	var stxo STXO
+	if err := stxo.Deserialize(bytes.NewReader(data)); err != nil {
+		return nil, err
+	}
+	return &stxo, nil
+}
+
+// List returns the UTXOs of the address ordered by outpoint.
+func (u *utxos) List(addr common.Uint168) ([]*UTXO, error) {
+	u.RLock()
+	defer u.RUnlock()
+
+	prefix := joinKey(BKTAddrUTXOs, addr[:])
+	it := u.db.NewIterator(kv.BytesPrefix(prefix))
+	defer it.Release()
+
+	var utxos []*UTXO
+	for it.Next() {
+		data, err := u.db.Get(toKey(BKTUTXOs, subKey(prefix, it.Key())...))
+		if err != nil {
+			return nil, err
+		}
+		var utxo UTXO
+		if err := utxo.Deserialize(bytes.NewReader(data)); err != nil {
+			return nil, err
+		}
+		utxos = append(utxos, &utxo)
+	}
+	return utxos, it.Error()
+}
+
+func (u *utxos) Clear() error {
+	u.Lock()
+	defer u.Unlock()
+
+	batch := u.db.NewBatch()
+	for _, prefix := range [][]byte{BKTUTXOs, BKTAddrUTXOs, BKTUTXOHeights,
+		BKTSTXOs, BKTSTXOHeights} {
+		it := u.db.NewIterator(kv.BytesPrefix(prefix))
+		for it.Next() {
+			batch.Delete(it.Key())
+		}
+		it.Release()
+	}
+	return u.db.Write(batch)
+}
+
+func (u *utxos) Close() error {
+	u.Lock()
+	return nil
+}
+
+func batchPutUTXO(utxo *UTXO, batch kv.Batch) error {
+	buf := new(bytes.Buffer)
+	if err := utxo.Serialize(buf); err != nil {
+		return err
+	}
+
+	op := utxo.Op.Bytes()
+	var height [4]byte
+	binary.BigEndian.PutUint32(height[:], utxo.AtHeight)
+	batch.Put(toKey(BKTUTXOs, op...), buf.Bytes())
+	batch.Put(joinKey(BKTAddrUTXOs, utxo.Address[:], op), empty)
+	batch.Put(joinKey(BKTUTXOHeights, height[:], op), empty)
+	return nil
+}
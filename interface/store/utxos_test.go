@@ -0,0 +1,80 @@
+package store
+
+import (
+	"testing"
+
+	"github.com/elastos/Elastos.ELA.SPV/util"
+
+	"github.com/elastos/Elastos.ELA/common"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUTXOs(t *testing.T) {
+	data, err := NewMemoryDataStore(nil, 36, "")
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	defer data.Close()
+
+	addr := common.Uint168{0x21, 1}
+	other := common.Uint168{0x21, 2}
+	utxo1 := &UTXO{Op: *util.NewOutPoint(common.Uint256{1}, 0),
+		Address: addr, Value: 10, AtHeight: 100}
+	utxo2 := &UTXO{Op: *util.NewOutPoint(common.Uint256{2}, 1),
+		Address: addr, Value: 20, LockTime: 200, AtHeight: 101}
+	utxo3 := &UTXO{Op: *util.NewOutPoint(common.Uint256{3}, 0),
+		Address: other, Value: 30, AtHeight: 101}
+
+	batch := data.db.NewBatch()
+	assert.NoError(t, data.UTXOs().BatchPut(utxo1, batch))
+	assert.NoError(t, data.db.Write(batch))
+
+	// utxo3 is created and spent on the same height.
+	batch = data.db.NewBatch()
+	assert.NoError(t, data.UTXOs().BatchPut(utxo2, batch))
+	assert.NoError(t, data.UTXOs().BatchPut(utxo3, batch))
+	stxo, err := data.UTXOs().BatchSpend(&utxo1.Op, 101, common.Uint256{4}, batch)
+	if assert.NoError(t, err) && assert.NotNil(t, stxo) {
+		assert.Equal(t, *utxo1, stxo.UTXO)
+	}
+	stxo, err = data.UTXOs().BatchSpend(&utxo3.Op, 101, common.Uint256{5}, batch)
+	assert.NoError(t, err)
+	assert.NotNil(t, stxo)
+	stxo, err = data.UTXOs().BatchSpend(util.NewOutPoint(common.Uint256{6}, 0),
+		101, common.Uint256{5}, batch)
+	assert.NoError(t, err)
+	assert.Nil(t, stxo)
+	assert.NoError(t, data.db.Write(batch))
+
+	utxos, err := data.UTXOs().List(addr)
+	assert.NoError(t, err)
+	assert.Equal(t, []*UTXO{utxo2}, utxos)
+	utxos, err = data.UTXOs().List(other)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, len(utxos))
+	_, err = data.UTXOs().Get(&utxo1.Op)
+	assert.Error(t, err)
+	stxo, err = data.UTXOs().GetSpent(&utxo1.Op)
+	if assert.NoError(t, err) {
+		assert.Equal(t, uint32(101), stxo.SpendHeight)
+		assert.Equal(t, common.Uint256{4}, stxo.SpendTxId)
+	}
+
+	// rollback restores the spent output and removes the created outputs.
+	dataBatch := data.Batch()
+	assert.NoError(t, dataBatch.DelAll(101))
+	assert.NoError(t, dataBatch.Commit())
+	utxos, err = data.UTXOs().List(addr)
+	assert.NoError(t, err)
+	assert.Equal(t, []*UTXO{utxo1}, utxos)
+	utxos, err = data.UTXOs().List(other)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, len(utxos))
+	for _, op := range []*util.OutPoint{&utxo1.Op, &utxo2.Op, &utxo3.Op} {
+		_, err = data.UTXOs().GetSpent(op)
+		assert.Error(t, err)
+	}
+	_, err = data.UTXOs().Get(&utxo3.Op)
+	assert.Error(t, err)
+}
@@ -0,0 +1,161 @@
+package main
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"fmt"
+
+	"github.com/elastos/Elastos.ELA.SPV/bloom"
+	"github.com/elastos/Elastos.ELA.SPV/interface/proof"
+	"github.com/elastos/Elastos.ELA.SPV/util"
+	"github.com/elastos/Elastos.ELA.SPV/wallet/sutil"
+
+	"github.com/elastos/Elastos.ELA/common"
+	elatx "github.com/elastos/Elastos.ELA/core/transaction"
+	"github.com/elastos/Elastos.ELA/utils/http"
+)
+
+// maxGetHeaders is the maximum headers can be returned by getheaders.
+const maxGetHeaders = 2000
+
+// headerInfo is the block header returned by the proof provider RPC.
+type headerInfo struct {
+	Hash         string   `json:"hash"`
+	Height       uint32   `json:"height"`
+	PreviousHash string   `json:"previousblockhash"`
+	MerkleRoot   string   `json:"merkleroot"`
+	Timestamp    uint32   `json:"time"`
+	NumTxs       uint32   `json:"numtxs"`
+	Hashes       []string `json:"hashes"`
+	Flags        string   `json:"flags"`
+	Header       string   `json:"header"`
+}
+
+func newHeaderInfo(header *util.Header) (*headerInfo, error) {
+	h, ok := header.BlockHeader.(*sutil.Header)
+	if !ok {
+		return nil, errors.New("invalid block header")
+	}
+	buf := new(bytes.Buffer)
+	if err := h.Header.Serialize(buf); err != nil {
+		return nil, err
+	}
+
+	hashes := make([]string, 0, len(header.Hashes))
+	for _, hash := range header.Hashes {
+		hashes = append(hashes, hash.String())
+	}
+	return &headerInfo{
+		Hash:         header.Hash().String(),
+		Height:       header.Height,
+		PreviousHash: header.Previous().String(),
+		MerkleRoot:   header.MerkleRoot().String(),
+		Timestamp:    h.Timestamp,
+		NumTxs:       header.NumTxs,
+		Hashes:       hashes,
+		Flags:        hex.EncodeToString(header.Flags),
+		Header:       hex.EncodeToString(buf.Bytes()),
+	}, nil
+}
+
+// Functions for proof provider RPC service.
+func (w *spvwallet) getTxProof(params http.Params) (interface{}, error) {
+	str, ok := params.String("txid")
+	if !ok {
+		return nil, ErrInvalidParameter
+	}
+	txId, err := common.Uint256FromHexString(str)
+	if err != nil {
+		return nil, ErrInvalidParameter
+	}
+
+	utx, err := w.db.Txs().Get(txId)
+	if err != nil {
+		return nil, fmt.Errorf("transaction %s not found", str)
+	}
+	if utx.Height == 0 {
+		return nil, fmt.Errorf("transaction %s not confirmed", str)
+	}
+	header, err := w.headers.GetByHeight(utx.Height)
+	if err != nil {
+		return nil, err
+	}
+	h, ok := header.BlockHeader.(*sutil.Header)
+	if !ok {
+		return nil, errors.New("invalid block header")
+	}
+
+	r := bytes.NewReader(utx.RawData)
+	tx, err := elatx.GetTransactionByBytes(r)
+	if err != nil {
+		return nil, fmt.Errorf("invalid transaction %s", str)
+	}
+	if err := tx.Deserialize(r); err != nil {
+		return nil, err
+	}
+
+	p := proof.TransactionProof{
+		Proof: bloom.MerkleProof{
+			BlockHash:    header.Hash(),
+			Height:       header.Height,
+			Transactions: header.NumTxs,
+			Hashes:       header.Hashes,
+			Flags:        header.Flags,
+		},
+		Header:      h.Header,
+		Transaction: tx,
+	}
+	if err := p.Verify(); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+func (w *spvwallet) getHeader(params http.Params) (interface{}, error) {
+	var header *util.Header
+	if str, ok := params.String("hash"); ok {
+		hash, err := common.Uint256FromHexString(str)
+		if err != nil {
+			return nil, ErrInvalidParameter
+		}
+		header, err = w.headers.Get(hash)
+		if err != nil {
+			return nil, err
+		}
+	} else if height, ok := params.Uint("height"); ok {
+		var err error
+		header, err = w.headers.GetByHeight(height)
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		return nil, ErrInvalidParameter
+	}
+	return newHeaderInfo(header)
+}
+
+func (w *spvwallet) getHeaders(params http.Params) (interface{}, error) {
+	start, ok := params.Uint("start")
+	if !ok {
+		return nil, ErrInvalidParameter
+	}
+	end, ok := params.Uint("end")
+	if !ok || end < start || end-start >= maxGetHeaders {
+		return nil, ErrInvalidParameter
+	}
+
+	headers, err := w.headers.GetRange(start, end)
+	if err != nil {
+		return nil, err
+	}
+	infos := make([]*headerInfo, 0, len(headers))
+	for _, header := range headers {
+		info, err := newHeaderInfo(header)
+		if err != nil {
+			return nil, err
+		}
+		infos = append(infos, info)
+	}
+	return infos, nil
+}
@@ -0,0 +1,164 @@
+package main
+
+import (
+	"math/big"
+	"os"
+	"testing"
+
+	"github.com/elastos/Elastos.ELA.SPV/bloom"
+	"github.com/elastos/Elastos.ELA.SPV/interface/proof"
+	"github.com/elastos/Elastos.ELA.SPV/util"
+	"github.com/elastos/Elastos.ELA.SPV/wallet/store/headers"
+	"github.com/elastos/Elastos.ELA.SPV/wallet/store/sqlite"
+	"github.com/elastos/Elastos.ELA.SPV/wallet/sutil"
+
+	"github.com/elastos/Elastos.ELA/common"
+	elatx "github.com/elastos/Elastos.ELA/core/transaction"
+	types "github.com/elastos/Elastos.ELA/core/types/common"
+	it "github.com/elastos/Elastos.ELA/core/types/interfaces"
+	"github.com/elastos/Elastos.ELA/core/types/payload"
+	"github.com/elastos/Elastos.ELA/utils/http"
+	"github.com/stretchr/testify/assert"
+)
+
+func newProofTx(lockTime uint32) it.Transaction {
+	return elatx.CreateTransaction(
+		types.TxVersion09,
+		types.TransferAsset,
+		0,
+		&payload.TransferAsset{},
+		[]*types.Attribute{},
+		[]*types.Input{},
+		[]*types.Output{},
+		lockTime,
+		nil,
+	)
+}
+
+// newProofBlock returns the header of a block packed two transactions, with
+// the merkle proof of the second transaction.
+func newProofBlock(previous *util.Header) (*util.Header, it.Transaction) {
+	var height uint32
+	var previousHash common.Uint256
+	if previous != nil {
+		height = previous.Height + 1
+		previousHash = previous.Hash()
+	}
+	tx0, tx1 := newProofTx(height*2), newProofTx(height*2+1)
+	hash0, hash1 := tx0.Hash(), tx1.Hash()
+	return &util.Header{
+		BlockHeader: sutil.NewHeader(&types.Header{
+			Previous:   previousHash,
+			MerkleRoot: *bloom.HashMerkleBranches(&hash0, &hash1),
+			Timestamp:  1000 + height*10,
+			Height:     height,
+		}),
+		Height:    height,
+		TotalWork: new(big.Int),
+		NumTxs:    2,
+		Hashes:    []*common.Uint256{&hash0, &hash1},
+		Flags:     []byte{0x05},
+	}, tx1
+}
+
+func TestSPVWallet_RPCActions(t *testing.T) {
+	w := &spvwallet{}
+	names := func(actions []rpcAction) map[string]bool {
+		m := make(map[string]bool)
+		for _, action := range actions {
+			m[action.name] = true
+		}
+		return m
+	}
+
+	actions := names(w.rpcActions(false))
+	assert.True(t, actions["sendrawtransaction"])
+	for _, name := range []string{"gettxproof", "getheader", "getheaders"} {
+		assert.False(t, actions[name], name)
+	}
+
+	actions = names(w.rpcActions(true))
+	assert.True(t, actions["sendrawtransaction"])
+	for _, name := range []string{"gettxproof", "getheader", "getheaders"} {
+		assert.True(t, actions[name], name)
+	}
+}
+
+func TestSPVWallet_ProofRPC(t *testing.T) {
+	dataDir := "spv_test"
+	os.RemoveAll(dataDir)
+	defer os.RemoveAll(dataDir)
+
+	chain, err := headers.NewDatabase(dataDir)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	defer chain.Close()
+	w := &spvwallet{headers: chain, db: sqlite.NewMemoryDatabase()}
+
+	genesis, _ := newProofBlock(nil)
+	assert.NoError(t, chain.Put(genesis, true))
+	header, tx := newProofBlock(genesis)
+	assert.NoError(t, chain.Put(header, true))
+	assert.NoError(t, w.db.Txs().Put(util.NewTx(sutil.NewTx(tx), header.Height)))
+	unconfirmed := newProofTx(100)
+	assert.NoError(t, w.db.Txs().Put(util.NewTx(sutil.NewTx(unconfirmed), 0)))
+
+	// gettxproof
+	result, err := w.getTxProof(http.Params{"txid": tx.Hash().String()})
+	if assert.NoError(t, err) {
+		p := result.(proof.TransactionProof)
+		assert.NoError(t, p.Verify())
+		assert.Equal(t, header.Hash(), p.Proof.BlockHash)
+		assert.Equal(t, tx.Hash(), p.Transaction.Hash())
+	}
+	_, err = w.getTxProof(http.Params{"txid": unconfirmed.Hash().String()})
+	assert.Error(t, err)
+	_, err = w.getTxProof(http.Params{"txid": newProofTx(200).Hash().String()})
+	assert.Error(t, err)
+	_, err = w.getTxProof(http.Params{"txid": "invalid"})
+	assert.Equal(t, ErrInvalidParameter, err)
+	_, err = w.getTxProof(http.Params{})
+	assert.Equal(t, ErrInvalidParameter, err)
+
+	// getheader
+	result, err = w.getHeader(http.Params{"hash": header.Hash().String()})
+	if assert.NoError(t, err) {
+		info := result.(*headerInfo)
+		assert.Equal(t, uint32(1), info.Height)
+		assert.Equal(t, genesis.Hash().String(), info.PreviousHash)
+		assert.Equal(t, uint32(2), info.NumTxs)
+		assert.Equal(t, "05", info.Flags)
+	}
+	result, err = w.getHeader(http.Params{"height": float64(0)})
+	if assert.NoError(t, err) {
+		assert.Equal(t, genesis.Hash().String(), result.(*headerInfo).Hash)
+	}
+	_, err = w.getHeader(http.Params{"hash": common.Uint256{1}.String()})
+	assert.Error(t, err)
+	_, err = w.getHeader(http.Params{"height": float64(2)})
+	assert.Error(t, err)
+	_, err = w.getHeader(http.Params{"hash": "invalid"})
+	assert.Equal(t, ErrInvalidParameter, err)
+	_, err = w.getHeader(http.Params{})
+	assert.Equal(t, ErrInvalidParameter, err)
+
+	// getheaders
+	result, err = w.getHeaders(http.Params{"start": float64(0), "end": float64(1)})
+	if assert.NoError(t, err) {
+		infos := result.([]*headerInfo)
+		if assert.Equal(t, 2, len(infos)) {
+			assert.Equal(t, genesis.Hash().String(), infos[0].Hash)
+			assert.Equal(t, header.Hash().String(), infos[1].Hash)
+		}
+	}
+	_, err = w.getHeaders(http.Params{"start": float64(2), "end": float64(3)})
+	assert.Error(t, err)
+	_, err = w.getHeaders(http.Params{"start": float64(1), "end": float64(0)})
+	assert.Equal(t, ErrInvalidParameter, err)
+	_, err = w.getHeaders(http.Params{"start": float64(0),
+		"end": float64(maxGetHeaders)})
+	assert.Equal(t, ErrInvalidParameter, err)
+	_, err = w.getHeaders(http.Params{"start": float64(0)})
+	assert.Equal(t, ErrInvalidParameter, err)
+}
@@ -28,6 +28,16 @@ type IService interface {
 	// in Config.
 	UpdateFilter()
 
+	// Rescan invokes the rollback function to roll the chain back while no
+	// block is processing, then downloads the blocks after the new chain tip
+	// again.  It's used to find the transactions of addresses added after
+	// their blocks have been synced.
+	Rescan(rollback func() error) error
+
+	// Pause halts the block processing until the returned channel is closed,
+	// so the databases can be read or repaired while no block is committing.
+	Pause() chan<- struct{}
+
 	// SendTransaction broadcast a transaction message to the peer to peer network.
 	SendTransaction(util.Transaction) error
 }
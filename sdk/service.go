@@ -461,6 +461,14 @@ func (s *service) UpdateFilter() {
 	s.IServer.BroadcastMessage(s.cfg.GetTxFilter())
 }
 
+func (s *service) Rescan(rollback func() error) error {
+	return s.syncManager.Rescan(rollback)
+}
+
+func (s *service) Pause() chan<- struct{} {
+	return s.syncManager.Pause()
+}
+
 func (s *service) Start() {
 	s.start()
 	s.syncManager.Start()
@@ -13,6 +13,7 @@ import (
 	"github.com/elastos/Elastos.ELA.SPV/wallet/sutil"
 	"github.com/elastos/Elastos.ELA/core"
 	"io"
+	"path/filepath"
 
 	"github.com/elastos/Elastos.ELA/common"
 	"github.com/elastos/Elastos.ELA/common/config"
@@ -32,8 +33,9 @@ var ErrInvalidParameter = fmt.Errorf("invalide parameter")
 
 type spvwallet struct {
 	sdk.IService
-	db     sqlite.DataStore
-	filter *sdk.AddrFilter
+	headers *headers.Database
+	db      sqlite.DataStore
+	filter  *sdk.AddrFilter
 }
 
 func (w *spvwallet) putTx(batch sqlite.DataBatch, utx util.Transaction,
@@ -301,6 +303,58 @@ func (w *spvwallet) notifyNewAddress(params http.Params) (interface{}, error) {
 	return nil, nil
 }
 
+// reloadFilter reloads the address filter from database, it's used after
+// addresses removed from the wallet.
+func (w *spvwallet) reloadFilter(params http.Params) (interface{}, error) {
+	w.loadAddrFilter()
+	w.UpdateFilter()
+	return nil, nil
+}
+
+// rescan rolls the wallet chain back to the given height and downloads the
+// blocks after it again, so the transactions of addresses added later can be
+// found.  Returns the chain height before rollback, the rescan is finished
+// after the wallet synced to that height again.
+func (w *spvwallet) rescan(params http.Params) (interface{}, error) {
+	height, ok := params.Uint("height")
+	if !ok {
+		return nil, ErrInvalidParameter
+	}
+
+	if !w.IsCurrent() {
+		return nil, fmt.Errorf("wallet is syncing, rescan later")
+	}
+
+	waltlog.Debugf("receive rescan from height %d", height)
+
+	// Reload address filter to include addresses added before rescan.
+	w.loadAddrFilter()
+	w.UpdateFilter()
+
+	var best uint32
+	err := w.Rescan(func() error {
+		var err error
+		best, err = w.headers.Rollback(height)
+		if err != nil {
+			return err
+		}
+
+		batch := w.db.Batch()
+		defer batch.Rollback()
+		for h := best; h > height; h-- {
+			if err := batch.RollbackHeight(h); err != nil {
+				return err
+			}
+		}
+		if err := batch.Commit(); err != nil {
+			return err
+		}
+		w.db.State().PutHeight(height)
+		return nil
+	})
+	return best, err
+}
+
 func (w *spvwallet) sendTransaction(params http.Params) (interface{}, error) {
 	data, ok := params.String("data")
 	if !ok {
@@ -334,8 +388,12 @@ func NewWallet(dataDir string) (*spvwallet, error) {
 		return nil, err
 	}
 
-	w := spvwallet{db: db}
-	chainStore := database.NewChainDB(headers, &w)
+	w := spvwallet{headers: headers, db: db}
+	chainStore, err := database.NewChainDBWithJournal(headers, &w,
+		database.NewFileJournal(filepath.Join(dataDir, "reorg.journal")))
+	if err != nil {
+		return nil, err
+	}
 
 	var params *config.Configuration
 	switch cfg.Network {
@@ -366,13 +424,40 @@ func NewWallet(dataDir string) (*spvwallet, error) {
 		Path:      "/spvwallet",
 		ServePort: cfg.RPCPort,
 	})
-	s.RegisterAction("notifynewaddress", w.notifyNewAddress, "addr")
-	s.RegisterAction("sendrawtransaction", w.sendTransaction, "data")
+	for _, action := range w.rpcActions(cfg.EnableProofRPC) {
+		s.RegisterAction(action.name, action.handler, action.params...)
+	}
 	go s.Start()
 
 	return &w, nil
 }
 
+// rpcAction is an action of the JSON-RPC service.
+type rpcAction struct {
+	name    string
+	handler func(http.Params) (interface{}, error)
+	params  []string
+}
+
+// rpcActions returns the JSON-RPC actions of the wallet, the proof provider
+// actions are included only if enableProofRPC is set.
+func (w *spvwallet) rpcActions(enableProofRPC bool) []rpcAction {
+	actions := []rpcAction{
+		{"notifynewaddress", w.notifyNewAddress, []string{"addr"}},
+		{"sendrawtransaction", w.sendTransaction, []string{"data"}},
+		{"rescan", w.rescan, []string{"height"}},
+		{"reloadfilter", w.reloadFilter, nil},
+	}
+	if enableProofRPC {
+		actions = append(actions,
+			rpcAction{"gettxproof", w.getTxProof, []string{"txid"}},
+			rpcAction{"getheader", w.getHeader, []string{"hash", "height"}},
+			rpcAction{"getheaders", w.getHeaders, []string{"start", "end"}},
+		)
+	}
+	return actions
+}
+
 func newTransaction(r io.Reader) util.Transaction {
 	tx, _ := tx.GetTransactionByBytes(r)
 	return sutil.NewTx(tx)
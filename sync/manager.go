@@ -87,9 +87,17 @@ type isCurrentMsg struct {
 // exclusive access over the manager until a receive is performed on the
 // unpause channel.
 type pauseMsg struct {
+	paused  chan<- struct{}
 	unpause <-chan struct{}
 }
 
+// rescanMsg is a message type to be sent across the message channel for
+// rolling the chain back and downloading the blocks after it again.
+type rescanMsg struct {
+	rollback func() error
+	reply    chan error
+}
+
 // peerSyncState stores additional information that the SyncManager tracks
 // about a peer.
 type peerSyncState struct {
@@ -446,6 +454,28 @@ func (sm *SyncManager) handleBlockMsg(bmsg *blockMsg) {
 	}
 }
 
+// handleRescanMsg rolls the chain back with the rollback function, then
+// restarts syncing from the new chain tip.  The rollback function must remove
+// the headers above the new chain tip, otherwise the blocks are considered as
+// known and will not be downloaded again.
+func (sm *SyncManager) handleRescanMsg(rollback func() error) error {
+	// The chain may be partially rolled back on error, restart syncing
+	// anyway.
+	err := rollback()
+
+	// Blocks requested before rollback do not connect to the new chain tip,
+	// clear the request state for the new sync.
+	sm.requestedBlocks = make(map[common.Uint256]struct{})
+	for peer, state := range sm.peerStates {
+		state.syncCandidate = sm.isSyncCandidate(peer)
+		state.requestQueue = []*msg.InvVect{}
+		state.requestedBlocks = make(map[common.Uint256]struct{})
+	}
+	sm.syncPeer = nil
+	sm.startSync()
+	return err
+}
+
 // haveInventory returns whether or not the inventory represented by the passed
 // inventory vector is known.  This includes checking all of the various places
 // inventory can be when it is in different states such as blocks that are part
@@ -634,8 +664,12 @@ out:
 
 			case pauseMsg:
 				// Wait until the sender unpauses the manager.
+				close(msg.paused)
 				<-msg.unpause
 
+			case rescanMsg:
+				msg.reply <- sm.handleRescanMsg(msg.rollback)
+
 			default:
 				log.Warnf("Invalid message type in block "+
 					"handler: %T", msg)
@@ -751,16 +785,36 @@ func (sm *SyncManager) IsCurrent() bool {
 	return <-reply
 }
 
-// Pause pauses the sync manager until the returned channel is closed.
+// Pause pauses the sync manager until the returned channel is closed, it
+// returns after the block in processing finished.  Nothing is paused if the
+// sync manager is not running.
 //
 // Note that while paused, all peer and block processing is halted.  The
 // message sender should avoid pausing the sync manager for long durations.
 func (sm *SyncManager) Pause() chan<- struct{} {
 	c := make(chan struct{})
-	sm.msgChan <- pauseMsg{c}
+	if atomic.LoadInt32(&sm.started) == 0 ||
+		atomic.LoadInt32(&sm.shutdown) != 0 {
+		return c
+	}
+	paused := make(chan struct{})
+	sm.msgChan <- pauseMsg{paused: paused, unpause: c}
+	select {
+	case <-paused:
+	case <-sm.quit:
+	}
 	return c
 }
 
+// Rescan rolls the chain back with the rollback function and downloads the
+// blocks after the new chain tip again.  The rollback function is invoked on
+// the block handler, so no block is processed while the chain rolling back.
+func (sm *SyncManager) Rescan(rollback func() error) error {
+	reply := make(chan error)
+	sm.msgChan <- rescanMsg{rollback: rollback, reply: reply}
+	return <-reply
+}
+
 // New constructs a new SyncManager. Use Start to begin processing asynchronous
 // block, tx, and inv updates.
 func New(cfg *Config) (*SyncManager, error) {
@@ -6,6 +6,7 @@ import (
 
 	"github.com/elastos/Elastos.ELA.SPV/wallet/client"
 	"github.com/elastos/Elastos.ELA.SPV/wallet/client/account"
+	"github.com/elastos/Elastos.ELA.SPV/wallet/client/multisig"
 	"github.com/elastos/Elastos.ELA.SPV/wallet/client/transaction"
 	"github.com/elastos/Elastos.ELA.SPV/wallet/client/wallet"
 	"github.com/elastos/Elastos.ELA/core"
@@ -30,10 +31,12 @@ func main() {
 	//commands
 	app.Commands = []cli.Command{
 		wallet.NewCreateCommand(),
+		wallet.NewRestoreCommand(),
 		wallet.NewChangePasswordCommand(),
 		wallet.NewResetCommand(),
 		account.NewCommand(),
 		transaction.NewCommand(),
+		multisig.NewCommand(),
 	}
 
 	app.Run(os.Args)
@@ -47,6 +47,29 @@ func newSubAccount(password []byte, wallet *client.Wallet) error {
 	return client.ShowAccounts(addrs, programHash, wallet)
 }
 
+func discoverAccounts(context *cli.Context, password []byte, wallet *client.Wallet) error {
+	var err error
+	password, err = client.GetPassword(password, false)
+	if err != nil {
+		return err
+	}
+
+	fmt.Println("Discovering used sub accounts, rescanning may take a while...")
+	indexes, err := wallet.DiscoverAccounts(password, context.Int("gap"),
+		uint32(context.Uint("birthday")))
+	if err != nil {
+		return err
+	}
+	fmt.Println("Found", len(indexes), "used sub accounts")
+
+	addrs, err := wallet.GetAddrs()
+	if err != nil {
+		return errors.New("get wallet addresses failed")
+	}
+
+	return client.ShowAccounts(addrs, nil, wallet)
+}
+
 func addMultiSignAccount(context *cli.Context, wallet *client.Wallet, content string) error {
 	// Get address content from file or cli input
 	publicKeys, err := getPublicKeys(content)
@@ -80,6 +103,48 @@ func addMultiSignAccount(context *cli.Context, wallet *client.Wallet, content st
 	return client.ShowAccounts(addrs, programHash, wallet)
 }
 
+func addWatchAccounts(wallet *client.Wallet, content string) error {
+	// Content can not be empty
+	if strings.TrimSpace(content) == "" {
+		return errors.New("content should be addresses or public keys separated by comma")
+	}
+
+	var programHash *common.Uint168
+	for _, item := range strings.Split(strings.TrimSpace(content), ",") {
+		item = strings.TrimSpace(item)
+
+		// Add watch-only address
+		if address, err := common.Uint168FromAddress(item); err == nil {
+			if err := wallet.AddWatchAddress(address); err != nil {
+				return err
+			}
+			programHash = address
+			continue
+		}
+
+		// Add watch-only account of the public key
+		keyBytes, err := common.HexStringToBytes(item)
+		if err != nil {
+			return errors.New(fmt.Sprint("invalid address or public key:", item))
+		}
+		publicKey, err := crypto.DecodePoint(keyBytes)
+		if err != nil {
+			return errors.New(fmt.Sprint("invalid address or public key:", item))
+		}
+		programHash, err = wallet.AddWatchAccount(publicKey)
+		if err != nil {
+			return err
+		}
+	}
+
+	addrs, err := wallet.GetAddrs()
+	if err != nil {
+		return errors.New("get wallet addresses failed")
+	}
+
+	return client.ShowAccounts(addrs, programHash, wallet)
+}
+
 func getPublicKeys(content string) ([]*crypto.PublicKey, error) {
 	// Content can not be empty
 	if content == "" {
@@ -161,6 +226,15 @@ func accountAction(context *cli.Context) {
 		return
 	}
 
+	// discover used sub accounts
+	if context.Bool("discover") {
+		if err := discoverAccounts(context, []byte(pass), wallet); err != nil {
+			fmt.Println("error: discover sub accounts failed,", err)
+			cli.ShowCommandHelpAndExit(context, "discover", 5)
+		}
+		return
+	}
+
 	// add multi sign account
 	if pubKeysStr := context.String("addmultisig"); pubKeysStr != "" {
 		if err := addMultiSignAccount(context, wallet, pubKeysStr); err != nil {
@@ -170,6 +244,15 @@ func accountAction(context *cli.Context) {
 		return
 	}
 
+	// add watch-only accounts
+	if content := context.String("addwatch"); content != "" {
+		if err := addWatchAccounts(wallet, content); err != nil {
+			fmt.Println("error: add watch-only account failed,", err)
+			cli.ShowCommandHelpAndExit(context, "addwatch", 5)
+		}
+		return
+	}
+
 	// show addresses balance in this wallet
 	if context.Bool("balance") {
 		if err := listBalanceInfo(wallet); err != nil {
@@ -185,7 +268,7 @@ func NewCommand() cli.Command {
 		Name:        "account",
 		ShortName:   "a",
 		Usage:       "account [command] [args]",
-		Description: "commands to create new sub account or multisig account and show accounts balances",
+		Description: "commands to create new sub account, multisig account or watch-only account and show accounts balances",
 		ArgsUsage:   "[args]",
 		Flags: append(client.CommonFlags,
 			cli.BoolFlag{
@@ -196,6 +279,21 @@ func NewCommand() cli.Command {
 				Name:  "new, n",
 				Usage: "create a new sub account",
 			},
+			cli.BoolFlag{
+				Name: "discover",
+				Usage: "discover the used sub accounts after wallet restored\n" +
+					"\tsub accounts are derived ahead and rescanned from the --birthday height,\n" +
+					"\tuntil --gap consecutive unused sub accounts found, the SPV service must be running",
+			},
+			cli.IntFlag{
+				Name:  "gap",
+				Usage: "the number of consecutive unused sub accounts to stop discovery",
+				Value: client.DefaultGapLimit,
+			},
+			cli.UintFlag{
+				Name:  "birthday",
+				Usage: "the block height to rescan from, the height wallet created",
+			},
 			cli.StringFlag{
 				Name: "addmultisig, multi",
 				Usage: "add a multi-sign account with signers public keys\n" +
@@ -207,6 +305,12 @@ func NewCommand() cli.Command {
 				Usage: "the M value to specify how many signatures are needed to create a valid transaction",
 				Value: 0,
 			},
+			cli.StringFlag{
+				Name: "addwatch, watch",
+				Usage: "add watch-only accounts with addresses or public keys separated by comma\n" +
+					"\twatch-only accounts are tracked for balance and history, transactions of the\n" +
+					"\tpublic key accounts can be created with --from and signed elsewhere",
+			},
 			cli.BoolFlag{
 				Name:  "balance, b",
 				Usage: "show accounts balances",
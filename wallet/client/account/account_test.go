@@ -0,0 +1,83 @@
+package account
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/elastos/Elastos.ELA.SPV/sdk"
+	"github.com/elastos/Elastos.ELA.SPV/wallet/client"
+	"github.com/elastos/Elastos.ELA.SPV/wallet/client/database"
+	"github.com/elastos/Elastos.ELA.SPV/wallet/sutil"
+
+	"github.com/elastos/Elastos.ELA/common"
+	"github.com/elastos/Elastos.ELA/crypto"
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestAccount(t *testing.T) *sdk.Account {
+	privateKey, publicKey, err := crypto.GenerateKeyPair()
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	account, err := sdk.NewAccount(privateKey, publicKey)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	return account
+}
+
+func TestAddWatchAccounts(t *testing.T) {
+	dataDir, err := ioutil.TempDir("", "spv_test")
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	defer os.RemoveAll(dataDir)
+	db, err := database.New(dataDir)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	defer db.Close()
+	wallet := &client.Wallet{Database: db}
+
+	watched, other := newTestAccount(t), newTestAccount(t)
+	address, err := other.ProgramHash().ToAddress()
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+
+	// Invalid content is refused.
+	for _, content := range []string{"", " ", "invalid", "0102",
+		address[:len(address)-1]} {
+		assert.Error(t, addWatchAccounts(wallet, content), content)
+	}
+	addrs, err := wallet.GetAddrs()
+	if assert.NoError(t, err) {
+		assert.Equal(t, 0, len(addrs))
+	}
+
+	// Addresses and public keys separated by comma.
+	publicKey, err := watched.PublicKey().EncodePoint(true)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	content := address + ", " + common.BytesToHexString(publicKey)
+	assert.NoError(t, addWatchAccounts(wallet, content))
+
+	// Duplicate addresses are added once.
+	assert.NoError(t, addWatchAccounts(wallet, content+","+address))
+	addrs, err = wallet.GetAddrs()
+	if assert.NoError(t, err) {
+		assert.Equal(t, 2, len(addrs))
+	}
+	for _, account := range []*sdk.Account{watched, other} {
+		addr, err := wallet.GetAddress(account.ProgramHash())
+		if assert.NoError(t, err) {
+			assert.Equal(t, sutil.TypeWatch, addr.Type())
+		}
+	}
+	addr, err := wallet.GetAddress(watched.ProgramHash())
+	if assert.NoError(t, err) {
+		assert.Equal(t, watched.RedeemScript(), addr.Script())
+	}
+}
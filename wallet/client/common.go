@@ -9,9 +9,12 @@ import (
 	"strconv"
 	"strings"
 
+	"github.com/elastos/Elastos.ELA.SPV/wallet/client/database"
 	"github.com/elastos/Elastos.ELA.SPV/wallet/sutil"
 
 	"github.com/elastos/Elastos.ELA/common"
+	"github.com/elastos/Elastos.ELA/core/contract"
+	"github.com/elastos/Elastos.ELA/crypto"
 	"github.com/howeyc/gopass"
 	"github.com/urfave/cli"
 )
@@ -54,6 +57,32 @@ func GetPassword(password []byte, confirmed bool) ([]byte, error) {
 	return password, nil
 }
 
+// GetPassphrase inputs the optional BIP39 passphrase interactively, an empty
+// input means no passphrase.
+func GetPassphrase(confirmed bool) (string, error) {
+	fmt.Print("INPUT BIP39 PASSPHRASE (PRESS ENTER FOR NONE):")
+
+	passphrase, err := gopass.GetPasswd()
+	if err != nil {
+		return "", err
+	}
+
+	if confirmed && len(passphrase) > 0 {
+		fmt.Print("CONFIRM BIP39 PASSPHRASE:")
+
+		confirm, err := gopass.GetPasswd()
+		if err != nil {
+			return "", err
+		}
+
+		if !bytes.Equal(passphrase, confirm) {
+			return "", errors.New("input passphrase unmatched")
+		}
+	}
+
+	return string(passphrase), nil
+}
+
 func ShowAccountInfo(password []byte) error {
 	var err error
 	password, err = GetPassword(password, false)
@@ -84,9 +113,47 @@ func ShowAccountInfo(password []byte) error {
 		fmt.Println("-----", strings.Repeat("-", 34), strings.Repeat("-", 66), "------")
 	}
 
+	// print watch-only accounts, they are stored in the wallet database which
+	// is not created on an offline machine.
+	if !database.Exists(dataPath) {
+		return nil
+	}
+	db, err := database.New(dataPath)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	addrs, err := db.GetAddrs()
+	if err != nil {
+		return err
+	}
+	index := len(keyStore.GetAccounts())
+	for _, addr := range addrs {
+		if addr.Type() != sutil.TypeWatch {
+			continue
+		}
+		index++
+		fmt.Printf("%5d %-34s %-66s %6s\n", index, addr.String(), watchPublicKey(addr), addr.TypeName())
+		fmt.Println("-----", strings.Repeat("-", 34), strings.Repeat("-", 66), "------")
+	}
+
 	return nil
 }
 
+// watchPublicKey returns the public key in the redeem script of the watch-only
+// account, or empty if the public key is unknown.
+func watchPublicKey(addr *sutil.Addr) string {
+	script := addr.Script()
+	if len(script) == 0 {
+		return ""
+	}
+	if signType, err := crypto.GetScriptType(script); err != nil ||
+		signType != common.STANDARD {
+		return ""
+	}
+	return common.BytesToHexString(script[1 : len(script)-1])
+}
+
 func SelectAccount(wallet *Wallet) (string, error) {
 	addrs, err := wallet.GetAddrs()
 	if err != nil || len(addrs) == 0 {
@@ -147,6 +214,93 @@ func ShowAccounts(addrs []*sutil.Addr, newAddr *common.Uint168, wallet *Wallet)
 	return nil
 }
 
+// ShowUnsignedTx verifies the unsigned transaction file and prints the
+// transaction summary for review before signing.
+func ShowUnsignedTx(unsignedTx *UnsignedTx) error {
+	txn, fee, err := unsignedTx.Verify()
+	if err != nil {
+		return err
+	}
+
+	fmt.Println("TRANSACTION:", txn.Hash().String())
+
+	// print inputs
+	fmt.Printf("%5s %-71s %34s %20s\n", "INPUT", "UTXO", "ADDRESS", "VALUE")
+	fmt.Println("-----", strings.Repeat("-", 71), strings.Repeat("-", 34), strings.Repeat("-", 20))
+	spenders := make(map[string]bool)
+	for i, input := range unsignedTx.Inputs {
+		spenders[input.Address] = true
+		utxo := fmt.Sprint(input.TxID, ":", input.Index)
+		fmt.Printf("%5d %-71s %34s %20s\n", i+1, utxo, input.Address, input.Value)
+	}
+
+	// print outputs, outputs back to spenders are change
+	fmt.Printf("%5s %34s %20s %10s %6s\n", "OUTPUT", "ADDRESS", "VALUE", "LOCK", "CHANGE")
+	fmt.Println("-----", strings.Repeat("-", 34), strings.Repeat("-", 20), strings.Repeat("-", 10), "------")
+	for i, output := range txn.Outputs() {
+		address, err := output.ProgramHash.ToAddress()
+		if err != nil {
+			return err
+		}
+		change := ""
+		if spenders[address] {
+			change = "YES"
+		}
+		fmt.Printf("%5d %34s %20s %10d %6s\n", i+1, address, output.Value.String(), output.OutputLock, change)
+	}
+
+	fmt.Println("FEE:", fee.String())
+	return nil
+}
+
+// ShowPartialTx shows the partially signed transaction, the signers of every
+// program and how many signatures are still missing.
+func ShowPartialTx(partialTx *PartialTx) error {
+	if err := ShowUnsignedTx(partialTx.UnsignedTx()); err != nil {
+		return err
+	}
+
+	missing := 0
+	for i, program := range partialTx.Programs {
+		fmt.Printf("PROGRAM %d: %s %d OF %d\n", i+1, program.Address,
+			program.M, len(program.Signers))
+		fmt.Printf("%5s %66s %34s %6s\n", "INDEX", "PUBLIC KEY", "ADDRESS", "SIGNED")
+		fmt.Println("-----", strings.Repeat("-", 66), strings.Repeat("-", 34), "------")
+		for j, signer := range program.Signers {
+			address, err := signerAddress(signer.PublicKey)
+			if err != nil {
+				return err
+			}
+			signed := "NO"
+			if signer.Signature != "" {
+				signed = "YES"
+			}
+			fmt.Printf("%5d %66s %34s %6s\n", j+1, signer.PublicKey, address, signed)
+		}
+		missing += program.Missing()
+	}
+
+	if missing > 0 {
+		fmt.Println("MISSING SIGNATURES:", missing)
+	} else {
+		fmt.Println("READY TO FINALIZE")
+	}
+	return nil
+}
+
+// signerAddress returns the standard address of the signer public key.
+func signerAddress(publicKey string) (string, error) {
+	key, err := decodePublicKey(publicKey)
+	if err != nil {
+		return "", err
+	}
+	contract, err := contract.CreateStandardContract(key)
+	if err != nil {
+		return "", err
+	}
+	return contract.ToProgramHash().ToAddress()
+}
+
 func getInput(max int) int {
 	fmt.Print("INPUT INDEX: ")
 	input, err := bufio.NewReader(os.Stdin).ReadString('\n')
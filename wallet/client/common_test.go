@@ -0,0 +1,34 @@
+package client
+
+import (
+	"testing"
+
+	"github.com/elastos/Elastos.ELA.SPV/wallet/client/database"
+	"github.com/elastos/Elastos.ELA.SPV/wallet/sutil"
+
+	"github.com/elastos/Elastos.ELA/common"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestShowAccountInfo(t *testing.T) {
+	defer inTempDir(t)()
+	Setup(".", "", common.Uint256{})
+
+	_, err := CreateKeystore(testPassword)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+
+	// the wallet database is not created on an offline machine.
+	assert.NoError(t, ShowAccountInfo(testPassword))
+	assert.False(t, database.Exists("."))
+
+	db, err := database.New(".")
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	assert.NoError(t, db.AddAddress(&common.Uint168{0x21, 1}, nil, sutil.TypeWatch))
+	assert.NoError(t, db.Close())
+	assert.True(t, database.Exists("."))
+	assert.NoError(t, ShowAccountInfo(testPassword))
+}
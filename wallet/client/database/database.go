@@ -1,8 +1,11 @@
 package database
 
 import (
+	"os"
+	"path/filepath"
 	"sync"
 
+	"github.com/elastos/Elastos.ELA.SPV/util"
 	"github.com/elastos/Elastos.ELA.SPV/wallet/store/headers"
 	"github.com/elastos/Elastos.ELA.SPV/wallet/store/sqlite"
 	"github.com/elastos/Elastos.ELA.SPV/wallet/sutil"
@@ -10,6 +13,12 @@ import (
 	"github.com/elastos/Elastos.ELA/common"
 )
 
+// Exists returns if the wallet database has been created in dataDir.
+func Exists(dataDir string) bool {
+	_, err := os.Stat(filepath.Join(dataDir, sqlite.DBName))
+	return err == nil
+}
+
 func New(dataDir string) (*database, error) {
 	dataStore, err := sqlite.NewDatabase(dataDir)
 	if err != nil {
@@ -57,6 +66,20 @@ func (d *database) DeleteAddress(address *common.Uint168) error {
 	return d.store.Addrs().Del(address)
 }
 
+func (d *database) SetAddressIndex(address *common.Uint168, index int, used bool) error {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	return d.store.Addrs().PutIndex(address, index, used)
+}
+
+func (d *database) GetUsedIndexes() ([]int, error) {
+	d.lock.RLock()
+	defer d.lock.RUnlock()
+
+	return d.store.Addrs().GetUsedIndexes()
+}
+
 func (d *database) GetAddressUTXOs(address *common.Uint168) ([]*sutil.UTXO, error) {
 	d.lock.RLock()
 	defer d.lock.RUnlock()
@@ -71,6 +94,13 @@ func (d *database) GetAddressSTXOs(address *common.Uint168) ([]*sutil.STXO, erro
 	return d.store.STXOs().GetAddrAll(address)
 }
 
+func (d *database) GetTransaction(txId *common.Uint256) (*util.Tx, error) {
+	d.lock.RLock()
+	defer d.lock.RUnlock()
+
+	return d.store.Txs().Get(txId)
+}
+
 func (d *database) BestHeight() uint32 {
 	d.lock.RLock()
 	defer d.lock.RUnlock()
@@ -99,3 +129,10 @@ func (d *database) Clear() error {
 
 	return nil
 }
+
+func (d *database) Close() error {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	return d.store.Close()
+}
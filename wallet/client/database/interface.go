@@ -1,6 +1,7 @@
 package database
 
 import (
+	"github.com/elastos/Elastos.ELA.SPV/util"
 	"github.com/elastos/Elastos.ELA.SPV/wallet/sutil"
 
 	"github.com/elastos/Elastos.ELA/common"
@@ -11,8 +12,12 @@ type Database interface {
 	GetAddress(address *common.Uint168) (*sutil.Addr, error)
 	GetAddrs() ([]*sutil.Addr, error)
 	DeleteAddress(address *common.Uint168) error
+	SetAddressIndex(address *common.Uint168, index int, used bool) error
+	GetUsedIndexes() ([]int, error)
 	GetAddressUTXOs(address *common.Uint168) ([]*sutil.UTXO, error)
 	GetAddressSTXOs(address *common.Uint168) ([]*sutil.STXO, error)
+	GetTransaction(txId *common.Uint256) (*util.Tx, error)
 	BestHeight() uint32
 	Clear() error
+	Close() error
 }
@@ -0,0 +1,220 @@
+package client
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/elastos/Elastos.ELA.SPV/sdk"
+	"github.com/elastos/Elastos.ELA.SPV/wallet/sutil"
+
+	"github.com/elastos/Elastos.ELA/common"
+	"github.com/elastos/Elastos.ELA/utils/http/jsonrpc"
+)
+
+const (
+	// DefaultGapLimit is the number of consecutive unused sub accounts to
+	// stop the discovery.
+	DefaultGapLimit = 20
+
+	// syncPollInterval is the interval to check if the SPV service has synced
+	// after rescan.
+	syncPollInterval = time.Second
+
+	// rescanStallTimeout is the duration to give up the rescan if the SPV
+	// service has not synced any block.
+	rescanStallTimeout = 10 * time.Minute
+)
+
+// DiscoverAccounts finds the used sub accounts of a restored wallet.  Sub
+// accounts are derived ahead and registered to the SPV service, then the SPV
+// service rescans from the birthday height, this repeats until gapLimit
+// consecutive unused sub accounts found after the last used one.  The sub
+// accounts until the last used one are added into keystore, returns the
+// indexes of the used sub accounts found.
+func (wallet *Wallet) DiscoverAccounts(password []byte, gapLimit int,
+	birthday uint32) ([]int, error) {
+	if gapLimit <= 0 {
+		return nil, errors.New("gap limit must be greater than 0")
+	}
+
+	err := wallet.VerifyPassword(password)
+	if err != nil {
+		return nil, err
+	}
+
+	found, err := wallet.discoverAccounts(gapLimit, notifyNewAddress,
+		func() error {
+			return wallet.rescan(birthday, rescanStallTimeout)
+		}, wallet.isUsed)
+	if err != nil {
+		return nil, err
+	}
+
+	// Reload the bloom filter of the SPV service, so the addresses derived
+	// ahead and removed are not watched anymore.
+	if _, err := jsonrpc.CallArray(jsonRpcUrl, "reloadfilter"); err != nil {
+		return nil, err
+	}
+	return found, nil
+}
+
+// discoverAccounts runs the discovery with the functions to notify the SPV
+// service of new addresses, rescan and look up the address usage.
+func (wallet *Wallet) discoverAccounts(gapLimit int,
+	notify func(*common.Uint168) error, rescan func() error,
+	isUsed func(*common.Uint168) (bool, error)) ([]int, error) {
+	// Continue from the last used index found before.
+	lastUsed := 0
+	indexes, err := wallet.GetUsedIndexes()
+	if err != nil {
+		return nil, err
+	}
+	if len(indexes) > 0 {
+		lastUsed = indexes[len(indexes)-1]
+	}
+
+	// Make sure the existing sub accounts are watched by the SPV service.
+	accounts := append([]*sdk.Account{}, wallet.GetAccounts()...)
+	for index := 1; index < len(accounts); index++ {
+		if err := wallet.watchSubAccount(index, accounts[index], notify); err != nil {
+			return nil, err
+		}
+	}
+
+	var found []int
+	for {
+		// Derive sub accounts ahead until gapLimit sub accounts after the
+		// last used one.
+		for index := len(accounts); index <= lastUsed+gapLimit; index++ {
+			account, err := wallet.deriveAccount(index)
+			if err != nil {
+				return nil, err
+			}
+			if err := wallet.watchSubAccount(index, account, notify); err != nil {
+				return nil, err
+			}
+			accounts = append(accounts, account)
+		}
+
+		if err := rescan(); err != nil {
+			return nil, err
+		}
+
+		next := lastUsed
+		for index := lastUsed + 1; index < len(accounts); index++ {
+			programHash := accounts[index].ProgramHash()
+			used, err := isUsed(programHash)
+			if err != nil {
+				return nil, err
+			}
+			if !used {
+				continue
+			}
+			if err := wallet.SetAddressIndex(programHash, index, true); err != nil {
+				return nil, err
+			}
+			found = append(found, index)
+			next = index
+		}
+		if next == lastUsed {
+			break
+		}
+		lastUsed = next
+	}
+
+	// Keep the sub accounts until the last used one, and remove the addresses
+	// derived ahead.
+	if count := wallet.SubAccountsCount; lastUsed > count {
+		err := wallet.addAccounts(accounts[count+1 : lastUsed+1]...)
+		if err != nil {
+			return nil, err
+		}
+	}
+	for index := wallet.SubAccountsCount + 1; index < len(accounts); index++ {
+		if err := wallet.DeleteAddress(accounts[index].ProgramHash()); err != nil {
+			return nil, err
+		}
+	}
+
+	return found, nil
+}
+
+// watchSubAccount adds the sub account address with it's key index into
+// database if not added, and notifies the SPV service.  The address added
+// before is notified again, in case the notification failed last time.
+func (wallet *Wallet) watchSubAccount(index int, account *sdk.Account,
+	notify func(*common.Uint168) error) error {
+	programHash := account.ProgramHash()
+	if _, err := wallet.GetAddress(programHash); err != nil {
+		err := wallet.AddAddress(programHash, account.RedeemScript(), sutil.TypeSub)
+		if err != nil {
+			return err
+		}
+		err = wallet.SetAddressIndex(programHash, index, false)
+		if err != nil {
+			return err
+		}
+	}
+
+	// Notify SPV service to reload bloom filter with the new address, the
+	// funds of the address will be missed by rescan if it's not watched.
+	if err := notify(programHash); err != nil {
+		return fmt.Errorf("notify new address %s failed, %s",
+			programHash.String(), err)
+	}
+	return nil
+}
+
+// notifyNewAddress requests the SPV service to watch the address.
+func notifyNewAddress(programHash *common.Uint168) error {
+	_, err := jsonrpc.CallArray(jsonRpcUrl, "notifynewaddress",
+		programHash.String())
+	return err
+}
+
+// rescan requests the SPV service to rescan from the birthday height, and
+// waits until the SPV service synced to the height before rescan.  It fails
+// if no block is synced within the stall timeout.
+func (wallet *Wallet) rescan(birthday uint32, stallTimeout time.Duration) error {
+	resp, err := jsonrpc.CallArray(jsonRpcUrl, "rescan", birthday)
+	if err != nil {
+		return err
+	}
+	height, ok := resp.(float64)
+	if !ok {
+		return errors.New("invalid rescan response")
+	}
+
+	best := wallet.BestHeight()
+	deadline := time.Now().Add(stallTimeout)
+	for best < uint32(height) {
+		if time.Now().After(deadline) {
+			return fmt.Errorf("rescan stalled on height %d, expect %d",
+				best, uint32(height))
+		}
+		time.Sleep(syncPollInterval)
+
+		if current := wallet.BestHeight(); current > best {
+			best = current
+			deadline = time.Now().Add(stallTimeout)
+		}
+	}
+	return nil
+}
+
+// isUsed returns if the address has ever received any transaction.
+func (wallet *Wallet) isUsed(programHash *common.Uint168) (bool, error) {
+	utxos, err := wallet.GetAddressUTXOs(programHash)
+	if err != nil {
+		return false, err
+	}
+	if len(utxos) > 0 {
+		return true, nil
+	}
+	stxos, err := wallet.GetAddressSTXOs(programHash)
+	if err != nil {
+		return false, err
+	}
+	return len(stxos) > 0, nil
+}
@@ -0,0 +1,150 @@
+package client
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/elastos/Elastos.ELA.SPV/wallet/client/database"
+
+	"github.com/elastos/Elastos.ELA/common"
+	"github.com/stretchr/testify/assert"
+)
+
+const testMnemonic = "abandon abandon abandon abandon abandon abandon" +
+	" abandon abandon abandon abandon abandon about"
+
+// inTempDir runs the test in a temporary working directory, the keystore
+// file is created in the working directory.
+func inTempDir(t *testing.T) func() {
+	dir, err := ioutil.TempDir("", "spv_test")
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	wd, err := os.Getwd()
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	if !assert.NoError(t, os.Chdir(dir)) {
+		t.FailNow()
+	}
+	return func() {
+		os.Chdir(wd)
+		os.RemoveAll(dir)
+	}
+}
+
+func TestWallet_DiscoverAccounts(t *testing.T) {
+	defer inTempDir(t)()
+
+	keystore, err := CreateKeystoreFromMnemonic([]byte("password"),
+		testMnemonic, "")
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	db, err := database.New(".")
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	wallet := &Wallet{Database: db, Keystore: keystore}
+
+	// sub accounts 2 and 5 are used, 5 is found after the second rescan.
+	used := make(map[common.Uint168]bool)
+	for _, index := range []int{2, 5} {
+		account, err := keystore.deriveAccount(index)
+		if !assert.NoError(t, err) {
+			t.FailNow()
+		}
+		used[*account.ProgramHash()] = true
+	}
+	rescans := 0
+	notified := make(map[common.Uint168]bool)
+	notify := func(programHash *common.Uint168) error {
+		notified[*programHash] = true
+		return nil
+	}
+	found, err := wallet.discoverAccounts(3, notify, func() error {
+		rescans++
+		return nil
+	}, func(programHash *common.Uint168) (bool, error) {
+		return used[*programHash], nil
+	})
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	assert.Equal(t, []int{2, 5}, found)
+	assert.Equal(t, 3, rescans)
+	assert.Equal(t, 8, len(notified))
+
+	// sub accounts until the last used one are kept, the ones derived ahead
+	// are removed.
+	assert.Equal(t, 5, keystore.SubAccountsCount)
+	indexes, err := db.GetUsedIndexes()
+	assert.NoError(t, err)
+	assert.Equal(t, []int{2, 5}, indexes)
+	addrs, err := db.GetAddrs()
+	assert.NoError(t, err)
+	assert.Equal(t, 5, len(addrs))
+	for index := 6; index <= 8; index++ {
+		account, err := keystore.deriveAccount(index)
+		assert.NoError(t, err)
+		_, err = db.GetAddress(account.ProgramHash())
+		assert.Error(t, err)
+	}
+
+	// discovery continues from the last used index and stops on errors.
+	rescanErr := errors.New("rescan stalled")
+	_, err = wallet.discoverAccounts(3, notify, func() error {
+		return rescanErr
+	}, func(programHash *common.Uint168) (bool, error) {
+		return false, nil
+	})
+	assert.Equal(t, rescanErr, err)
+}
+
+func TestWallet_DiscoverAccountsNotifyFailed(t *testing.T) {
+	defer inTempDir(t)()
+
+	keystore, err := CreateKeystoreFromMnemonic([]byte("password"),
+		testMnemonic, "")
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	db, err := database.New(".")
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	defer db.Close()
+	wallet := &Wallet{Database: db, Keystore: keystore}
+
+	// discovery stops before rescan if the SPV service is not notified.
+	notifyErr := errors.New("connection refused")
+	rescans := 0
+	_, err = wallet.discoverAccounts(3, func(*common.Uint168) error {
+		return notifyErr
+	}, func() error {
+		rescans++
+		return nil
+	}, func(programHash *common.Uint168) (bool, error) {
+		return false, nil
+	})
+	assert.Error(t, err)
+	assert.Equal(t, 0, rescans)
+
+	// the address saved is notified again on next discovery.
+	var notified []common.Uint168
+	_, err = wallet.discoverAccounts(3, func(programHash *common.Uint168) error {
+		notified = append(notified, *programHash)
+		return nil
+	}, func() error {
+		return nil
+	}, func(programHash *common.Uint168) (bool, error) {
+		return false, nil
+	})
+	assert.NoError(t, err)
+	account, err := keystore.deriveAccount(1)
+	if assert.NoError(t, err) && assert.NotEmpty(t, notified) {
+		assert.Equal(t, *account.ProgramHash(), notified[0])
+	}
+}
@@ -0,0 +1,145 @@
+package hd
+
+import (
+	"crypto/elliptic"
+	"crypto/hmac"
+	"crypto/sha512"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math/big"
+)
+
+const (
+	// HardenedKeyStart is the index of the first hardened child key.
+	HardenedKeyStart = 0x80000000
+
+	// Purpose is the BIP44 purpose field.
+	Purpose = 44
+
+	// CoinType is the BIP44 coin type used by Elastos wallets.
+	CoinType = 0
+
+	// ExternalChain is the BIP44 change field of receiving addresses.
+	ExternalChain = 0
+)
+
+// masterKeySeed is the HMAC key to generate master key from seed.
+var masterKeySeed = []byte("Bitcoin seed")
+
+// ErrInvalidKey indicates the derived key is out of the curve order, the
+// next index should be used.
+var ErrInvalidKey = errors.New("invalid derived key")
+
+// curve is the elliptic curve of Elastos keys.
+var curve = elliptic.P256()
+
+// ExtendedKey is a BIP32 extended private key on the P-256 curve.
+type ExtendedKey struct {
+	privateKey []byte
+	chainCode  []byte
+}
+
+// NewMasterKey creates the master extended key from seed.
+func NewMasterKey(seed []byte) (*ExtendedKey, error) {
+	if len(seed) < 16 || len(seed) > 64 {
+		return nil, fmt.Errorf("invalid seed length %d", len(seed))
+	}
+	mac := hmac.New(sha512.New, masterKeySeed)
+	mac.Write(seed)
+	sum := mac.Sum(nil)
+
+	key := new(big.Int).SetBytes(sum[:32])
+	if key.Sign() == 0 || key.Cmp(curve.Params().N) >= 0 {
+		return nil, ErrInvalidKey
+	}
+	return &ExtendedKey{privateKey: sum[:32], chainCode: sum[32:]}, nil
+}
+
+// PrivateKey returns the 32 bytes private key.
+func (k *ExtendedKey) PrivateKey() []byte {
+	return append([]byte{}, k.privateKey...)
+}
+
+// ChainCode returns the chain code of the key.
+func (k *ExtendedKey) ChainCode() []byte {
+	return append([]byte{}, k.chainCode...)
+}
+
+// PublicKey returns the compressed public key.
+func (k *ExtendedKey) PublicKey() []byte {
+	x, y := curve.ScalarBaseMult(k.privateKey)
+	return elliptic.MarshalCompressed(curve, x, y)
+}
+
+// Child derives the child key of index, indexes from HardenedKeyStart derive
+// hardened keys.
+func (k *ExtendedKey) Child(index uint32) (*ExtendedKey, error) {
+	data := make([]byte, 0, 37)
+	if index >= HardenedKeyStart {
+		data = append(data, 0)
+		data = append(data, k.privateKey...)
+	} else {
+		data = append(data, k.PublicKey()...)
+	}
+	var i [4]byte
+	binary.BigEndian.PutUint32(i[:], index)
+	data = append(data, i[:]...)
+
+	mac := hmac.New(sha512.New, k.chainCode)
+	mac.Write(data)
+	sum := mac.Sum(nil)
+
+	n := curve.Params().N
+	il := new(big.Int).SetBytes(sum[:32])
+	if il.Cmp(n) >= 0 {
+		return nil, ErrInvalidKey
+	}
+	key := il.Add(il, new(big.Int).SetBytes(k.privateKey))
+	key.Mod(key, n)
+	if key.Sign() == 0 {
+		return nil, ErrInvalidKey
+	}
+
+	privateKey := make([]byte, 32)
+	b := key.Bytes()
+	copy(privateKey[32-len(b):], b)
+	return &ExtendedKey{privateKey: privateKey, chainCode: sum[32:]}, nil
+}
+
+// Derive derives the descendant key along path.
+func (k *ExtendedKey) Derive(path []uint32) (*ExtendedKey, error) {
+	key := k
+	for _, index := range path {
+		var err error
+		if key, err = key.Child(index); err != nil {
+			return nil, err
+		}
+	}
+	return key, nil
+}
+
+// AccountPath returns the BIP44 path of the receiving address index in the
+// account, m/44'/0'/account'/0/index.
+func AccountPath(account, index uint32) []uint32 {
+	return []uint32{
+		HardenedKeyStart + Purpose,
+		HardenedKeyStart + CoinType,
+		HardenedKeyStart + account,
+		ExternalChain,
+		index,
+	}
+}
+
+// PathString returns the path in m/44'/0'/0'/0/0 format.
+func PathString(path []uint32) string {
+	s := "m"
+	for _, index := range path {
+		if index >= HardenedKeyStart {
+			s += fmt.Sprintf("/%d'", index-HardenedKeyStart)
+		} else {
+			s += fmt.Sprintf("/%d", index)
+		}
+	}
+	return s
+}
@@ -0,0 +1,172 @@
+package hd
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/binary"
+	"errors"
+	"math/big"
+	"strings"
+)
+
+const (
+	// seedIterations is the PBKDF2 iterations to generate seed from
+	// mnemonic.
+	seedIterations = 2048
+
+	// SeedSize is the size of the seed generated from mnemonic.
+	SeedSize = 64
+)
+
+var (
+	// ErrInvalidEntropy indicates the entropy size is not 128 to 256 bits
+	// in multiple of 32 bits.
+	ErrInvalidEntropy = errors.New("invalid entropy size")
+
+	// ErrInvalidMnemonic indicates the mnemonic has invalid word count,
+	// unknown words or wrong checksum.
+	ErrInvalidMnemonic = errors.New("invalid mnemonic")
+)
+
+// EntropySize returns the entropy size in bits of the mnemonic with the given
+// words count, words must be 12, 15, 18, 21 or 24.
+func EntropySize(words int) (int, error) {
+	if words%3 != 0 {
+		return 0, ErrInvalidEntropy
+	}
+	bits := words / 3 * 32
+	if err := checkEntropySize(bits); err != nil {
+		return 0, err
+	}
+	return bits, nil
+}
+
+// NewEntropy generates random entropy of bits size, bits must be 128 to 256
+// in multiple of 32.
+func NewEntropy(bits int) ([]byte, error) {
+	if err := checkEntropySize(bits); err != nil {
+		return nil, err
+	}
+	entropy := make([]byte, bits/8)
+	if _, err := rand.Read(entropy); err != nil {
+		return nil, err
+	}
+	return entropy, nil
+}
+
+// NewMnemonic returns the BIP39 English mnemonic of the entropy.
+func NewMnemonic(entropy []byte) (string, error) {
+	bits := len(entropy) * 8
+	if err := checkEntropySize(bits); err != nil {
+		return "", err
+	}
+
+	// Append checksum bits to entropy, every 11 bits is a word index.
+	checksumBits := uint(bits / 32)
+	hash := sha256.Sum256(entropy)
+	data := new(big.Int).SetBytes(entropy)
+	data.Lsh(data, checksumBits)
+	data.Or(data, big.NewInt(int64(hash[0]>>(8-checksumBits))))
+
+	count := (bits + int(checksumBits)) / 11
+	words := make([]string, count)
+	mask := big.NewInt(2047)
+	index := new(big.Int)
+	for i := count - 1; i >= 0; i-- {
+		index.And(data, mask)
+		words[i] = englishWords[index.Int64()]
+		data.Rsh(data, 11)
+	}
+	return strings.Join(words, " "), nil
+}
+
+// EntropyFromMnemonic returns the entropy of the mnemonic and verifies the
+// checksum.
+func EntropyFromMnemonic(mnemonic string) ([]byte, error) {
+	words := strings.Fields(mnemonic)
+	count := len(words)
+	if count < 12 || count > 24 || count%3 != 0 {
+		return nil, ErrInvalidMnemonic
+	}
+
+	data := new(big.Int)
+	for _, word := range words {
+		index, ok := englishIndexes[strings.ToLower(word)]
+		if !ok {
+			return nil, ErrInvalidMnemonic
+		}
+		data.Lsh(data, 11)
+		data.Or(data, big.NewInt(int64(index)))
+	}
+
+	checksumBits := uint(count * 11 / 33)
+	checksum := new(big.Int).And(data, big.NewInt(1<<checksumBits-1))
+	data.Rsh(data, checksumBits)
+
+	entropy := make([]byte, (count*11-int(checksumBits))/8)
+	bytes := data.Bytes()
+	copy(entropy[len(entropy)-len(bytes):], bytes)
+
+	hash := sha256.Sum256(entropy)
+	if checksum.Int64() != int64(hash[0]>>(8-checksumBits)) {
+		return nil, ErrInvalidMnemonic
+	}
+	return entropy, nil
+}
+
+// IsMnemonicValid returns if the mnemonic is a valid BIP39 English mnemonic.
+func IsMnemonicValid(mnemonic string) bool {
+	_, err := EntropyFromMnemonic(mnemonic)
+	return err == nil
+}
+
+// NewSeed returns the seed of the mnemonic protected by passphrase, an empty
+// passphrase is allowed.  The mnemonic is verified before generating seed.
+// Mnemonic and passphrase are used as is without Unicode normalization, so
+// the passphrase should be ASCII to be compatible with other wallets.
+func NewSeed(mnemonic, passphrase string) ([]byte, error) {
+	if !IsMnemonicValid(mnemonic) {
+		return nil, ErrInvalidMnemonic
+	}
+	mnemonic = strings.Join(strings.Fields(strings.ToLower(mnemonic)), " ")
+	return pbkdf2([]byte(mnemonic), []byte("mnemonic"+passphrase),
+		seedIterations, SeedSize), nil
+}
+
+func checkEntropySize(bits int) error {
+	if bits < 128 || bits > 256 || bits%32 != 0 {
+		return ErrInvalidEntropy
+	}
+	return nil
+}
+
+// pbkdf2 derives a key with PBKDF2 using HMAC-SHA512.
+func pbkdf2(password, salt []byte, iterations, keyLen int) []byte {
+	prf := hmac.New(sha512.New, password)
+	hashLen := prf.Size()
+	blocks := (keyLen + hashLen - 1) / hashLen
+
+	var counter [4]byte
+	key := make([]byte, 0, blocks*hashLen)
+	u := make([]byte, hashLen)
+	for block := 1; block <= blocks; block++ {
+		prf.Reset()
+		prf.Write(salt)
+		binary.BigEndian.PutUint32(counter[:], uint32(block))
+		prf.Write(counter[:])
+		u = prf.Sum(u[:0])
+		t := append([]byte{}, u...)
+		for i := 1; i < iterations; i++ {
+			prf.Reset()
+			prf.Write(u)
+			u = prf.Sum(u[:0])
+			for j := range t {
+				t[j] ^= u[j]
+			}
+		}
+		key = append(key, t...)
+	}
+	return key[:keyLen]
+}
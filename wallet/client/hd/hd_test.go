@@ -0,0 +1,123 @@
+package hd
+
+import (
+	"encoding/hex"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMnemonic(t *testing.T) {
+	vectors := []struct {
+		entropy  string
+		mnemonic string
+	}{
+		{"00000000000000000000000000000000",
+			"abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon about"},
+		{"7f7f7f7f7f7f7f7f7f7f7f7f7f7f7f7f",
+			"legal winner thank year wave sausage worth useful legal winner thank yellow"},
+		{"ffffffffffffffffffffffffffffffff",
+			"zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo wrong"},
+		{"6610b25967cdcca9d59875f5cb50b0ea75433311869e930b",
+			"gravity machine north sort system female filter attitude volume fold club stay feature office ecology stable narrow fog"},
+		{"68a79eaca2324873eacc50cb9c6eca8cc68ea5d936f98787c60c7ebc74e6ce7c",
+			"hamster diagram private dutch cause delay private meat slide toddler razor book happy fancy gospel tennis maple dilemma loan word shrug inflict delay length"},
+	}
+	for _, v := range vectors {
+		entropy, _ := hex.DecodeString(v.entropy)
+		mnemonic, err := NewMnemonic(entropy)
+		assert.NoError(t, err)
+		assert.Equal(t, v.mnemonic, mnemonic)
+
+		result, err := EntropyFromMnemonic(v.mnemonic)
+		assert.NoError(t, err)
+		assert.Equal(t, entropy, result)
+	}
+
+	assert.Equal(t, 2048, len(englishWords))
+	assert.False(t, IsMnemonicValid("abandon abandon abandon abandon abandon"+
+		" abandon abandon abandon abandon abandon abandon abandon"))
+	assert.False(t, IsMnemonicValid("abandon abandon abandon"))
+	assert.False(t, IsMnemonicValid("abandon abandon abandon abandon abandon"+
+		" abandon abandon abandon abandon abandon abandon unknown"))
+
+	for _, words := range []int{0, 9, 13, 16, 27} {
+		_, err := EntropySize(words)
+		assert.Equal(t, ErrInvalidEntropy, err, words)
+	}
+	for words, expected := range map[int]int{12: 128, 15: 160, 18: 192,
+		21: 224, 24: 256} {
+		bits, err := EntropySize(words)
+		assert.NoError(t, err)
+		assert.Equal(t, expected, bits)
+	}
+
+	_, err := NewEntropy(100)
+	assert.Equal(t, ErrInvalidEntropy, err)
+	entropy, err := NewEntropy(128)
+	if assert.NoError(t, err) {
+		mnemonic, err := NewMnemonic(entropy)
+		assert.NoError(t, err)
+		assert.True(t, IsMnemonicValid(mnemonic))
+	}
+}
+
+func TestNewSeed(t *testing.T) {
+	seed, err := NewSeed("abandon abandon abandon abandon abandon abandon"+
+		" abandon abandon abandon abandon abandon about", "TREZOR")
+	assert.NoError(t, err)
+	assert.Equal(t, "c55257c360c07c72029aebc1b53c05ed0362ada38ead3e3e9efa3708"+
+		"e53495531f09a6987599d18264c1e1c92f2cf141630c7a3c4ab7c81b2f001698e7463b04",
+		hex.EncodeToString(seed))
+
+	_, err = NewSeed("abandon abandon", "")
+	assert.Equal(t, ErrInvalidMnemonic, err)
+}
+
+func TestExtendedKey(t *testing.T) {
+	// BIP32 test vector 1, the master key and hardened child are the same on
+	// all curves.
+	seed, _ := hex.DecodeString("000102030405060708090a0b0c0d0e0f")
+	master, err := NewMasterKey(seed)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	assert.Equal(t, "e8f32e723decf4051aefac8e2c93c9c5b214313817cdb01a1494b917c8436b35",
+		hex.EncodeToString(master.PrivateKey()))
+	assert.Equal(t, "873dff81c02f525623fd1fe5167eac3a55a049de3d314bb42ee227ffed37d508",
+		hex.EncodeToString(master.ChainCode()))
+	child, err := master.Child(HardenedKeyStart)
+	if assert.NoError(t, err) {
+		assert.Equal(t, "edb2e14f9ee77d26dd93b4ecede8d16ed408ce149b6cd80b0715a2d911a0afea",
+			hex.EncodeToString(child.PrivateKey()))
+		assert.Equal(t, "47fdacbd0f1097043b78c63c20c34ef4ed9a111d980047ad16282c7ae6236141",
+			hex.EncodeToString(child.ChainCode()))
+	}
+
+	// Elastos accounts derived on P-256 curve.
+	seed, err = NewSeed("abandon abandon abandon abandon abandon abandon"+
+		" abandon abandon abandon abandon abandon about", "")
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	master, err = NewMasterKey(seed)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	for i, v := range []struct {
+		privateKey string
+		publicKey  string
+	}{
+		{"9007fb0e9149e8940559a6c69f53c276d94cf94956d8e7b10ef6c2b2e5237d1a",
+			"023559273eec17bbfcedd041d2044163123a9bba34530540d864a6f3f484f7054a"},
+		{"41f59015e36b402414b5dc945f4b398dbcf4e8bf1dd6a70099c458a0ebd49367",
+			"03fc9935cf27355cf6eba6ad7a3f585a08d1686e9c8791ab25bca9eeb6939a14fe"},
+	} {
+		key, err := master.Derive(AccountPath(0, uint32(i)))
+		if assert.NoError(t, err) {
+			assert.Equal(t, v.privateKey, hex.EncodeToString(key.PrivateKey()))
+			assert.Equal(t, v.publicKey, hex.EncodeToString(key.PublicKey()))
+		}
+	}
+	assert.Equal(t, "m/44'/0'/0'/0/1", PathString(AccountPath(0, 1)))
+}
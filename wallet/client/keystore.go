@@ -9,6 +9,7 @@ import (
 	"sync"
 
 	"github.com/elastos/Elastos.ELA.SPV/sdk"
+	"github.com/elastos/Elastos.ELA.SPV/wallet/client/hd"
 	"github.com/elastos/Elastos.ELA/common"
 	"github.com/elastos/Elastos.ELA/crypto"
 )
@@ -24,10 +25,28 @@ type Keystore struct {
 
 	masterKey []byte
 
+	// hdKey is the BIP32 master key of HD keystore, nil for legacy keystore.
+	hdKey *hd.ExtendedKey
+
 	accounts []*sdk.Account
 }
 
 func CreateKeystore(password []byte) (*Keystore, error) {
+	return createKeystore(password, nil)
+}
+
+// CreateKeystoreFromMnemonic creates a HD keystore from the BIP39 mnemonic
+// and passphrase, accounts are derived along the Elastos BIP44 path.
+func CreateKeystoreFromMnemonic(password []byte, mnemonic, passphrase string) (*Keystore, error) {
+	seed, err := hd.NewSeed(mnemonic, passphrase)
+	if err != nil {
+		return nil, err
+	}
+	defer common.ClearBytes(seed)
+	return createKeystore(password, seed)
+}
+
+func createKeystore(password, seed []byte) (*Keystore, error) {
 	keystoreFile, err := CreateKeystoreFile()
 	if err != nil {
 		return nil, err
@@ -63,13 +82,34 @@ func CreateKeystore(password []byte) (*Keystore, error) {
 	// Set master key encrypted
 	keystoreFile.SetMasterKeyEncrypted(masterKeyEncrypted)
 
-	// Generate new key pair
-	privateKey, publicKey, err := crypto.GenerateKeyPair()
+	var privateKey []byte
+	var publicKey *crypto.PublicKey
+	if seed != nil {
+		// Set seed encrypted
+		var seedEncrypted []byte
+		seedEncrypted, err = keystore.encryptSeed(masterKey, seed)
+		if err != nil {
+			return nil, err
+		}
+		keystoreFile.SetSeedEncrypted(seedEncrypted)
+
+		if keystore.hdKey, err = hd.NewMasterKey(seed); err != nil {
+			return nil, err
+		}
+		// Derive main account key pair
+		privateKey, publicKey, err = keystore.deriveKeyPair(0)
+	} else {
+		// Generate new key pair
+		privateKey, publicKey, err = crypto.GenerateKeyPair()
+	}
 	if err != nil {
 		return nil, err
 	}
 
 	privateKeyEncrypted, err := keystore.encryptPrivateKey(masterKey, passwordKey, privateKey, publicKey)
+	if err != nil {
+		return nil, err
+	}
 	// Set private key encrypted
 	keystoreFile.SetPrivateKeyEncrypted(privateKeyEncrypted)
 
@@ -113,6 +153,18 @@ func (store *Keystore) initKeystore(keystoreFile *KeystoreFile, password []byte)
 		return err
 	}
 
+	if len(store.SeedEncrypted) > 0 {
+		seed, err := store.decryptSeed(masterKey)
+		if err != nil {
+			return err
+		}
+		store.hdKey, err = hd.NewMasterKey(seed)
+		common.ClearBytes(seed)
+		if err != nil {
+			return err
+		}
+	}
+
 	return store.initAccounts(masterKey, privateKey, publicKey)
 }
 
@@ -131,7 +183,7 @@ func (store *Keystore) initAccounts(masterKey, privateKey []byte, publicKey *cry
 
 	// initiate sub accounts
 	for i := 1; i <= store.SubAccountsCount; i++ {
-		privateKey, publicKey, err := crypto.GenerateSubKeyPair(i, masterKey, privateKey)
+		privateKey, publicKey, err := store.subKeyPair(i, masterKey, privateKey)
 		if err != nil {
 			return err
 		}
@@ -212,26 +264,61 @@ func (store *Keystore) MainAccount() *sdk.Account {
 
 func (store *Keystore) NewAccount() *sdk.Account {
 	// create sub account
-	privateKey, publicKey, err := crypto.GenerateSubKeyPair(
-		store.SubAccountsCount+1, store.masterKey, store.accounts[0].PrivateKey())
+	account, err := store.deriveAccount(store.SubAccountsCount + 1)
 	if err != nil {
 		panic(fmt.Sprint("New sub account failed,", err))
 	}
 
-	account, err := sdk.NewAccount(privateKey, publicKey)
+	err = store.addAccounts(account)
 	if err != nil {
 		panic(fmt.Sprint("New sub account failed,", err))
 	}
 
-	store.accounts = append(store.accounts, account)
+	return account
+}
 
-	store.SubAccountsCount += 1
-	err = store.SaveToFile()
+// deriveAccount returns the sub account on index without adding it into the
+// keystore.
+func (store *Keystore) deriveAccount(index int) (*sdk.Account, error) {
+	privateKey, publicKey, err := store.subKeyPair(
+		index, store.masterKey, store.accounts[0].PrivateKey())
 	if err != nil {
-		panic(fmt.Sprint("New sub account failed,", err))
+		return nil, err
 	}
+	return sdk.NewAccount(privateKey, publicKey)
+}
 
-	return account
+// addAccounts appends the sub accounts derived in index order and saves the
+// sub accounts count into keystore file.
+func (store *Keystore) addAccounts(accounts ...*sdk.Account) error {
+	store.accounts = append(store.accounts, accounts...)
+	store.SubAccountsCount += len(accounts)
+	return store.SaveToFile()
+}
+
+// IsHD returns if the accounts are derived from a BIP39 mnemonic.
+func (store *Keystore) IsHD() bool {
+	return store.hdKey != nil
+}
+
+// subKeyPair returns the key pair of the sub account on index, derived along
+// the BIP44 path for HD keystore.
+func (store *Keystore) subKeyPair(index int, masterKey, privateKey []byte) ([]byte, *crypto.PublicKey, error) {
+	if store.hdKey != nil {
+		return store.deriveKeyPair(uint32(index))
+	}
+	return crypto.GenerateSubKeyPair(index, masterKey, privateKey)
+}
+
+// deriveKeyPair derives the key pair of the BIP44 address index in the first
+// account.
+func (store *Keystore) deriveKeyPair(index uint32) ([]byte, *crypto.PublicKey, error) {
+	key, err := store.hdKey.Derive(hd.AccountPath(0, index))
+	if err != nil {
+		return nil, nil, err
+	}
+	privateKey := key.PrivateKey()
+	return privateKey, crypto.NewPubKey(privateKey), nil
 }
 
 func (store *Keystore) GetAccounts() []*sdk.Account {
@@ -339,6 +426,32 @@ func (store *Keystore) decryptPrivateKey(masterKey, passwordKey []byte) ([]byte,
 	return privateKey, crypto.NewPubKey(privateKey), nil
 }
 
+func (store *Keystore) encryptSeed(masterKey, seed []byte) ([]byte, error) {
+	iv, err := store.GetIV()
+	if err != nil {
+		return nil, err
+	}
+
+	return crypto.AesEncrypt(seed, masterKey, iv)
+}
+
+func (store *Keystore) decryptSeed(masterKey []byte) ([]byte, error) {
+	seedEncrypted, err := store.GetSeedEncrypted()
+	if err != nil {
+		return nil, err
+	}
+	if len(seedEncrypted) != hd.SeedSize {
+		return nil, errors.New("invalid encrypted seed")
+	}
+
+	iv, err := store.GetIV()
+	if err != nil {
+		return nil, err
+	}
+
+	return crypto.AesDecrypt(seedEncrypted, masterKey, iv)
+}
+
 func (store *Keystore) FromJson(str string, password string) error {
 	file := new(KeystoreFile)
 	file.FromJson(str)
@@ -24,6 +24,10 @@ type KeystoreFile struct {
 	MasterKeyEncrypted  string
 	PrivateKeyEncrypted string
 
+	// SeedEncrypted is the BIP39 seed of HD keystore, accounts are derived
+	// from the seed along the BIP44 path.  It's empty for legacy keystore.
+	SeedEncrypted string `json:",omitempty"`
+
 	SubAccountsCount int
 }
 
@@ -67,6 +71,10 @@ func (store *KeystoreFile) SetPrivateKeyEncrypted(privateKeyEncrypted []byte) {
 	store.PrivateKeyEncrypted = common.BytesToHexString(privateKeyEncrypted)
 }
 
+func (store *KeystoreFile) SetSeedEncrypted(seedEncrypted []byte) {
+	store.SeedEncrypted = common.BytesToHexString(seedEncrypted)
+}
+
 func (store *KeystoreFile) GetIV() ([]byte, error) {
 
 	iv, err := common.HexStringToBytes(store.IV)
@@ -107,6 +115,16 @@ func (store *KeystoreFile) GetPrivetKeyEncrypted() ([]byte, error) {
 	return privateKeyEncrypted, nil
 }
 
+func (store *KeystoreFile) GetSeedEncrypted() ([]byte, error) {
+
+	seedEncrypted, err := common.HexStringToBytes(store.SeedEncrypted)
+	if err != nil {
+		return nil, err
+	}
+
+	return seedEncrypted, nil
+}
+
 func (store *KeystoreFile) LoadFromFile() error {
 	store.Lock()
 	defer store.Unlock()
@@ -0,0 +1,290 @@
+package multisig
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+
+	"github.com/elastos/Elastos.ELA.SPV/wallet/client"
+
+	"github.com/elastos/Elastos.ELA/common"
+	"github.com/urfave/cli"
+)
+
+const (
+	// DefaultPartialTxFile is the default file name of the partially signed
+	// transaction imported.
+	DefaultPartialTxFile = "partial_tx.json"
+
+	// ReadyToSendFile is the file name of the finalized transaction.
+	ReadyToSendFile = "ready_to_send.tx"
+)
+
+// importPartialTx creates the partially signed transaction from the portable
+// unsigned transaction file.
+func importPartialTx(context *cli.Context) error {
+	content, err := readFile(context.String("import"))
+	if err != nil {
+		return err
+	}
+	unsignedTx, err := client.ParseUnsignedTx(content)
+	if err != nil {
+		return err
+	}
+	partialTx, err := client.NewPartialTx(unsignedTx)
+	if err != nil {
+		return err
+	}
+
+	if err := client.ShowPartialTx(partialTx); err != nil {
+		return err
+	}
+	return writePartialTx(context, partialTx, DefaultPartialTxFile)
+}
+
+func inspectPartialTx(context *cli.Context) error {
+	partialTx, err := getPartialTx(context)
+	if err != nil {
+		return err
+	}
+	return client.ShowPartialTx(partialTx)
+}
+
+// signPartialTx signs the partially signed transaction with keystore only, no
+// database or network is needed.  The transaction summary is shown for review
+// before signing.
+func signPartialTx(password []byte, context *cli.Context) error {
+	partialTx, err := getPartialTx(context)
+	if err != nil {
+		return err
+	}
+
+	if err := client.ShowPartialTx(partialTx); err != nil {
+		return err
+	}
+	fmt.Print("SIGN THIS TRANSACTION? [y/N]:")
+	input, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil {
+		return err
+	}
+	if strings.ToLower(strings.TrimSpace(input)) != "y" {
+		return errors.New("signing canceled")
+	}
+
+	password, err = client.GetPassword(password, false)
+	if err != nil {
+		return err
+	}
+	count, err := new(client.Wallet).SignPartialTx(password, partialTx)
+	if err != nil {
+		return err
+	}
+	fmt.Println("Signatures added:", count)
+
+	return writePartialTx(context, partialTx, context.String("file"))
+}
+
+// mergePartialTx merges the signatures collected by co-signers into the
+// partially signed transaction.
+func mergePartialTx(context *cli.Context) error {
+	partialTx, err := getPartialTx(context)
+	if err != nil {
+		return err
+	}
+
+	for _, path := range strings.Split(context.String("merge"), ",") {
+		path = strings.TrimSpace(path)
+		if path == "" {
+			continue
+		}
+		content, err := readFile(path)
+		if err != nil {
+			return err
+		}
+		other, err := client.ParsePartialTx(content)
+		if err != nil {
+			return fmt.Errorf("%s: %s", path, err)
+		}
+		if err := partialTx.Merge(other); err != nil {
+			return fmt.Errorf("%s: %s", path, err)
+		}
+	}
+
+	if err := client.ShowPartialTx(partialTx); err != nil {
+		return err
+	}
+	return writePartialTx(context, partialTx, context.String("file"))
+}
+
+// finalizePartialTx writes the signed transaction file to be sent, every
+// program must have collected enough signatures.
+func finalizePartialTx(context *cli.Context) error {
+	partialTx, err := getPartialTx(context)
+	if err != nil {
+		return err
+	}
+	txn, err := partialTx.Finalize()
+	if err != nil {
+		return err
+	}
+
+	buf := new(bytes.Buffer)
+	if err := txn.Serialize(buf); err != nil {
+		return err
+	}
+	content := common.BytesToHexString(buf.Bytes())
+	fmt.Println(content)
+
+	fileName := context.String("output")
+	if fileName == "" {
+		fileName = ReadyToSendFile
+	}
+	if err := ioutil.WriteFile(fileName, []byte(content), 0600); err != nil {
+		return err
+	}
+
+	fmt.Println("Transaction successfully finalized, file:", fileName)
+	return nil
+}
+
+func getPartialTx(context *cli.Context) (*client.PartialTx, error) {
+	path := strings.TrimSpace(context.String("file"))
+	if path == "" {
+		return nil, errors.New("use --file to specify the partially signed transaction file")
+	}
+	content, err := readFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return client.ParsePartialTx(content)
+}
+
+// writePartialTx writes the partially signed transaction to the --output file
+// path, or the default file path if not specified.
+func writePartialTx(context *cli.Context, partialTx *client.PartialTx, defaultPath string) error {
+	content, err := partialTx.Json()
+	if err != nil {
+		return err
+	}
+
+	fileName := context.String("output")
+	if fileName == "" {
+		fileName = defaultPath
+	}
+	if err := ioutil.WriteFile(fileName, content, 0600); err != nil {
+		return err
+	}
+
+	fmt.Println("Partially signed transaction file:", fileName)
+	return nil
+}
+
+func readFile(path string) ([]byte, error) {
+	path = strings.TrimSpace(path)
+	if _, err := os.Stat(path); err != nil {
+		return nil, errors.New("invalid transaction file path " + path)
+	}
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, errors.New("read transaction file failed")
+	}
+	return bytes.TrimSpace(content), nil
+}
+
+func multisigAction(context *cli.Context) {
+	if context.NumFlags() == 0 {
+		cli.ShowSubcommandHelp(context)
+		os.Exit(0)
+	}
+	pass := context.String("password")
+
+	switch {
+	// import the portable unsigned transaction file
+	case context.IsSet("import"):
+		if err := importPartialTx(context); err != nil {
+			fmt.Println("error:", err)
+			cli.ShowCommandHelpAndExit(context, "import", 801)
+		}
+
+	// inspect the partially signed transaction
+	case context.Bool("inspect"):
+		if err := inspectPartialTx(context); err != nil {
+			fmt.Println("error:", err)
+			cli.ShowCommandHelpAndExit(context, "inspect", 802)
+		}
+
+	// sign the partially signed transaction
+	case context.Bool("sign"):
+		if err := signPartialTx([]byte(pass), context); err != nil {
+			fmt.Println("error:", err)
+			cli.ShowCommandHelpAndExit(context, "sign", 803)
+		}
+
+	// merge signatures from co-signers
+	case context.IsSet("merge"):
+		if err := mergePartialTx(context); err != nil {
+			fmt.Println("error:", err)
+			cli.ShowCommandHelpAndExit(context, "merge", 804)
+		}
+
+	// finalize the transaction to be sent
+	case context.Bool("finalize"):
+		if err := finalizePartialTx(context); err != nil {
+			fmt.Println("error:", err)
+			cli.ShowCommandHelpAndExit(context, "finalize", 805)
+		}
+
+	default:
+		cli.ShowSubcommandHelp(context)
+	}
+}
+
+func NewCommand() cli.Command {
+	return cli.Command{
+		Name:        "multisig",
+		ShortName:   "ms",
+		Usage:       "use [--import, --inspect, --sign, --merge, --finalize], to coordinate a multi sign transaction",
+		Description: "import, inspect, sign, merge signatures of and finalize a partially signed multi sign transaction",
+		ArgsUsage:   "[args]",
+		Flags: append(client.CommonFlags,
+			cli.StringFlag{
+				Name: "import",
+				Usage: "the portable unsigned transaction file path created by transaction --create --offline\n" +
+					"\tto create a partially signed transaction file, the signatures already in it are kept",
+			},
+			cli.BoolFlag{
+				Name:  "inspect",
+				Usage: "use --file to show the transaction, the signers and the signatures still missing",
+			},
+			cli.BoolFlag{
+				Name:  "sign",
+				Usage: "use --file to sign the partially signed transaction with this keystore",
+			},
+			cli.StringFlag{
+				Name:  "merge",
+				Usage: "the comma separated partially signed transaction file paths signed by co-signers to merge into --file",
+			},
+			cli.BoolFlag{
+				Name:  "finalize",
+				Usage: "use --file to create the signed transaction file to be sent by transaction --send",
+			},
+			cli.StringFlag{
+				Name:  "file",
+				Usage: "the partially signed transaction file path",
+			},
+			cli.StringFlag{
+				Name: "output",
+				Usage: "the output file path, by default --sign and --merge update --file, --import writes " + DefaultPartialTxFile +
+					"\n\tand --finalize writes " + ReadyToSendFile,
+			},
+		),
+		Action: multisigAction,
+		OnUsageError: func(c *cli.Context, err error, subCommand bool) error {
+			return cli.NewExitError(err, 1)
+		},
+	}
+}
@@ -0,0 +1,278 @@
+package client
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/elastos/Elastos.ELA.SPV/util"
+
+	"github.com/elastos/Elastos.ELA/common"
+	"github.com/elastos/Elastos.ELA/core/contract"
+	tx "github.com/elastos/Elastos.ELA/core/transaction"
+	it "github.com/elastos/Elastos.ELA/core/types/interfaces"
+	"github.com/elastos/Elastos.ELA/crypto"
+)
+
+const (
+	UnsignedTxVersion = 1
+)
+
+// UnsignedTx is the portable unsigned transaction file.  Besides the
+// transaction, it contains the previous transactions and redeem scripts of
+// the UTXOs referenced by the transaction inputs, so the transaction can be
+// verified, reviewed and signed by a keystore-only wallet without database
+// and network.
+type UnsignedTx struct {
+	Version int
+
+	// Transaction is the transaction in hex string format, the signatures
+	// are filled in after signed.
+	Transaction string
+
+	// Inputs are the UTXOs referenced by the transaction inputs in the same
+	// order.
+	Inputs []*UnsignedInput
+
+	// PrevTxs are the previous transactions of the inputs in hex string
+	// format, the UTXO addresses and values are verified against them.
+	PrevTxs []string
+}
+
+// UnsignedInput is the UTXO referenced by a transaction input.
+type UnsignedInput struct {
+	TxID         string
+	Index        uint16
+	Address      string
+	Value        string
+	RedeemScript string
+}
+
+// CreateUnsignedTx creates the portable unsigned transaction file of the
+// transaction created by this wallet, the referenced UTXOs are looked up from
+// the addresses of the transaction programs.
+func (wallet *Wallet) CreateUnsignedTx(txn it.Transaction) (*UnsignedTx, error) {
+	utxos := make(map[util.OutPoint]*UnsignedInput)
+	for _, program := range txn.Programs() {
+		programHash, err := redeemScriptHash(program.Code)
+		if err != nil {
+			return nil, err
+		}
+		address, err := programHash.ToAddress()
+		if err != nil {
+			return nil, err
+		}
+		addrUTXOs, err := wallet.GetAddressUTXOs(programHash)
+		if err != nil {
+			return nil, err
+		}
+		for _, utxo := range addrUTXOs {
+			utxos[*utxo.Op] = &UnsignedInput{
+				TxID:         utxo.Op.TxID.String(),
+				Index:        utxo.Op.Index,
+				Address:      address,
+				Value:        utxo.Value.String(),
+				RedeemScript: common.BytesToHexString(program.Code),
+			}
+		}
+	}
+
+	inputs := make([]*UnsignedInput, 0, len(txn.Inputs()))
+	var prevTxs []string
+	added := make(map[common.Uint256]bool)
+	for _, input := range txn.Inputs() {
+		op := util.NewOutPoint(input.Previous.TxID, input.Previous.Index)
+		utxo, ok := utxos[*op]
+		if !ok {
+			return nil, fmt.Errorf("[Wallet], UTXO %s:%d not found",
+				input.Previous.TxID.String(), input.Previous.Index)
+		}
+		inputs = append(inputs, utxo)
+
+		if added[op.TxID] {
+			continue
+		}
+		prevTx, err := wallet.GetTransaction(&op.TxID)
+		if err != nil {
+			return nil, fmt.Errorf("[Wallet], transaction %s not found",
+				op.TxID.String())
+		}
+		prevTxs = append(prevTxs, common.BytesToHexString(prevTx.RawData))
+		added[op.TxID] = true
+	}
+
+	unsignedTx := &UnsignedTx{Version: UnsignedTxVersion, Inputs: inputs,
+		PrevTxs: prevTxs}
+	if err := unsignedTx.SetTransaction(txn); err != nil {
+		return nil, err
+	}
+	return unsignedTx, nil
+}
+
+// ParseUnsignedTx parses the portable unsigned transaction file content.
+func ParseUnsignedTx(data []byte) (*UnsignedTx, error) {
+	var unsignedTx UnsignedTx
+	if err := json.Unmarshal(data, &unsignedTx); err != nil {
+		return nil, err
+	}
+	if unsignedTx.Version != UnsignedTxVersion {
+		return nil, fmt.Errorf("unsupported unsigned transaction version %d",
+			unsignedTx.Version)
+	}
+	return &unsignedTx, nil
+}
+
+// GetTransaction returns the transaction in the file.
+func (u *UnsignedTx) GetTransaction() (it.Transaction, error) {
+	return decodeTransaction(u.Transaction)
+}
+
+// getPrevTxs returns the previous transactions in the file by hash.
+func (u *UnsignedTx) getPrevTxs() (map[common.Uint256]it.Transaction, error) {
+	prevTxs := make(map[common.Uint256]it.Transaction, len(u.PrevTxs))
+	for i, str := range u.PrevTxs {
+		prevTx, err := decodeTransaction(str)
+		if err != nil {
+			return nil, fmt.Errorf("invalid previous transaction %d", i)
+		}
+		prevTxs[prevTx.Hash()] = prevTx
+	}
+	return prevTxs, nil
+}
+
+// decodeTransaction decodes the transaction in hex string format.
+func decodeTransaction(str string) (it.Transaction, error) {
+	data, err := common.HexStringToBytes(str)
+	if err != nil {
+		return nil, err
+	}
+	r := bytes.NewReader(data)
+	txn, err := tx.GetTransactionByBytes(r)
+	if err != nil {
+		return nil, err
+	}
+	if err := txn.Deserialize(r); err != nil {
+		return nil, err
+	}
+	return txn, nil
+}
+
+// SetTransaction replaces the transaction in the file, it's used to save the
+// signed transaction.
+func (u *UnsignedTx) SetTransaction(txn it.Transaction) error {
+	buf := new(bytes.Buffer)
+	if err := txn.Serialize(buf); err != nil {
+		return err
+	}
+	u.Transaction = common.BytesToHexString(buf.Bytes())
+	return nil
+}
+
+// Verify checks the transaction inputs reference the UTXOs in file, the UTXO
+// addresses and values match the outputs of the previous transactions, the
+// redeem scripts match the UTXO addresses and the transaction programs, and
+// the outputs do not spend more than inputs.  Returns the transaction and the
+// fee.
+func (u *UnsignedTx) Verify() (it.Transaction, common.Fixed64, error) {
+	txn, err := u.GetTransaction()
+	if err != nil {
+		return nil, 0, err
+	}
+	prevTxs, err := u.getPrevTxs()
+	if err != nil {
+		return nil, 0, err
+	}
+
+	if len(txn.Inputs()) != len(u.Inputs) {
+		return nil, 0, errors.New("inputs count not match")
+	}
+	scripts := make(map[string]bool)
+	var inputsValue common.Fixed64
+	for i, input := range txn.Inputs() {
+		utxo := u.Inputs[i]
+		if utxo.TxID != input.Previous.TxID.String() ||
+			utxo.Index != input.Previous.Index {
+			return nil, 0, fmt.Errorf("input %d not match UTXO %s:%d", i,
+				utxo.TxID, utxo.Index)
+		}
+
+		// The previous transaction is found by it's hash, so the output
+		// can not be forged.
+		prevTx, ok := prevTxs[input.Previous.TxID]
+		if !ok || int(input.Previous.Index) >= len(prevTx.Outputs()) {
+			return nil, 0, fmt.Errorf("previous output of input %d not found", i)
+		}
+		output := prevTx.Outputs()[input.Previous.Index]
+		if address, err := output.ProgramHash.ToAddress(); err != nil ||
+			address != utxo.Address {
+			return nil, 0, fmt.Errorf("address of input %d not match previous output", i)
+		}
+		if value, err := common.StringToFixed64(utxo.Value); err != nil ||
+			*value != output.Value {
+			return nil, 0, fmt.Errorf("value of input %d not match previous output", i)
+		}
+
+		code, err := common.HexStringToBytes(utxo.RedeemScript)
+		if err != nil {
+			return nil, 0, fmt.Errorf("invalid redeem script of input %d", i)
+		}
+		programHash, err := redeemScriptHash(code)
+		if err != nil {
+			return nil, 0, fmt.Errorf("invalid redeem script of input %d", i)
+		}
+		address, err := programHash.ToAddress()
+		if err != nil || address != utxo.Address {
+			return nil, 0, fmt.Errorf("redeem script not match address %s",
+				utxo.Address)
+		}
+		scripts[utxo.RedeemScript] = true
+		inputsValue += output.Value
+	}
+
+	// Every redeem script must have a program to be signed, and programs can
+	// only sign the referenced UTXOs.
+	if len(txn.Programs()) != len(scripts) {
+		return nil, 0, errors.New("programs not match redeem scripts")
+	}
+	for _, program := range txn.Programs() {
+		if !scripts[common.BytesToHexString(program.Code)] {
+			return nil, 0, errors.New("programs not match redeem scripts")
+		}
+	}
+
+	var outputsValue common.Fixed64
+	for _, output := range txn.Outputs() {
+		outputsValue += output.Value
+	}
+	if outputsValue > inputsValue {
+		return nil, 0, errors.New("outputs value exceeds inputs value")
+	}
+
+	return txn, inputsValue - outputsValue, nil
+}
+
+// Json returns the file content in JSON format.
+func (u *UnsignedTx) Json() ([]byte, error) {
+	return json.MarshalIndent(u, "", "  ")
+}
+
+// redeemScriptHash returns the program hash of the standard or multi sign
+// redeem script.
+func redeemScriptHash(code []byte) (*common.Uint168, error) {
+	if len(code) == 0 {
+		return nil, errors.New("empty redeem script")
+	}
+	signType, err := crypto.GetScriptType(code)
+	if err != nil {
+		return nil, err
+	}
+	switch signType {
+	case common.STANDARD:
+		return common.ToProgramHash(byte(contract.PrefixStandard), code), nil
+	case common.MULTISIG:
+		return common.ToProgramHash(byte(contract.PrefixMultiSig), code), nil
+	default:
+		return nil, errors.New("unsupported redeem script")
+	}
+}
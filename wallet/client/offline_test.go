@@ -0,0 +1,156 @@
+package client
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/elastos/Elastos.ELA.SPV/sdk"
+
+	"github.com/elastos/Elastos.ELA/common"
+	"github.com/elastos/Elastos.ELA/core/contract/program"
+	tx "github.com/elastos/Elastos.ELA/core/transaction"
+	types "github.com/elastos/Elastos.ELA/core/types/common"
+	it "github.com/elastos/Elastos.ELA/core/types/interfaces"
+	"github.com/elastos/Elastos.ELA/core/types/payload"
+	"github.com/elastos/Elastos.ELA/crypto"
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestAccount(t *testing.T) *sdk.Account {
+	privateKey, publicKey, err := crypto.GenerateKeyPair()
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	account, err := sdk.NewAccount(privateKey, publicKey)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	return account
+}
+
+// newTransferTx returns a transfer transaction with a program of every
+// redeem script.
+func newTransferTx(inputs []*types.Input, outputs []*types.Output,
+	codes ...[]byte) it.Transaction {
+	var programs []*program.Program
+	for _, code := range codes {
+		programs = append(programs, &program.Program{Code: code})
+	}
+	return tx.CreateTransaction(
+		types.TxVersion09,
+		types.TransferAsset,
+		0,
+		&payload.TransferAsset{},
+		[]*types.Attribute{},
+		inputs,
+		outputs,
+		0,
+		programs,
+	)
+}
+
+func txHex(t *testing.T, txn it.Transaction) string {
+	buf := new(bytes.Buffer)
+	if !assert.NoError(t, txn.Serialize(buf)) {
+		t.FailNow()
+	}
+	return common.BytesToHexString(buf.Bytes())
+}
+
+// newUnsignedTx returns the unsigned transaction spends the output of value
+// paid to the redeem script of address, and pays spend back.
+func newUnsignedTx(t *testing.T, address *common.Uint168, redeemScript []byte,
+	value, spend common.Fixed64) *UnsignedTx {
+	prevTx := newTransferTx(nil, []*types.Output{
+		{ProgramHash: *address, Value: value},
+	})
+	txn := newTransferTx([]*types.Input{{
+		Previous: *types.NewOutPoint(prevTx.Hash(), 0),
+	}}, []*types.Output{
+		{ProgramHash: *address, Value: spend},
+	}, redeemScript)
+
+	addr, err := address.ToAddress()
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	unsignedTx := &UnsignedTx{
+		Version: UnsignedTxVersion,
+		Inputs: []*UnsignedInput{{
+			TxID:         prevTx.Hash().String(),
+			Index:        0,
+			Address:      addr,
+			Value:        value.String(),
+			RedeemScript: common.BytesToHexString(redeemScript),
+		}},
+		PrevTxs: []string{txHex(t, prevTx)},
+	}
+	if !assert.NoError(t, unsignedTx.SetTransaction(txn)) {
+		t.FailNow()
+	}
+	return unsignedTx
+}
+
+func TestUnsignedTx_Verify(t *testing.T) {
+	account, other := newTestAccount(t), newTestAccount(t)
+	unsignedTx := newUnsignedTx(t, account.ProgramHash(),
+		account.RedeemScript(), 10, 9)
+	txn, fee, err := unsignedTx.Verify()
+	if assert.NoError(t, err) {
+		assert.Equal(t, common.Fixed64(1), fee)
+		assert.Equal(t, 1, len(txn.Inputs()))
+	}
+
+	tests := []struct {
+		name   string
+		modify func(u *UnsignedTx)
+	}{
+		{"input not match UTXO", func(u *UnsignedTx) {
+			u.Inputs[0].Index = 1
+		}},
+		{"inputs count not match", func(u *UnsignedTx) {
+			u.Inputs = append(u.Inputs, u.Inputs[0])
+		}},
+		{"value not match previous output", func(u *UnsignedTx) {
+			u.Inputs[0].Value = common.Fixed64(100).String()
+		}},
+		{"address not match previous output", func(u *UnsignedTx) {
+			u.Inputs[0].Address = other.Address()
+			u.Inputs[0].RedeemScript = common.BytesToHexString(other.RedeemScript())
+		}},
+		{"previous transaction missing", func(u *UnsignedTx) {
+			u.PrevTxs = nil
+		}},
+		{"previous transaction forged", func(u *UnsignedTx) {
+			u.PrevTxs = []string{txHex(t, newTransferTx(nil, []*types.Output{
+				{ProgramHash: *account.ProgramHash(), Value: 100},
+			}))}
+		}},
+		{"redeem script not match address", func(u *UnsignedTx) {
+			u.Inputs[0].RedeemScript = common.BytesToHexString(other.RedeemScript())
+		}},
+		{"program not match redeem script", func(u *UnsignedTx) {
+			txn, _ := u.GetTransaction()
+			txn.Programs()[0].Code = other.RedeemScript()
+			u.SetTransaction(txn)
+		}},
+		{"extra program", func(u *UnsignedTx) {
+			txn, _ := u.GetTransaction()
+			txn.SetPrograms(append(txn.Programs(),
+				&program.Program{Code: other.RedeemScript()}))
+			u.SetTransaction(txn)
+		}},
+		{"outputs exceed inputs", func(u *UnsignedTx) {
+			txn, _ := u.GetTransaction()
+			txn.Outputs()[0].Value = 11
+			u.SetTransaction(txn)
+		}},
+	}
+	for _, test := range tests {
+		u := newUnsignedTx(t, account.ProgramHash(),
+			account.RedeemScript(), 10, 9)
+		test.modify(u)
+		_, _, err := u.Verify()
+		assert.Error(t, err, test.name)
+	}
+}
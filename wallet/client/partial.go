@@ -0,0 +1,402 @@
+package client
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/elastos/Elastos.ELA/common"
+	"github.com/elastos/Elastos.ELA/core/contract"
+	it "github.com/elastos/Elastos.ELA/core/types/interfaces"
+	"github.com/elastos/Elastos.ELA/crypto"
+)
+
+const (
+	PartialTxVersion = 1
+)
+
+// PartialTx is the partially signed transaction container for multi sign
+// coordination.  It lists the signers of every redeem script and the
+// signatures collected, so co-signers can sign in parallel, merge their
+// signatures and see what is still missing.
+type PartialTx struct {
+	Version int
+
+	// Transaction is the transaction in hex string format without
+	// signatures, signatures are filled in when finalized.
+	Transaction string
+
+	// Inputs are the UTXOs referenced by the transaction inputs in the same
+	// order.
+	Inputs []*UnsignedInput
+
+	// PrevTxs are the previous transactions of the inputs in hex string
+	// format.
+	PrevTxs []string
+
+	// Programs are the redeem scripts to be signed in the same order as the
+	// transaction programs.
+	Programs []*PartialProgram
+}
+
+// PartialProgram is a M-of-N redeem script and the signatures collected.
+type PartialProgram struct {
+	Address      string
+	RedeemScript string
+	M            int
+
+	// Signers are the N signers in the redeem script order.
+	Signers []*PartialSigner
+}
+
+// PartialSigner is a signer of the redeem script, the signature is empty if
+// not signed yet.
+type PartialSigner struct {
+	PublicKey string
+	Signature string `json:",omitempty"`
+}
+
+// Missing returns how many signatures are still needed.
+func (p *PartialProgram) Missing() int {
+	signed := 0
+	for _, signer := range p.Signers {
+		if signer.Signature != "" {
+			signed++
+		}
+	}
+	if signed >= p.M {
+		return 0
+	}
+	return p.M - signed
+}
+
+// NewPartialTx creates the partially signed transaction from the portable
+// unsigned transaction file, the signatures already in the transaction are
+// collected.
+func NewPartialTx(unsignedTx *UnsignedTx) (*PartialTx, error) {
+	txn, _, err := unsignedTx.Verify()
+	if err != nil {
+		return nil, err
+	}
+	data := signData(txn)
+
+	partialTx := &PartialTx{Version: PartialTxVersion, Inputs: unsignedTx.Inputs,
+		PrevTxs: unsignedTx.PrevTxs}
+	for _, program := range txn.Programs() {
+		partialProgram, err := newPartialProgram(program.Code)
+		if err != nil {
+			return nil, err
+		}
+
+		// Collect the signatures in parameter.
+		param := program.Parameter
+		for len(param) > 0 {
+			size := int(param[0])
+			if len(param) < size+1 {
+				return nil, errors.New("invalid program parameter")
+			}
+			if err := partialProgram.addSignature(param[1:size+1], data); err != nil {
+				return nil, err
+			}
+			param = param[size+1:]
+		}
+		partialTx.Programs = append(partialTx.Programs, partialProgram)
+
+		program.Parameter = nil
+	}
+
+	buf := new(bytes.Buffer)
+	if err := txn.Serialize(buf); err != nil {
+		return nil, err
+	}
+	partialTx.Transaction = common.BytesToHexString(buf.Bytes())
+	return partialTx, nil
+}
+
+// ParsePartialTx parses and verifies the partially signed transaction file
+// content.
+func ParsePartialTx(data []byte) (*PartialTx, error) {
+	var partialTx PartialTx
+	if err := json.Unmarshal(data, &partialTx); err != nil {
+		return nil, err
+	}
+	if partialTx.Version != PartialTxVersion {
+		return nil, fmt.Errorf("unsupported partially signed transaction version %d",
+			partialTx.Version)
+	}
+	if err := partialTx.Verify(); err != nil {
+		return nil, err
+	}
+	return &partialTx, nil
+}
+
+// UnsignedTx returns the portable unsigned transaction of it, which is used
+// to verify and show the transaction.
+func (p *PartialTx) UnsignedTx() *UnsignedTx {
+	return &UnsignedTx{
+		Version:     UnsignedTxVersion,
+		Transaction: p.Transaction,
+		Inputs:      p.Inputs,
+		PrevTxs:     p.PrevTxs,
+	}
+}
+
+// Verify checks the transaction and inputs, the programs match the redeem
+// scripts of the transaction, and the signatures collected are valid.
+func (p *PartialTx) Verify() error {
+	txn, _, err := p.UnsignedTx().Verify()
+	if err != nil {
+		return err
+	}
+	data := signData(txn)
+
+	if len(txn.Programs()) != len(p.Programs) {
+		return errors.New("programs count not match")
+	}
+	for i, program := range txn.Programs() {
+		if len(program.Parameter) > 0 {
+			return errors.New("signatures must not be in transaction")
+		}
+
+		expected, err := newPartialProgram(program.Code)
+		if err != nil {
+			return err
+		}
+		partialProgram := p.Programs[i]
+		if partialProgram.RedeemScript != expected.RedeemScript ||
+			partialProgram.Address != expected.Address ||
+			partialProgram.M != expected.M ||
+			len(partialProgram.Signers) != len(expected.Signers) {
+			return fmt.Errorf("program %d not match redeem script", i)
+		}
+
+		for j, signer := range partialProgram.Signers {
+			if signer.PublicKey != expected.Signers[j].PublicKey {
+				return fmt.Errorf("program %d signer %d not match redeem script", i, j)
+			}
+			if signer.Signature == "" {
+				continue
+			}
+			if err := verifySignature(signer, data); err != nil {
+				return fmt.Errorf("program %d signer %d %s", i, j, err)
+			}
+		}
+	}
+	return nil
+}
+
+// Merge merges the signatures of the same transaction collected by other
+// co-signers.
+func (p *PartialTx) Merge(other *PartialTx) error {
+	if other.Transaction != p.Transaction ||
+		len(other.Programs) != len(p.Programs) {
+		return errors.New("not the same transaction")
+	}
+
+	txn, err := p.UnsignedTx().GetTransaction()
+	if err != nil {
+		return err
+	}
+	data := signData(txn)
+
+	for i, program := range other.Programs {
+		partialProgram := p.Programs[i]
+		if program.RedeemScript != partialProgram.RedeemScript ||
+			len(program.Signers) != len(partialProgram.Signers) {
+			return errors.New("not the same transaction")
+		}
+		for j, signer := range program.Signers {
+			if signer.PublicKey != partialProgram.Signers[j].PublicKey {
+				return errors.New("not the same transaction")
+			}
+			if signer.Signature == "" || partialProgram.Signers[j].Signature != "" {
+				continue
+			}
+			if err := verifySignature(signer, data); err != nil {
+				return err
+			}
+			partialProgram.Signers[j].Signature = signer.Signature
+		}
+	}
+	return nil
+}
+
+// Finalize returns the signed transaction with the first M signatures of
+// every program in the redeem script order.
+func (p *PartialTx) Finalize() (it.Transaction, error) {
+	txn, err := p.UnsignedTx().GetTransaction()
+	if err != nil {
+		return nil, err
+	}
+
+	for i, program := range txn.Programs() {
+		partialProgram := p.Programs[i]
+		if missing := partialProgram.Missing(); missing > 0 {
+			return nil, fmt.Errorf("%s needs %d more signatures",
+				partialProgram.Address, missing)
+		}
+
+		buf := new(bytes.Buffer)
+		signed := 0
+		for _, signer := range partialProgram.Signers {
+			if signer.Signature == "" {
+				continue
+			}
+			signature, err := common.HexStringToBytes(signer.Signature)
+			if err != nil {
+				return nil, err
+			}
+			buf.WriteByte(byte(len(signature)))
+			buf.Write(signature)
+			if signed++; signed == partialProgram.M {
+				break
+			}
+		}
+		program.Parameter = buf.Bytes()
+	}
+	return txn, nil
+}
+
+// SignPartialTx signs the programs of the partially signed transaction with
+// the keystore accounts, returns how many signatures are added.
+func (wallet *Wallet) SignPartialTx(password []byte, partialTx *PartialTx) (int, error) {
+	err := wallet.VerifyPassword(password)
+	if err != nil {
+		return 0, err
+	}
+
+	txn, err := partialTx.UnsignedTx().GetTransaction()
+	if err != nil {
+		return 0, err
+	}
+	data := signData(txn)
+
+	count := 0
+	for _, program := range partialTx.Programs {
+		for _, signer := range program.Signers {
+			if signer.Signature != "" {
+				continue
+			}
+			publicKey, err := decodePublicKey(signer.PublicKey)
+			if err != nil {
+				return 0, err
+			}
+			contract, err := contract.CreateStandardContract(publicKey)
+			if err != nil {
+				return 0, err
+			}
+			account := wallet.Keystore.GetAccountByProgramHash(contract.ToProgramHash())
+			if account == nil {
+				continue
+			}
+
+			signature, err := account.Sign(data)
+			if err != nil {
+				return 0, err
+			}
+			signer.Signature = common.BytesToHexString(signature)
+			count++
+		}
+	}
+	if count == 0 {
+		return 0, errors.New("[Wallet], No unsigned signer in this wallet")
+	}
+	return count, nil
+}
+
+// Json returns the file content in JSON format.
+func (p *PartialTx) Json() ([]byte, error) {
+	return json.MarshalIndent(p, "", "  ")
+}
+
+// newPartialProgram returns the program of the standard or multi sign redeem
+// script without signatures.
+func newPartialProgram(code []byte) (*PartialProgram, error) {
+	programHash, err := redeemScriptHash(code)
+	if err != nil {
+		return nil, err
+	}
+	address, err := programHash.ToAddress()
+	if err != nil {
+		return nil, err
+	}
+	_, m, err := crypto.GetSignStatus(code, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	// Public keys in redeem script are prefixed with the length.
+	var publicKeys [][]byte
+	signType, err := crypto.GetScriptType(code)
+	if err != nil {
+		return nil, err
+	}
+	if signType == common.MULTISIG {
+		publicKeys, err = crypto.ParseMultisigScript(code)
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		publicKeys = [][]byte{code[:len(code)-1]}
+	}
+
+	program := &PartialProgram{
+		Address:      address,
+		RedeemScript: common.BytesToHexString(code),
+		M:            m,
+	}
+	for _, publicKey := range publicKeys {
+		program.Signers = append(program.Signers, &PartialSigner{
+			PublicKey: common.BytesToHexString(publicKey[1:]),
+		})
+	}
+	return program, nil
+}
+
+// addSignature fills the signature into the signer it belongs to.
+func (p *PartialProgram) addSignature(signature, data []byte) error {
+	for _, signer := range p.Signers {
+		if signer.Signature != "" {
+			continue
+		}
+		signer.Signature = common.BytesToHexString(signature)
+		if verifySignature(signer, data) == nil {
+			return nil
+		}
+		signer.Signature = ""
+	}
+	return fmt.Errorf("signature of %s not match any signer", p.Address)
+}
+
+// verifySignature verifies the signature of signer on the data.
+func verifySignature(signer *PartialSigner, data []byte) error {
+	publicKey, err := decodePublicKey(signer.PublicKey)
+	if err != nil {
+		return err
+	}
+	signature, err := common.HexStringToBytes(signer.Signature)
+	if err != nil {
+		return errors.New("invalid signature")
+	}
+	if err := crypto.Verify(*publicKey, data, signature); err != nil {
+		return errors.New("invalid signature")
+	}
+	return nil
+}
+
+// decodePublicKey decodes the public key in hex string format.
+func decodePublicKey(str string) (*crypto.PublicKey, error) {
+	keyBytes, err := common.HexStringToBytes(str)
+	if err != nil {
+		return nil, err
+	}
+	return crypto.DecodePoint(keyBytes)
+}
+
+// signData returns the data of the transaction to be signed.
+func signData(txn it.Transaction) []byte {
+	buf := new(bytes.Buffer)
+	txn.SerializeUnsigned(buf)
+	return buf.Bytes()
+}
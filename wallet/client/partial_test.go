@@ -0,0 +1,152 @@
+package client
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/elastos/Elastos.ELA/core/contract"
+	"github.com/elastos/Elastos.ELA/crypto"
+	"github.com/stretchr/testify/assert"
+)
+
+var testPassword = []byte("password")
+
+// testSigner is a co-signer with the keystore in its own directory.
+type testSigner struct {
+	dir    string
+	wallet *Wallet
+}
+
+func newTestSigner(t *testing.T) *testSigner {
+	dir, err := ioutil.TempDir("", "spv_test")
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	signer := &testSigner{dir: dir}
+	signer.inDir(t, func() {
+		keystore, err := CreateKeystore(testPassword)
+		if !assert.NoError(t, err) {
+			t.FailNow()
+		}
+		signer.wallet = &Wallet{Keystore: keystore}
+	})
+	return signer
+}
+
+// inDir runs f in the keystore directory of the signer.
+func (s *testSigner) inDir(t *testing.T, f func()) {
+	wd, err := os.Getwd()
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	if !assert.NoError(t, os.Chdir(s.dir)) {
+		t.FailNow()
+	}
+	defer os.Chdir(wd)
+	f()
+}
+
+func (s *testSigner) sign(t *testing.T, partialTx *PartialTx) (count int, err error) {
+	s.inDir(t, func() {
+		count, err = s.wallet.SignPartialTx(testPassword, partialTx)
+	})
+	return count, err
+}
+
+func parsePartialTx(t *testing.T, data []byte) *PartialTx {
+	partialTx, err := ParsePartialTx(data)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	return partialTx
+}
+
+func TestPartialTx(t *testing.T) {
+	var signers []*testSigner
+	var publicKeys []*crypto.PublicKey
+	for i := 0; i < 3; i++ {
+		signer := newTestSigner(t)
+		defer os.RemoveAll(signer.dir)
+		signers = append(signers, signer)
+		publicKeys = append(publicKeys,
+			signer.wallet.Keystore.MainAccount().PublicKey())
+	}
+	multiSig, err := contract.CreateMultiSigContract(2, publicKeys)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	unsignedTx := newUnsignedTx(t, multiSig.ToProgramHash(), multiSig.Code, 10, 9)
+
+	partialTx, err := NewPartialTx(unsignedTx)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	assert.Equal(t, 1, len(partialTx.Programs))
+	assert.Equal(t, 3, len(partialTx.Programs[0].Signers))
+	assert.Equal(t, 2, partialTx.Programs[0].Missing())
+	data, err := partialTx.Json()
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+
+	// Signers 0 and 2 sign their own copies in parallel.
+	var signed []*PartialTx
+	for _, i := range []int{0, 2} {
+		copied := parsePartialTx(t, data)
+		count, err := signers[i].sign(t, copied)
+		if !assert.NoError(t, err) {
+			t.FailNow()
+		}
+		assert.Equal(t, 1, count)
+		assert.Equal(t, 1, copied.Programs[0].Missing())
+		assert.NotEqual(t, "", copied.Programs[0].Signers[i].Signature)
+		signed = append(signed, copied)
+
+		// Signing again adds nothing.
+		_, err = signers[i].sign(t, copied)
+		assert.Error(t, err)
+	}
+
+	// Finalizing with signatures missing fails.
+	_, err = signed[0].Finalize()
+	assert.Error(t, err)
+
+	// Merging a signature of another signer fails.
+	forged := parsePartialTx(t, data)
+	forged.Programs[0].Signers[1].Signature =
+		signed[0].Programs[0].Signers[0].Signature
+	merged := parsePartialTx(t, data)
+	assert.Error(t, merged.Merge(forged))
+	assert.Equal(t, "", merged.Programs[0].Signers[1].Signature)
+	forgedData, err := forged.Json()
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	_, err = ParsePartialTx(forgedData)
+	assert.Error(t, err)
+
+	// Merge the signatures and finalize.
+	for _, copied := range signed {
+		if !assert.NoError(t, merged.Merge(copied)) {
+			t.FailNow()
+		}
+	}
+	assert.Equal(t, 0, merged.Programs[0].Missing())
+	txn, err := merged.Finalize()
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	assert.Equal(t, 2*(1+64), len(txn.Programs()[0].Parameter))
+
+	// Importing the signed transaction collects the signatures in parameter.
+	if !assert.NoError(t, unsignedTx.SetTransaction(txn)) {
+		t.FailNow()
+	}
+	imported, err := NewPartialTx(unsignedTx)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	assert.Equal(t, merged.Transaction, imported.Transaction)
+	assert.Equal(t, merged.Programs, imported.Programs)
+}
@@ -24,6 +24,13 @@ func CreateTransaction(c *cli.Context, wallet *client.Wallet) error {
 	if err != nil {
 		return err
 	}
+	if c.Bool("offline") {
+		unsignedTx, err := wallet.CreateUnsignedTx(txn)
+		if err != nil {
+			return err
+		}
+		return outputUnsignedTx(unsignedTx, txn)
+	}
 	return output(txn)
 }
 
@@ -152,6 +159,47 @@ func SignTransaction(password []byte, context *cli.Context, wallet *client.Walle
 	return output(txn)
 }
 
+// SignUnsignedTx signs the portable unsigned transaction file with keystore
+// only, no database or network is needed.  The transaction summary is shown
+// for review before signing.
+func SignUnsignedTx(password []byte, context *cli.Context) error {
+	content, err := getContent(context)
+	if err != nil {
+		return err
+	}
+	unsignedTx, err := client.ParseUnsignedTx([]byte(*content))
+	if err != nil {
+		return err
+	}
+
+	if err := client.ShowUnsignedTx(unsignedTx); err != nil {
+		return err
+	}
+	fmt.Print("SIGN THIS TRANSACTION? [y/N]:")
+	input, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil {
+		return err
+	}
+	if strings.ToLower(strings.TrimSpace(input)) != "y" {
+		return errors.New("signing canceled")
+	}
+
+	txn, err := unsignedTx.GetTransaction()
+	if err != nil {
+		return err
+	}
+	// The wallet signs with keystore only, database is not opened.
+	txn, err = signTransaction(password, new(client.Wallet), txn)
+	if err != nil {
+		return err
+	}
+	if err := unsignedTx.SetTransaction(txn); err != nil {
+		return err
+	}
+
+	return outputUnsignedTx(unsignedTx, txn)
+}
+
 func signTransaction(password []byte, wallet *client.Wallet, tx it.Transaction) (it.Transaction, error) {
 	haveSign, needSign, err := crypto.GetSignStatus(tx.Programs()[0].Code, tx.Programs()[0].Parameter)
 	if haveSign == needSign {
@@ -181,6 +229,15 @@ func SendTransaction(password []byte, context *cli.Context, wallet *client.Walle
 		if err != nil {
 			return err
 		}
+	} else if isUnsignedTx(*content) {
+		unsignedTx, err := client.ParseUnsignedTx([]byte(*content))
+		if err != nil {
+			return err
+		}
+		tx, err = unsignedTx.GetTransaction()
+		if err != nil {
+			return err
+		}
 	} else {
 		data, err := common.HexStringToBytes(*content)
 		if err != nil {
@@ -263,6 +320,40 @@ func getTransaction(context *cli.Context) (it.Transaction, error) {
 	return txn, nil
 }
 
+// isUnsignedTx returns if the content is a portable unsigned transaction file
+// in JSON format.
+func isUnsignedTx(content string) bool {
+	return strings.HasPrefix(content, "{")
+}
+
+// outputUnsignedTx writes the portable unsigned transaction file, named by
+// the sign status of the transaction.
+func outputUnsignedTx(unsignedTx *client.UnsignedTx, tx it.Transaction) error {
+	content, err := unsignedTx.Json()
+	if err != nil {
+		return err
+	}
+
+	haveSign, needSign, _ := crypto.GetSignStatus(tx.Programs()[0].Code, tx.Programs()[0].Parameter)
+
+	fileName := "to_be_signed"
+	if needSign > haveSign {
+		fileName = fmt.Sprint(fileName, "_", haveSign, "_of_", needSign)
+	} else if needSign == haveSign {
+		fileName = "ready_to_send"
+	}
+	fileName = fileName + ".json"
+
+	err = ioutil.WriteFile(fileName, content, 0600)
+	if err != nil {
+		return err
+	}
+
+	fmt.Println("[", haveSign, "/", needSign, "] Unsigned transaction file:", fileName)
+
+	return nil
+}
+
 func output(tx it.Transaction) error {
 	// Serialise transaction content
 	buf := new(bytes.Buffer)
@@ -307,6 +398,18 @@ func transactionAction(context *cli.Context) {
 	}
 	pass := context.String("password")
 
+	// sign the portable unsigned transaction file on the offline machine,
+	// wallet database is not opened.
+	if context.Bool("sign") && !context.Bool("create") && !context.Bool("send") {
+		if content, err := getContent(context); err == nil && isUnsignedTx(*content) {
+			if err := SignUnsignedTx([]byte(pass), context); err != nil {
+				fmt.Println("error:", err)
+				cli.ShowCommandHelpAndExit(context, "sign", 702)
+			}
+			return
+		}
+	}
+
 	wallet, err := client.Open()
 	if err != nil {
 		fmt.Println("error: open wallet failed,", err)
@@ -366,7 +469,8 @@ func NewCommand() cli.Command {
 				Name: "from",
 				Usage: "the spend address of the transaction\n" +
 					"\tby default this argument is not necessary and the from address will be set to the main account address\n" +
-					"\tif you have added mulitsig account, this argument can be used to specify the multisig address you want to use",
+					"\tif you have added mulitsig account, this argument can be used to specify the multisig address you want to use\n" +
+					"\tor the watch-only address to create a transaction to be signed elsewhere",
 			},
 			cli.StringFlag{
 				Name:  "to",
@@ -384,6 +488,11 @@ func NewCommand() cli.Command {
 				Name:  "lock",
 				Usage: "the lock time to specify when the received asset can be spent",
 			},
+			cli.BoolFlag{
+				Name: "offline",
+				Usage: "use with --create to create a portable unsigned transaction file with the referenced UTXOs\n" +
+					"\tthe file can be signed with --sign by a keystore-only wallet on an offline machine",
+			},
 			cli.StringFlag{
 				Name:  "hex",
 				Usage: "the transaction content in hex string format to be signed or sent",
@@ -61,6 +61,24 @@ func Create(password []byte) error {
 		mainAccount.RedeemScript(), sutil.TypeMaster)
 }
 
+// CreateFromMnemonic creates a HD wallet from the BIP39 mnemonic and
+// passphrase, it's used to create a new HD wallet or restore one.
+func CreateFromMnemonic(password []byte, mnemonic, passphrase string) error {
+	keyStore, err := CreateKeystoreFromMnemonic(password, mnemonic, passphrase)
+	if err != nil {
+		return err
+	}
+
+	db, err := database.New(dataPath)
+	if err != nil {
+		return err
+	}
+
+	mainAccount := keyStore.GetAccountByIndex(0)
+	return db.AddAddress(mainAccount.ProgramHash(),
+		mainAccount.RedeemScript(), sutil.TypeMaster)
+}
+
 func Open() (*Wallet, error) {
 	db, err := database.New(dataPath)
 	if err != nil {
@@ -92,6 +110,10 @@ func (wallet *Wallet) NewSubAccount(password []byte) (*common.Uint168, error) {
 	if err != nil {
 		return nil, err
 	}
+	err = wallet.SetAddressIndex(account.ProgramHash(), wallet.SubAccountsCount, false)
+	if err != nil {
+		return nil, err
+	}
 
 	// Notify SPV service to reload bloom filter with the new address
 	jsonrpc.CallArray(jsonRpcUrl, "notifynewaddress", account.ProgramHash().String())
@@ -118,6 +140,52 @@ func (wallet *Wallet) AddMultiSignAccount(m int, publicKeys ...*crypto.PublicKey
 	return programHash, nil
 }
 
+// AddWatchAccount adds a watch-only account of the public key, the account is
+// tracked by the SPV service and it's transactions can be created here to be
+// signed elsewhere.
+func (wallet *Wallet) AddWatchAccount(publicKey *crypto.PublicKey) (*common.Uint168, error) {
+	contract, err := contract.CreateStandardContract(publicKey)
+	if err != nil {
+		return nil, errors.New("[Wallet], CreateStandardContract failed")
+	}
+
+	programHash := contract.ToProgramHash()
+	err = wallet.addWatchAddress(programHash, contract.Code)
+	if err != nil {
+		return nil, err
+	}
+	return programHash, nil
+}
+
+// AddWatchAddress adds a watch-only address without public key, the address is
+// tracked by the SPV service for balance and history only, transactions can
+// not be created without it's redeem script.
+func (wallet *Wallet) AddWatchAddress(programHash *common.Uint168) error {
+	return wallet.addWatchAddress(programHash, nil)
+}
+
+func (wallet *Wallet) addWatchAddress(programHash *common.Uint168, script []byte) error {
+	// Watch-only address can not replace the accounts in this wallet, but
+	// can be upgraded with the public key.
+	addr, err := wallet.GetAddress(programHash)
+	if err == nil && addr.Type() != sutil.TypeWatch {
+		return errors.New("[Wallet], Address already exists in wallet")
+	}
+	if err == nil && len(script) == 0 {
+		script = addr.Script()
+	}
+
+	err = wallet.AddAddress(programHash, script, sutil.TypeWatch)
+	if err != nil {
+		return err
+	}
+
+	// Notify SPV service to reload bloom filter with the new address
+	jsonrpc.CallArray(jsonRpcUrl, "notifynewaddress", programHash.String())
+
+	return nil
+}
+
 func (wallet *Wallet) CreateTransaction(fromAddress, toAddress string, amount,
 	fee *common.Fixed64) (it.Transaction, error) {
 	return wallet.CreateLockedTransaction(fromAddress, toAddress, amount, fee, uint32(0))
@@ -207,6 +275,9 @@ func (wallet *Wallet) createTransaction(fromAddress string, fee *common.Fixed64,
 	if err != nil {
 		return nil, errors.New("[Wallet], Get spenders redeem script failed")
 	}
+	if len(addr.Script()) == 0 {
+		return nil, errors.New("[Wallet], Watch-only address without public key can not create transaction")
+	}
 
 	return wallet.newTransaction(addr.Script(), txInputs, txOutputs), nil
 }
@@ -1,32 +1,113 @@
 package wallet
 
 import (
+	"bufio"
 	"fmt"
+	"os"
+	"strings"
 
 	"github.com/elastos/Elastos.ELA.SPV/wallet/client"
+	"github.com/elastos/Elastos.ELA.SPV/wallet/client/hd"
 
 	"github.com/urfave/cli"
 )
 
 func createWallet(context *cli.Context) {
-	password := []byte(context.String("password"))
+	bits, err := hd.EntropySize(context.Int("words"))
+	if context.Bool("mnemonic") && err != nil {
+		fmt.Println("--INVALID MNEMONIC WORDS COUNT--")
+		return
+	}
 
-	var err error
+	password := []byte(context.String("password"))
 	password, err = client.GetPassword(password, true)
 	if err != nil {
 		fmt.Println("--GET PASSWORD FAILED--")
 		return
 	}
 
-	err = client.Create(password)
+	if context.Bool("mnemonic") {
+		passphrase, err := getPassphrase(context, true)
+		if err != nil {
+			fmt.Println("--GET PASSPHRASE FAILED--")
+			return
+		}
+		entropy, err := hd.NewEntropy(bits)
+		if err != nil {
+			fmt.Println("--CREATE MNEMONIC FAILED--")
+			return
+		}
+		mnemonic, err := hd.NewMnemonic(entropy)
+		if err != nil {
+			fmt.Println("--CREATE MNEMONIC FAILED--")
+			return
+		}
+
+		err = client.CreateFromMnemonic(password, mnemonic, passphrase)
+		if err != nil {
+			fmt.Println("--CREATE WALLET FAILED--")
+			return
+		}
+
+		fmt.Println("--PLEASE WRITE DOWN THE MNEMONIC AND KEEP IT SAFE--")
+		fmt.Println(mnemonic)
+	} else {
+		err = client.Create(password)
+		if err != nil {
+			fmt.Println("--CREATE WALLET FAILED--")
+			return
+		}
+	}
+
+	client.ShowAccountInfo(password)
+}
+
+func restoreWallet(context *cli.Context) {
+	mnemonic := context.String("mnemonic")
+	if len(mnemonic) == 0 {
+		fmt.Print("INPUT MNEMONIC:")
+		input, err := bufio.NewReader(os.Stdin).ReadString('\n')
+		if err != nil {
+			fmt.Println("--GET MNEMONIC FAILED--")
+			return
+		}
+		mnemonic = strings.TrimSpace(input)
+	}
+	if !hd.IsMnemonicValid(mnemonic) {
+		fmt.Println("--INVALID MNEMONIC--")
+		return
+	}
+
+	password, err := client.GetPassword([]byte(context.String("password")), true)
 	if err != nil {
-		fmt.Println("--CREATE WALLET FAILED--")
+		fmt.Println("--GET PASSWORD FAILED--")
+		return
+	}
+
+	passphrase, err := getPassphrase(context, false)
+	if err != nil {
+		fmt.Println("--GET PASSPHRASE FAILED--")
+		return
+	}
+
+	err = client.CreateFromMnemonic(password, mnemonic, passphrase)
+	if err != nil {
+		fmt.Println("--RESTORE WALLET FAILED--")
 		return
 	}
 
 	client.ShowAccountInfo(password)
 }
 
+// getPassphrase returns the --passphrase flag, or inputs the passphrase
+// interactively if the flag is not set.
+func getPassphrase(context *cli.Context, confirmed bool) (string, error) {
+	if context.IsSet("passphrase") {
+		return context.String("passphrase"), nil
+	}
+	return client.GetPassphrase(confirmed)
+}
+
 func changePassword(context *cli.Context) {
 	password := []byte(context.String("password"))
 
@@ -98,9 +179,23 @@ func resetDatabase(context *cli.Context) {
 
 func NewCreateCommand() cli.Command {
 	return cli.Command{
-		Name:   "create",
-		Usage:  "create wallet",
-		Flags:  append(client.CommonFlags),
+		Name:  "create",
+		Usage: "create wallet",
+		Flags: append(client.CommonFlags,
+			cli.BoolFlag{
+				Name:  "mnemonic, m",
+				Usage: "create a HD wallet from a new BIP39 mnemonic",
+			},
+			cli.IntFlag{
+				Name:  "words",
+				Usage: "the mnemonic words count, 12, 15, 18, 21 or 24",
+				Value: 12,
+			},
+			cli.StringFlag{
+				Name:  "passphrase",
+				Usage: "the optional BIP39 passphrase protecting the mnemonic, input it interactively if not set",
+			},
+		),
 		Action: createWallet,
 		OnUsageError: func(c *cli.Context, err error, subCommand bool) error {
 			return cli.NewExitError(err, 1)
@@ -108,6 +203,27 @@ func NewCreateCommand() cli.Command {
 	}
 }
 
+func NewRestoreCommand() cli.Command {
+	return cli.Command{
+		Name:  "restore",
+		Usage: "restore HD wallet from BIP39 mnemonic",
+		Flags: append(client.CommonFlags,
+			cli.StringFlag{
+				Name:  "mnemonic, m",
+				Usage: "the BIP39 mnemonic words separated by space, input it interactively if not set",
+			},
+			cli.StringFlag{
+				Name:  "passphrase",
+				Usage: "the BIP39 passphrase used when creating the wallet, input it interactively if not set",
+			},
+		),
+		Action: restoreWallet,
+		OnUsageError: func(c *cli.Context, err error, subCommand bool) error {
+			return cli.NewExitError(err, 1)
+		},
+	}
+}
+
 func NewChangePasswordCommand() cli.Command {
 	return cli.Command{
 		Name:   "changepassword",
@@ -0,0 +1,143 @@
+package client
+
+import (
+	"testing"
+
+	"github.com/elastos/Elastos.ELA.SPV/wallet/client/database"
+	"github.com/elastos/Elastos.ELA.SPV/wallet/sutil"
+
+	"github.com/elastos/Elastos.ELA/common"
+	"github.com/elastos/Elastos.ELA/core/contract"
+	"github.com/elastos/Elastos.ELA/crypto"
+	"github.com/stretchr/testify/assert"
+)
+
+// newTestWallet creates a wallet with the keystore in the working directory
+// and the main account added into database.
+func newTestWallet(t *testing.T) *Wallet {
+	keystore, err := CreateKeystore(testPassword)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	db, err := database.New(".")
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	main := keystore.MainAccount()
+	err = db.AddAddress(main.ProgramHash(), main.RedeemScript(), sutil.TypeMaster)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	return &Wallet{Database: db, Keystore: keystore}
+}
+
+func TestWallet_AddWatchAccount(t *testing.T) {
+	defer inTempDir(t)()
+	wallet := newTestWallet(t)
+	defer wallet.Close()
+
+	// Watch-only address without public key.
+	account := newTestAccount(t)
+	programHash := account.ProgramHash()
+	assert.NoError(t, wallet.AddWatchAddress(programHash))
+	addr, err := wallet.GetAddress(programHash)
+	if assert.NoError(t, err) {
+		assert.Equal(t, sutil.TypeWatch, addr.Type())
+		assert.Equal(t, 0, len(addr.Script()))
+	}
+
+	// Upgraded with the public key.
+	watched, err := wallet.AddWatchAccount(account.PublicKey())
+	if assert.NoError(t, err) {
+		assert.Equal(t, *programHash, *watched)
+	}
+	addr, err = wallet.GetAddress(programHash)
+	if assert.NoError(t, err) {
+		assert.Equal(t, sutil.TypeWatch, addr.Type())
+		assert.Equal(t, account.RedeemScript(), addr.Script())
+	}
+
+	// Adding the address again keeps the redeem script.
+	assert.NoError(t, wallet.AddWatchAddress(programHash))
+	addr, err = wallet.GetAddress(programHash)
+	if assert.NoError(t, err) {
+		assert.Equal(t, sutil.TypeWatch, addr.Type())
+		assert.Equal(t, account.RedeemScript(), addr.Script())
+	}
+	addrs, err := wallet.GetAddrs()
+	if assert.NoError(t, err) {
+		assert.Equal(t, 2, len(addrs))
+	}
+}
+
+func TestWallet_AddWatchAccountDuplicate(t *testing.T) {
+	defer inTempDir(t)()
+	wallet := newTestWallet(t)
+	defer wallet.Close()
+
+	// Accounts in this wallet can not be replaced by watch-only accounts.
+	main := wallet.Keystore.MainAccount()
+	assert.Error(t, wallet.AddWatchAddress(main.ProgramHash()))
+	_, err := wallet.AddWatchAccount(main.PublicKey())
+	assert.Error(t, err)
+
+	sub, err := wallet.NewSubAccount(testPassword)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	assert.Error(t, wallet.AddWatchAddress(sub))
+
+	for _, programHash := range []*common.Uint168{main.ProgramHash(), sub} {
+		addr, err := wallet.GetAddress(programHash)
+		if assert.NoError(t, err) {
+			assert.NotEqual(t, sutil.TypeWatch, addr.Type())
+		}
+	}
+}
+
+func TestWallet_SignWatchAccount(t *testing.T) {
+	defer inTempDir(t)()
+	wallet := newTestWallet(t)
+	defer wallet.Close()
+
+	account := newTestAccount(t)
+	programHash, err := wallet.AddWatchAccount(account.PublicKey())
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+
+	// The watch-only account has no private key in this wallet.
+	unsignedTx := newUnsignedTx(t, programHash, account.RedeemScript(), 10, 9)
+	txn, err := unsignedTx.GetTransaction()
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	_, err = wallet.Sign(testPassword, txn)
+	assert.Error(t, err)
+	assert.Equal(t, 0, len(txn.Programs()[0].Parameter))
+
+	partialTx, err := NewPartialTx(unsignedTx)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	_, err = wallet.SignPartialTx(testPassword, partialTx)
+	assert.Error(t, err)
+	assert.Equal(t, 1, partialTx.Programs[0].Missing())
+
+	// Multi-sign account of the watch-only public key is signed by the
+	// account in this wallet only.
+	multiSig, err := contract.CreateMultiSigContract(2, []*crypto.PublicKey{
+		account.PublicKey(), wallet.Keystore.MainAccount().PublicKey()})
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	partialTx, err = NewPartialTx(newUnsignedTx(t, multiSig.ToProgramHash(),
+		multiSig.Code, 10, 9))
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	count, err := wallet.SignPartialTx(testPassword, partialTx)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, count)
+	assert.Equal(t, 1, partialTx.Programs[0].Missing())
+}
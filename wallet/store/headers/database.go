@@ -1,7 +1,10 @@
 package headers
 
 import (
+	"bytes"
+	"encoding/binary"
 	"encoding/hex"
+	"errors"
 	"fmt"
 	"path/filepath"
 	"sync"
@@ -12,10 +15,11 @@ import (
 
 	"github.com/elastos/Elastos.ELA/common"
 	"github.com/syndtr/goleveldb/leveldb"
+	"github.com/syndtr/goleveldb/leveldb/opt"
 )
 
 // Ensure Database implement headers interface
-var _ database.Headers = (*Database)(nil)
+var _ database.ChainHeaders = (*Database)(nil)
 
 // Headers implements Headers using bolt DB
 type Database struct {
@@ -28,6 +32,7 @@ type Database struct {
 var (
 	BKTHeaders  = []byte("H")
 	BKTChainTip = []byte("B")
+	BKTIndexes  = []byte("I")
 )
 
 func NewDatabase(dataDir string) (*Database, error) {
@@ -45,6 +50,13 @@ func NewDatabase(dataDir string) (*Database, error) {
 
 	headers.initCache()
 
+	// Index headers stored before height index introduced.
+	if best, err := headers.GetBest(); err == nil {
+		if err := headers.putIndexes(best); err != nil {
+			return nil, err
+		}
+	}
+
 	return headers, nil
 }
 
@@ -92,10 +104,48 @@ func (d *Database) Put(header *util.Header, newTip bool) error {
 		if err != nil {
 			return err
 		}
+		return d.putIndexes(header)
 	}
 	return nil
 }
 
+// putIndexes indexes the new tip and it's previous headers by height, until
+// the height already indexed with the same header. Fork headers replaced by
+// the new best chain will be re-indexed.
+func (d *Database) putIndexes(tip *util.Header) error {
+	batch := new(leveldb.Batch)
+
+	// Remove indexes above the new tip if the best chain becomes shorter.
+	for height := tip.Height + 1; ; height++ {
+		key := indexKey(height)
+		if ok, err := d.db.Has(key, nil); err != nil || !ok {
+			break
+		}
+		batch.Delete(key)
+	}
+
+	header := tip
+	for {
+		hash := header.Hash()
+		key := indexKey(header.Height)
+		indexed, err := d.db.Get(key, nil)
+		if err == nil && bytes.Equal(indexed, hash.Bytes()) {
+			break
+		}
+		batch.Put(key, hash.Bytes())
+
+		if header.Height == 0 {
+			break
+		}
+		previous := header.Previous()
+		header, err = d.get(&previous, nil)
+		if err != nil {
+			break
+		}
+	}
+	return d.db.Write(batch, nil)
+}
+
 func (d *Database) GetPrevious(header *util.Header) (*util.Header, error) {
 	hash := header.Previous()
 	return d.Get(&hash)
@@ -105,12 +155,7 @@ func (d *Database) Get(hash *common.Uint256) (header *util.Header, err error) {
 	d.RLock()
 	defer d.RUnlock()
 
-	header, err = d.cache.get(hash)
-	if err == nil {
-		return header, nil
-	}
-
-	return d.getHeader(toKey(BKTHeaders, hash.Bytes()...))
+	return d.get(hash, nil)
 }
 
 func (d *Database) GetBest() (header *util.Header, err error) {
@@ -121,7 +166,85 @@ func (d *Database) GetBest() (header *util.Header, err error) {
 		return d.cache.tip, nil
 	}
 
-	return d.getHeader(BKTChainTip)
+	return d.getHeader(BKTChainTip, nil)
+}
+
+func (d *Database) GetByHeight(height uint32) (*util.Header, error) {
+	d.RLock()
+	defer d.RUnlock()
+	return d.getByHeight(height, nil)
+}
+
+// GetRange returns headers on the best chain from start to end height, both
+// inclusive.
+func (d *Database) GetRange(start, end uint32) ([]*util.Header, error) {
+	return database.GetHeaderRange(d.Iterator(start, end))
+}
+
+// Iterator returns an iterator walks the best chain from start to end height,
+// both inclusive. Headers read by the iterator will not fill the caches, so
+// a long scan will not evict the recent headers.
+func (d *Database) Iterator(start, end uint32) database.HeaderIterator {
+	return database.NewHeaderIterator(func(height uint32) (*util.Header, error) {
+		d.RLock()
+		defer d.RUnlock()
+		return d.getByHeight(height, &opt.ReadOptions{DontFillCache: true})
+	}, start, end)
+}
+
+// GetByTime returns the first header on the best chain with timestamp at or
+// after the given unix timestamp.
+func (d *Database) GetByTime(timestamp uint32) (*util.Header, error) {
+	best, err := d.GetBest()
+	if err != nil {
+		return nil, err
+	}
+	return database.SearchHeaderByTime(d.GetByHeight, headerTime,
+		best.Height, timestamp)
+}
+
+// MedianTimePast returns the median timestamp of the last blocks ended with
+// the given height.
+func (d *Database) MedianTimePast(height uint32) (uint32, error) {
+	return database.CalcMedianTimePast(d.GetByHeight, headerTime, height)
+}
+
+// Rollback removes the best chain headers above height and makes the header on
+// height the chain tip, returns the previous chain tip height.  Headers are
+// removed so the blocks will be downloaded again.
+func (d *Database) Rollback(height uint32) (uint32, error) {
+	d.Lock()
+	defer d.Unlock()
+
+	tip, err := d.getByHeight(height, nil)
+	if err != nil {
+		return 0, err
+	}
+	data, err := tip.Serialize()
+	if err != nil {
+		return 0, err
+	}
+
+	best := height
+	batch := new(leveldb.Batch)
+	for {
+		key := indexKey(best + 1)
+		hash, err := d.db.Get(key, nil)
+		if err != nil {
+			break
+		}
+		batch.Delete(toKey(BKTHeaders, hash...))
+		batch.Delete(key)
+		best++
+	}
+	batch.Put(BKTChainTip, data)
+	if err := d.db.Write(batch, nil); err != nil {
+		return 0, err
+	}
+
+	d.cache = newCache(100)
+	d.cache.tip = tip
+	return best, nil
 }
 
 func (d *Database) Clear() error {
@@ -134,6 +257,7 @@ func (d *Database) Clear() error {
 		batch.Delete(inter.Key())
 	}
 	inter.Release()
+	d.cache = newCache(100)
 	return d.db.Write(batch, nil)
 }
 
@@ -144,8 +268,29 @@ func (d *Database) Close() error {
 	return d.db.Close()
 }
 
-func (d *Database) getHeader(key []byte) (*util.Header, error) {
-	data, err := d.db.Get(key, nil)
+func (d *Database) get(hash *common.Uint256, ro *opt.ReadOptions) (*util.Header, error) {
+	header, err := d.cache.get(hash)
+	if err == nil {
+		return header, nil
+	}
+
+	return d.getHeader(toKey(BKTHeaders, hash.Bytes()...), ro)
+}
+
+func (d *Database) getByHeight(height uint32, ro *opt.ReadOptions) (*util.Header, error) {
+	hashBytes, err := d.db.Get(indexKey(height), ro)
+	if err != nil {
+		return nil, err
+	}
+	hash, err := common.Uint256FromBytes(hashBytes)
+	if err != nil {
+		return nil, err
+	}
+	return d.get(hash, ro)
+}
+
+func (d *Database) getHeader(key []byte, ro *opt.ReadOptions) (*util.Header, error) {
+	data, err := d.db.Get(key, ro)
 	if err != nil {
 		return nil, fmt.Errorf("header %s does not exist in database",
 			hex.EncodeToString(key))
@@ -161,6 +306,22 @@ func (d *Database) getHeader(key []byte) (*util.Header, error) {
 	return &header, nil
 }
 
+// indexKey returns the key of the height index.
+func indexKey(height uint32) []byte {
+	var key [4]byte
+	binary.BigEndian.PutUint32(key[:], height)
+	return append(append([]byte{}, BKTIndexes...), key[:]...)
+}
+
+// headerTime returns the timestamp of the main chain header.
+func headerTime(header *util.Header) (uint32, error) {
+	h, ok := header.BlockHeader.(*sutil.Header)
+	if !ok {
+		return 0, errors.New("invalid block header")
+	}
+	return h.Timestamp, nil
+}
+
 func toKey(bucket []byte, index ...byte) []byte {
 	return append(bucket, index...)
 }
@@ -0,0 +1,124 @@
+package headers
+
+import (
+	"math/big"
+	"os"
+	"testing"
+
+	"github.com/elastos/Elastos.ELA.SPV/database/dbtest"
+	"github.com/elastos/Elastos.ELA.SPV/util"
+	"github.com/elastos/Elastos.ELA.SPV/wallet/sutil"
+
+	"github.com/elastos/Elastos.ELA/common"
+	types "github.com/elastos/Elastos.ELA/core/types/common"
+	"github.com/stretchr/testify/assert"
+)
+
+func newHeader(previous *util.Header, timestamp, nonce uint32) *util.Header {
+	var height uint32
+	var previousHash common.Uint256
+	if previous != nil {
+		height = previous.Height + 1
+		previousHash = previous.Hash()
+	}
+	return &util.Header{
+		BlockHeader: sutil.NewHeader(&types.Header{
+			Previous:  previousHash,
+			Timestamp: timestamp,
+			Height:    height,
+			Nonce:     nonce,
+		}),
+		Height:    height,
+		TotalWork: new(big.Int),
+	}
+}
+
+func TestConformance(t *testing.T) {
+	dataDir := "spv_test"
+	os.RemoveAll(dataDir)
+	defer os.RemoveAll(dataDir)
+
+	headers, err := NewDatabase(dataDir)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	defer headers.Close()
+	dbtest.TestChainHeaders(t, headers, newHeader)
+}
+
+func TestDatabase_Clear(t *testing.T) {
+	dataDir := "spv_test"
+	os.RemoveAll(dataDir)
+	defer os.RemoveAll(dataDir)
+
+	headers, err := NewDatabase(dataDir)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	defer headers.Close()
+
+	header := &util.Header{
+		BlockHeader: sutil.NewHeader(&types.Header{Timestamp: 1000}),
+		TotalWork:   new(big.Int),
+	}
+	assert.NoError(t, headers.Put(header, true))
+	assert.NoError(t, headers.Clear())
+
+	// cached headers are removed with the database.
+	_, err = headers.GetBest()
+	assert.Error(t, err)
+	hash := header.Hash()
+	_, err = headers.Get(&hash)
+	assert.Error(t, err)
+}
+
+func TestRollback(t *testing.T) {
+	dataDir := "spv_test_rollback"
+	os.RemoveAll(dataDir)
+	defer os.RemoveAll(dataDir)
+
+	headers, err := NewDatabase(dataDir)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	defer headers.Close()
+
+	var chain []*util.Header
+	var previous *util.Header
+	for i := uint32(0); i < 10; i++ {
+		header := newHeader(previous, 1000+i, 0)
+		assert.NoError(t, headers.Put(header, true))
+		chain = append(chain, header)
+		previous = header
+	}
+
+	best, err := headers.Rollback(5)
+	assert.NoError(t, err)
+	assert.Equal(t, uint32(9), best)
+
+	tip, err := headers.GetBest()
+	if assert.NoError(t, err) {
+		assert.Equal(t, chain[5].Hash(), tip.Hash())
+	}
+	for _, header := range chain[6:] {
+		hash := header.Hash()
+		_, err = headers.Get(&hash)
+		assert.Error(t, err)
+		_, err = headers.GetByHeight(header.Height)
+		assert.Error(t, err)
+	}
+	header, err := headers.GetByHeight(5)
+	if assert.NoError(t, err) {
+		assert.Equal(t, chain[5].Hash(), header.Hash())
+	}
+
+	// the removed headers can be put again.
+	assert.NoError(t, headers.Put(chain[6], true))
+	header, err = headers.GetByHeight(6)
+	if assert.NoError(t, err) {
+		assert.Equal(t, chain[6].Hash(), header.Hash())
+	}
+
+	_, err = headers.Rollback(100)
+	assert.Error(t, err)
+}
@@ -15,9 +15,21 @@ var _ Addrs = (*addrs)(nil)
 const CreateAddrsDB = `CREATE TABLE IF NOT EXISTS Addrs(
 				Hash BLOB NOT NULL PRIMARY KEY,
 				Script BLOB,
-				Type INTEGER NOT NULL
+				Type INTEGER NOT NULL,
+				KeyIndex INTEGER NOT NULL DEFAULT -1,
+				Used INTEGER NOT NULL DEFAULT 0
 			);`
 
+// addrsColumns are the columns added after the Addrs table introduced, they
+// are added to the tables created by previous versions.
+var addrsColumns = []struct {
+	name       string
+	definition string
+}{
+	{"KeyIndex", "INTEGER NOT NULL DEFAULT -1"},
+	{"Used", "INTEGER NOT NULL DEFAULT 0"},
+}
+
 type addrs struct {
 	*sync.RWMutex
 	*sql.DB
@@ -28,9 +40,48 @@ func NewAddrs(db *sql.DB, lock *sync.RWMutex) (*addrs, error) {
 	if err != nil {
 		return nil, err
 	}
+	if err := addAddrsColumns(db); err != nil {
+		return nil, err
+	}
 	return &addrs{RWMutex: lock, DB: db}, nil
 }
 
+// addAddrsColumns adds the columns missing in the Addrs table.
+func addAddrsColumns(db *sql.DB) error {
+	rows, err := db.Query("PRAGMA table_info(Addrs)")
+	if err != nil {
+		return err
+	}
+	columns := make(map[string]bool)
+	for rows.Next() {
+		var cid, notNull, pk int
+		var name, columnType string
+		var defaultValue interface{}
+		err := rows.Scan(&cid, &name, &columnType, &notNull, &defaultValue, &pk)
+		if err != nil {
+			rows.Close()
+			return err
+		}
+		columns[name] = true
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for _, column := range addrsColumns {
+		if columns[column.name] {
+			continue
+		}
+		_, err := db.Exec("ALTER TABLE Addrs ADD COLUMN " + column.name +
+			" " + column.definition)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 // put a script to database
 func (a *addrs) Put(hash *common.Uint168, script []byte, addrType int) error {
 	a.Lock()
@@ -87,6 +138,51 @@ func (a *addrs) GetAll() ([]*sutil.Addr, error) {
 	return addrs, nil
 }
 
+// PutIndex saves the key derivation index of the address and whether the
+// address has been used.
+func (a *addrs) PutIndex(hash *common.Uint168, index int, used bool) error {
+	a.Lock()
+	defer a.Unlock()
+
+	result, err := a.Exec("UPDATE Addrs SET KeyIndex=?, Used=? WHERE Hash=?",
+		index, used, hash.Bytes())
+	if err != nil {
+		return err
+	}
+	count, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if count == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// GetUsedIndexes returns the key derivation indexes of the used addresses in
+// ascending order.
+func (a *addrs) GetUsedIndexes() ([]int, error) {
+	a.RLock()
+	defer a.RUnlock()
+
+	rows, err := a.Query(`SELECT KeyIndex FROM Addrs WHERE Used=1 AND KeyIndex>=0
+							ORDER BY KeyIndex`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var indexes []int
+	for rows.Next() {
+		var index int
+		if err := rows.Scan(&index); err != nil {
+			return nil, err
+		}
+		indexes = append(indexes, index)
+	}
+	return indexes, rows.Err()
+}
+
 // delete a script from database
 func (a *addrs) Del(hash *common.Uint168) error {
 	a.Lock()
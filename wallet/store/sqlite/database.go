@@ -11,6 +11,9 @@ import (
 
 const (
 	DriverName = "sqlite3"
+
+	// DBName is the file name of the wallet database in the data directory.
+	DBName = "wallet.db"
 )
 
 // Ensure database implement DataStore interface
@@ -28,7 +31,7 @@ type database struct {
 }
 
 func NewDatabase(dataDir string) (*database, error) {
-	db, err := sql.Open(DriverName, filepath.Join(dataDir, "wallet.db"))
+	db, err := sql.Open(DriverName, filepath.Join(dataDir, DBName))
 	if err != nil {
 		fmt.Println("Open sqlite db error:", err)
 		return nil, err
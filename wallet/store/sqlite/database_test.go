@@ -0,0 +1,180 @@
+package sqlite
+
+import (
+	"database/sql"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/elastos/Elastos.ELA.SPV/util"
+	"github.com/elastos/Elastos.ELA.SPV/wallet/sutil"
+
+	"github.com/elastos/Elastos.ELA/common"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConformance(t *testing.T) {
+	dataDir := "spv_test"
+
+	t.Run("sqlite", func(t *testing.T) {
+		os.RemoveAll(dataDir)
+		os.MkdirAll(dataDir, os.ModePerm)
+		defer os.RemoveAll(dataDir)
+
+		db, err := NewDatabase(dataDir)
+		if !assert.NoError(t, err) {
+			t.FailNow()
+		}
+		defer db.Close()
+		testDataStore(t, db)
+	})
+
+	t.Run("memory", func(t *testing.T) {
+		db := NewMemoryDatabase()
+		defer db.Close()
+		testDataStore(t, db)
+	})
+}
+
+// testDataStore checks the batch commit, rollback and rollback by height
+// semantics of the data store.
+func testDataStore(t *testing.T, db DataStore) {
+	addr := common.Uint168{1}
+	tx1 := &util.Tx{Hash: common.Uint256{1}, Height: 100,
+		Timestamp: time.Unix(1000, 0), RawData: []byte{1}}
+	tx2 := &util.Tx{Hash: common.Uint256{2}, Height: 101,
+		Timestamp: time.Unix(1010, 0), RawData: []byte{2}}
+	utxo1 := sutil.NewUTXO(tx1.Hash, 100, 0, 10, 0, addr)
+	utxo2 := sutil.NewUTXO(tx2.Hash, 101, 0, 9, 0, addr)
+
+	db.State().PutHeight(99)
+	assert.Equal(t, uint32(99), db.State().GetHeight())
+	assert.NoError(t, db.Addrs().Put(&addr, []byte{0xac}, 0))
+	a, err := db.Addrs().Get(&addr)
+	assert.NoError(t, err)
+	assert.Equal(t, []byte{0xac}, a.Script())
+
+	// changes in a rolled back batch are discarded.
+	batch := db.Batch()
+	assert.NoError(t, batch.Txs().Put(tx1))
+	assert.NoError(t, batch.Rollback())
+	_, err = db.Txs().Get(&tx1.Hash)
+	assert.Error(t, err)
+
+	// block 100 receives utxo1.
+	batch = db.Batch()
+	assert.NoError(t, batch.Txs().Put(tx1))
+	assert.NoError(t, batch.UTXOs().Put(utxo1))
+	if !assert.NoError(t, batch.Commit()) {
+		t.FailNow()
+	}
+	tx, err := db.Txs().Get(&tx1.Hash)
+	assert.NoError(t, err)
+	assert.Equal(t, tx1.RawData, tx.RawData)
+	assert.Equal(t, tx1.Timestamp.Unix(), tx.Timestamp.Unix())
+
+	// block 101 spends utxo1 and receives utxo2.
+	batch = db.Batch()
+	assert.NoError(t, batch.Txs().Put(tx2))
+	assert.NoError(t, batch.UTXOs().Del(utxo1.Op))
+	assert.NoError(t, batch.STXOs().Put(sutil.NewSTXO(utxo1, 101, tx2.Hash)))
+	assert.NoError(t, batch.UTXOs().Put(utxo2))
+	assert.NoError(t, batch.Commit())
+
+	utxos, err := db.UTXOs().GetAddrAll(&addr)
+	assert.NoError(t, err)
+	if assert.Equal(t, 1, len(utxos)) {
+		assert.Equal(t, *utxo2.Op, *utxos[0].Op)
+		assert.Equal(t, utxo2.Value, utxos[0].Value)
+	}
+	stxo, err := db.STXOs().Get(utxo1.Op)
+	assert.NoError(t, err)
+	assert.Equal(t, tx2.Hash, stxo.SpendTxId)
+	assert.Equal(t, uint32(101), stxo.SpendHeight)
+
+	// rollback block 101.
+	batch = db.Batch()
+	assert.NoError(t, batch.RollbackHeight(101))
+	assert.NoError(t, batch.Commit())
+
+	_, err = db.Txs().Get(&tx2.Hash)
+	assert.Error(t, err)
+	_, err = db.STXOs().Get(utxo1.Op)
+	assert.Error(t, err)
+	utxos, err = db.UTXOs().GetAll()
+	assert.NoError(t, err)
+	if assert.Equal(t, 1, len(utxos)) {
+		assert.Equal(t, *utxo1.Op, *utxos[0].Op)
+	}
+
+	// fork transactions.
+	forkHash := common.Uint256{3}
+	assert.NoError(t, db.Txs().PutForkTxs([]*util.Tx{tx2}, &forkHash))
+	txs, err := db.Txs().GetForkTxs(&forkHash)
+	assert.NoError(t, err)
+	if assert.Equal(t, 1, len(txs)) {
+		assert.Equal(t, tx2.Hash, txs[0].Hash)
+	}
+
+	// key derivation indexes.
+	sub1, sub2 := common.Uint168{2}, common.Uint168{3}
+	assert.NoError(t, db.Addrs().Put(&sub1, []byte{0xac}, sutil.TypeSub))
+	assert.NoError(t, db.Addrs().Put(&sub2, []byte{0xac}, sutil.TypeSub))
+	assert.NoError(t, db.Addrs().PutIndex(&sub2, 2, true))
+	assert.NoError(t, db.Addrs().PutIndex(&sub1, 1, false))
+	assert.Error(t, db.Addrs().PutIndex(&common.Uint168{4}, 3, true))
+	indexes, err := db.Addrs().GetUsedIndexes()
+	assert.NoError(t, err)
+	assert.Equal(t, []int{2}, indexes)
+	assert.NoError(t, db.Addrs().PutIndex(&sub1, 1, true))
+	indexes, err = db.Addrs().GetUsedIndexes()
+	assert.NoError(t, err)
+	assert.Equal(t, []int{1, 2}, indexes)
+	assert.NoError(t, db.Addrs().Put(&sub1, []byte{0xac}, sutil.TypeSub))
+	assert.NoError(t, db.Addrs().Del(&sub2))
+	indexes, err = db.Addrs().GetUsedIndexes()
+	assert.NoError(t, err)
+	assert.Equal(t, 0, len(indexes))
+
+	assert.NoError(t, db.Addrs().Del(&addr))
+	_, err = db.Addrs().Get(&addr)
+	assert.Error(t, err)
+}
+
+func TestAddAddrsColumns(t *testing.T) {
+	dataDir := "spv_test_columns"
+	os.RemoveAll(dataDir)
+	os.MkdirAll(dataDir, os.ModePerm)
+	defer os.RemoveAll(dataDir)
+
+	// Addrs table created by previous versions.
+	addr := common.Uint168{1}
+	db, err := sql.Open(DriverName, filepath.Join(dataDir, "wallet.db"))
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	_, err = db.Exec(`CREATE TABLE Addrs(
+				Hash BLOB NOT NULL PRIMARY KEY,
+				Script BLOB,
+				Type INTEGER NOT NULL
+			);`)
+	assert.NoError(t, err)
+	_, err = db.Exec("INSERT INTO Addrs(Hash, Script, Type) VALUES(?,?,?)",
+		addr.Bytes(), []byte{0xac}, sutil.TypeSub)
+	assert.NoError(t, err)
+	assert.NoError(t, db.Close())
+
+	store, err := NewDatabase(dataDir)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	defer store.Close()
+	indexes, err := store.Addrs().GetUsedIndexes()
+	assert.NoError(t, err)
+	assert.Equal(t, 0, len(indexes))
+	assert.NoError(t, store.Addrs().PutIndex(&addr, 1, true))
+	indexes, err = store.Addrs().GetUsedIndexes()
+	assert.NoError(t, err)
+	assert.Equal(t, []int{1}, indexes)
+}
@@ -75,6 +75,6 @@ func (d *dataBatch) RollbackHeight(height uint32) error {
 	}
 
 	// Rollback TXNs
-	_, err = d.Exec("DELETE FROM TXNs WHERE Height=?", height)
+	_, err = d.Exec("DELETE FROM Txs WHERE Height=?", height)
 	return err
 }
@@ -0,0 +1,47 @@
+package sqlite
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"github.com/elastos/Elastos.ELA.SPV/util"
+
+	"github.com/elastos/Elastos.ELA/common"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDataBatch_RollbackHeight(t *testing.T) {
+	dataDir := "spv_test"
+	os.RemoveAll(dataDir)
+	os.MkdirAll(dataDir, os.ModePerm)
+	defer os.RemoveAll(dataDir)
+
+	db, err := NewDatabase(dataDir)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	defer db.Close()
+
+	tx1 := &util.Tx{Hash: common.Uint256{1}, Height: 100,
+		Timestamp: time.Unix(1000, 0), RawData: []byte{1}}
+	tx2 := &util.Tx{Hash: common.Uint256{2}, Height: 101,
+		Timestamp: time.Unix(1010, 0), RawData: []byte{2}}
+	batch := db.Batch()
+	assert.NoError(t, batch.Txs().Put(tx1))
+	assert.NoError(t, batch.Txs().Put(tx2))
+	if !assert.NoError(t, batch.Commit()) {
+		t.FailNow()
+	}
+
+	// only the transactions on the rolled back height are removed.
+	batch = db.Batch()
+	assert.NoError(t, batch.RollbackHeight(101))
+	if !assert.NoError(t, batch.Commit()) {
+		t.FailNow()
+	}
+	_, err = db.Txs().Get(&tx2.Hash)
+	assert.Error(t, err)
+	_, err = db.Txs().Get(&tx1.Hash)
+	assert.NoError(t, err)
+}
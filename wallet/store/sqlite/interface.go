@@ -53,6 +53,15 @@ type Addrs interface {
 	// delete a address from database
 	Del(hash *common.Uint168) error
 
+	// PutIndex saves the key derivation index of the address and whether the
+	// address has been used, the address must be put before.  The index is
+	// reset when the address put again.
+	PutIndex(hash *common.Uint168, index int, used bool) error
+
+	// GetUsedIndexes returns the key derivation indexes of the used addresses
+	// in ascending order.
+	GetUsedIndexes() ([]int, error)
+
 	// Batch return a AddrsBatch
 	Batch() AddrsBatch
 }
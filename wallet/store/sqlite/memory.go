@@ -0,0 +1,565 @@
+package sqlite
+
+import (
+	"database/sql"
+	"sort"
+	"sync"
+
+	"github.com/elastos/Elastos.ELA.SPV/util"
+	"github.com/elastos/Elastos.ELA.SPV/wallet/sutil"
+
+	"github.com/elastos/Elastos.ELA/common"
+)
+
+// Ensure memoryDatabase implement DataStore interface
+var _ DataStore = (*memoryDatabase)(nil)
+
+// memoryData holds all the wallet data in memory.
+type memoryData struct {
+	height  uint32
+	addrs   map[common.Uint168]*sutil.Addr
+	indexes map[common.Uint168]addrIndex
+	txs     map[common.Uint256]util.Tx
+	forkTxs map[common.Uint256][]util.Tx
+	utxos   map[util.OutPoint]sutil.UTXO
+	stxos   map[util.OutPoint]sutil.STXO
+}
+
+func newMemoryData() *memoryData {
+	return &memoryData{
+		addrs:   make(map[common.Uint168]*sutil.Addr),
+		indexes: make(map[common.Uint168]addrIndex),
+		txs:     make(map[common.Uint256]util.Tx),
+		forkTxs: make(map[common.Uint256][]util.Tx),
+		utxos:   make(map[util.OutPoint]sutil.UTXO),
+		stxos:   make(map[util.OutPoint]sutil.STXO),
+	}
+}
+
+// memoryDatabase is the DataStore implementation keeps all data in memory, it
+// returns the same errors as the sqlite database, sql.ErrNoRows for the data
+// not found.
+type memoryDatabase struct {
+	*sync.RWMutex
+	data *memoryData
+}
+
+// NewMemoryDatabase creates a DataStore keeps all data in memory, data will
+// be lost after the database closed.
+func NewMemoryDatabase() *memoryDatabase {
+	return &memoryDatabase{RWMutex: new(sync.RWMutex), data: newMemoryData()}
+}
+
+func (d *memoryDatabase) State() State {
+	return &memoryState{d}
+}
+
+func (d *memoryDatabase) Addrs() Addrs {
+	return &memoryAddrs{d}
+}
+
+func (d *memoryDatabase) Txs() Txs {
+	return &memoryTxs{d}
+}
+
+func (d *memoryDatabase) UTXOs() UTXOs {
+	return &memoryUTXOs{d}
+}
+
+func (d *memoryDatabase) STXOs() STXOs {
+	return &memorySTXOs{d}
+}
+
+func (d *memoryDatabase) Batch() DataBatch {
+	return &memoryDataBatch{memoryBatch: d.newBatch()}
+}
+
+// Clear delete all data except addresses, same as the sqlite database.
+func (d *memoryDatabase) Clear() error {
+	d.Lock()
+	defer d.Unlock()
+
+	addrs, indexes := d.data.addrs, d.data.indexes
+	d.data = newMemoryData()
+	d.data.addrs, d.data.indexes = addrs, indexes
+	return nil
+}
+
+func (d *memoryDatabase) Close() error {
+	return nil
+}
+
+// update applies the change to data under the write lock.
+func (d *memoryDatabase) update(change func(data *memoryData)) error {
+	d.Lock()
+	defer d.Unlock()
+	change(d.data)
+	return nil
+}
+
+func (d *memoryDatabase) newBatch() *memoryBatch {
+	return &memoryBatch{db: d}
+}
+
+// memoryBatch records the changes and applies them to the database together
+// on commit.
+type memoryBatch struct {
+	sync.Mutex
+	db      *memoryDatabase
+	changes []func(data *memoryData)
+}
+
+func (b *memoryBatch) add(change func(data *memoryData)) error {
+	b.Lock()
+	defer b.Unlock()
+	b.changes = append(b.changes, change)
+	return nil
+}
+
+func (b *memoryBatch) Rollback() error {
+	b.Lock()
+	defer b.Unlock()
+	b.changes = nil
+	return nil
+}
+
+func (b *memoryBatch) Commit() error {
+	b.Lock()
+	defer b.Unlock()
+
+	changes := b.changes
+	b.changes = nil
+	return b.db.update(func(data *memoryData) {
+		for _, change := range changes {
+			change(data)
+		}
+	})
+}
+
+type memoryDataBatch struct {
+	*memoryBatch
+}
+
+func (b *memoryDataBatch) Addrs() AddrsBatch {
+	return &memoryAddrsBatch{b.memoryBatch}
+}
+
+func (b *memoryDataBatch) Txs() TxsBatch {
+	return &memoryTxsBatch{b.memoryBatch}
+}
+
+func (b *memoryDataBatch) UTXOs() UTXOsBatch {
+	return &memoryUTXOsBatch{b.memoryBatch}
+}
+
+func (b *memoryDataBatch) STXOs() STXOsBatch {
+	return &memorySTXOsBatch{b.memoryBatch}
+}
+
+func (b *memoryDataBatch) RollbackHeight(height uint32) error {
+	return b.add(func(data *memoryData) {
+		// Rollback UTXOs
+		for op, utxo := range data.utxos {
+			if utxo.AtHeight == height {
+				delete(data.utxos, op)
+			}
+		}
+
+		// Rollback STXOs, move UTXOs back first, then delete the STXOs
+		for op, stxo := range data.stxos {
+			if stxo.SpendHeight == height {
+				data.utxos[op] = stxo.UTXO
+				delete(data.stxos, op)
+			}
+		}
+
+		// Rollback TXNs
+		for hash, tx := range data.txs {
+			if tx.Height == height {
+				delete(data.txs, hash)
+			}
+		}
+	})
+}
+
+type memoryState struct {
+	db *memoryDatabase
+}
+
+func (s *memoryState) PutHeight(height uint32) {
+	s.db.update(func(data *memoryData) {
+		data.height = height
+	})
+}
+
+func (s *memoryState) GetHeight() uint32 {
+	s.db.RLock()
+	defer s.db.RUnlock()
+	return s.db.data.height
+}
+
+func putAddr(hash *common.Uint168, script []byte, addrType int) func(data *memoryData) {
+	key, script := *hash, append([]byte{}, script...)
+	return func(data *memoryData) {
+		data.addrs[key] = sutil.NewAddr(&key, script, addrType)
+		delete(data.indexes, key)
+	}
+}
+
+func delAddr(hash *common.Uint168) func(data *memoryData) {
+	key := *hash
+	return func(data *memoryData) {
+		delete(data.addrs, key)
+		delete(data.indexes, key)
+	}
+}
+
+// addrIndex is the key derivation index of an address.
+type addrIndex struct {
+	index int
+	used  bool
+}
+
+type memoryAddrs struct {
+	db *memoryDatabase
+}
+
+func (a *memoryAddrs) Put(hash *common.Uint168, script []byte, addrType int) error {
+	return a.db.update(putAddr(hash, script, addrType))
+}
+
+func (a *memoryAddrs) Get(hash *common.Uint168) (*sutil.Addr, error) {
+	a.db.RLock()
+	defer a.db.RUnlock()
+
+	addr, ok := a.db.data.addrs[*hash]
+	if !ok {
+		return nil, sql.ErrNoRows
+	}
+	return addr, nil
+}
+
+func (a *memoryAddrs) GetAll() ([]*sutil.Addr, error) {
+	a.db.RLock()
+	defer a.db.RUnlock()
+
+	var addrs []*sutil.Addr
+	for _, addr := range a.db.data.addrs {
+		addrs = append(addrs, addr)
+	}
+	return addrs, nil
+}
+
+func (a *memoryAddrs) Del(hash *common.Uint168) error {
+	return a.db.update(delAddr(hash))
+}
+
+func (a *memoryAddrs) PutIndex(hash *common.Uint168, index int, used bool) error {
+	a.db.Lock()
+	defer a.db.Unlock()
+
+	if _, ok := a.db.data.addrs[*hash]; !ok {
+		return sql.ErrNoRows
+	}
+	a.db.data.indexes[*hash] = addrIndex{index: index, used: used}
+	return nil
+}
+
+func (a *memoryAddrs) GetUsedIndexes() ([]int, error) {
+	a.db.RLock()
+	defer a.db.RUnlock()
+
+	var indexes []int
+	for _, index := range a.db.data.indexes {
+		if index.used && index.index >= 0 {
+			indexes = append(indexes, index.index)
+		}
+	}
+	sort.Ints(indexes)
+	return indexes, nil
+}
+
+func (a *memoryAddrs) Batch() AddrsBatch {
+	return &memoryAddrsBatch{a.db.newBatch()}
+}
+
+type memoryAddrsBatch struct {
+	*memoryBatch
+}
+
+func (b *memoryAddrsBatch) Put(hash *common.Uint168, script []byte, addrType int) error {
+	return b.add(putAddr(hash, script, addrType))
+}
+
+func (b *memoryAddrsBatch) Del(hash *common.Uint168) error {
+	return b.add(delAddr(hash))
+}
+
+func putTx(tx *util.Tx) func(data *memoryData) {
+	t := *tx
+	return func(data *memoryData) {
+		data.txs[t.Hash] = t
+	}
+}
+
+func delTx(txId *common.Uint256) func(data *memoryData) {
+	key := *txId
+	return func(data *memoryData) {
+		delete(data.txs, key)
+	}
+}
+
+type memoryTxs struct {
+	db *memoryDatabase
+}
+
+func (t *memoryTxs) Put(tx *util.Tx) error {
+	return t.db.update(putTx(tx))
+}
+
+func (t *memoryTxs) Get(txId *common.Uint256) (*util.Tx, error) {
+	t.db.RLock()
+	defer t.db.RUnlock()
+
+	tx, ok := t.db.data.txs[*txId]
+	if !ok {
+		return nil, sql.ErrNoRows
+	}
+	return &tx, nil
+}
+
+func (t *memoryTxs) GetAll() ([]*util.Tx, error) {
+	t.db.RLock()
+	defer t.db.RUnlock()
+
+	var txs []*util.Tx
+	for _, tx := range t.db.data.txs {
+		tx := tx
+		txs = append(txs, &tx)
+	}
+	return txs, nil
+}
+
+func (t *memoryTxs) GetAllFrom(height uint32) ([]*util.Tx, error) {
+	t.db.RLock()
+	defer t.db.RUnlock()
+
+	var txs []*util.Tx
+	for _, tx := range t.db.data.txs {
+		if tx.Height == height {
+			tx := tx
+			txs = append(txs, &tx)
+		}
+	}
+	return txs, nil
+}
+
+func (t *memoryTxs) GetAllUnconfirmed() ([]*util.Tx, error) {
+	return t.GetAllFrom(0)
+}
+
+func (t *memoryTxs) Del(txId *common.Uint256) error {
+	return t.db.update(delTx(txId))
+}
+
+func (t *memoryTxs) PutForkTxs(txs []*util.Tx, hash *common.Uint256) error {
+	ftxs := make([]util.Tx, 0, len(txs))
+	for _, tx := range txs {
+		ftxs = append(ftxs, *tx)
+	}
+	return t.db.update(func(data *memoryData) {
+		data.forkTxs[*hash] = ftxs
+	})
+}
+
+func (t *memoryTxs) GetForkTxs(hash *common.Uint256) ([]*util.Tx, error) {
+	t.db.RLock()
+	defer t.db.RUnlock()
+
+	ftxs, ok := t.db.data.forkTxs[*hash]
+	if !ok {
+		return nil, sql.ErrNoRows
+	}
+	txs := make([]*util.Tx, 0, len(ftxs))
+	for _, tx := range ftxs {
+		tx := tx
+		txs = append(txs, &tx)
+	}
+	return txs, nil
+}
+
+func (t *memoryTxs) Batch() TxsBatch {
+	return &memoryTxsBatch{t.db.newBatch()}
+}
+
+type memoryTxsBatch struct {
+	*memoryBatch
+}
+
+func (b *memoryTxsBatch) Put(tx *util.Tx) error {
+	return b.add(putTx(tx))
+}
+
+func (b *memoryTxsBatch) Del(txId *common.Uint256) error {
+	return b.add(delTx(txId))
+}
+
+func putUTXO(utxo *sutil.UTXO) func(data *memoryData) {
+	u, op := *utxo, *utxo.Op
+	u.Op = &op
+	return func(data *memoryData) {
+		data.utxos[op] = u
+	}
+}
+
+func delUTXO(op *util.OutPoint) func(data *memoryData) {
+	key := *op
+	return func(data *memoryData) {
+		delete(data.utxos, key)
+	}
+}
+
+type memoryUTXOs struct {
+	db *memoryDatabase
+}
+
+func (u *memoryUTXOs) Put(utxo *sutil.UTXO) error {
+	return u.db.update(putUTXO(utxo))
+}
+
+func (u *memoryUTXOs) Get(op *util.OutPoint) (*sutil.UTXO, error) {
+	u.db.RLock()
+	defer u.db.RUnlock()
+
+	utxo, ok := u.db.data.utxos[*op]
+	if !ok {
+		return nil, sql.ErrNoRows
+	}
+	return &utxo, nil
+}
+
+func (u *memoryUTXOs) GetAddrAll(hash *common.Uint168) ([]*sutil.UTXO, error) {
+	u.db.RLock()
+	defer u.db.RUnlock()
+
+	var utxos []*sutil.UTXO
+	for _, utxo := range u.db.data.utxos {
+		if utxo.Address == *hash {
+			utxo := utxo
+			utxos = append(utxos, &utxo)
+		}
+	}
+	return utxos, nil
+}
+
+func (u *memoryUTXOs) GetAll() ([]*sutil.UTXO, error) {
+	u.db.RLock()
+	defer u.db.RUnlock()
+
+	var utxos []*sutil.UTXO
+	for _, utxo := range u.db.data.utxos {
+		utxo := utxo
+		utxos = append(utxos, &utxo)
+	}
+	return utxos, nil
+}
+
+func (u *memoryUTXOs) Del(op *util.OutPoint) error {
+	return u.db.update(delUTXO(op))
+}
+
+func (u *memoryUTXOs) Batch() UTXOsBatch {
+	return &memoryUTXOsBatch{u.db.newBatch()}
+}
+
+type memoryUTXOsBatch struct {
+	*memoryBatch
+}
+
+func (b *memoryUTXOsBatch) Put(utxo *sutil.UTXO) error {
+	return b.add(putUTXO(utxo))
+}
+
+func (b *memoryUTXOsBatch) Del(op *util.OutPoint) error {
+	return b.add(delUTXO(op))
+}
+
+func putSTXO(stxo *sutil.STXO) func(data *memoryData) {
+	s, op := *stxo, *stxo.Op
+	s.Op = &op
+	return func(data *memoryData) {
+		data.stxos[op] = s
+	}
+}
+
+func delSTXO(op *util.OutPoint) func(data *memoryData) {
+	key := *op
+	return func(data *memoryData) {
+		delete(data.stxos, key)
+	}
+}
+
+type memorySTXOs struct {
+	db *memoryDatabase
+}
+
+func (s *memorySTXOs) Put(stxo *sutil.STXO) error {
+	return s.db.update(putSTXO(stxo))
+}
+
+func (s *memorySTXOs) Get(op *util.OutPoint) (*sutil.STXO, error) {
+	s.db.RLock()
+	defer s.db.RUnlock()
+
+	stxo, ok := s.db.data.stxos[*op]
+	if !ok {
+		return nil, sql.ErrNoRows
+	}
+	return &stxo, nil
+}
+
+func (s *memorySTXOs) GetAddrAll(hash *common.Uint168) ([]*sutil.STXO, error) {
+	s.db.RLock()
+	defer s.db.RUnlock()
+
+	var stxos []*sutil.STXO
+	for _, stxo := range s.db.data.stxos {
+		if stxo.Address == *hash {
+			stxo := stxo
+			stxos = append(stxos, &stxo)
+		}
+	}
+	return stxos, nil
+}
+
+func (s *memorySTXOs) GetAll() ([]*sutil.STXO, error) {
+	s.db.RLock()
+	defer s.db.RUnlock()
+
+	var stxos []*sutil.STXO
+	for _, stxo := range s.db.data.stxos {
+		stxo := stxo
+		stxos = append(stxos, &stxo)
+	}
+	return stxos, nil
+}
+
+func (s *memorySTXOs) Del(op *util.OutPoint) error {
+	return s.db.update(delSTXO(op))
+}
+
+func (s *memorySTXOs) Batch() STXOsBatch {
+	return &memorySTXOsBatch{s.db.newBatch()}
+}
+
+type memorySTXOsBatch struct {
+	*memoryBatch
+}
+
+func (b *memorySTXOsBatch) Put(stxo *sutil.STXO) error {
+	return b.add(putSTXO(stxo))
+}
+
+func (b *memorySTXOsBatch) Del(op *util.OutPoint) error {
+	return b.add(delSTXO(op))
+}
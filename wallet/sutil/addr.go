@@ -7,6 +7,7 @@ const (
 	TypeSub    = 1 << 1
 	TypeMulti  = 1 << 2
 	TypeNotify = 1 << 3
+	TypeWatch  = 1 << 4
 )
 
 type Addr struct {
@@ -46,6 +47,8 @@ func (addr *Addr) TypeName() string {
 		return "MULTI"
 	case TypeNotify:
 		return "NOTIFY"
+	case TypeWatch:
+		return "WATCH"
 	default:
 		return ""
 	}